@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"todo-app/internal/config"
+)
+
+// runConfig печатает действующую конфигурацию без секретов и сообщает,
+// прошла ли она проверку.
+func runConfig() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if err := cfg.WriteRedacted(os.Stdout); err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	return nil
+}
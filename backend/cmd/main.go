@@ -1,88 +1,117 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
-	"github.com/gorilla/mux"
-	"todo-app/internal/handlers"
-	"todo-app/internal/db"
-	"todo-app/internal/middleware"
+	"os/signal"
+	"syscall"
 	"time"
+	// Часовые пояса пользователей не должны зависеть от tzdata в образе
+	_ "time/tzdata"
+	"todo-app/internal/config"
+	"todo-app/internal/db"
+	"todo-app/internal/email"
+	"todo-app/internal/events"
+	"todo-app/internal/scheduler"
+	"todo-app/internal/server"
 	"todo-app/internal/models"
+	"todo-app/internal/webhook"
 )
 
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
-		
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-		next.ServeHTTP(w, r)
-	})
-}
+const (
+	// shutdownTimeout — сколько ждать завершения запросов при остановке.
+	shutdownTimeout = 10 * time.Second
+	// mailDrainTimeout — сколько ждать писем со ссылками, отправка которых
+	// уже началась.
+	mailDrainTimeout = time.Minute
+)
 
 func main() {
-	err := db.InitDB(
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_PORT"),
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_NAME"),
-	)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		if err := runConfig(); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Failed to load configuration: ", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
+	if cfg.IsDevelopment() && cfg.Auth.JWTSecret == config.PlaceholderSecret {
+		log.Println("WARNING: using the placeholder JWT secret; set JWT_SECRET before deploying")
+	}
+
+	conn, err := db.InitDB(cfg.Database)
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
-	r := mux.NewRouter()
-	r.Use(corsMiddleware)
-	
-	jwtSecret := []byte("your-secret-key")
-	authHandler := handlers.NewAuthHandler(string(jwtSecret))
-	taskHandler := handlers.NewTaskHandler()
-	notificationHandler := handlers.NewNotificationHandler()
-	categoryHandler := handlers.NewCategoryHandler()
+	store := models.NewPostgresStore(conn)
 
-	r.HandleFunc("/api/auth/login", authHandler.Login).Methods("POST", "OPTIONS")
-	r.HandleFunc("/api/auth/register", authHandler.Register).Methods("POST", "OPTIONS")
+	hub := events.NewHub()
+	go func() {
+		if err := events.Listen(context.Background(), cfg.Database.DSN(), hub); err != nil {
+			log.Fatal("Event listener stopped: ", err)
+		}
+	}()
 
-	taskRouter := r.PathPrefix("/api/tasks").Subrouter()
-	taskRouter.Use(middleware.AuthMiddleware(jwtSecret))
-	taskRouter.HandleFunc("", taskHandler.Create).Methods("POST", "OPTIONS")
-	taskRouter.HandleFunc("", taskHandler.List).Methods("GET", "OPTIONS")
-	taskRouter.HandleFunc("/{id}", taskHandler.Update).Methods("PUT", "OPTIONS")
-	taskRouter.HandleFunc("/{id}", taskHandler.Delete).Methods("DELETE", "OPTIONS")
+	var sender email.Sender
+	if cfg.Mail.Enabled() {
+		smtpSender, err := email.NewSMTPSender(cfg.Mail)
+		if err != nil {
+			log.Fatal("Invalid mail configuration: ", err)
+		}
+		sender = smtpSender
+		go email.NewDispatcher(cfg.Mail, store, sender).Run(context.Background())
+	} else {
+		// Ссылки из писем нужны для локальной проверки, но вне development
+		// не должны попадать в журнал
+		sender = email.LogSender{Body: cfg.IsDevelopment()}
+		log.Println("SMTP_HOST is not set; notification emails and digests are disabled, other emails are logged")
+	}
 
-	categoryRouter := r.PathPrefix("/api/categories").Subrouter()
-	categoryRouter.Use(middleware.AuthMiddleware(jwtSecret))
-	categoryRouter.HandleFunc("", categoryHandler.List).Methods("GET", "OPTIONS")
-	categoryRouter.HandleFunc("", categoryHandler.Create).Methods("POST", "OPTIONS")
-	categoryRouter.HandleFunc("/{id}", categoryHandler.Delete).Methods("DELETE", "OPTIONS")
-	categoryRouter.HandleFunc("/{id}/tasks", categoryHandler.GetTasks).Methods("GET", "OPTIONS")
-	categoryRouter.HandleFunc("/tasks/{id}", categoryHandler.UpdateTaskCategory).Methods("PUT", "OPTIONS")
+	outbox := email.NewOutbox(sender)
+	r := server.NewRouter(cfg, store, hub, outbox)
 
-	notificationRouter := r.PathPrefix("/api/notifications").Subrouter()
-	notificationRouter.Use(middleware.AuthMiddleware(jwtSecret))
-	notificationRouter.HandleFunc("", notificationHandler.List).Methods("GET", "OPTIONS")
-	notificationRouter.HandleFunc("/{id}/read", notificationHandler.MarkAsRead).Methods("POST", "OPTIONS")
-	notificationRouter.HandleFunc("/check", notificationHandler.CheckDueTasks).Methods("POST", "OPTIONS")
+	go scheduler.New(cfg, store).Run(context.Background())
+	go webhook.NewDispatcher(cfg.Webhooks, store).Run(context.Background())
 
+	srv := &http.Server{Addr: cfg.Server.Addr, Handler: r}
 	go func() {
-		ticker := time.NewTicker(15 * time.Minute)
-		for range ticker.C {
-			if err := models.CheckDueTasks(); err != nil {
-				log.Printf("Error checking due tasks: %v", err)
-			}
+		log.Printf("Server starting on %s...", cfg.Server.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
 		}
 	}()
 
-	log.Println("Server starting on port 8080...")
-	if err := http.ListenAndServe(":8080", r); err != nil {
-		log.Fatal(err)
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	<-stop
+	log.Println("Shutting down...")
+
+	// Потоки уведомлений сами не завершаются, поэтому ожидание запросов
+	// ограничено отдельно от ожидания писем
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("Error shutting down server: %v", err)
+	}
+	mailCtx, mailCancel := context.WithTimeout(context.Background(), mailDrainTimeout)
+	defer mailCancel()
+	if err := outbox.Wait(mailCtx); err != nil {
+		log.Printf("Some emails were not sent before shutdown: %v", err)
 	}
 } 
\ No newline at end of file
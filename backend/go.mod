@@ -7,4 +7,5 @@ require (
 	github.com/gorilla/mux v1.8.1
 	github.com/lib/pq v1.10.9
 	golang.org/x/crypto v0.17.0
-) 
\ No newline at end of file
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,129 @@
+// Package apierror формирует единый JSON-ответ об ошибке:
+// {"code": ..., "message": ..., "details": ..., "request_id": ...}.
+package apierror
+
+import (
+    "encoding/json"
+    "errors"
+    "log"
+    "net/http"
+    "todo-app/internal/models"
+    "todo-app/internal/requestid"
+)
+
+// Коды стабильны: клиент ветвится по ним, а не по тексту сообщения.
+const (
+    CodeInvalidRequest     = "invalid_request"
+    CodeValidation         = "validation_failed"
+    CodeUnauthorized       = "unauthorized"
+    CodeForbidden          = "forbidden"
+    CodeEmailNotVerified   = "email_not_verified"
+    CodeNotFound           = "not_found"
+    CodeMethodNotAllowed   = "method_not_allowed"
+    CodeConflict           = "conflict"
+    CodePreconditionFailed = "precondition_failed"
+    CodeInternal           = "internal_error"
+)
+
+type Response struct {
+    Code      string      `json:"code"`
+    Message   string      `json:"message"`
+    Details   interface{} `json:"details,omitempty"`
+    RequestID string      `json:"request_id,omitempty"`
+}
+
+func Write(w http.ResponseWriter, r *http.Request, status int, code, message string, details interface{}) {
+    writeResponse(w, status, newResponse(r, code, message, details))
+}
+
+func newResponse(r *http.Request, code, message string, details interface{}) Response {
+    return Response{
+        Code:      code,
+        Message:   message,
+        Details:   details,
+        RequestID: requestid.FromContext(r.Context()),
+    }
+}
+
+func writeResponse(w http.ResponseWriter, status int, resp Response) {
+    w.Header().Set("Content-Type", "application/json")
+    w.Header().Set("X-Content-Type-Options", "nosniff")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(resp)
+}
+
+// BadRequest — запрос не удалось разобрать (битый JSON, нечисловой id).
+func BadRequest(w http.ResponseWriter, r *http.Request, message string) {
+    Write(w, r, http.StatusBadRequest, CodeInvalidRequest, message, nil)
+}
+
+// Invalid — запрос разобран, но значение поля недопустимо.
+func Invalid(w http.ResponseWriter, r *http.Request, field, message string) {
+    var details interface{}
+    if field != "" {
+        details = map[string]string{field: message}
+    }
+    Write(w, r, http.StatusUnprocessableEntity, CodeValidation, message, details)
+}
+
+func Unauthorized(w http.ResponseWriter, r *http.Request, message string) {
+    Write(w, r, http.StatusUnauthorized, CodeUnauthorized, message, nil)
+}
+
+func NotFound(w http.ResponseWriter, r *http.Request, message string) {
+    Write(w, r, http.StatusNotFound, CodeNotFound, message, nil)
+}
+
+// Internal логирует причину вместе с идентификатором запроса; клиент
+// получает только общее сообщение.
+func Internal(w http.ResponseWriter, r *http.Request, err error, message string) {
+    log.Printf("[%s] %s: %v", requestid.FromContext(r.Context()), message, err)
+    Write(w, r, http.StatusInternalServerError, CodeInternal, message, nil)
+}
+
+// FromError выбирает ответ по виду доменной ошибки. notFound используется для
+// models.ErrNotFound, fallback — для всех непредвиденных ошибок.
+func FromError(w http.ResponseWriter, r *http.Request, err error, notFound, fallback string) {
+    status, resp := Describe(r, err, notFound, fallback)
+    writeResponse(w, status, resp)
+}
+
+// Describe возвращает статус и тело ответа, которые выбрал бы FromError, не
+// записывая их: так ошибки отдельных операций пачки попадают в общий ответ.
+// Непредвиденная ошибка логируется, как в Internal.
+func Describe(r *http.Request, err error, notFound, fallback string) (int, Response) {
+    var validation *models.ValidationError
+    var conflict *models.ConflictError
+    invalid := func(field, message string) (int, Response) {
+        var details interface{}
+        if field != "" {
+            details = map[string]string{field: message}
+        }
+        return http.StatusUnprocessableEntity, newResponse(r, CodeValidation, message, details)
+    }
+    switch {
+    case errors.Is(err, models.ErrInvalidCategory):
+        return http.StatusNotFound, newResponse(r, CodeNotFound, "Category not found", map[string]string{"category_id": "category not found"})
+    case errors.Is(err, models.ErrNotFound):
+        return http.StatusNotFound, newResponse(r, CodeNotFound, notFound, nil)
+    case errors.As(err, &validation):
+        return invalid(validation.Field, validation.Message)
+    case errors.As(err, &conflict):
+        var details interface{}
+        if conflict.Field != "" {
+            details = map[string]string{conflict.Field: conflict.Message}
+        }
+        return http.StatusConflict, newResponse(r, CodeConflict, conflict.Message, details)
+    case errors.Is(err, models.ErrValidation):
+        return invalid("", err.Error())
+    case errors.Is(err, models.ErrConflict):
+        return http.StatusConflict, newResponse(r, CodeConflict, err.Error(), nil)
+    case errors.Is(err, models.ErrVersionMismatch):
+        return http.StatusPreconditionFailed, newResponse(r, CodePreconditionFailed, "Resource has been modified", nil)
+    case errors.Is(err, models.ErrForbidden):
+        return http.StatusForbidden, newResponse(r, CodeForbidden, "Forbidden", nil)
+    default:
+        log.Printf("[%s] %s: %v", requestid.FromContext(r.Context()), fallback, err)
+        return http.StatusInternalServerError, newResponse(r, CodeInternal, fallback, nil)
+    }
+}
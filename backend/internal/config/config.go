@@ -0,0 +1,477 @@
+// Package config собирает настройки сервера из значений по умолчанию,
+// необязательного YAML-файла (CONFIG_FILE) и переменных окружения —
+// именно в таком порядке приоритета.
+package config
+
+import (
+    "errors"
+    "fmt"
+    "io"
+    "net/mail"
+    "net/url"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+    "gopkg.in/yaml.v3"
+)
+
+const (
+    EnvDevelopment = "development"
+    EnvProduction  = "production"
+
+    // PlaceholderSecret допустим только в режиме разработки.
+    PlaceholderSecret = "your-secret-key"
+    minSecretLength   = 32
+
+    redacted = "******"
+)
+
+type Config struct {
+    Env           string              `yaml:"env"`
+    Server        ServerConfig        `yaml:"server"`
+    Database      DatabaseConfig      `yaml:"database"`
+    Auth          AuthConfig          `yaml:"auth"`
+    CORS          CORSConfig          `yaml:"cors"`
+    Scheduler     SchedulerConfig     `yaml:"scheduler"`
+    Stream        StreamConfig        `yaml:"stream"`
+    Notifications NotificationsConfig `yaml:"notifications"`
+    Mail          MailConfig          `yaml:"mail"`
+    Webhooks      WebhooksConfig      `yaml:"webhooks"`
+    Trash         TrashConfig         `yaml:"trash"`
+}
+
+type ServerConfig struct {
+    Addr string `yaml:"addr"`
+}
+
+type DatabaseConfig struct {
+    Host            string        `yaml:"host"`
+    Port            string        `yaml:"port"`
+    User            string        `yaml:"user"`
+    Password        string        `yaml:"password"`
+    Name            string        `yaml:"name"`
+    SSLMode         string        `yaml:"sslmode"`
+    MaxOpenConns    int           `yaml:"max_open_conns"`
+    MaxIdleConns    int           `yaml:"max_idle_conns"`
+    ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+    ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+}
+
+type AuthConfig struct {
+    JWTSecret       string        `yaml:"jwt_secret"`
+    AccessTokenTTL  time.Duration `yaml:"access_token_ttl"`
+    RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl"`
+    // VerifyTokenTTL и ResetTokenTTL — сколько действуют ссылки из писем.
+    VerifyTokenTTL  time.Duration `yaml:"verify_token_ttl"`
+    ResetTokenTTL   time.Duration `yaml:"reset_token_ttl"`
+}
+
+type CORSConfig struct {
+    AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+type SchedulerConfig struct {
+    DueTasksInterval time.Duration `yaml:"due_tasks_interval"`
+}
+
+type StreamConfig struct {
+    Heartbeat      time.Duration `yaml:"heartbeat"`
+    EventRetention time.Duration `yaml:"event_retention"`
+}
+
+type NotificationsConfig struct {
+    // ReadRetention — сколько хранить прочитанные уведомления.
+    ReadRetention time.Duration `yaml:"read_retention"`
+}
+
+// MailConfig настраивает доставку уведомлений и сводок по почте. Без
+// SMTPHost письма не отправляются.
+type MailConfig struct {
+    SMTPHost     string        `yaml:"smtp_host"`
+    SMTPPort     string        `yaml:"smtp_port"`
+    SMTPUser     string        `yaml:"smtp_user"`
+    SMTPPassword string        `yaml:"smtp_password"`
+    From         string        `yaml:"from"`
+    // AppURL — адрес веб-клиента для ссылок в письмах.
+    AppURL       string        `yaml:"app_url"`
+    Interval     time.Duration `yaml:"interval"`
+    MaxAttempts  int           `yaml:"max_attempts"`
+    // RetryBackoff — пауза после первой неудачи; дальше она удваивается.
+    RetryBackoff time.Duration `yaml:"retry_backoff"`
+    // MaxAge — уведомления старше не отправляются, даже если ждут в очереди.
+    MaxAge       time.Duration `yaml:"max_age"`
+    // DigestHour — локальный час пользователя, начиная с которого уходит
+    // ежедневная сводка; -1 отключает сводки.
+    DigestHour   int           `yaml:"digest_hour"`
+}
+
+// WebhooksConfig настраивает отправку событий на вебхуки пользователей.
+type WebhooksConfig struct {
+    Interval     time.Duration `yaml:"interval"`
+    // Timeout ограничивает один запрос к получателю.
+    Timeout      time.Duration `yaml:"timeout"`
+    MaxAttempts  int           `yaml:"max_attempts"`
+    // RetryBackoff — пауза после первой неудачи; дальше она удваивается.
+    RetryBackoff time.Duration `yaml:"retry_backoff"`
+    // LogRetention — сколько хранить завершённые доставки в журнале.
+    LogRetention time.Duration `yaml:"log_retention"`
+}
+
+type TrashConfig struct {
+    // Retention — сколько удалённые задачи и категории лежат в корзине.
+    Retention time.Duration `yaml:"retention"`
+}
+
+func Default() *Config {
+    return &Config{
+        Env: EnvProduction,
+        Server: ServerConfig{
+            Addr: ":8080",
+        },
+        Database: DatabaseConfig{
+            Host:            "localhost",
+            Port:            "5432",
+            User:            "postgres",
+            Name:            "todoapp",
+            SSLMode:         "disable",
+            MaxOpenConns:    25,
+            MaxIdleConns:    5,
+            ConnMaxLifetime: 30 * time.Minute,
+            ConnMaxIdleTime: 5 * time.Minute,
+        },
+        Auth: AuthConfig{
+            JWTSecret:       PlaceholderSecret,
+            AccessTokenTTL:  15 * time.Minute,
+            RefreshTokenTTL: 30 * 24 * time.Hour,
+            VerifyTokenTTL:  48 * time.Hour,
+            ResetTokenTTL:   time.Hour,
+        },
+        CORS: CORSConfig{
+            AllowedOrigins: []string{"http://localhost:3000"},
+        },
+        Scheduler: SchedulerConfig{
+            DueTasksInterval: time.Minute,
+        },
+        Stream: StreamConfig{
+            Heartbeat:      25 * time.Second,
+            EventRetention: 72 * time.Hour,
+        },
+        Notifications: NotificationsConfig{
+            ReadRetention: 30 * 24 * time.Hour,
+        },
+        Mail: MailConfig{
+            SMTPPort:     "587",
+            AppURL:       "http://localhost:3000",
+            Interval:     30 * time.Second,
+            MaxAttempts:  5,
+            RetryBackoff: time.Minute,
+            MaxAge:       24 * time.Hour,
+            DigestHour:   8,
+        },
+        Webhooks: WebhooksConfig{
+            Interval:     10 * time.Second,
+            Timeout:      10 * time.Second,
+            MaxAttempts:  8,
+            RetryBackoff: 30 * time.Second,
+            LogRetention: 30 * 24 * time.Hour,
+        },
+        Trash: TrashConfig{
+            Retention: 30 * 24 * time.Hour,
+        },
+    }
+}
+
+// Load читает конфигурацию, но не проверяет её: это делает Validate.
+func Load() (*Config, error) {
+    return load(os.LookupEnv)
+}
+
+func load(lookup func(string) (string, bool)) (*Config, error) {
+    cfg := Default()
+
+    if path, ok := lookup("CONFIG_FILE"); ok && path != "" {
+        file, err := os.Open(path)
+        if err != nil {
+            return nil, fmt.Errorf("read config file: %w", err)
+        }
+        defer file.Close()
+
+        decoder := yaml.NewDecoder(file)
+        decoder.KnownFields(true)
+        if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+            return nil, fmt.Errorf("parse config file %s: %w", path, err)
+        }
+    }
+
+    for _, f := range cfg.fields() {
+        value, ok := lookup(f.env)
+        if !ok {
+            continue
+        }
+        if err := f.set(value); err != nil {
+            return nil, fmt.Errorf("%s: %w", f.env, err)
+        }
+    }
+    return cfg, nil
+}
+
+// field связывает переменную окружения с полем конфигурации.
+type field struct {
+    env    string
+    value  interface{}
+    secret bool
+}
+
+func (c *Config) fields() []field {
+    return []field{
+        {"APP_ENV", &c.Env, false},
+        {"HTTP_ADDR", &c.Server.Addr, false},
+        {"DB_HOST", &c.Database.Host, false},
+        {"DB_PORT", &c.Database.Port, false},
+        {"DB_USER", &c.Database.User, false},
+        {"DB_PASSWORD", &c.Database.Password, true},
+        {"DB_NAME", &c.Database.Name, false},
+        {"DB_SSLMODE", &c.Database.SSLMode, false},
+        {"DB_MAX_OPEN_CONNS", &c.Database.MaxOpenConns, false},
+        {"DB_MAX_IDLE_CONNS", &c.Database.MaxIdleConns, false},
+        {"DB_CONN_MAX_LIFETIME", &c.Database.ConnMaxLifetime, false},
+        {"DB_CONN_MAX_IDLE_TIME", &c.Database.ConnMaxIdleTime, false},
+        {"JWT_SECRET", &c.Auth.JWTSecret, true},
+        {"ACCESS_TOKEN_TTL", &c.Auth.AccessTokenTTL, false},
+        {"REFRESH_TOKEN_TTL", &c.Auth.RefreshTokenTTL, false},
+        {"VERIFY_TOKEN_TTL", &c.Auth.VerifyTokenTTL, false},
+        {"RESET_TOKEN_TTL", &c.Auth.ResetTokenTTL, false},
+        {"CORS_ORIGIN", &c.CORS.AllowedOrigins, false},
+        {"DUE_TASKS_INTERVAL", &c.Scheduler.DueTasksInterval, false},
+        {"STREAM_HEARTBEAT", &c.Stream.Heartbeat, false},
+        {"EVENT_RETENTION", &c.Stream.EventRetention, false},
+        {"NOTIFICATION_RETENTION", &c.Notifications.ReadRetention, false},
+        {"SMTP_HOST", &c.Mail.SMTPHost, false},
+        {"SMTP_PORT", &c.Mail.SMTPPort, false},
+        {"SMTP_USER", &c.Mail.SMTPUser, false},
+        {"SMTP_PASSWORD", &c.Mail.SMTPPassword, true},
+        {"MAIL_FROM", &c.Mail.From, false},
+        {"APP_URL", &c.Mail.AppURL, false},
+        {"MAIL_INTERVAL", &c.Mail.Interval, false},
+        {"MAIL_MAX_ATTEMPTS", &c.Mail.MaxAttempts, false},
+        {"MAIL_RETRY_BACKOFF", &c.Mail.RetryBackoff, false},
+        {"MAIL_MAX_AGE", &c.Mail.MaxAge, false},
+        {"DIGEST_HOUR", &c.Mail.DigestHour, false},
+        {"WEBHOOK_INTERVAL", &c.Webhooks.Interval, false},
+        {"WEBHOOK_TIMEOUT", &c.Webhooks.Timeout, false},
+        {"WEBHOOK_MAX_ATTEMPTS", &c.Webhooks.MaxAttempts, false},
+        {"WEBHOOK_RETRY_BACKOFF", &c.Webhooks.RetryBackoff, false},
+        {"WEBHOOK_LOG_RETENTION", &c.Webhooks.LogRetention, false},
+        {"TRASH_RETENTION", &c.Trash.Retention, false},
+    }
+}
+
+func (f field) set(value string) error {
+    value = strings.TrimSpace(value)
+    switch p := f.value.(type) {
+    case *string:
+        *p = value
+    case *int:
+        n, err := strconv.Atoi(value)
+        if err != nil {
+            return fmt.Errorf("invalid integer %q", value)
+        }
+        *p = n
+    case *time.Duration:
+        d, err := time.ParseDuration(value)
+        if err != nil {
+            return fmt.Errorf("invalid duration %q", value)
+        }
+        *p = d
+    case *[]string:
+        var items []string
+        for _, item := range strings.Split(value, ",") {
+            if item = strings.TrimSpace(item); item != "" {
+                items = append(items, item)
+            }
+        }
+        *p = items
+    }
+    return nil
+}
+
+func (f field) String() string {
+    var s string
+    switch p := f.value.(type) {
+    case *string:
+        s = *p
+    case *int:
+        s = strconv.Itoa(*p)
+    case *time.Duration:
+        s = p.String()
+    case *[]string:
+        s = strings.Join(*p, ",")
+    }
+    if f.secret && s != "" {
+        return redacted
+    }
+    return s
+}
+
+// WriteRedacted печатает действующую конфигурацию в виде переменных
+// окружения, скрывая секреты.
+func (c *Config) WriteRedacted(w io.Writer) error {
+    for _, f := range c.fields() {
+        if _, err := fmt.Fprintf(w, "%s=%s\n", f.env, f); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (c *Config) IsDevelopment() bool {
+    return c.Env == EnvDevelopment
+}
+
+// Validate возвращает все найденные ошибки сразу.
+func (c *Config) Validate() error {
+    var errs []error
+    if c.Env != EnvDevelopment && c.Env != EnvProduction {
+        errs = append(errs, fmt.Errorf("APP_ENV must be %q or %q, got %q", EnvDevelopment, EnvProduction, c.Env))
+    }
+    if c.Server.Addr == "" {
+        errs = append(errs, errors.New("HTTP_ADDR is required"))
+    }
+    errs = append(errs, c.Database.Validate())
+    errs = append(errs, c.validateAuth())
+    errs = append(errs, c.CORS.validate())
+    if c.Scheduler.DueTasksInterval <= 0 {
+        errs = append(errs, errors.New("DUE_TASKS_INTERVAL must be positive"))
+    }
+    if c.Stream.Heartbeat <= 0 || c.Stream.EventRetention <= 0 {
+        errs = append(errs, errors.New("STREAM_HEARTBEAT and EVENT_RETENTION must be positive"))
+    }
+    if c.Notifications.ReadRetention <= 0 {
+        errs = append(errs, errors.New("NOTIFICATION_RETENTION must be positive"))
+    }
+    errs = append(errs, c.Mail.validate())
+    errs = append(errs, c.Webhooks.validate())
+    if c.Trash.Retention <= 0 {
+        errs = append(errs, errors.New("TRASH_RETENTION must be positive"))
+    }
+    return errors.Join(errs...)
+}
+
+func (c DatabaseConfig) Validate() error {
+    var errs []error
+    if c.Host == "" || c.Port == "" || c.User == "" || c.Name == "" {
+        errs = append(errs, errors.New("DB_HOST, DB_PORT, DB_USER and DB_NAME are required"))
+    }
+    if c.MaxOpenConns < 0 || c.MaxIdleConns < 0 {
+        errs = append(errs, errors.New("DB_MAX_OPEN_CONNS and DB_MAX_IDLE_CONNS must not be negative"))
+    }
+    if c.MaxOpenConns > 0 && c.MaxIdleConns > c.MaxOpenConns {
+        errs = append(errs, errors.New("DB_MAX_IDLE_CONNS must not exceed DB_MAX_OPEN_CONNS"))
+    }
+    if c.ConnMaxLifetime < 0 || c.ConnMaxIdleTime < 0 {
+        errs = append(errs, errors.New("DB_CONN_MAX_LIFETIME and DB_CONN_MAX_IDLE_TIME must not be negative"))
+    }
+    return errors.Join(errs...)
+}
+
+// DSN возвращает строку подключения lib/pq; значения берутся в кавычки,
+// чтобы пароль с пробелами или апострофами не ломал разбор.
+func (c DatabaseConfig) DSN() string {
+    quote := func(s string) string {
+        return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+    }
+    return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+        quote(c.Host), quote(c.Port), quote(c.User), quote(c.Password), quote(c.Name), quote(c.SSLMode))
+}
+
+func (c *Config) validateAuth() error {
+    var errs []error
+    if !c.IsDevelopment() {
+        switch {
+        case c.Auth.JWTSecret == PlaceholderSecret:
+            errs = append(errs, errors.New("JWT_SECRET must be changed from the placeholder outside development"))
+        case len(c.Auth.JWTSecret) < minSecretLength:
+            errs = append(errs, fmt.Errorf("JWT_SECRET must be at least %d bytes outside development", minSecretLength))
+        }
+    } else if c.Auth.JWTSecret == "" {
+        errs = append(errs, errors.New("JWT_SECRET is required"))
+    }
+    if c.Auth.AccessTokenTTL <= 0 || c.Auth.RefreshTokenTTL <= 0 {
+        errs = append(errs, errors.New("ACCESS_TOKEN_TTL and REFRESH_TOKEN_TTL must be positive"))
+    } else if c.Auth.AccessTokenTTL >= c.Auth.RefreshTokenTTL {
+        errs = append(errs, errors.New("ACCESS_TOKEN_TTL must be shorter than REFRESH_TOKEN_TTL"))
+    }
+    if c.Auth.VerifyTokenTTL <= 0 || c.Auth.ResetTokenTTL <= 0 {
+        errs = append(errs, errors.New("VERIFY_TOKEN_TTL and RESET_TOKEN_TTL must be positive"))
+    }
+    return errors.Join(errs...)
+}
+
+func (c CORSConfig) validate() error {
+    if len(c.AllowedOrigins) == 0 {
+        return errors.New("CORS_ORIGIN must list at least one origin")
+    }
+    var errs []error
+    for _, origin := range c.AllowedOrigins {
+        // Запросы отправляются с credentials, поэтому "*" браузер не примет
+        u, err := url.Parse(origin)
+        if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+            errs = append(errs, fmt.Errorf("CORS_ORIGIN: invalid origin %q", origin))
+        }
+    }
+    return errors.Join(errs...)
+}
+
+// Enabled сообщает, настроена ли отправка писем.
+func (c MailConfig) Enabled() bool {
+    return c.SMTPHost != ""
+}
+
+func (c MailConfig) validate() error {
+    if !c.Enabled() {
+        return nil
+    }
+    var errs []error
+    if _, err := strconv.Atoi(c.SMTPPort); err != nil {
+        errs = append(errs, fmt.Errorf("SMTP_PORT: invalid port %q", c.SMTPPort))
+    }
+    if _, err := mail.ParseAddress(c.From); err != nil {
+        errs = append(errs, fmt.Errorf("MAIL_FROM: invalid address %q", c.From))
+    }
+    if u, err := url.Parse(c.AppURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+        errs = append(errs, fmt.Errorf("APP_URL: invalid URL %q", c.AppURL))
+    }
+    if c.Interval <= 0 || c.RetryBackoff <= 0 || c.MaxAge <= 0 {
+        errs = append(errs, errors.New("MAIL_INTERVAL, MAIL_RETRY_BACKOFF and MAIL_MAX_AGE must be positive"))
+    }
+    if c.MaxAttempts < 1 {
+        errs = append(errs, errors.New("MAIL_MAX_ATTEMPTS must be at least 1"))
+    }
+    if c.DigestHour < -1 || c.DigestHour > 23 {
+        errs = append(errs, errors.New("DIGEST_HOUR must be between 0 and 23, or -1 to disable digests"))
+    }
+    return errors.Join(errs...)
+}
+
+func (c WebhooksConfig) validate() error {
+    var errs []error
+    if c.Interval <= 0 || c.Timeout <= 0 || c.RetryBackoff <= 0 || c.LogRetention <= 0 {
+        errs = append(errs, errors.New("WEBHOOK_INTERVAL, WEBHOOK_TIMEOUT, WEBHOOK_RETRY_BACKOFF and WEBHOOK_LOG_RETENTION must be positive"))
+    }
+    if c.MaxAttempts < 1 {
+        errs = append(errs, errors.New("WEBHOOK_MAX_ATTEMPTS must be at least 1"))
+    }
+    return errors.Join(errs...)
+}
+
+// AllowsOrigin сообщает, разрешён ли источник запроса.
+func (c CORSConfig) AllowsOrigin(origin string) bool {
+    origin = strings.TrimSuffix(origin, "/")
+    for _, allowed := range c.AllowedOrigins {
+        if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,222 @@
+package config
+
+import (
+    "bytes"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+// env подменяет os.LookupEnv набором переменных.
+func env(vars map[string]string) func(string) (string, bool) {
+    return func(name string) (string, bool) {
+        value, ok := vars[name]
+        return value, ok
+    }
+}
+
+func writeFile(t *testing.T, content string) string {
+    t.Helper()
+    path := filepath.Join(t.TempDir(), "config.yaml")
+    if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+        t.Fatalf("write config file: %v", err)
+    }
+    return path
+}
+
+// validConfig проходит Validate в боевом режиме.
+func validConfig() *Config {
+    cfg := Default()
+    cfg.Auth.JWTSecret = strings.Repeat("s", minSecretLength)
+    return cfg
+}
+
+func TestLoadDefaults(t *testing.T) {
+    cfg, err := load(env(nil))
+    if err != nil {
+        t.Fatalf("load: %v", err)
+    }
+    if cfg.Env != EnvProduction || cfg.Auth.JWTSecret != PlaceholderSecret || cfg.Server.Addr != ":8080" {
+        t.Errorf("defaults = %+v", cfg)
+    }
+}
+
+func TestLoadPrecedence(t *testing.T) {
+    path := writeFile(t, `
+env: development
+server:
+  addr: ":9000"
+database:
+  host: db.internal
+  max_open_conns: 10
+auth:
+  jwt_secret: from-file
+  access_token_ttl: 5m
+cors:
+  allowed_origins: ["https://file.example.com"]
+`)
+    cfg, err := load(env(map[string]string{
+        "CONFIG_FILE":       path,
+        "DB_HOST":           " db.env ",
+        "JWT_SECRET":        "from-env",
+        "REFRESH_TOKEN_TTL": "72h",
+        "CORS_ORIGIN":       "https://a.example.com, ,https://b.example.com",
+        "DIGEST_HOUR":       "-1",
+    }))
+    if err != nil {
+        t.Fatalf("load: %v", err)
+    }
+
+    // Файл перекрывает значения по умолчанию, окружение — файл
+    tests := []struct {
+        name      string
+        got, want interface{}
+    }{
+        {"env", cfg.Env, EnvDevelopment},
+        {"addr", cfg.Server.Addr, ":9000"},
+        {"db host", cfg.Database.Host, "db.env"},
+        {"db port", cfg.Database.Port, "5432"},
+        {"max open conns", cfg.Database.MaxOpenConns, 10},
+        {"jwt secret", cfg.Auth.JWTSecret, "from-env"},
+        {"access ttl", cfg.Auth.AccessTokenTTL, 5 * time.Minute},
+        {"refresh ttl", cfg.Auth.RefreshTokenTTL, 72 * time.Hour},
+        {"cors", strings.Join(cfg.CORS.AllowedOrigins, " "), "https://a.example.com https://b.example.com"},
+        {"digest hour", cfg.Mail.DigestHour, -1},
+    }
+    for _, tt := range tests {
+        if tt.got != tt.want {
+            t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+        }
+    }
+}
+
+func TestLoadErrors(t *testing.T) {
+    tests := []struct {
+        name string
+        vars map[string]string
+        want string
+    }{
+        {"bad duration", map[string]string{"ACCESS_TOKEN_TTL": "15"}, "ACCESS_TOKEN_TTL"},
+        {"bad integer", map[string]string{"DB_MAX_OPEN_CONNS": "many"}, "DB_MAX_OPEN_CONNS"},
+        {"missing file", map[string]string{"CONFIG_FILE": filepath.Join(t.TempDir(), "missing.yaml")}, "read config file"},
+        {"unknown yaml key", map[string]string{"CONFIG_FILE": writeFile(t, "auth:\n  jwt_secrt: typo\n")}, "jwt_secrt"},
+        {"bad yaml duration", map[string]string{"CONFIG_FILE": writeFile(t, "auth:\n  access_token_ttl: soon\n")}, "parse config file"},
+    }
+    for _, tt := range tests {
+        if _, err := load(env(tt.vars)); err == nil || !strings.Contains(err.Error(), tt.want) {
+            t.Errorf("%s: load = %v, want an error mentioning %q", tt.name, err, tt.want)
+        }
+    }
+
+    // Пустой файл оставляет значения по умолчанию
+    if cfg, err := load(env(map[string]string{"CONFIG_FILE": writeFile(t, "")})); err != nil || cfg.Server.Addr != ":8080" {
+        t.Errorf("load with an empty file = %+v, %v", cfg, err)
+    }
+}
+
+func TestValidate(t *testing.T) {
+    if err := validConfig().Validate(); err != nil {
+        t.Fatalf("valid config: %v", err)
+    }
+
+    tests := []struct {
+        name   string
+        modify func(*Config)
+        want   string
+    }{
+        {"placeholder secret", func(c *Config) { c.Auth.JWTSecret = PlaceholderSecret }, "placeholder"},
+        {"short secret", func(c *Config) { c.Auth.JWTSecret = "short" }, "at least 32 bytes"},
+        {"empty secret", func(c *Config) { c.Auth.JWTSecret = "" }, "JWT_SECRET"},
+        {"empty secret in development", func(c *Config) { c.Env = EnvDevelopment; c.Auth.JWTSecret = "" }, "JWT_SECRET is required"},
+        {"unknown env", func(c *Config) { c.Env = "staging" }, "APP_ENV"},
+        {"access ttl not shorter", func(c *Config) { c.Auth.AccessTokenTTL = c.Auth.RefreshTokenTTL }, "shorter"},
+        {"idle over open conns", func(c *Config) { c.Database.MaxIdleConns = 30 }, "DB_MAX_IDLE_CONNS"},
+        {"wildcard origin", func(c *Config) { c.CORS.AllowedOrigins = []string{"*"} }, "invalid origin"},
+        {"mail without sender", func(c *Config) { c.Mail.SMTPHost = "smtp.example.com" }, "MAIL_FROM"},
+        {"digest hour", func(c *Config) { c.Mail.SMTPHost, c.Mail.From, c.Mail.DigestHour = "smtp.example.com", "todo@example.com", 24 }, "DIGEST_HOUR"},
+        {"webhook attempts", func(c *Config) { c.Webhooks.MaxAttempts = 0 }, "WEBHOOK_MAX_ATTEMPTS"},
+    }
+    for _, tt := range tests {
+        cfg := validConfig()
+        tt.modify(cfg)
+        if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+            t.Errorf("%s: Validate = %v, want an error mentioning %q", tt.name, err, tt.want)
+        }
+    }
+
+    // В режиме разработки слабый ключ допустим
+    for _, secret := range []string{PlaceholderSecret, "short"} {
+        cfg := validConfig()
+        cfg.Env = EnvDevelopment
+        cfg.Auth.JWTSecret = secret
+        if err := cfg.Validate(); err != nil {
+            t.Errorf("development with secret %q: %v", secret, err)
+        }
+    }
+
+    // Все ошибки возвращаются сразу
+    cfg := validConfig()
+    cfg.Auth.JWTSecret = PlaceholderSecret
+    cfg.Server.Addr = ""
+    if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "JWT_SECRET") || !strings.Contains(err.Error(), "HTTP_ADDR") {
+        t.Errorf("Validate = %v, want both errors", err)
+    }
+}
+
+func TestWriteRedacted(t *testing.T) {
+    cfg := validConfig()
+    cfg.Auth.JWTSecret = "jwt-" + strings.Repeat("x", minSecretLength)
+    cfg.Database.Password = "db-password"
+    cfg.Mail.SMTPPassword = "smtp-password"
+    cfg.CORS.AllowedOrigins = []string{"https://a.example.com", "https://b.example.com"}
+
+    var out bytes.Buffer
+    if err := cfg.WriteRedacted(&out); err != nil {
+        t.Fatalf("WriteRedacted: %v", err)
+    }
+    text := out.String()
+    for _, secret := range []string{cfg.Auth.JWTSecret, "db-password", "smtp-password"} {
+        if strings.Contains(text, secret) {
+            t.Errorf("output leaks %q:\n%s", secret, text)
+        }
+    }
+    for _, line := range []string{
+        "JWT_SECRET=" + redacted,
+        "DB_PASSWORD=" + redacted,
+        "SMTP_PASSWORD=" + redacted,
+        "DB_HOST=localhost",
+        "ACCESS_TOKEN_TTL=15m0s",
+        "CORS_ORIGIN=https://a.example.com,https://b.example.com",
+    } {
+        if !strings.Contains(text, line+"\n") {
+            t.Errorf("output lacks %q:\n%s", line, text)
+        }
+    }
+
+    // Незаданный секрет печатается пустым, чтобы было видно, что его нет
+    cfg.Mail.SMTPPassword = ""
+    out.Reset()
+    cfg.WriteRedacted(&out)
+    if !strings.Contains(out.String(), "SMTP_PASSWORD=\n") {
+        t.Errorf("empty secret is not printed as empty:\n%s", out.String())
+    }
+
+    // Вывод читается обратно как окружение
+    vars := map[string]string{}
+    for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+        name, value, _ := strings.Cut(line, "=")
+        if value != redacted {
+            vars[name] = value
+        }
+    }
+    loaded, err := load(env(vars))
+    if err != nil {
+        t.Fatalf("load the printed config: %v", err)
+    }
+    if loaded.Auth.AccessTokenTTL != cfg.Auth.AccessTokenTTL || loaded.Mail.DigestHour != cfg.Mail.DigestHour ||
+        strings.Join(loaded.CORS.AllowedOrigins, ",") != strings.Join(cfg.CORS.AllowedOrigins, ",") {
+        t.Errorf("printed config does not load back: %+v", loaded)
+    }
+}
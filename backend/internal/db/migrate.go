@@ -0,0 +1,322 @@
+package db
+
+import (
+    "context"
+    "crypto/sha256"
+    "database/sql"
+    "embed"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io/fs"
+    "path"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Ключ advisory lock, под которым выполняются миграции. Общий для всех реплик.
+const migrationLockKey int64 = 0x746f646f6d6967
+
+var (
+    ErrChecksumMismatch = errors.New("applied migration checksum mismatch")
+    ErrIrreversible     = errors.New("migration has no down script")
+    ErrUnknownMigration = errors.New("applied migration is missing from the source tree")
+)
+
+type Migration struct {
+    Version  int64
+    Name     string
+    Up       string
+    Down     string
+    Checksum string
+}
+
+type MigrationStatus struct {
+    Version   int64
+    Name      string
+    Applied   bool
+    AppliedAt *time.Time
+    Checksum  string
+    Dirty     bool
+}
+
+type Migrator struct {
+    db         *sql.DB
+    migrations []Migration
+}
+
+func NewMigrator(conn *sql.DB) (*Migrator, error) {
+    migrations, err := LoadMigrations(migrationFiles, "migrations")
+    if err != nil {
+        return nil, err
+    }
+    return &Migrator{db: conn, migrations: migrations}, nil
+}
+
+// LoadMigrations читает пары файлов вида 0001_name.up.sql / 0001_name.down.sql.
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+    entries, err := fs.ReadDir(fsys, dir)
+    if err != nil {
+        return nil, err
+    }
+
+    byVersion := map[int64]*Migration{}
+    for _, entry := range entries {
+        if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+            continue
+        }
+
+        base := strings.TrimSuffix(entry.Name(), ".sql")
+        direction := path.Ext(base)
+        if direction != ".up" && direction != ".down" {
+            return nil, fmt.Errorf("migration %s: expected .up.sql or .down.sql suffix", entry.Name())
+        }
+        base = strings.TrimSuffix(base, direction)
+
+        versionStr, name, ok := strings.Cut(base, "_")
+        if !ok {
+            return nil, fmt.Errorf("migration %s: expected <version>_<name>", entry.Name())
+        }
+        version, err := strconv.ParseInt(versionStr, 10, 64)
+        if err != nil {
+            return nil, fmt.Errorf("migration %s: invalid version: %w", entry.Name(), err)
+        }
+
+        content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+        if err != nil {
+            return nil, err
+        }
+
+        m, exists := byVersion[version]
+        if !exists {
+            m = &Migration{Version: version, Name: name}
+            byVersion[version] = m
+        } else if m.Name != name {
+            return nil, fmt.Errorf("migration %d: conflicting names %q and %q", version, m.Name, name)
+        }
+
+        if direction == ".up" {
+            m.Up = string(content)
+        } else {
+            m.Down = string(content)
+        }
+    }
+
+    migrations := make([]Migration, 0, len(byVersion))
+    for _, m := range byVersion {
+        if m.Up == "" {
+            return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+        }
+        sum := sha256.Sum256([]byte(m.Up))
+        m.Checksum = hex.EncodeToString(sum[:])
+        migrations = append(migrations, *m)
+    }
+    sort.Slice(migrations, func(i, j int) bool {
+        return migrations[i].Version < migrations[j].Version
+    })
+    return migrations, nil
+}
+
+type appliedMigration struct {
+    name      string
+    checksum  string
+    appliedAt time.Time
+}
+
+func ensureMigrationsTable(ctx context.Context, conn *sql.Conn) error {
+    _, err := conn.ExecContext(ctx, `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version BIGINT PRIMARY KEY,
+            name VARCHAR(255) NOT NULL,
+            checksum VARCHAR(64) NOT NULL,
+            applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+        )
+    `)
+    return err
+}
+
+func loadApplied(ctx context.Context, conn *sql.Conn) (map[int64]appliedMigration, error) {
+    rows, err := conn.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM schema_migrations")
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    applied := map[int64]appliedMigration{}
+    for rows.Next() {
+        var version int64
+        var a appliedMigration
+        if err := rows.Scan(&version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+            return nil, err
+        }
+        applied[version] = a
+    }
+    return applied, rows.Err()
+}
+
+// withLock выполняет fn на выделенном соединении, удерживая advisory lock,
+// чтобы несколько одновременно стартующих реплик не применяли миграции параллельно.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+    conn, err := m.db.Conn(ctx)
+    if err != nil {
+        return err
+    }
+    defer conn.Close()
+
+    if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+        return fmt.Errorf("acquire migration lock: %w", err)
+    }
+    defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey)
+
+    if err := ensureMigrationsTable(ctx, conn); err != nil {
+        return err
+    }
+    return fn(conn)
+}
+
+func (m *Migrator) verify(applied map[int64]appliedMigration) error {
+    known := map[int64]Migration{}
+    for _, mig := range m.migrations {
+        known[mig.Version] = mig
+    }
+    for version, a := range applied {
+        mig, ok := known[version]
+        if !ok {
+            return fmt.Errorf("%w: %d_%s", ErrUnknownMigration, version, a.name)
+        }
+        if mig.Checksum != a.checksum {
+            return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, version, mig.Name)
+        }
+    }
+    return nil
+}
+
+// Up применяет все ещё не применённые миграции и возвращает их список.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+    var done []Migration
+    err := m.withLock(ctx, func(conn *sql.Conn) error {
+        applied, err := loadApplied(ctx, conn)
+        if err != nil {
+            return err
+        }
+        if err := m.verify(applied); err != nil {
+            return err
+        }
+
+        for _, mig := range m.migrations {
+            if _, ok := applied[mig.Version]; ok {
+                continue
+            }
+            err := runInTx(ctx, conn, func(tx *sql.Tx) error {
+                if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+                    return err
+                }
+                _, err := tx.ExecContext(ctx,
+                    "INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES ($1, $2, $3, NOW())",
+                    mig.Version, mig.Name, mig.Checksum,
+                )
+                return err
+            })
+            if err != nil {
+                return fmt.Errorf("migration %d_%s up: %w", mig.Version, mig.Name, err)
+            }
+            done = append(done, mig)
+        }
+        return nil
+    })
+    return done, err
+}
+
+// Down откатывает последние steps применённых миграций.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+    var done []Migration
+    err := m.withLock(ctx, func(conn *sql.Conn) error {
+        applied, err := loadApplied(ctx, conn)
+        if err != nil {
+            return err
+        }
+        if err := m.verify(applied); err != nil {
+            return err
+        }
+
+        for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+            mig := m.migrations[i]
+            if _, ok := applied[mig.Version]; !ok {
+                continue
+            }
+            if strings.TrimSpace(mig.Down) == "" {
+                return fmt.Errorf("%w: %d_%s", ErrIrreversible, mig.Version, mig.Name)
+            }
+            err := runInTx(ctx, conn, func(tx *sql.Tx) error {
+                if _, err := tx.ExecContext(ctx, mig.Down); err != nil {
+                    return err
+                }
+                _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", mig.Version)
+                return err
+            })
+            if err != nil {
+                return fmt.Errorf("migration %d_%s down: %w", mig.Version, mig.Name, err)
+            }
+            done = append(done, mig)
+        }
+        return nil
+    })
+    return done, err
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+    var statuses []MigrationStatus
+    err := m.withLock(ctx, func(conn *sql.Conn) error {
+        applied, err := loadApplied(ctx, conn)
+        if err != nil {
+            return err
+        }
+
+        for _, mig := range m.migrations {
+            s := MigrationStatus{Version: mig.Version, Name: mig.Name, Checksum: mig.Checksum}
+            if a, ok := applied[mig.Version]; ok {
+                appliedAt := a.appliedAt
+                s.Applied = true
+                s.AppliedAt = &appliedAt
+                s.Dirty = a.checksum != mig.Checksum
+                delete(applied, mig.Version)
+            }
+            statuses = append(statuses, s)
+        }
+
+        // Применённые миграции, файлов которых больше нет
+        for version, a := range applied {
+            appliedAt := a.appliedAt
+            statuses = append(statuses, MigrationStatus{
+                Version:   version,
+                Name:      a.name,
+                Applied:   true,
+                AppliedAt: &appliedAt,
+                Checksum:  a.checksum,
+                Dirty:     true,
+            })
+        }
+        sort.Slice(statuses, func(i, j int) bool {
+            return statuses[i].Version < statuses[j].Version
+        })
+        return nil
+    })
+    return statuses, err
+}
+
+func runInTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+    tx, err := conn.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    if err := fn(tx); err != nil {
+        tx.Rollback()
+        return err
+    }
+    return tx.Commit()
+}
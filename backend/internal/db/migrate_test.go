@@ -0,0 +1,300 @@
+package db
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "io/fs"
+    "os"
+    "regexp"
+    "strconv"
+    "strings"
+    "testing"
+    "testing/fstest"
+)
+
+var migrationName = regexp.MustCompile(`^(\d{4})_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// TestEmbeddedMigrations проверяет, что у каждой миграции есть откат и
+// версии идут подряд с 0001.
+func TestEmbeddedMigrations(t *testing.T) {
+    entries, err := fs.ReadDir(migrationFiles, "migrations")
+    if err != nil {
+        t.Fatalf("ReadDir: %v", err)
+    }
+    files := map[string]bool{}
+    for _, entry := range entries {
+        if !migrationName.MatchString(entry.Name()) {
+            t.Errorf("migration file %s does not match NNNN_name.(up|down).sql", entry.Name())
+        }
+        files[entry.Name()] = true
+    }
+    for name := range files {
+        if base, ok := strings.CutSuffix(name, ".up.sql"); ok && !files[base+".down.sql"] {
+            t.Errorf("%s has no matching %s.down.sql", name, base)
+        }
+        if base, ok := strings.CutSuffix(name, ".down.sql"); ok && !files[base+".up.sql"] {
+            t.Errorf("%s has no matching %s.up.sql", name, base)
+        }
+    }
+
+    migrations, err := LoadMigrations(migrationFiles, "migrations")
+    if err != nil {
+        t.Fatalf("LoadMigrations: %v", err)
+    }
+    if len(migrations) == 0 {
+        t.Fatal("no embedded migrations")
+    }
+    for i, m := range migrations {
+        if m.Version != int64(i+1) {
+            t.Fatalf("migration %d_%s follows version %d, want %d", m.Version, m.Name, i, i+1)
+        }
+        if strings.TrimSpace(m.Down) == "" {
+            t.Errorf("migration %d_%s has an empty down script", m.Version, m.Name)
+        }
+    }
+}
+
+func TestLoadMigrations(t *testing.T) {
+    fsys := fstest.MapFS{
+        "m/0002_second.up.sql":  {Data: []byte("SELECT 2")},
+        "m/0001_first.up.sql":   {Data: []byte("SELECT 1")},
+        "m/0001_first.down.sql": {Data: []byte("SELECT -1")},
+        "m/README.md":           {Data: []byte("not a migration")},
+    }
+    migrations, err := LoadMigrations(fsys, "m")
+    if err != nil {
+        t.Fatalf("LoadMigrations: %v", err)
+    }
+    if len(migrations) != 2 || migrations[0].Name != "first" || migrations[1].Name != "second" {
+        t.Fatalf("LoadMigrations = %+v, want first and second in order", migrations)
+    }
+    if migrations[0].Down != "SELECT -1" || migrations[1].Down != "" {
+        t.Errorf("down scripts = %q, %q", migrations[0].Down, migrations[1].Down)
+    }
+    // Контрольная сумма зависит только от up-скрипта
+    if len(migrations[0].Checksum) != 64 || migrations[0].Checksum == migrations[1].Checksum {
+        t.Errorf("checksums = %s, %s", migrations[0].Checksum, migrations[1].Checksum)
+    }
+
+    broken := map[string]fstest.MapFS{
+        "no direction":      {"m/0001_first.sql": {Data: []byte("SELECT 1")}},
+        "no name":           {"m/0001.up.sql": {Data: []byte("SELECT 1")}},
+        "bad version":       {"m/first_step.up.sql": {Data: []byte("SELECT 1")}},
+        "down without up":   {"m/0001_first.down.sql": {Data: []byte("SELECT 1")}},
+        "conflicting names": {"m/0001_first.up.sql": {Data: []byte("SELECT 1")}, "m/0001_other.down.sql": {Data: []byte("SELECT 1")}},
+    }
+    for name, fsys := range broken {
+        if migrations, err := LoadMigrations(fsys, "m"); err == nil {
+            t.Errorf("%s: LoadMigrations = %+v, want an error", name, migrations)
+        }
+    }
+}
+
+// testMigrations — небольшая цепочка, в которой откат в неверном порядке
+// падает: индекс из 0003 нельзя удалить после удаления столбца из 0002.
+var testMigrations = fstest.MapFS{
+    "m/0001_items.up.sql":        {Data: []byte("CREATE TABLE items (id SERIAL PRIMARY KEY)")},
+    "m/0001_items.down.sql":      {Data: []byte("DROP TABLE items")},
+    "m/0002_item_name.up.sql":    {Data: []byte("ALTER TABLE items ADD COLUMN name TEXT")},
+    "m/0002_item_name.down.sql":  {Data: []byte("ALTER TABLE items DROP COLUMN name")},
+    "m/0003_name_index.up.sql":   {Data: []byte("CREATE INDEX items_name ON items (name)")},
+    "m/0003_name_index.down.sql": {Data: []byte("DROP INDEX items_name")},
+}
+
+// openTestDB подключается к базе из TEST_DATABASE_URL и работает в
+// отдельной схеме, чтобы не трогать таблицы приложения; без переменной
+// тест пропускается.
+func openTestDB(t *testing.T) *sql.DB {
+    t.Helper()
+    dsn := os.Getenv("TEST_DATABASE_URL")
+    if dsn == "" {
+        t.Skip("TEST_DATABASE_URL is not set")
+    }
+
+    conn, err := sql.Open("postgres", dsn)
+    if err != nil {
+        t.Fatalf("open: %v", err)
+    }
+    t.Cleanup(func() { conn.Close() })
+    // search_path задаётся на соединении, поэтому оно должно быть одно
+    conn.SetMaxOpenConns(1)
+    conn.SetMaxIdleConns(1)
+    for _, stmt := range []string{
+        "DROP SCHEMA IF EXISTS migrate_test CASCADE",
+        "CREATE SCHEMA migrate_test",
+        "SET search_path TO migrate_test",
+    } {
+        if _, err := conn.Exec(stmt); err != nil {
+            t.Fatalf("%s: %v", stmt, err)
+        }
+    }
+    t.Cleanup(func() { conn.Exec("DROP SCHEMA IF EXISTS migrate_test CASCADE") })
+    return conn
+}
+
+func testMigrator(t *testing.T, conn *sql.DB, fsys fs.FS) *Migrator {
+    t.Helper()
+    migrations, err := LoadMigrations(fsys, "m")
+    if err != nil {
+        t.Fatalf("LoadMigrations: %v", err)
+    }
+    return &Migrator{db: conn, migrations: migrations}
+}
+
+func versions(migrations []Migration) []int64 {
+    out := make([]int64, len(migrations))
+    for i, m := range migrations {
+        out[i] = m.Version
+    }
+    return out
+}
+
+func sameVersions(got []Migration, want ...int64) bool {
+    v := versions(got)
+    if len(v) != len(want) {
+        return false
+    }
+    for i := range v {
+        if v[i] != want[i] {
+            return false
+        }
+    }
+    return true
+}
+
+// states сводит Status к строке вида "1:applied 2:pending 3:dirty".
+func states(t *testing.T, m *Migrator) string {
+    t.Helper()
+    statuses, err := m.Status(context.Background())
+    if err != nil {
+        t.Fatalf("Status: %v", err)
+    }
+    var parts []string
+    for _, s := range statuses {
+        state := "pending"
+        switch {
+        case s.Dirty:
+            state = "dirty"
+        case s.Applied:
+            state = "applied"
+        }
+        if s.Applied != (s.AppliedAt != nil) {
+            t.Errorf("migration %d: Applied = %v, AppliedAt = %v", s.Version, s.Applied, s.AppliedAt)
+        }
+        parts = append(parts, strconv.FormatInt(s.Version, 10)+":"+state)
+    }
+    return strings.Join(parts, " ")
+}
+
+func TestMigratorUpDown(t *testing.T) {
+    conn := openTestDB(t)
+    ctx := context.Background()
+    m := testMigrator(t, conn, testMigrations)
+
+    if got := states(t, m); got != "1:pending 2:pending 3:pending" {
+        t.Errorf("status before Up = %s", got)
+    }
+    applied, err := m.Up(ctx)
+    if err != nil || !sameVersions(applied, 1, 2, 3) {
+        t.Fatalf("Up = %v, %v, want 1, 2, 3", versions(applied), err)
+    }
+    if applied, err := m.Up(ctx); err != nil || len(applied) != 0 {
+        t.Errorf("second Up = %v, %v, want nothing", versions(applied), err)
+    }
+    if got := states(t, m); got != "1:applied 2:applied 3:applied" {
+        t.Errorf("status after Up = %s", got)
+    }
+
+    // Откат идёт от последней миграции к первой
+    reverted, err := m.Down(ctx, 2)
+    if err != nil || !sameVersions(reverted, 3, 2) {
+        t.Fatalf("Down(2) = %v, %v, want 3, 2", versions(reverted), err)
+    }
+    if got := states(t, m); got != "1:applied 2:pending 3:pending" {
+        t.Errorf("status after Down = %s", got)
+    }
+    var columns int
+    conn.QueryRow("SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = 'migrate_test' AND table_name = 'items' AND column_name = 'name'").Scan(&columns)
+    if columns != 0 {
+        t.Error("Down left the column from 0002")
+    }
+
+    if applied, err := m.Up(ctx); err != nil || !sameVersions(applied, 2, 3) {
+        t.Errorf("Up after Down = %v, %v, want 2, 3", versions(applied), err)
+    }
+    if reverted, err := m.Down(ctx, 10); err != nil || !sameVersions(reverted, 3, 2, 1) {
+        t.Errorf("Down(10) = %v, %v, want 3, 2, 1", versions(reverted), err)
+    }
+}
+
+func TestMigratorChecksumMismatch(t *testing.T) {
+    conn := openTestDB(t)
+    ctx := context.Background()
+    if _, err := testMigrator(t, conn, testMigrations).Up(ctx); err != nil {
+        t.Fatalf("Up: %v", err)
+    }
+
+    // Применённую миграцию отредактировали задним числом
+    edited := fstest.MapFS{}
+    for name, file := range testMigrations {
+        edited[name] = file
+    }
+    edited["m/0002_item_name.up.sql"] = &fstest.MapFile{Data: []byte("ALTER TABLE items ADD COLUMN name VARCHAR(100)")}
+    m := testMigrator(t, conn, edited)
+    if _, err := m.Up(ctx); !errors.Is(err, ErrChecksumMismatch) {
+        t.Errorf("Up = %v, want ErrChecksumMismatch", err)
+    }
+    if _, err := m.Down(ctx, 1); !errors.Is(err, ErrChecksumMismatch) {
+        t.Errorf("Down = %v, want ErrChecksumMismatch", err)
+    }
+    if got := states(t, m); got != "1:applied 2:dirty 3:applied" {
+        t.Errorf("status = %s", got)
+    }
+
+    // Файл применённой миграции удалён
+    missing := fstest.MapFS{}
+    for name, file := range testMigrations {
+        if !strings.Contains(name, "0003_") {
+            missing[name] = file
+        }
+    }
+    m = testMigrator(t, conn, missing)
+    if _, err := m.Up(ctx); !errors.Is(err, ErrUnknownMigration) {
+        t.Errorf("Up without 0003 = %v, want ErrUnknownMigration", err)
+    }
+    if got := states(t, m); got != "1:applied 2:applied 3:dirty" {
+        t.Errorf("status without 0003 = %s", got)
+    }
+}
+
+func TestMigratorFailures(t *testing.T) {
+    conn := openTestDB(t)
+    ctx := context.Background()
+
+    fsys := fstest.MapFS{}
+    for name, file := range testMigrations {
+        fsys[name] = file
+    }
+    delete(fsys, "m/0003_name_index.down.sql")
+    fsys["m/0004_broken.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE labels (id SERIAL); SELECT no_such_column FROM items")}
+    m := testMigrator(t, conn, fsys)
+
+    // Упавшая миграция откатывается целиком, предыдущие остаются
+    applied, err := m.Up(ctx)
+    if err == nil || !sameVersions(applied, 1, 2, 3) {
+        t.Fatalf("Up = %v, %v, want 1, 2, 3 and an error", versions(applied), err)
+    }
+    if got := states(t, m); got != "1:applied 2:applied 3:applied 4:pending" {
+        t.Errorf("status after a failed Up = %s", got)
+    }
+    var labels sql.NullString
+    conn.QueryRow("SELECT to_regclass('migrate_test.labels')::text").Scan(&labels)
+    if labels.Valid {
+        t.Error("failed migration left the labels table")
+    }
+
+    if _, err := m.Down(ctx, 1); !errors.Is(err, ErrIrreversible) {
+        t.Errorf("Down without a script = %v, want ErrIrreversible", err)
+    }
+}
@@ -0,0 +1,3 @@
+ALTER TABLE tasks
+    DROP COLUMN recurrence_start,
+    DROP COLUMN recurrence;
@@ -0,0 +1,3 @@
+ALTER TABLE tasks
+    ADD COLUMN recurrence TEXT NOT NULL DEFAULT '',
+    ADD COLUMN recurrence_start TIMESTAMP;
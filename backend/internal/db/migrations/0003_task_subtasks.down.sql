@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS tasks_parent_id_idx;
+
+ALTER TABLE tasks
+    DROP COLUMN auto_complete,
+    DROP COLUMN position,
+    DROP COLUMN parent_id;
@@ -0,0 +1,6 @@
+ALTER TABLE tasks
+    ADD COLUMN parent_id INTEGER REFERENCES tasks(id) ON DELETE CASCADE,
+    ADD COLUMN position INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN auto_complete BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX tasks_parent_id_idx ON tasks(parent_id);
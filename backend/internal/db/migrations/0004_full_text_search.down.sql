@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS categories_search_vector_idx;
+ALTER TABLE categories DROP COLUMN IF EXISTS search_vector;
+
+DROP INDEX IF EXISTS tasks_search_vector_idx;
+ALTER TABLE tasks DROP COLUMN IF EXISTS search_vector;
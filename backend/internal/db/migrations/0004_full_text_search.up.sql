@@ -0,0 +1,14 @@
+-- Конфигурация russian обрабатывает кириллицу стеммером russian_stem,
+-- а латиницу — english_stem, поэтому одна конфигурация покрывает оба языка.
+ALTER TABLE tasks ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('russian', coalesce(description, '')), 'B')
+) STORED;
+
+CREATE INDEX tasks_search_vector_idx ON tasks USING GIN (search_vector);
+
+ALTER TABLE categories ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    to_tsvector('russian', coalesce(name, ''))
+) STORED;
+
+CREATE INDEX categories_search_vector_idx ON categories USING GIN (search_vector);
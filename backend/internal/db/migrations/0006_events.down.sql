@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS tasks_event_update ON tasks;
+DROP TRIGGER IF EXISTS tasks_event ON tasks;
+DROP TRIGGER IF EXISTS notifications_event ON notifications;
+DROP FUNCTION IF EXISTS record_task_event();
+DROP FUNCTION IF EXISTS record_notification_event();
+DROP TABLE IF EXISTS events;
+DROP FUNCTION IF EXISTS notify_event();
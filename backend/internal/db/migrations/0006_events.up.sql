@@ -0,0 +1,68 @@
+-- Журнал событий для потока /api/notifications/stream. Строки пишут триггеры,
+-- поэтому событие появляется при любом изменении, в том числе из SQL-запросов
+-- вроде CheckDueTasks. Внешнего ключа на users нет: при каскадном удалении
+-- пользователя триггер tasks пишет события уже удаляемого пользователя.
+CREATE TABLE events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    type VARCHAR(64) NOT NULL,
+    data JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX events_user_id_id_idx ON events(user_id, id);
+CREATE INDEX events_created_at_idx ON events(created_at);
+
+-- В NOTIFY передаётся только user_id: подписчики сами дочитывают события
+-- из таблицы, так что размер полезной нагрузки не ограничивает данные.
+CREATE FUNCTION notify_event() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('events', NEW.user_id::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER events_notify AFTER INSERT ON events
+    FOR EACH ROW EXECUTE FUNCTION notify_event();
+
+CREATE FUNCTION record_notification_event() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO events (user_id, type, data)
+    VALUES (NEW.user_id, 'notification.created', json_build_object(
+        'id', NEW.id,
+        'user_id', NEW.user_id,
+        'task_id', NEW.task_id,
+        'message', NEW.message,
+        'created_at', NEW.created_at,
+        'read', NEW.read
+    ));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER notifications_event AFTER INSERT ON notifications
+    FOR EACH ROW EXECUTE FUNCTION record_notification_event();
+
+CREATE FUNCTION record_task_event() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO events (user_id, type, data)
+        VALUES (OLD.user_id, 'task.deleted', json_build_object(
+            'id', OLD.id, 'parent_id', OLD.parent_id, 'completed', OLD.completed
+        ));
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO events (user_id, type, data)
+    VALUES (NEW.user_id, CASE TG_OP WHEN 'INSERT' THEN 'task.created' ELSE 'task.updated' END, json_build_object(
+        'id', NEW.id, 'parent_id', NEW.parent_id, 'completed', NEW.completed
+    ));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tasks_event AFTER INSERT OR DELETE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION record_task_event();
+
+CREATE TRIGGER tasks_event_update AFTER UPDATE ON tasks
+    FOR EACH ROW WHEN (OLD.* IS DISTINCT FROM NEW.*) EXECUTE FUNCTION record_task_event();
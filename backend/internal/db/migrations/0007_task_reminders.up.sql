@@ -0,0 +1,18 @@
+-- Последняя сработавшая ступень напоминания (1 — меньше 3 дней, 2 — меньше
+-- суток, 3 — просрочена) и срок, для которого она сработала. После переноса
+-- срока due_date перестаёт совпадать и ступени отсчитываются заново.
+CREATE TABLE task_reminders (
+    task_id INTEGER PRIMARY KEY REFERENCES tasks(id) ON DELETE CASCADE,
+    due_date TIMESTAMP NOT NULL,
+    stage SMALLINT NOT NULL,
+    fired_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Уже просроченные задачи получали уведомление каждый час; считаем, что
+-- ступень «просрочена» для них уже сработала.
+INSERT INTO task_reminders (task_id, due_date, stage, fired_at)
+SELECT t.id, t.due_date, 3, MAX(n.created_at)
+FROM tasks t
+JOIN notifications n ON n.task_id = t.id
+WHERE t.completed = false AND t.due_date < NOW()
+GROUP BY t.id, t.due_date;
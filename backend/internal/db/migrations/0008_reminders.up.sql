@@ -0,0 +1,17 @@
+-- Пользовательские напоминания: либо абсолютное время remind_at, либо
+-- смещение minutes_before относительно срока задачи. fired_for хранит
+-- момент, на который напоминание сработало: у абсолютного он не меняется,
+-- а смещённое после переноса срока снова ждёт своего времени.
+CREATE TABLE reminders (
+    id SERIAL PRIMARY KEY,
+    task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    remind_at TIMESTAMP,
+    minutes_before INTEGER,
+    fired_at TIMESTAMP,
+    fired_for TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK ((remind_at IS NULL) <> (minutes_before IS NULL))
+);
+
+CREATE INDEX reminders_task_id_idx ON reminders(task_id);
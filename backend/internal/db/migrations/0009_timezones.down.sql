@@ -0,0 +1,35 @@
+DROP TRIGGER tasks_event_update ON tasks;
+ALTER TABLE tasks DROP COLUMN IF EXISTS all_day;
+
+ALTER TABLE reminders
+    ALTER COLUMN remind_at TYPE TIMESTAMP USING remind_at AT TIME ZONE 'UTC',
+    ALTER COLUMN fired_at TYPE TIMESTAMP USING fired_at AT TIME ZONE 'UTC',
+    ALTER COLUMN fired_for TYPE TIMESTAMP USING fired_for AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE task_reminders
+    ALTER COLUMN due_date TYPE TIMESTAMP USING due_date AT TIME ZONE 'UTC',
+    ALTER COLUMN fired_at TYPE TIMESTAMP USING fired_at AT TIME ZONE 'UTC';
+ALTER TABLE events
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE refresh_tokens
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN expires_at TYPE TIMESTAMP USING expires_at AT TIME ZONE 'UTC',
+    ALTER COLUMN used_at TYPE TIMESTAMP USING used_at AT TIME ZONE 'UTC';
+ALTER TABLE sessions
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN last_used_at TYPE TIMESTAMP USING last_used_at AT TIME ZONE 'UTC',
+    ALTER COLUMN revoked_at TYPE TIMESTAMP USING revoked_at AT TIME ZONE 'UTC';
+ALTER TABLE notifications
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE tasks
+    ALTER COLUMN due_date TYPE TIMESTAMP USING due_date AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE 'UTC',
+    ALTER COLUMN recurrence_start TYPE TIMESTAMP USING recurrence_start AT TIME ZONE 'UTC';
+ALTER TABLE categories
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC';
+
+CREATE TRIGGER tasks_event_update AFTER UPDATE ON tasks
+    FOR EACH ROW WHEN (OLD.* IS DISTINCT FROM NEW.*) EXECUTE FUNCTION record_task_event();
+
+ALTER TABLE users DROP COLUMN IF EXISTS timezone;
@@ -0,0 +1,45 @@
+-- Часовой пояс пользователя (имя IANA) задаёт границы «сегодня» и смысл дат
+-- без времени. Все отметки времени переводятся в TIMESTAMPTZ; прежние
+-- значения записывались в UTC.
+ALTER TABLE users ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+
+-- Условие триггера ссылается на всю строку tasks; он пересоздаётся после
+-- смены типов и разметки all_day, чтобы не породить task.updated для каждой задачи.
+DROP TRIGGER tasks_event_update ON tasks;
+
+ALTER TABLE categories
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE tasks
+    ALTER COLUMN due_date TYPE TIMESTAMPTZ USING due_date AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE 'UTC',
+    ALTER COLUMN recurrence_start TYPE TIMESTAMPTZ USING recurrence_start AT TIME ZONE 'UTC';
+ALTER TABLE notifications
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE sessions
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN last_used_at TYPE TIMESTAMPTZ USING last_used_at AT TIME ZONE 'UTC',
+    ALTER COLUMN revoked_at TYPE TIMESTAMPTZ USING revoked_at AT TIME ZONE 'UTC';
+ALTER TABLE refresh_tokens
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN expires_at TYPE TIMESTAMPTZ USING expires_at AT TIME ZONE 'UTC',
+    ALTER COLUMN used_at TYPE TIMESTAMPTZ USING used_at AT TIME ZONE 'UTC';
+ALTER TABLE events
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE task_reminders
+    ALTER COLUMN due_date TYPE TIMESTAMPTZ USING due_date AT TIME ZONE 'UTC',
+    ALTER COLUMN fired_at TYPE TIMESTAMPTZ USING fired_at AT TIME ZONE 'UTC';
+ALTER TABLE reminders
+    ALTER COLUMN remind_at TYPE TIMESTAMPTZ USING remind_at AT TIME ZONE 'UTC',
+    ALTER COLUMN fired_at TYPE TIMESTAMPTZ USING fired_at AT TIME ZONE 'UTC',
+    ALTER COLUMN fired_for TYPE TIMESTAMPTZ USING fired_for AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC';
+
+-- Задача на весь день хранит в due_date начало этого дня в часовом поясе
+-- владельца. Даты без времени раньше сохранялись как полночь UTC.
+ALTER TABLE tasks ADD COLUMN all_day BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE tasks SET all_day = TRUE
+WHERE due_date = date_trunc('day', due_date AT TIME ZONE 'UTC') AT TIME ZONE 'UTC';
+
+CREATE TRIGGER tasks_event_update AFTER UPDATE ON tasks
+    FOR EACH ROW WHEN (OLD.* IS DISTINCT FROM NEW.*) EXECUTE FUNCTION record_task_event();
@@ -0,0 +1,32 @@
+CREATE OR REPLACE FUNCTION record_notification_event() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO events (user_id, type, data)
+    VALUES (NEW.user_id, 'notification.created', json_build_object(
+        'id', NEW.id,
+        'user_id', NEW.user_id,
+        'task_id', NEW.task_id,
+        'message', NEW.message,
+        'created_at', NEW.created_at,
+        'read', NEW.read
+    ));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+UPDATE notifications n
+SET message = p.prefix || (n.params->>'title')
+FROM (VALUES
+    ('task_created', 'Новая задача создана: '),
+    ('task_due_soon', 'До срока выполнения задачи осталось менее 3 дней: '),
+    ('task_due_today', 'Задача должна быть выполнена сегодня: '),
+    ('task_overdue', 'Задача просрочена: '),
+    ('reminder', 'Напоминание: ')
+) AS p(type, prefix)
+WHERE n.type = p.type;
+
+ALTER TABLE notifications
+    ALTER COLUMN message DROP DEFAULT,
+    DROP COLUMN params,
+    DROP COLUMN type;
+
+ALTER TABLE users DROP COLUMN locale;
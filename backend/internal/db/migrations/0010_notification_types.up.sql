@@ -0,0 +1,45 @@
+-- Уведомление хранит тип и параметры вместо готового текста; текст рендерится
+-- на языке пользователя при выдаче. Пустой locale — язык из Accept-Language.
+ALTER TABLE users ADD COLUMN locale VARCHAR(8) NOT NULL DEFAULT '';
+
+ALTER TABLE notifications
+    ADD COLUMN type VARCHAR(32) NOT NULL DEFAULT 'message',
+    ADD COLUMN params JSONB NOT NULL DEFAULT '{}',
+    ALTER COLUMN message SET DEFAULT '';
+
+-- Прежние уведомления распознаются по префиксу русского текста; остальные
+-- остаются с типом message и исходным текстом.
+UPDATE notifications n
+SET type = p.type,
+    params = jsonb_strip_nulls(jsonb_build_object(
+        'task_id', n.task_id,
+        'title', substr(n.message, length(p.prefix) + 1),
+        'due_date', t.due_date,
+        'all_day', t.all_day
+    )),
+    message = ''
+FROM (VALUES
+    ('task_created', 'Новая задача создана: '),
+    ('task_due_soon', 'До срока выполнения задачи осталось менее 3 дней: '),
+    ('task_due_today', 'Задача должна быть выполнена сегодня: '),
+    ('task_overdue', 'Задача просрочена: '),
+    ('reminder', 'Напоминание: ')
+) AS p(type, prefix), tasks t
+WHERE t.id = n.task_id AND left(n.message, length(p.prefix)) = p.prefix;
+
+CREATE OR REPLACE FUNCTION record_notification_event() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO events (user_id, type, data)
+    VALUES (NEW.user_id, 'notification.created', json_build_object(
+        'id', NEW.id,
+        'user_id', NEW.user_id,
+        'task_id', NEW.task_id,
+        'type', NEW.type,
+        'params', NEW.params,
+        'message', NEW.message,
+        'created_at', NEW.created_at,
+        'read', NEW.read
+    ));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
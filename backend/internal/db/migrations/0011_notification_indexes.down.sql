@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS notifications_read_created_at_idx;
+DROP INDEX IF EXISTS notifications_unread_idx;
+DROP INDEX IF EXISTS notifications_user_id_id_idx;
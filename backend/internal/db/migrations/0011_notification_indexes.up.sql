@@ -0,0 +1,5 @@
+-- Страницы уведомлений читаются по (user_id, id), счётчик непрочитанных —
+-- по частичному индексу, очистка — по времени создания прочитанных.
+CREATE INDEX notifications_user_id_id_idx ON notifications(user_id, id);
+CREATE INDEX notifications_unread_idx ON notifications(user_id) WHERE NOT read;
+CREATE INDEX notifications_read_created_at_idx ON notifications(created_at) WHERE read;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS email_digests;
+DROP TRIGGER IF EXISTS notifications_delivery ON notifications;
+DROP FUNCTION IF EXISTS queue_notification_delivery();
+DROP TABLE IF EXISTS notification_deliveries;
@@ -0,0 +1,37 @@
+-- Доставка уведомлений по внешним каналам. Строку очереди ставит триггер при
+-- создании уведомления; отправитель забирает её, сдвигая next_attempt_at на
+-- время отправки, и записывает результат попытки.
+CREATE TABLE notification_deliveries (
+    id SERIAL PRIMARY KEY,
+    notification_id INTEGER NOT NULL REFERENCES notifications(id) ON DELETE CASCADE,
+    channel VARCHAR(16) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (notification_id, channel)
+);
+
+CREATE INDEX notification_deliveries_pending_idx ON notification_deliveries(channel, next_attempt_at)
+    WHERE status = 'pending';
+
+CREATE FUNCTION queue_notification_delivery() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO notification_deliveries (notification_id, channel) VALUES (NEW.id, 'email');
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER notifications_delivery AFTER INSERT ON notifications
+    FOR EACH ROW EXECUTE FUNCTION queue_notification_delivery();
+
+-- Ежедневная сводка: строка на пользователя и его локальную дату. Вставка
+-- строки захватывает отправку, так что сводку шлёт одна реплика.
+CREATE TABLE email_digests (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, day)
+);
@@ -0,0 +1,22 @@
+CREATE OR REPLACE FUNCTION record_notification_event() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO events (user_id, type, data)
+    VALUES (NEW.user_id, 'notification.created', json_build_object(
+        'id', NEW.id,
+        'user_id', NEW.user_id,
+        'task_id', NEW.task_id,
+        'type', NEW.type,
+        'params', NEW.params,
+        'message', NEW.message,
+        'created_at', NEW.created_at,
+        'read', NEW.read
+    ));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Скрытые уведомления при откате стали бы видимыми
+DELETE FROM notifications WHERE NOT in_app;
+ALTER TABLE notifications DROP COLUMN in_app;
+
+DROP TABLE IF EXISTS notification_settings;
@@ -0,0 +1,39 @@
+-- Настройки уведомлений. Пользователь без строки получает значения по
+-- умолчанию: все типы и каналы включены, тихих часов нет, письма сразу.
+-- Тихие часы хранятся минутами от полуночи в часовом поясе пользователя.
+CREATE TABLE notification_settings (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    disabled_types TEXT[] NOT NULL DEFAULT '{}',
+    in_app BOOLEAN NOT NULL DEFAULT true,
+    email BOOLEAN NOT NULL DEFAULT true,
+    webhook BOOLEAN NOT NULL DEFAULT true,
+    quiet_start SMALLINT,
+    quiet_end SMALLINT,
+    email_delivery VARCHAR(16) NOT NULL DEFAULT 'immediate',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK ((quiet_start IS NULL) = (quiet_end IS NULL))
+);
+
+-- Уведомление с выключенным каналом in_app остаётся источником для внешних
+-- каналов, но не показывается в приложении и не попадает в поток.
+ALTER TABLE notifications ADD COLUMN in_app BOOLEAN NOT NULL DEFAULT true;
+
+CREATE OR REPLACE FUNCTION record_notification_event() RETURNS trigger AS $$
+BEGIN
+    IF NOT NEW.in_app THEN
+        RETURN NULL;
+    END IF;
+    INSERT INTO events (user_id, type, data)
+    VALUES (NEW.user_id, 'notification.created', json_build_object(
+        'id', NEW.id,
+        'user_id', NEW.user_id,
+        'task_id', NEW.task_id,
+        'type', NEW.type,
+        'params', NEW.params,
+        'message', NEW.message,
+        'created_at', NEW.created_at,
+        'read', NEW.read
+    ));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,47 @@
+DROP TRIGGER IF EXISTS categories_event_update ON categories;
+DROP TRIGGER IF EXISTS categories_event ON categories;
+DROP FUNCTION IF EXISTS record_category_event();
+
+CREATE OR REPLACE FUNCTION record_task_event() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO events (user_id, type, data)
+        VALUES (OLD.user_id, 'task.deleted', json_build_object(
+            'id', OLD.id, 'parent_id', OLD.parent_id, 'completed', OLD.completed
+        ));
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO events (user_id, type, data)
+    VALUES (NEW.user_id, CASE TG_OP WHEN 'INSERT' THEN 'task.created' ELSE 'task.updated' END, json_build_object(
+        'id', NEW.id, 'parent_id', NEW.parent_id, 'completed', NEW.completed
+    ));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION record_notification_event() RETURNS trigger AS $$
+BEGIN
+    IF NOT NEW.in_app THEN
+        RETURN NULL;
+    END IF;
+    INSERT INTO events (user_id, type, data)
+    VALUES (NEW.user_id, 'notification.created', json_build_object(
+        'id', NEW.id,
+        'user_id', NEW.user_id,
+        'task_id', NEW.task_id,
+        'type', NEW.type,
+        'params', NEW.params,
+        'message', NEW.message,
+        'created_at', NEW.created_at,
+        'read', NEW.read
+    ));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS events_webhooks ON events;
+DROP FUNCTION IF EXISTS queue_event_webhooks();
+DROP FUNCTION IF EXISTS queue_webhooks(INTEGER, TEXT, JSONB);
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
@@ -0,0 +1,125 @@
+-- Исходящие вебхуки. events — типы событий ("task.created") или группы
+-- ("task.*"), на которые подписан адрес.
+CREATE TABLE webhooks (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    events TEXT[] NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX webhooks_user_id_idx ON webhooks(user_id);
+
+-- Очередь и журнал доставок. Данные события копируются в строку, поэтому
+-- доставка не зависит от очистки журнала events.
+CREATE TABLE webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    webhook_id INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event VARCHAR(64) NOT NULL,
+    data JSONB NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_status INTEGER,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX webhook_deliveries_pending_idx ON webhook_deliveries(next_attempt_at)
+    WHERE status = 'pending';
+CREATE INDEX webhook_deliveries_webhook_id_idx ON webhook_deliveries(webhook_id, id);
+CREATE INDEX webhook_deliveries_created_at_idx ON webhook_deliveries(created_at);
+
+CREATE FUNCTION queue_webhooks(p_user_id INTEGER, p_event TEXT, p_data JSONB) RETURNS void AS $$
+    INSERT INTO webhook_deliveries (webhook_id, event, data)
+    SELECT id, p_event, p_data
+    FROM webhooks
+    WHERE user_id = p_user_id AND active
+        AND (p_event = ANY(events) OR split_part(p_event, '.', 1) || '.*' = ANY(events));
+$$ LANGUAGE sql;
+
+-- notification.created ставит в очередь триггер notifications: уведомление
+-- без канала in_app не пишется в events, но уходит на вебхуки.
+CREATE FUNCTION queue_event_webhooks() RETURNS trigger AS $$
+BEGIN
+    PERFORM queue_webhooks(NEW.user_id, NEW.type, NEW.data);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER events_webhooks AFTER INSERT ON events
+    FOR EACH ROW WHEN (NEW.type <> 'notification.created') EXECUTE FUNCTION queue_event_webhooks();
+
+CREATE OR REPLACE FUNCTION record_notification_event() RETURNS trigger AS $$
+DECLARE
+    payload JSONB := jsonb_build_object(
+        'id', NEW.id,
+        'user_id', NEW.user_id,
+        'task_id', NEW.task_id,
+        'type', NEW.type,
+        'params', NEW.params,
+        'message', NEW.message,
+        'created_at', NEW.created_at,
+        'read', NEW.read
+    );
+BEGIN
+    IF COALESCE((SELECT webhook FROM notification_settings WHERE user_id = NEW.user_id), true) THEN
+        PERFORM queue_webhooks(NEW.user_id, 'notification.created', payload);
+    END IF;
+    IF NEW.in_app THEN
+        INSERT INTO events (user_id, type, data) VALUES (NEW.user_id, 'notification.created', payload);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- task.completed пишется вслед за task.updated, когда задача становится
+-- выполненной.
+CREATE OR REPLACE FUNCTION record_task_event() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO events (user_id, type, data)
+        VALUES (OLD.user_id, 'task.deleted', json_build_object(
+            'id', OLD.id, 'parent_id', OLD.parent_id, 'completed', OLD.completed
+        ));
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO events (user_id, type, data)
+    VALUES (NEW.user_id, CASE TG_OP WHEN 'INSERT' THEN 'task.created' ELSE 'task.updated' END, json_build_object(
+        'id', NEW.id, 'parent_id', NEW.parent_id, 'completed', NEW.completed
+    ));
+    IF TG_OP = 'UPDATE' AND NEW.completed AND NOT OLD.completed THEN
+        INSERT INTO events (user_id, type, data)
+        VALUES (NEW.user_id, 'task.completed', json_build_object(
+            'id', NEW.id, 'parent_id', NEW.parent_id, 'completed', NEW.completed
+        ));
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION record_category_event() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO events (user_id, type, data)
+        VALUES (OLD.user_id, 'category.deleted', json_build_object('id', OLD.id, 'name', OLD.name));
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO events (user_id, type, data)
+    VALUES (NEW.user_id, CASE TG_OP WHEN 'INSERT' THEN 'category.created' ELSE 'category.updated' END,
+        json_build_object('id', NEW.id, 'name', NEW.name));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER categories_event AFTER INSERT OR DELETE ON categories
+    FOR EACH ROW EXECUTE FUNCTION record_category_event();
+
+CREATE TRIGGER categories_event_update AFTER UPDATE ON categories
+    FOR EACH ROW WHEN (OLD.* IS DISTINCT FROM NEW.*) EXECUTE FUNCTION record_category_event();
@@ -0,0 +1,18 @@
+-- Адрес подтверждается ссылкой из письма. Уже зарегистрированные
+-- пользователи считаются подтверждёнными, чтобы не потерять доступ.
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+UPDATE users SET email_verified_at = NOW();
+
+-- Одноразовые токены из писем: подтверждение адреса и сброс пароля.
+-- Храним только SHA-256; использованный токен удаляется вместе с остальными
+-- токенами того же назначения, так что у пользователя их не больше двух.
+CREATE TABLE auth_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(32) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX auth_tokens_user_id_idx ON auth_tokens(user_id, purpose);
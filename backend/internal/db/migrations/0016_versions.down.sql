@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS categories_version ON categories;
+DROP TRIGGER IF EXISTS tasks_version ON tasks;
+DROP FUNCTION IF EXISTS bump_version();
+
+ALTER TABLE categories DROP COLUMN IF EXISTS version;
+ALTER TABLE tasks DROP COLUMN IF EXISTS version;
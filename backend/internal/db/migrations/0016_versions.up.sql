@@ -0,0 +1,21 @@
+-- Версия записи для оптимистичной блокировки: клиент получает её в ETag и
+-- передаёт в If-Match. Версию увеличивает триггер, поэтому её меняет любой
+-- UPDATE, в том числе ON DELETE SET NULL при удалении категории.
+ALTER TABLE tasks ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE categories ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+-- Условие WHEN с NEW.* здесь недопустимо: в BEFORE-триггере оно не может
+-- ссылаться на генерируемую колонку search_vector. Запросы приложения и так
+-- не выполняют UPDATE без изменений.
+CREATE FUNCTION bump_version() RETURNS trigger AS $$
+BEGIN
+    NEW.version := OLD.version + 1;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tasks_version BEFORE UPDATE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION bump_version();
+
+CREATE TRIGGER categories_version BEFORE UPDATE ON categories
+    FOR EACH ROW EXECUTE FUNCTION bump_version();
@@ -0,0 +1,12 @@
+DROP TRIGGER IF EXISTS notifications_change_delete ON notifications;
+DROP TRIGGER IF EXISTS notifications_change ON notifications;
+DROP TRIGGER IF EXISTS categories_change ON categories;
+DROP TRIGGER IF EXISTS tasks_change ON tasks;
+DROP FUNCTION IF EXISTS record_change();
+
+DROP INDEX IF EXISTS categories_client_id_idx;
+DROP INDEX IF EXISTS tasks_client_id_idx;
+ALTER TABLE categories DROP COLUMN IF EXISTS client_id;
+ALTER TABLE tasks DROP COLUMN IF EXISTS client_id;
+
+DROP TABLE IF EXISTS changes;
@@ -0,0 +1,70 @@
+-- Журнал изменений для синхронизации клиентов (/api/sync). На каждую запись
+-- хранится одна строка с номером её последнего изменения: новое изменение
+-- переносит строку в конец журнала, удаление оставляет надгробие. Строки
+-- пишут триггеры, как и в events, поэтому журнал видит и каскадные удаления.
+CREATE TABLE changes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    entity VARCHAR(32) NOT NULL,
+    entity_id INTEGER NOT NULL,
+    deleted BOOLEAN NOT NULL DEFAULT false,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (entity, entity_id)
+);
+
+CREATE INDEX changes_user_id_id_idx ON changes(user_id, id);
+
+-- Идентификаторы, выданные клиентом записям, созданным без связи.
+-- Повторная отправка той же пачки не создаёт дубликатов.
+ALTER TABLE tasks ADD COLUMN client_id VARCHAR(64);
+ALTER TABLE categories ADD COLUMN client_id VARCHAR(64);
+CREATE UNIQUE INDEX tasks_client_id_idx ON tasks(user_id, client_id) WHERE client_id IS NOT NULL;
+CREATE UNIQUE INDEX categories_client_id_idx ON categories(user_id, client_id) WHERE client_id IS NOT NULL;
+
+-- Номера изменений одного пользователя выдаются в порядке фиксации
+-- транзакций: блокировка держится до конца транзакции. Иначе клиент мог бы
+-- получить токен дальше изменения, которое ещё не зафиксировано, и потерять его.
+CREATE FUNCTION record_change() RETURNS trigger AS $$
+DECLARE
+    row_user INTEGER;
+    row_id INTEGER;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        row_user := OLD.user_id;
+        row_id := OLD.id;
+    ELSE
+        row_user := NEW.user_id;
+        row_id := NEW.id;
+    END IF;
+
+    PERFORM pg_advisory_xact_lock(hashtext('changes'), row_user);
+    INSERT INTO changes (user_id, entity, entity_id, deleted)
+    VALUES (row_user, TG_ARGV[0], row_id, TG_OP = 'DELETE')
+    ON CONFLICT (entity, entity_id) DO UPDATE
+        SET id = nextval('changes_id_seq'),
+            user_id = EXCLUDED.user_id,
+            deleted = EXCLUDED.deleted,
+            changed_at = NOW();
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tasks_change AFTER INSERT OR UPDATE OR DELETE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION record_change('task');
+
+CREATE TRIGGER categories_change AFTER INSERT OR UPDATE OR DELETE ON categories
+    FOR EACH ROW EXECUTE FUNCTION record_change('category');
+
+-- Уведомления только для внешних каналов клиенту не нужны
+CREATE TRIGGER notifications_change AFTER INSERT OR UPDATE ON notifications
+    FOR EACH ROW WHEN (NEW.in_app) EXECUTE FUNCTION record_change('notification');
+
+CREATE TRIGGER notifications_change_delete AFTER DELETE ON notifications
+    FOR EACH ROW WHEN (OLD.in_app) EXECUTE FUNCTION record_change('notification');
+
+INSERT INTO changes (user_id, entity, entity_id)
+SELECT user_id, 'category', id FROM categories ORDER BY id;
+INSERT INTO changes (user_id, entity, entity_id)
+SELECT user_id, 'task', id FROM tasks ORDER BY id;
+INSERT INTO changes (user_id, entity, entity_id)
+SELECT user_id, 'notification', id FROM notifications WHERE in_app ORDER BY id;
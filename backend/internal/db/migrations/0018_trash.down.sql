@@ -0,0 +1,74 @@
+-- Строки из корзины удаляются окончательно: без deleted_at они бы вернулись.
+DELETE FROM tasks WHERE deleted_at IS NOT NULL;
+DELETE FROM categories WHERE deleted_at IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION record_task_event() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO events (user_id, type, data)
+        VALUES (OLD.user_id, 'task.deleted', json_build_object(
+            'id', OLD.id, 'parent_id', OLD.parent_id, 'completed', OLD.completed
+        ));
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO events (user_id, type, data)
+    VALUES (NEW.user_id, CASE TG_OP WHEN 'INSERT' THEN 'task.created' ELSE 'task.updated' END, json_build_object(
+        'id', NEW.id, 'parent_id', NEW.parent_id, 'completed', NEW.completed
+    ));
+    IF TG_OP = 'UPDATE' AND NEW.completed AND NOT OLD.completed THEN
+        INSERT INTO events (user_id, type, data)
+        VALUES (NEW.user_id, 'task.completed', json_build_object(
+            'id', NEW.id, 'parent_id', NEW.parent_id, 'completed', NEW.completed
+        ));
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION record_category_event() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO events (user_id, type, data)
+        VALUES (OLD.user_id, 'category.deleted', json_build_object('id', OLD.id, 'name', OLD.name));
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO events (user_id, type, data)
+    VALUES (NEW.user_id, CASE TG_OP WHEN 'INSERT' THEN 'category.created' ELSE 'category.updated' END,
+        json_build_object('id', NEW.id, 'name', NEW.name));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION record_change() RETURNS trigger AS $$
+DECLARE
+    row_user INTEGER;
+    row_id INTEGER;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        row_user := OLD.user_id;
+        row_id := OLD.id;
+    ELSE
+        row_user := NEW.user_id;
+        row_id := NEW.id;
+    END IF;
+
+    PERFORM pg_advisory_xact_lock(hashtext('changes'), row_user);
+    INSERT INTO changes (user_id, entity, entity_id, deleted)
+    VALUES (row_user, TG_ARGV[0], row_id, TG_OP = 'DELETE')
+    ON CONFLICT (entity, entity_id) DO UPDATE
+        SET id = nextval('changes_id_seq'),
+            user_id = EXCLUDED.user_id,
+            deleted = EXCLUDED.deleted,
+            changed_at = NOW();
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS tasks_trashed_category_id_idx;
+DROP INDEX IF EXISTS categories_deleted_at_idx;
+DROP INDEX IF EXISTS tasks_deleted_at_idx;
+ALTER TABLE tasks DROP COLUMN IF EXISTS trashed_category_id;
+ALTER TABLE categories DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE tasks DROP COLUMN IF EXISTS deleted_at;
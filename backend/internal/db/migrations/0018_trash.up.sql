@@ -0,0 +1,119 @@
+-- Корзина: удалённые задачи и категории хранятся с отметкой deleted_at, пока
+-- их не очистит планировщик. Запросы приложения такие строки не видят.
+ALTER TABLE tasks ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE categories ADD COLUMN deleted_at TIMESTAMPTZ;
+
+-- Категория, из которой задачу убрало удаление категории в корзину.
+-- Восстановление категории возвращает в неё такие задачи.
+ALTER TABLE tasks ADD COLUMN trashed_category_id INTEGER REFERENCES categories(id) ON DELETE SET NULL;
+
+CREATE INDEX tasks_deleted_at_idx ON tasks(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX categories_deleted_at_idx ON categories(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX tasks_trashed_category_id_idx ON tasks(trashed_category_id) WHERE trashed_category_id IS NOT NULL;
+
+-- Перенос в корзину — task.deleted, возврат — task.restored. Изменения строк
+-- в корзине и их окончательное удаление событий не пишут: для подписчиков
+-- задача уже удалена.
+CREATE OR REPLACE FUNCTION record_task_event() RETURNS trigger AS $$
+DECLARE
+    row_data tasks%ROWTYPE;
+    event_type TEXT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        IF OLD.deleted_at IS NOT NULL THEN
+            RETURN NULL;
+        END IF;
+        row_data := OLD;
+        event_type := 'task.deleted';
+    ELSIF TG_OP = 'INSERT' THEN
+        row_data := NEW;
+        event_type := 'task.created';
+    ELSIF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+        row_data := NEW;
+        event_type := 'task.deleted';
+    ELSIF OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN
+        row_data := NEW;
+        event_type := 'task.restored';
+    ELSIF NEW.deleted_at IS NOT NULL THEN
+        RETURN NULL;
+    ELSE
+        row_data := NEW;
+        event_type := 'task.updated';
+    END IF;
+
+    INSERT INTO events (user_id, type, data)
+    VALUES (row_data.user_id, event_type, json_build_object(
+        'id', row_data.id, 'parent_id', row_data.parent_id, 'completed', row_data.completed
+    ));
+    IF event_type = 'task.updated' AND NEW.completed AND NOT OLD.completed THEN
+        INSERT INTO events (user_id, type, data)
+        VALUES (NEW.user_id, 'task.completed', json_build_object(
+            'id', NEW.id, 'parent_id', NEW.parent_id, 'completed', NEW.completed
+        ));
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION record_category_event() RETURNS trigger AS $$
+DECLARE
+    row_data categories%ROWTYPE;
+    event_type TEXT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        IF OLD.deleted_at IS NOT NULL THEN
+            RETURN NULL;
+        END IF;
+        row_data := OLD;
+        event_type := 'category.deleted';
+    ELSIF TG_OP = 'INSERT' THEN
+        row_data := NEW;
+        event_type := 'category.created';
+    ELSIF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+        row_data := NEW;
+        event_type := 'category.deleted';
+    ELSIF OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN
+        row_data := NEW;
+        event_type := 'category.restored';
+    ELSIF NEW.deleted_at IS NOT NULL THEN
+        RETURN NULL;
+    ELSE
+        row_data := NEW;
+        event_type := 'category.updated';
+    END IF;
+
+    INSERT INTO events (user_id, type, data)
+    VALUES (row_data.user_id, event_type, json_build_object('id', row_data.id, 'name', row_data.name));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Для синхронизации запись в корзине удалена: журнал хранит её надгробием,
+-- восстановление снова делает её изменённой. У уведомлений корзины нет.
+CREATE OR REPLACE FUNCTION record_change() RETURNS trigger AS $$
+DECLARE
+    row_user INTEGER;
+    row_id INTEGER;
+    row_deleted BOOLEAN;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        row_user := OLD.user_id;
+        row_id := OLD.id;
+        row_deleted := true;
+    ELSE
+        row_user := NEW.user_id;
+        row_id := NEW.id;
+        row_deleted := to_jsonb(NEW) ->> 'deleted_at' IS NOT NULL;
+    END IF;
+
+    PERFORM pg_advisory_xact_lock(hashtext('changes'), row_user);
+    INSERT INTO changes (user_id, entity, entity_id, deleted)
+    VALUES (row_user, TG_ARGV[0], row_id, row_deleted)
+    ON CONFLICT (entity, entity_id) DO UPDATE
+        SET id = nextval('changes_id_seq'),
+            user_id = EXCLUDED.user_id,
+            deleted = EXCLUDED.deleted,
+            changed_at = NOW();
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
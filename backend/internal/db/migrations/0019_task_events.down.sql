@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS tasks_history ON tasks;
+DROP FUNCTION IF EXISTS record_task_history();
+DROP TRIGGER IF EXISTS task_events_append_only ON task_events;
+DROP TABLE IF EXISTS task_events;
+DROP FUNCTION IF EXISTS forbid_task_event_update();
@@ -0,0 +1,84 @@
+-- История задач: кто, когда и откуда изменил поля задачи. Строки пишет
+-- триггер в той же транзакции, что и само изменение; журнал только
+-- дополняется. Внешних ключей нет, как у events: история переживает
+-- окончательное удаление задачи.
+CREATE TABLE task_events (
+    id BIGSERIAL PRIMARY KEY,
+    task_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    actor_id INTEGER,
+    source VARCHAR(16) NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    changes JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX task_events_task_id_idx ON task_events(task_id, id);
+CREATE INDEX task_events_user_id_idx ON task_events(user_id, id);
+
+CREATE FUNCTION forbid_task_event_update() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'task_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER task_events_append_only BEFORE UPDATE ON task_events
+    FOR EACH ROW EXECUTE FUNCTION forbid_task_event_update();
+
+-- Автора и источник изменения приложение передаёт через set_config
+-- app.actor_id и app.source в начале транзакции. Поля, которых нет в списке
+-- (позиция, версия, служебные отметки), в историю не попадают; изменение
+-- только таких полей не записывается. Список совпадает с historyFields в
+-- models/history.go.
+CREATE FUNCTION record_task_history() RETURNS trigger AS $$
+DECLARE
+    row_data tasks%ROWTYPE;
+    old_row JSONB := '{}';
+    new_row JSONB := '{}';
+    task_action TEXT;
+    diff JSONB := '{}';
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        row_data := NEW;
+        new_row := to_jsonb(NEW);
+        task_action := 'created';
+    ELSIF TG_OP = 'DELETE' THEN
+        row_data := OLD;
+        task_action := 'purged';
+    ELSE
+        row_data := NEW;
+        old_row := to_jsonb(OLD);
+        new_row := to_jsonb(NEW);
+        IF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+            task_action := 'deleted';
+        ELSIF OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN
+            task_action := 'restored';
+        ELSE
+            task_action := 'updated';
+        END IF;
+    END IF;
+
+    IF TG_OP <> 'DELETE' THEN
+        SELECT COALESCE(jsonb_object_agg(field, jsonb_build_object(
+                   'old', COALESCE(old_row -> field, 'null'),
+                   'new', COALESCE(new_row -> field, 'null'))), '{}')
+        INTO diff
+        FROM unnest(ARRAY['title', 'description', 'completed', 'due_date', 'all_day', 'priority',
+                          'category_id', 'parent_id', 'recurrence', 'recurrence_start', 'auto_complete']) AS field
+        WHERE COALESCE(old_row -> field, 'null') IS DISTINCT FROM COALESCE(new_row -> field, 'null');
+    END IF;
+    IF task_action = 'updated' AND diff = '{}' THEN
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO task_events (task_id, user_id, actor_id, source, action, changes)
+    VALUES (row_data.id, row_data.user_id,
+            NULLIF(current_setting('app.actor_id', true), '')::integer,
+            COALESCE(NULLIF(current_setting('app.source', true), ''), 'api'),
+            task_action, diff);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tasks_history AFTER INSERT OR UPDATE OR DELETE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION record_task_history();
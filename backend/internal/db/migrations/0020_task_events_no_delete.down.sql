@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS task_events_append_only ON task_events;
+CREATE TRIGGER task_events_append_only BEFORE UPDATE ON task_events
+    FOR EACH ROW EXECUTE FUNCTION forbid_task_event_update();
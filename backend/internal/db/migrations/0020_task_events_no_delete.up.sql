@@ -0,0 +1,5 @@
+-- История задач только дополняется: кроме изменения записей запрещено и
+-- их удаление.
+DROP TRIGGER task_events_append_only ON task_events;
+CREATE TRIGGER task_events_append_only BEFORE UPDATE OR DELETE ON task_events
+    FOR EACH ROW EXECUTE FUNCTION forbid_task_event_update();
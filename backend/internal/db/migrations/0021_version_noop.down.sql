@@ -0,0 +1,16 @@
+DROP TRIGGER IF EXISTS categories_change_update ON categories;
+DROP TRIGGER IF EXISTS categories_change ON categories;
+CREATE TRIGGER categories_change AFTER INSERT OR UPDATE OR DELETE ON categories
+    FOR EACH ROW EXECUTE FUNCTION record_change('category');
+
+DROP TRIGGER IF EXISTS tasks_change_update ON tasks;
+DROP TRIGGER IF EXISTS tasks_change ON tasks;
+CREATE TRIGGER tasks_change AFTER INSERT OR UPDATE OR DELETE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION record_change('task');
+
+CREATE OR REPLACE FUNCTION bump_version() RETURNS trigger AS $$
+BEGIN
+    NEW.version := OLD.version + 1;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,29 @@
+-- UPDATE без новых значений (например, PATCH с теми же полями) не должен
+-- менять версию и порождать события и записи для синхронизации. Такой
+-- UPDATE, кроме version и updated_at, ничего не меняет; триггер возвращает
+-- строку без изменений, и условия WHEN последующих триггеров не срабатывают.
+-- search_vector исключён: в BEFORE-триггере генерируемая колонка ещё не
+-- вычислена.
+CREATE OR REPLACE FUNCTION bump_version() RETURNS trigger AS $$
+BEGIN
+    IF to_jsonb(NEW) - 'version' - 'updated_at' - 'search_vector'
+        = to_jsonb(OLD) - 'version' - 'updated_at' - 'search_vector' THEN
+        RETURN OLD;
+    END IF;
+    NEW.version := OLD.version + 1;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Журнал изменений для синхронизации тоже пишется только при изменении строки
+DROP TRIGGER tasks_change ON tasks;
+CREATE TRIGGER tasks_change AFTER INSERT OR DELETE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION record_change('task');
+CREATE TRIGGER tasks_change_update AFTER UPDATE ON tasks
+    FOR EACH ROW WHEN (OLD.* IS DISTINCT FROM NEW.*) EXECUTE FUNCTION record_change('task');
+
+DROP TRIGGER categories_change ON categories;
+CREATE TRIGGER categories_change AFTER INSERT OR DELETE ON categories
+    FOR EACH ROW EXECUTE FUNCTION record_change('category');
+CREATE TRIGGER categories_change_update AFTER UPDATE ON categories
+    FOR EACH ROW WHEN (OLD.* IS DISTINCT FROM NEW.*) EXECUTE FUNCTION record_change('category');
@@ -0,0 +1,176 @@
+package email
+
+import (
+    "context"
+    "log"
+    "time"
+    "todo-app/internal/config"
+    "todo-app/internal/models"
+)
+
+const (
+    batchSize = 50
+    // claimLease — сколько захваченная доставка недоступна другим репликам.
+    claimLease = 5 * time.Minute
+)
+
+type Store interface {
+    ClaimDeliveries(ctx context.Context, channel models.DeliveryChannel, limit int, lease time.Duration) ([]models.PendingDelivery, error)
+    CompleteDelivery(ctx context.Context, id uint, result models.DeliveryResult) error
+    ClaimDigests(ctx context.Context, hour int) ([]models.Digest, error)
+    ReleaseDigest(ctx context.Context, userID uint, day time.Time) error
+}
+
+// Dispatcher разбирает очередь писем об уведомлениях и рассылает
+// ежедневные сводки.
+type Dispatcher struct {
+    store  Store
+    sender Sender
+    cfg    config.MailConfig
+
+    // Now подменяется в тестах; по умолчанию time.Now.
+    Now func() time.Time
+}
+
+func NewDispatcher(cfg config.MailConfig, store Store, sender Sender) *Dispatcher {
+    return &Dispatcher{store: store, sender: sender, cfg: cfg, Now: time.Now}
+}
+
+// Run выполняет Tick сразу и затем каждые cfg.Interval, пока не отменён ctx.
+func (d *Dispatcher) Run(ctx context.Context) {
+    ticker := time.NewTicker(d.cfg.Interval)
+    defer ticker.Stop()
+    for {
+        d.Tick(ctx)
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+func (d *Dispatcher) Tick(ctx context.Context) {
+    sent, err := d.DeliverPending(ctx)
+    if err != nil {
+        log.Printf("Error delivering notification emails: %v", err)
+    } else if sent > 0 {
+        log.Printf("Sent %d notification emails", sent)
+    }
+
+    if d.cfg.DigestHour < 0 {
+        return
+    }
+    digests, err := d.SendDigests(ctx)
+    if err != nil {
+        log.Printf("Error sending digests: %v", err)
+    } else if digests > 0 {
+        log.Printf("Sent %d digests", digests)
+    }
+}
+
+// emailed сообщает, отправляется ли уведомление этого типа по почте: о
+// только что созданной задаче пользователь знает и так.
+func emailed(kind models.NotificationType) bool {
+    return kind != models.NotificationTaskCreated
+}
+
+// DeliverPending отправляет письма, время попытки которых наступило, и
+// возвращает число отправленных.
+func (d *Dispatcher) DeliverPending(ctx context.Context) (int, error) {
+    sent := 0
+    for {
+        claimed, err := d.store.ClaimDeliveries(ctx, models.ChannelEmail, batchSize, claimLease)
+        if err != nil {
+            return sent, err
+        }
+        for _, p := range claimed {
+            result := d.deliver(ctx, p)
+            if err := d.store.CompleteDelivery(ctx, p.ID, result); err != nil {
+                return sent, err
+            }
+            if result.Status == models.DeliverySent {
+                sent++
+            }
+        }
+        if len(claimed) < batchSize {
+            return sent, nil
+        }
+    }
+}
+
+// deliver отправляет письмо с учётом настроек получателя на момент
+// отправки: в тихие часы письмо откладывается до их окончания. На
+// неподтверждённый адрес уведомления не отправляются.
+func (d *Dispatcher) deliver(ctx context.Context, p models.PendingDelivery) models.DeliveryResult {
+    settings := &p.Settings
+    switch {
+    case !emailed(p.Notification.Type) || !settings.TypeEnabled(p.Notification.Type):
+        return models.DeliveryResult{Status: models.DeliverySkipped}
+    case !p.Recipient.Verified():
+        return models.DeliveryResult{Status: models.DeliverySkipped, Error: "email not verified"}
+    case !settings.Channels.Email:
+        return models.DeliveryResult{Status: models.DeliverySkipped, Error: "email disabled"}
+    case settings.EmailDelivery == models.EmailDigest:
+        return models.DeliveryResult{Status: models.DeliverySkipped, Error: "digest"}
+    case d.Now().Sub(p.Notification.CreatedAt) > d.cfg.MaxAge:
+        return models.DeliveryResult{Status: models.DeliverySkipped, Error: "expired"}
+    }
+    if loc := models.Location(p.Recipient.Timezone); settings.Quiet(d.Now(), loc) {
+        retryAt := settings.QuietUntil(d.Now(), loc)
+        return models.DeliveryResult{Status: models.DeliveryPending, RetryAt: &retryAt}
+    }
+
+    msg, err := RenderNotification(p.Recipient, p.Notification, d.cfg.AppURL)
+    if err != nil {
+        return models.DeliveryResult{Status: models.DeliveryFailed, Error: err.Error()}
+    }
+    if err := d.sender.Send(ctx, msg); err != nil {
+        if p.Attempts >= d.cfg.MaxAttempts {
+            return models.DeliveryResult{Status: models.DeliveryFailed, Error: err.Error()}
+        }
+        retryAt := d.Now().Add(d.backoff(p.Attempts))
+        return models.DeliveryResult{Status: models.DeliveryPending, Error: err.Error(), RetryAt: &retryAt}
+    }
+    return models.DeliveryResult{Status: models.DeliverySent}
+}
+
+// backoff возвращает паузу после неудачной попытки attempt (с единицы).
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+    delay := d.cfg.RetryBackoff
+    for i := 1; i < attempt && delay < 24*time.Hour; i++ {
+        delay *= 2
+    }
+    return delay
+}
+
+// SendDigests рассылает сводки пользователям, выбравшим их вместо отдельных
+// писем, у которых наступил час cfg.DigestHour, и возвращает число
+// отправленных. Сводка без задач не отправляется, но считается выполненной;
+// неотправленная из-за ошибки возвращается в очередь до следующего Tick.
+func (d *Dispatcher) SendDigests(ctx context.Context) (int, error) {
+    digests, err := d.store.ClaimDigests(ctx, d.cfg.DigestHour)
+    if err != nil {
+        return 0, err
+    }
+
+    sent := 0
+    for _, digest := range digests {
+        if len(digest.Overdue) == 0 && len(digest.DueToday) == 0 {
+            continue
+        }
+        msg, err := RenderDigest(digest, d.cfg.AppURL)
+        if err == nil {
+            err = d.sender.Send(ctx, msg)
+        }
+        if err != nil {
+            log.Printf("Error sending digest to user %d: %v", digest.User.ID, err)
+            if err := d.store.ReleaseDigest(ctx, digest.User.ID, digest.Day); err != nil {
+                return sent, err
+            }
+            continue
+        }
+        sent++
+    }
+    return sent, nil
+}
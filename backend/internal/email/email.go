@@ -0,0 +1,71 @@
+// Package email доставляет по почте уведомления, ежедневные сводки и письма
+// со ссылками подтверждения адреса и сброса пароля.
+// Письма отправляет Sender: SMTPSender в работе, Fake в тестах, LogSender
+// без настроенного SMTP.
+package email
+
+import (
+    "context"
+    "log"
+    "sync"
+)
+
+// Message — письмо с текстовой и HTML-версией.
+type Message struct {
+    To      string
+    Subject string
+    Text    string
+    HTML    string
+}
+
+type Sender interface {
+    Send(ctx context.Context, msg Message) error
+}
+
+// Fake запоминает письма вместо отправки.
+type Fake struct {
+    mu       sync.Mutex
+    messages []Message
+    err      error
+}
+
+func (f *Fake) Send(ctx context.Context, msg Message) error {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+
+    if f.err != nil {
+        return f.err
+    }
+    f.messages = append(f.messages, msg)
+    return nil
+}
+
+// Fail заставляет следующие вызовы Send возвращать err; nil снова включает
+// отправку.
+func (f *Fake) Fail(err error) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    f.err = err
+}
+
+// Messages возвращает отправленные письма в порядке отправки.
+func (f *Fake) Messages() []Message {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    return append([]Message(nil), f.messages...)
+}
+
+// LogSender пишет письма в журнал вместо отправки; используется, когда SMTP
+// не настроен. Тело письма с одноразовыми ссылками пишется только при Body.
+type LogSender struct {
+    Body bool
+}
+
+func (l LogSender) Send(ctx context.Context, msg Message) error {
+    if l.Body {
+        log.Printf("Email to %s: %s\n%s", msg.To, msg.Subject, msg.Text)
+    } else {
+        log.Printf("Email to %s not sent, SMTP is not configured: %s", msg.To, msg.Subject)
+    }
+    return nil
+}
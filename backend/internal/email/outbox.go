@@ -0,0 +1,52 @@
+package email
+
+import (
+    "context"
+    "sync"
+    "time"
+)
+
+// sendTimeout ограничивает отправку одного письма из Outbox.
+const sendTimeout = time.Minute
+
+// Outbox отправляет письма в фоне, чтобы ответ не ждал SMTP-сервер, и при
+// остановке сервера позволяет дождаться писем, которые ещё отправляются.
+type Outbox struct {
+    sender Sender
+    wg     sync.WaitGroup
+}
+
+func NewOutbox(sender Sender) *Outbox {
+    return &Outbox{sender: sender}
+}
+
+// Send отправляет письмо в фоне. Из ctx берутся значения, например id
+// запроса, но не отмена: письмо уходит и после ответа. Ошибка отправки
+// передаётся в onError.
+func (o *Outbox) Send(ctx context.Context, msg Message, onError func(error)) {
+    o.wg.Add(1)
+    go func() {
+        defer o.wg.Done()
+        ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sendTimeout)
+        defer cancel()
+        if err := o.sender.Send(ctx, msg); err != nil {
+            onError(err)
+        }
+    }()
+}
+
+// Wait ждёт завершения начатых отправок или отмены ctx. Вызывается после
+// остановки HTTP-сервера, когда новых писем уже не будет.
+func (o *Outbox) Wait(ctx context.Context) error {
+    done := make(chan struct{})
+    go func() {
+        o.wg.Wait()
+        close(done)
+    }()
+    select {
+    case <-done:
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
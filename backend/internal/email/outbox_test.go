@@ -0,0 +1,65 @@
+package email
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+)
+
+// blockingSender отправляет письмо только после закрытия release.
+type blockingSender struct {
+    release chan struct{}
+    Fake
+}
+
+func (s *blockingSender) Send(ctx context.Context, msg Message) error {
+    <-s.release
+    return s.Fake.Send(ctx, msg)
+}
+
+func TestOutboxWait(t *testing.T) {
+    sender := &blockingSender{release: make(chan struct{})}
+    outbox := NewOutbox(sender)
+
+    // Отмена контекста запроса не прерывает отправку
+    ctx, cancel := context.WithCancel(context.Background())
+    outbox.Send(ctx, Message{To: "alice@example.com"}, func(err error) {
+        t.Errorf("Send failed: %v", err)
+    })
+    cancel()
+
+    short, stop := context.WithTimeout(context.Background(), 10*time.Millisecond)
+    defer stop()
+    if err := outbox.Wait(short); !errors.Is(err, context.DeadlineExceeded) {
+        t.Fatalf("Wait with a pending email = %v, want DeadlineExceeded", err)
+    }
+
+    close(sender.release)
+    if err := outbox.Wait(context.Background()); err != nil {
+        t.Fatalf("Wait: %v", err)
+    }
+    if got := sender.Messages(); len(got) != 1 || got[0].To != "alice@example.com" {
+        t.Errorf("sent = %+v, want the email to alice", got)
+    }
+}
+
+func TestOutboxError(t *testing.T) {
+    sender := &Fake{}
+    sender.Fail(errors.New("smtp down"))
+    outbox := NewOutbox(sender)
+
+    failed := make(chan error, 1)
+    outbox.Send(context.Background(), Message{To: "bob@example.com"}, func(err error) { failed <- err })
+    if err := outbox.Wait(context.Background()); err != nil {
+        t.Fatalf("Wait: %v", err)
+    }
+    select {
+    case err := <-failed:
+        if err.Error() != "smtp down" {
+            t.Errorf("onError got %v", err)
+        }
+    default:
+        t.Error("onError was not called")
+    }
+}
@@ -0,0 +1,156 @@
+package email
+
+import (
+    "bytes"
+    "context"
+    "crypto/rand"
+    "crypto/tls"
+    "encoding/hex"
+    "fmt"
+    "mime"
+    "mime/multipart"
+    "mime/quotedprintable"
+    "net"
+    "net/mail"
+    "net/smtp"
+    "net/textproto"
+    "strings"
+    "time"
+    "todo-app/internal/config"
+)
+
+const smtpTimeout = 30 * time.Second
+
+// SMTPSender отправляет письма через SMTP-сервер. STARTTLS используется,
+// если сервер его поддерживает; без TLS логин и пароль передаются только
+// на localhost.
+type SMTPSender struct {
+    host     string
+    addr     string
+    from     *mail.Address
+    username string
+    password string
+}
+
+// NewSMTPSender ожидает конфигурацию, уже проверенную config.Validate.
+func NewSMTPSender(cfg config.MailConfig) (*SMTPSender, error) {
+    from, err := mail.ParseAddress(cfg.From)
+    if err != nil {
+        return nil, fmt.Errorf("invalid sender address: %w", err)
+    }
+    return &SMTPSender{
+        host:     cfg.SMTPHost,
+        addr:     net.JoinHostPort(cfg.SMTPHost, cfg.SMTPPort),
+        from:     from,
+        username: cfg.SMTPUser,
+        password: cfg.SMTPPassword,
+    }, nil
+}
+
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+    to, err := mail.ParseAddress(msg.To)
+    if err != nil {
+        return fmt.Errorf("invalid recipient address: %w", err)
+    }
+    data, err := s.compose(to, msg)
+    if err != nil {
+        return err
+    }
+
+    var dialer net.Dialer
+    conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+    if err != nil {
+        return err
+    }
+    deadline, ok := ctx.Deadline()
+    if !ok {
+        deadline = time.Now().Add(smtpTimeout)
+    }
+    conn.SetDeadline(deadline)
+
+    client, err := smtp.NewClient(conn, s.host)
+    if err != nil {
+        conn.Close()
+        return err
+    }
+    defer client.Close()
+
+    if ok, _ := client.Extension("STARTTLS"); ok {
+        if err := client.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+            return err
+        }
+    }
+    if s.username != "" {
+        if err := client.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+            return err
+        }
+    }
+    if err := client.Mail(s.from.Address); err != nil {
+        return err
+    }
+    if err := client.Rcpt(to.Address); err != nil {
+        return err
+    }
+    w, err := client.Data()
+    if err != nil {
+        return err
+    }
+    if _, err := w.Write(data); err != nil {
+        return err
+    }
+    if err := w.Close(); err != nil {
+        return err
+    }
+    return client.Quit()
+}
+
+// compose собирает письмо multipart/alternative из текстовой и HTML-частей.
+func (s *SMTPSender) compose(to *mail.Address, msg Message) ([]byte, error) {
+    var body bytes.Buffer
+    parts := multipart.NewWriter(&body)
+    for _, part := range []struct{ contentType, content string }{
+        {"text/plain; charset=utf-8", msg.Text},
+        {"text/html; charset=utf-8", msg.HTML},
+    } {
+        w, err := parts.CreatePart(textproto.MIMEHeader{
+            "Content-Type":              {part.contentType},
+            "Content-Transfer-Encoding": {"quoted-printable"},
+        })
+        if err != nil {
+            return nil, err
+        }
+        qp := quotedprintable.NewWriter(w)
+        if _, err := qp.Write([]byte(part.content)); err != nil {
+            return nil, err
+        }
+        if err := qp.Close(); err != nil {
+            return nil, err
+        }
+    }
+    if err := parts.Close(); err != nil {
+        return nil, err
+    }
+
+    id := make([]byte, 16)
+    if _, err := rand.Read(id); err != nil {
+        return nil, err
+    }
+    domain := s.from.Address[strings.LastIndex(s.from.Address, "@")+1:]
+
+    var out bytes.Buffer
+    headers := [][2]string{
+        {"From", s.from.String()},
+        {"To", to.String()},
+        {"Subject", mime.QEncoding.Encode("utf-8", msg.Subject)},
+        {"Date", time.Now().Format(time.RFC1123Z)},
+        {"Message-ID", "<" + hex.EncodeToString(id) + "@" + domain + ">"},
+        {"MIME-Version", "1.0"},
+        {"Content-Type", "multipart/alternative; boundary=" + parts.Boundary()},
+    }
+    for _, h := range headers {
+        fmt.Fprintf(&out, "%s: %s\r\n", h[0], h[1])
+    }
+    out.WriteString("\r\n")
+    out.Write(body.Bytes())
+    return out.Bytes(), nil
+}
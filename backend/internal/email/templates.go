@@ -0,0 +1,192 @@
+package email
+
+import (
+    "bytes"
+    "embed"
+    "fmt"
+    htmltemplate "html/template"
+    "net/url"
+    "strings"
+    texttemplate "text/template"
+    "todo-app/internal/i18n"
+    "todo-app/internal/models"
+)
+
+//go:embed templates
+var templateFiles embed.FS
+
+const (
+    digestTemplate        = "digest"
+    verifyEmailTemplate   = "verify_email"
+    resetPasswordTemplate = "reset_password"
+)
+
+// Для каждого типа уведомления, для сводки и для писем со ссылками есть пара
+// шаблонов templates/<имя>.txt и .html, которые вставляются в общий layout.
+var templateNames = []string{
+    string(models.NotificationTaskCreated),
+    string(models.NotificationTaskDueSoon),
+    string(models.NotificationTaskDueToday),
+    string(models.NotificationTaskOverdue),
+    string(models.NotificationReminder),
+    string(models.NotificationMessage),
+    digestTemplate,
+    verifyEmailTemplate,
+    resetPasswordTemplate,
+}
+
+type templateSet struct {
+    text *texttemplate.Template
+    html *htmltemplate.Template
+}
+
+var templates = parseTemplates()
+
+// Функция t нужна уже при разборе; при рендеринге она заменяется
+// переводом на язык письма.
+var parseFuncs = map[string]interface{}{"t": translator(i18n.Default)}
+
+func parseTemplates() map[string]templateSet {
+    sets := map[string]templateSet{}
+    for _, name := range templateNames {
+        text := texttemplate.Must(texttemplate.New(name).Funcs(parseFuncs).
+            ParseFS(templateFiles, "templates/layout.txt", "templates/"+name+".txt"))
+        html := htmltemplate.Must(htmltemplate.New(name).Funcs(parseFuncs).
+            ParseFS(templateFiles, "templates/layout.html", "templates/"+name+".html"))
+        sets[name] = templateSet{text: text, html: html}
+    }
+    return sets
+}
+
+// translator возвращает функцию t для шаблонов: ключ каталога и пары
+// имя-значение параметров.
+func translator(locale i18n.Locale) func(key string, params ...string) string {
+    return func(key string, params ...string) string {
+        values := map[string]string{}
+        for i := 0; i+1 < len(params); i += 2 {
+            values[params[i]] = params[i+1]
+        }
+        return i18n.Text(locale, key, values)
+    }
+}
+
+type layoutData struct {
+    Locale  i18n.Locale
+    Name    string
+    Subject string
+    AppURL  string
+}
+
+type notificationData struct {
+    layoutData
+    Due string
+}
+
+type linkData struct {
+    layoutData
+    Link string
+}
+
+type digestTask struct {
+    Title string
+    Due   string
+}
+
+type digestData struct {
+    layoutData
+    Overdue  []digestTask
+    DueToday []digestTask
+}
+
+func newLayout(recipient models.User, appURL string) layoutData {
+    name := recipient.Name
+    if name == "" {
+        name = recipient.Email
+    }
+    return layoutData{
+        Locale: i18n.Resolve(recipient.Locale, ""),
+        Name:   name,
+        AppURL: appURL,
+    }
+}
+
+// RenderNotification собирает письмо об уведомлении на языке получателя;
+// сроки показываются в его часовом поясе.
+func RenderNotification(recipient models.User, n models.Notification, appURL string) (Message, error) {
+    data := notificationData{layoutData: newLayout(recipient, appURL)}
+    n.Localize(data.Locale)
+    data.Subject = n.Message
+    if n.Params.DueDate != nil {
+        due := n.Params.DueDate.In(models.Location(recipient.Timezone))
+        data.Due = i18n.FormatTime(data.Locale, due, n.Params.AllDay)
+    }
+    return render(string(n.Type), recipient.Email, data.layoutData, data)
+}
+
+// RenderDigest собирает ежедневную сводку.
+func RenderDigest(digest models.Digest, appURL string) (Message, error) {
+    data := digestData{layoutData: newLayout(digest.User, appURL)}
+    data.Subject = i18n.Text(data.Locale, "digest.subject", map[string]string{
+        "date": i18n.FormatTime(data.Locale, digest.Day, true),
+    })
+    loc := models.Location(digest.User.Timezone)
+    tasks := func(list []models.Task) []digestTask {
+        result := make([]digestTask, len(list))
+        for i, t := range list {
+            result[i] = digestTask{Title: t.Title, Due: i18n.FormatTime(data.Locale, t.DueDate.In(loc), t.AllDay)}
+        }
+        return result
+    }
+    data.Overdue = tasks(digest.Overdue)
+    data.DueToday = tasks(digest.DueToday)
+    return render(digestTemplate, digest.User.Email, data.layoutData, data)
+}
+
+// RenderVerifyEmail собирает письмо со ссылкой подтверждения адреса
+// <appURL>/verify-email?token=...; acceptLanguage выбирает язык, если
+// пользователь его не задал.
+func RenderVerifyEmail(recipient models.User, token, appURL, acceptLanguage string) (Message, error) {
+    return renderLink(verifyEmailTemplate, "auth.verify_subject", "/verify-email", recipient, token, appURL, acceptLanguage)
+}
+
+// RenderPasswordReset собирает письмо со ссылкой сброса пароля
+// <appURL>/reset-password?token=....
+func RenderPasswordReset(recipient models.User, token, appURL, acceptLanguage string) (Message, error) {
+    return renderLink(resetPasswordTemplate, "auth.reset_subject", "/reset-password", recipient, token, appURL, acceptLanguage)
+}
+
+func renderLink(name, subject, path string, recipient models.User, token, appURL, acceptLanguage string) (Message, error) {
+    link := strings.TrimSuffix(appURL, "/") + path + "?token=" + url.QueryEscape(token)
+    data := linkData{layoutData: newLayout(recipient, appURL), Link: link}
+    data.Locale = i18n.Resolve(recipient.Locale, acceptLanguage)
+    data.Subject = i18n.Text(data.Locale, subject, nil)
+    return render(name, recipient.Email, data.layoutData, data)
+}
+
+func render(name, to string, layout layoutData, data interface{}) (Message, error) {
+    set, ok := templates[name]
+    if !ok {
+        return Message{}, fmt.Errorf("no email template for %q", name)
+    }
+    funcs := map[string]interface{}{"t": translator(layout.Locale)}
+
+    text, err := set.text.Clone()
+    if err != nil {
+        return Message{}, err
+    }
+    var textBody bytes.Buffer
+    if err := text.Funcs(funcs).ExecuteTemplate(&textBody, "layout", data); err != nil {
+        return Message{}, err
+    }
+
+    html, err := set.html.Clone()
+    if err != nil {
+        return Message{}, err
+    }
+    var htmlBody bytes.Buffer
+    if err := html.Funcs(funcs).ExecuteTemplate(&htmlBody, "layout", data); err != nil {
+        return Message{}, err
+    }
+
+    return Message{To: to, Subject: layout.Subject, Text: textBody.String(), HTML: htmlBody.String()}, nil
+}
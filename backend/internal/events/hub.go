@@ -0,0 +1,67 @@
+// Package events будит открытые потоки уведомлений, когда у их пользователя
+// появляются новые записи в журнале событий. Сами события подписчики читают
+// из хранилища, поэтому хаб передаёт только сигнал «есть новое».
+package events
+
+import (
+    "sync"
+)
+
+type Hub struct {
+    mu          sync.Mutex
+    subscribers map[uint]map[chan struct{}]struct{}
+}
+
+func NewHub() *Hub {
+    return &Hub{subscribers: map[uint]map[chan struct{}]struct{}{}}
+}
+
+// Subscribe возвращает канал сигналов для пользователя и функцию отписки.
+// Канал буферизован на один сигнал: несколько событий подряд сливаются в одно
+// пробуждение, а медленный подписчик не блокирует остальных.
+func (h *Hub) Subscribe(userID uint) (<-chan struct{}, func()) {
+    ch := make(chan struct{}, 1)
+
+    h.mu.Lock()
+    if h.subscribers[userID] == nil {
+        h.subscribers[userID] = map[chan struct{}]struct{}{}
+    }
+    h.subscribers[userID][ch] = struct{}{}
+    h.mu.Unlock()
+
+    return ch, func() {
+        h.mu.Lock()
+        defer h.mu.Unlock()
+        delete(h.subscribers[userID], ch)
+        if len(h.subscribers[userID]) == 0 {
+            delete(h.subscribers, userID)
+        }
+    }
+}
+
+func (h *Hub) Notify(userID uint) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    for ch := range h.subscribers[userID] {
+        wake(ch)
+    }
+}
+
+// NotifyAll будит всех подписчиков; нужен после переподключения к Postgres,
+// когда часть NOTIFY могла потеряться.
+func (h *Hub) NotifyAll() {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    for _, subs := range h.subscribers {
+        for ch := range subs {
+            wake(ch)
+        }
+    }
+}
+
+func wake(ch chan struct{}) {
+    select {
+    case ch <- struct{}{}:
+    default:
+    }
+}
@@ -0,0 +1,50 @@
+package events
+
+import (
+    "context"
+    "log"
+    "strconv"
+    "time"
+    "github.com/lib/pq"
+)
+
+// Channel — канал NOTIFY, в который триггер events пишет id пользователя.
+const Channel = "events"
+
+// Listen пересылает NOTIFY из Postgres в хаб, пока не отменён ctx. Так события,
+// записанные другим экземпляром сервера, доходят до клиентов этого.
+func Listen(ctx context.Context, dsn string, hub *Hub) error {
+    listener := pq.NewListener(dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+        if err != nil {
+            log.Printf("Event listener: %v", err)
+        }
+    })
+    defer listener.Close()
+
+    if err := listener.Listen(Channel); err != nil {
+        return err
+    }
+
+    ping := time.NewTicker(90 * time.Second)
+    defer ping.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case n := <-listener.Notify:
+            // nil приходит после переподключения
+            if n == nil {
+                hub.NotifyAll()
+                continue
+            }
+            userID, err := strconv.ParseUint(n.Extra, 10, 32)
+            if err != nil {
+                log.Printf("Event listener: bad payload %q", n.Extra)
+                continue
+            }
+            hub.Notify(uint(userID))
+        case <-ping.C:
+            go listener.Ping()
+        }
+    }
+}
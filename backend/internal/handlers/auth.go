@@ -2,14 +2,31 @@ package handlers
 
 import (
     "encoding/json"
+    "errors"
+    "log"
     "net/http"
+    "net/mail"
+    "strings"
     "github.com/golang-jwt/jwt/v5"
     "time"
+    "todo-app/internal/apierror"
+    "todo-app/internal/config"
+    "todo-app/internal/email"
+    "todo-app/internal/middleware"
     "todo-app/internal/models"
+    "todo-app/internal/requestid"
 )
 
 type AuthHandler struct {
-    jwtSecret []byte
+    jwtSecret       []byte
+    accessTokenTTL  time.Duration
+    refreshTokenTTL time.Duration
+    verifyTokenTTL  time.Duration
+    resetTokenTTL   time.Duration
+    appURL          string
+    users           models.UserStore
+    sessions        models.SessionStore
+    outbox          *email.Outbox
 }
 
 type LoginRequest struct {
@@ -21,75 +38,353 @@ type RegisterRequest struct {
     Email    string `json:"email"`
     Password string `json:"password"`
     Name     string `json:"name"`
+    // Timezone — имя IANA, например "Europe/Moscow"; по умолчанию UTC.
+    Timezone string `json:"timezone"`
 }
 
-type AuthResponse struct {
+type RefreshRequest struct {
+    RefreshToken string `json:"refresh_token"`
+}
+
+type ForgotPasswordRequest struct {
+    Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+    Token    string `json:"token"`
+    Password string `json:"password"`
+}
+
+type VerifyEmailRequest struct {
     Token string `json:"token"`
 }
 
-func NewAuthHandler(secret string) *AuthHandler {
+type AuthResponse struct {
+    Token        string `json:"token"`
+    RefreshToken string `json:"refresh_token"`
+    ExpiresIn    int    `json:"expires_in"`
+}
+
+// NewAuthHandler отправляет письма со ссылками на appURL через outbox.
+func NewAuthHandler(cfg config.AuthConfig, appURL string, users models.UserStore, sessions models.SessionStore, outbox *email.Outbox) *AuthHandler {
     return &AuthHandler{
-        jwtSecret: []byte(secret),
+        jwtSecret:       []byte(cfg.JWTSecret),
+        accessTokenTTL:  cfg.AccessTokenTTL,
+        refreshTokenTTL: cfg.RefreshTokenTTL,
+        verifyTokenTTL:  cfg.VerifyTokenTTL,
+        resetTokenTTL:   cfg.ResetTokenTTL,
+        appURL:          appURL,
+        users:           users,
+        sessions:        sessions,
+        outbox:          outbox,
     }
 }
 
+// validEmail принимает только голый адрес, без имени и угловых скобок.
+func validEmail(address string) bool {
+    parsed, err := mail.ParseAddress(address)
+    return err == nil && parsed.Address == address
+}
+
+// sendLink выпускает одноразовый токен и отправляет письмо со ссылкой.
+// Письмо уходит в фоне: ответ не ждёт SMTP-сервер, а по времени ответа
+// нельзя понять, зарегистрирован ли адрес.
+func (h *AuthHandler) sendLink(r *http.Request, user *models.User, purpose models.AuthTokenPurpose) error {
+    token, hash, err := models.NewToken()
+    if err != nil {
+        return err
+    }
+
+    ttl := h.verifyTokenTTL
+    render := email.RenderVerifyEmail
+    if purpose == models.TokenResetPassword {
+        ttl = h.resetTokenTTL
+        render = email.RenderPasswordReset
+    }
+    if err := h.users.CreateAuthToken(r.Context(), user.ID, purpose, hash, time.Now().Add(ttl)); err != nil {
+        return err
+    }
+    msg, err := render(*user, token, h.appURL, r.Header.Get("Accept-Language"))
+    if err != nil {
+        return err
+    }
+
+    id := requestid.FromContext(r.Context())
+    h.outbox.Send(r.Context(), msg, func(err error) {
+        log.Printf("[%s] Could not send %s email: %v", id, purpose, err)
+    })
+    return nil
+}
+
+func (h *AuthHandler) accessToken(user *models.User, sessionID uint) (string, error) {
+    now := time.Now()
+    token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+        "user_id": user.ID,
+        "email":   user.Email,
+        "sid":     sessionID,
+        "iat":     now.Unix(),
+        "exp":     now.Add(h.accessTokenTTL).Unix(),
+    })
+    return token.SignedString(h.jwtSecret)
+}
+
+// startSession открывает новую сессию и выдаёт для неё пару токенов.
+func (h *AuthHandler) startSession(r *http.Request, user *models.User) (*AuthResponse, error) {
+    refresh, hash, err := models.NewToken()
+    if err != nil {
+        return nil, err
+    }
+
+    session, err := h.sessions.CreateSession(r.Context(), user.ID, r.UserAgent(), hash, time.Now().Add(h.refreshTokenTTL))
+    if err != nil {
+        return nil, err
+    }
+
+    access, err := h.accessToken(user, session.ID)
+    if err != nil {
+        return nil, err
+    }
+    return &AuthResponse{Token: access, RefreshToken: refresh, ExpiresIn: int(h.accessTokenTTL.Seconds())}, nil
+}
+
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
     var req LoginRequest
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        http.Error(w, "Invalid request", http.StatusBadRequest)
+        apierror.BadRequest(w, r, "Invalid request body")
         return
     }
 
-    user, err := models.GetUserByEmail(req.Email)
-    if err != nil {
-        http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+    user, err := h.users.GetUserByEmail(r.Context(), req.Email)
+    if err != nil && !errors.Is(err, models.ErrNotFound) {
+        apierror.Internal(w, r, err, "Could not log in")
         return
     }
-
-    if !user.CheckPassword(req.Password) {
-        http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+    if err != nil || !user.CheckPassword(req.Password) {
+        apierror.Unauthorized(w, r, "Invalid credentials")
         return
     }
-    
-    token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-        "user_id": user.ID,
-        "email": user.Email,
-        "exp":   time.Now().Add(time.Hour * 24).Unix(),
-    })
 
-    tokenString, err := token.SignedString(h.jwtSecret)
+    resp, err := h.startSession(r, user)
     if err != nil {
-        http.Error(w, "Could not generate token", http.StatusInternalServerError)
+        apierror.Internal(w, r, err, "Could not generate token")
         return
     }
 
-    json.NewEncoder(w).Encode(AuthResponse{Token: tokenString})
+    json.NewEncoder(w).Encode(resp)
 }
 
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
     var req RegisterRequest
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        http.Error(w, "Invalid request", http.StatusBadRequest)
+        apierror.BadRequest(w, r, "Invalid request body")
+        return
+    }
+    req.Email = strings.TrimSpace(req.Email)
+    switch {
+    case !validEmail(req.Email):
+        apierror.Invalid(w, r, "email", "email is invalid")
+        return
+    case req.Password == "":
+        apierror.Invalid(w, r, "password", "password is required")
+        return
+    }
+    if err := models.ValidateTimezone(req.Timezone); err != nil {
+        apierror.FromError(w, r, err, "", "Invalid timezone")
         return
     }
 
-    user, err := models.CreateUser(req.Email, req.Password, req.Name)
+    user, err := h.users.CreateUser(r.Context(), req.Email, req.Password, req.Name, req.Timezone)
     if err != nil {
-        http.Error(w, "Could not create user", http.StatusInternalServerError)
+        apierror.FromError(w, r, err, "", "Could not create user")
         return
     }
+    // Без письма пользователь запросит его повторно, регистрация не
+    // откатывается
+    if err := h.sendLink(r, user, models.TokenVerifyEmail); err != nil {
+        log.Printf("[%s] Could not send verification email: %v", requestid.FromContext(r.Context()), err)
+    }
 
-    token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-        "user_id": user.ID,
-        "email": user.Email,
-        "exp":   time.Now().Add(time.Hour * 24).Unix(),
-    })
+    resp, err := h.startSession(r, user)
+    if err != nil {
+        apierror.Internal(w, r, err, "Could not generate token")
+        return
+    }
 
-    tokenString, err := token.SignedString(h.jwtSecret)
+    json.NewEncoder(w).Encode(resp)
+}
+
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+    var req RefreshRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        apierror.BadRequest(w, r, "Invalid request body")
+        return
+    }
+    if req.RefreshToken == "" {
+        apierror.Invalid(w, r, "refresh_token", "refresh_token is required")
+        return
+    }
+
+    refresh, hash, err := models.NewToken()
+    if err != nil {
+        apierror.Internal(w, r, err, "Could not generate token")
+        return
+    }
+
+    session, err := h.sessions.RotateRefreshToken(r.Context(), models.HashToken(req.RefreshToken), hash, time.Now().Add(h.refreshTokenTTL))
     if err != nil {
-        http.Error(w, "Could not generate token", http.StatusInternalServerError)
+        switch {
+        case errors.Is(err, models.ErrRefreshTokenReused):
+            log.Printf("[%s] Refresh token reuse detected, session revoked", requestid.FromContext(r.Context()))
+            apierror.Unauthorized(w, r, "Invalid refresh token")
+        case errors.Is(err, models.ErrNotFound),
+            errors.Is(err, models.ErrSessionRevoked),
+            errors.Is(err, models.ErrRefreshTokenExpired):
+            apierror.Unauthorized(w, r, "Invalid refresh token")
+        default:
+            apierror.Internal(w, r, err, "Could not refresh token")
+        }
         return
     }
 
-    json.NewEncoder(w).Encode(AuthResponse{Token: tokenString})
-} 
\ No newline at end of file
+    user, err := h.users.GetUserByID(r.Context(), session.UserID)
+    if err != nil {
+        apierror.Unauthorized(w, r, "Invalid refresh token")
+        return
+    }
+
+    access, err := h.accessToken(user, session.ID)
+    if err != nil {
+        apierror.Internal(w, r, err, "Could not generate token")
+        return
+    }
+
+    json.NewEncoder(w).Encode(AuthResponse{Token: access, RefreshToken: refresh, ExpiresIn: int(h.accessTokenTTL.Seconds())})
+}
+
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+    userID := getUserIDFromToken(r)
+    sessionID := getSessionIDFromToken(r)
+
+    err := h.sessions.RevokeSession(r.Context(), sessionID, userID)
+    if err != nil && !errors.Is(err, models.ErrNotFound) {
+        apierror.Internal(w, r, err, "Could not log out")
+        return
+    }
+
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+    userID := getUserIDFromToken(r)
+
+    if err := h.sessions.RevokeUserSessions(r.Context(), userID); err != nil {
+        apierror.Internal(w, r, err, "Could not log out")
+        return
+    }
+
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// ForgotPassword отправляет ссылку сброса пароля. Ответ одинаков для
+// известных и неизвестных адресов.
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+    var req ForgotPasswordRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        apierror.BadRequest(w, r, "Invalid request body")
+        return
+    }
+    req.Email = strings.TrimSpace(req.Email)
+    if !validEmail(req.Email) {
+        apierror.Invalid(w, r, "email", "email is invalid")
+        return
+    }
+
+    user, err := h.users.GetUserByEmail(r.Context(), req.Email)
+    switch {
+    case errors.Is(err, models.ErrNotFound):
+    case err != nil:
+        apierror.Internal(w, r, err, "Could not send reset email")
+        return
+    default:
+        if err := h.sendLink(r, user, models.TokenResetPassword); err != nil {
+            apierror.Internal(w, r, err, "Could not send reset email")
+            return
+        }
+    }
+
+    w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword задаёт новый пароль по токену из письма и завершает все
+// сессии пользователя.
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+    var req ResetPasswordRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        apierror.BadRequest(w, r, "Invalid request body")
+        return
+    }
+    switch {
+    case req.Token == "":
+        apierror.Invalid(w, r, "token", "token is required")
+        return
+    case req.Password == "":
+        apierror.Invalid(w, r, "password", "password is required")
+        return
+    }
+
+    if _, err := h.users.ResetPassword(r.Context(), models.HashToken(req.Token), req.Password); err != nil {
+        apierror.FromError(w, r, err, "", "Could not reset password")
+        return
+    }
+
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+    var req VerifyEmailRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        apierror.BadRequest(w, r, "Invalid request body")
+        return
+    }
+    if req.Token == "" {
+        apierror.Invalid(w, r, "token", "token is required")
+        return
+    }
+
+    if _, err := h.users.VerifyEmail(r.Context(), models.HashToken(req.Token)); err != nil {
+        apierror.FromError(w, r, err, "", "Could not verify email")
+        return
+    }
+
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// ResendVerification повторно отправляет ссылку подтверждения; прежняя
+// ссылка перестаёт действовать.
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+    user, err := h.users.GetUserByID(r.Context(), getUserIDFromToken(r))
+    if err != nil {
+        apierror.FromError(w, r, err, "User not found", "Could not send verification email")
+        return
+    }
+    if user.Verified() {
+        apierror.Invalid(w, r, "email", "email is already verified")
+        return
+    }
+
+    if err := h.sendLink(r, user, models.TokenVerifyEmail); err != nil {
+        apierror.Internal(w, r, err, "Could not send verification email")
+        return
+    }
+
+    w.WriteHeader(http.StatusAccepted)
+}
+
+func getSessionIDFromToken(r *http.Request) uint {
+    claims, ok := middleware.Claims(r.Context())
+    if !ok {
+        return 0
+    }
+    sessionID, _ := claims["sid"].(float64)
+    return uint(sessionID)
+}
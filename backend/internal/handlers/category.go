@@ -4,25 +4,35 @@ import (
     "encoding/json"
     "net/http"
     "strconv"
+    "strings"
     "github.com/gorilla/mux"
+    "todo-app/internal/apierror"
     "todo-app/internal/models"
 )
 
-type CategoryHandler struct{}
+type CategoryHandler struct {
+    categories models.CategoryStore
+    tasks      models.TaskStore
+    users      models.UserStore
+}
 
 type CreateCategoryRequest struct {
     Name string `json:"name"`
 }
 
-func NewCategoryHandler() *CategoryHandler {
-    return &CategoryHandler{}
+func NewCategoryHandler(categories models.CategoryStore, tasks models.TaskStore, users models.UserStore) *CategoryHandler {
+    return &CategoryHandler{
+        categories: categories,
+        tasks:      tasks,
+        users:      users,
+    }
 }
 
 func (h *CategoryHandler) List(w http.ResponseWriter, r *http.Request) {
     userID := getUserIDFromToken(r)
-    categories, err := models.GetUserCategories(userID)
+    categories, err := h.categories.GetUserCategories(r.Context(), userID)
     if err != nil {
-        http.Error(w, "Could not get categories", http.StatusInternalServerError)
+        apierror.Internal(w, r, err, "Could not get categories")
         return
     }
     json.NewEncoder(w).Encode(categories)
@@ -31,33 +41,66 @@ func (h *CategoryHandler) List(w http.ResponseWriter, r *http.Request) {
 func (h *CategoryHandler) Create(w http.ResponseWriter, r *http.Request) {
     var req CreateCategoryRequest
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        http.Error(w, "Invalid request", http.StatusBadRequest)
+        apierror.BadRequest(w, r, "Invalid request body")
+        return
+    }
+    if strings.TrimSpace(req.Name) == "" {
+        apierror.Invalid(w, r, "name", "name is required")
         return
     }
 
     userID := getUserIDFromToken(r)
-    category, err := models.CreateCategory(req.Name, userID)
+    category, err := h.categories.CreateCategory(r.Context(), &models.Category{Name: req.Name, UserID: userID})
     if err != nil {
-        http.Error(w, "Could not create category", http.StatusInternalServerError)
+        apierror.FromError(w, r, err, "", "Could not create category")
         return
     }
 
+    w.Header().Set("ETag", entityTag(category.Version))
     w.WriteHeader(http.StatusCreated)
     json.NewEncoder(w).Encode(category)
 }
 
+func (h *CategoryHandler) Get(w http.ResponseWriter, r *http.Request) {
+    categoryID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+    if err != nil {
+        apierror.BadRequest(w, r, "Invalid category ID")
+        return
+    }
+
+    category, err := h.categories.GetCategory(r.Context(), uint(categoryID), getUserIDFromToken(r))
+    if err != nil {
+        apierror.FromError(w, r, err, "Category not found", "Could not get category")
+        return
+    }
+    if notModified(w, r, entityTag(category.Version)) {
+        return
+    }
+    json.NewEncoder(w).Encode(category)
+}
+
 func (h *CategoryHandler) Delete(w http.ResponseWriter, r *http.Request) {
     vars := mux.Vars(r)
     categoryID, err := strconv.ParseUint(vars["id"], 10, 32)
     if err != nil {
-        http.Error(w, "Invalid category ID", http.StatusBadRequest)
+        apierror.BadRequest(w, r, "Invalid category ID")
         return
     }
 
     userID := getUserIDFromToken(r)
-    err = models.DeleteCategory(uint(categoryID), userID)
+    category, err := h.categories.GetCategory(r.Context(), uint(categoryID), userID)
+    if err != nil {
+        apierror.FromError(w, r, err, "Category not found", "Could not delete category")
+        return
+    }
+    version, ok := ifMatch(w, r, category.Version)
+    if !ok {
+        return
+    }
+
+    err = h.categories.DeleteCategory(r.Context(), category.ID, userID, version)
     if err != nil {
-        http.Error(w, "Could not delete category", http.StatusInternalServerError)
+        apierror.FromError(w, r, err, "Category not found", "Could not delete category")
         return
     }
 
@@ -68,25 +111,43 @@ func (h *CategoryHandler) GetTasks(w http.ResponseWriter, r *http.Request) {
     vars := mux.Vars(r)
     categoryID, err := strconv.ParseUint(vars["id"], 10, 32)
     if err != nil {
-        http.Error(w, "Invalid category ID", http.StatusBadRequest)
+        apierror.BadRequest(w, r, "Invalid category ID")
+        return
+    }
+
+    loc, err := userLocation(r, h.users)
+    if err != nil {
+        apierror.FromError(w, r, err, "User not found", "Could not get tasks")
+        return
+    }
+    filter, err := parseTaskFilter(r, loc)
+    if err != nil {
+        apierror.FromError(w, r, err, "", "Could not get tasks")
         return
     }
+    id := uint(categoryID)
+    filter.CategoryID = &id
 
     userID := getUserIDFromToken(r)
-    tasks, err := models.GetTasksByCategory(uint(categoryID), userID)
+    if _, err := h.categories.GetCategory(r.Context(), id, userID); err != nil {
+        apierror.FromError(w, r, err, "Category not found", "Could not get tasks")
+        return
+    }
+
+    page, err := h.tasks.ListTasks(r.Context(), userID, filter)
     if err != nil {
-        http.Error(w, "Could not get tasks", http.StatusInternalServerError)
+        apierror.FromError(w, r, err, "", "Could not get tasks")
         return
     }
 
-    json.NewEncoder(w).Encode(tasks)
+    json.NewEncoder(w).Encode(page)
 }
 
 func (h *CategoryHandler) UpdateTaskCategory(w http.ResponseWriter, r *http.Request) {
     vars := mux.Vars(r)
     taskID, err := strconv.ParseUint(vars["id"], 10, 32)
     if err != nil {
-        http.Error(w, "Invalid task ID", http.StatusBadRequest)
+        apierror.BadRequest(w, r, "Invalid task ID")
         return
     }
 
@@ -94,16 +155,27 @@ func (h *CategoryHandler) UpdateTaskCategory(w http.ResponseWriter, r *http.Requ
         CategoryID uint `json:"category_id"`
     }
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        http.Error(w, "Invalid request", http.StatusBadRequest)
+        apierror.BadRequest(w, r, "Invalid request body")
         return
     }
 
     userID := getUserIDFromToken(r)
-    err = models.UpdateTaskCategory(uint(taskID), req.CategoryID, userID)
+    existing, err := h.tasks.GetTask(r.Context(), uint(taskID), userID)
+    if err != nil {
+        apierror.FromError(w, r, err, "Task not found", "Could not update task category")
+        return
+    }
+    version, ok := ifMatch(w, r, existing.Version)
+    if !ok {
+        return
+    }
+
+    task, err := h.tasks.UpdateTaskCategory(r.Context(), existing.ID, req.CategoryID, userID, version)
     if err != nil {
-        http.Error(w, "Could not update task category", http.StatusInternalServerError)
+        apierror.FromError(w, r, err, "Task not found", "Could not update task category")
         return
     }
 
-    w.WriteHeader(http.StatusOK)
+    w.Header().Set("ETag", entityTag(task.Version))
+    json.NewEncoder(w).Encode(task)
 } 
\ No newline at end of file
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+    "net/http"
+    "strconv"
+    "strings"
+    "todo-app/internal/apierror"
+    "todo-app/internal/models"
+)
+
+// entityTag — ETag записи версии version.
+func entityTag(version int) string {
+    return `"` + strconv.Itoa(version) + `"`
+}
+
+func headerTags(r *http.Request, name string) []string {
+    var tags []string
+    for _, value := range r.Header.Values(name) {
+        for _, tag := range strings.Split(value, ",") {
+            if tag = strings.TrimSpace(tag); tag != "" {
+                tags = append(tags, tag)
+            }
+        }
+    }
+    return tags
+}
+
+// ifMatch сверяет If-Match с текущей версией записи и возвращает версию,
+// которую хранилище проверит при записи; ноль — условия нет. Слабые теги
+// с If-Match не совпадают (RFC 9110). При несовпадении отвечает 412.
+func ifMatch(w http.ResponseWriter, r *http.Request, current int) (int, bool) {
+    tags := headerTags(r, "If-Match")
+    if len(tags) == 0 {
+        return 0, true
+    }
+    for _, tag := range tags {
+        switch tag {
+        case "*":
+            return 0, true
+        case entityTag(current):
+            return current, true
+        }
+    }
+    w.Header().Set("ETag", entityTag(current))
+    apierror.FromError(w, r, models.ErrVersionMismatch, "", "")
+    return 0, false
+}
+
+// notModified выставляет ETag и отвечает 304, если клиент прислал его же в
+// If-None-Match. Теги сравниваются без учёта W/.
+func notModified(w http.ResponseWriter, r *http.Request, tag string) bool {
+    w.Header().Set("ETag", tag)
+    for _, t := range headerTags(r, "If-None-Match") {
+        if t == "*" || strings.TrimPrefix(t, "W/") == strings.TrimPrefix(tag, "W/") {
+            w.WriteHeader(http.StatusNotModified)
+            return true
+        }
+    }
+    return false
+}
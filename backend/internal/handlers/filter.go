@@ -0,0 +1,89 @@
+package handlers
+
+import (
+    "fmt"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+    "todo-app/internal/models"
+)
+
+// parseTaskFilter разбирает параметры запроса списка задач:
+// completed, priority, category_id, due_from, due_to, overdue, q, sort, cursor, limit.
+// Ошибки — *models.ValidationError с именем параметра. Даты без смещения
+// трактуются в часовом поясе пользователя loc.
+func parseTaskFilter(r *http.Request, loc *time.Location) (models.TaskFilter, error) {
+    query := r.URL.Query()
+    var filter models.TaskFilter
+
+    parseBool := func(name string) (*bool, error) {
+        v := query.Get(name)
+        if v == "" {
+            return nil, nil
+        }
+        b, err := strconv.ParseBool(v)
+        if err != nil {
+            return nil, models.Invalid(name, "must be true or false")
+        }
+        return &b, nil
+    }
+
+    var err error
+    if filter.Completed, err = parseBool("completed"); err != nil {
+        return filter, err
+    }
+    if filter.Overdue, err = parseBool("overdue"); err != nil {
+        return filter, err
+    }
+
+    if v := query.Get("priority"); v != "" {
+        p, err := strconv.Atoi(v)
+        if err != nil || p < int(models.Low) || p > int(models.High) {
+            return filter, models.Invalid("priority", fmt.Sprintf("must be between %d and %d", models.Low, models.High))
+        }
+        priority := models.Priority(p)
+        filter.Priority = &priority
+    }
+
+    if v := query.Get("category_id"); v != "" {
+        id, err := strconv.ParseUint(v, 10, 32)
+        if err != nil {
+            return filter, models.Invalid("category_id", "must be a positive integer")
+        }
+        categoryID := uint(id)
+        filter.CategoryID = &categoryID
+    }
+
+    if v := query.Get("due_from"); v != "" {
+        t, _, err := parseDate(v, loc)
+        if err != nil {
+            return filter, models.Invalid("due_from", err.Error())
+        }
+        filter.DueFrom = &t
+    }
+    if v := query.Get("due_to"); v != "" {
+        t, _, err := parseDate(v, loc)
+        if err != nil {
+            return filter, models.Invalid("due_to", err.Error())
+        }
+        filter.DueTo = &t
+    }
+
+    if v := query.Get("limit"); v != "" {
+        limit, err := strconv.Atoi(v)
+        if err != nil || limit < 1 || limit > models.MaxTaskPageSize {
+            return filter, models.Invalid("limit", fmt.Sprintf("must be between 1 and %d", models.MaxTaskPageSize))
+        }
+        filter.Limit = limit
+    }
+
+    filter.Query = strings.TrimSpace(query.Get("q"))
+    filter.Sort = query.Get("sort")
+    filter.Cursor = query.Get("cursor")
+
+    if err := filter.Validate(); err != nil {
+        return filter, err
+    }
+    return filter, nil
+}
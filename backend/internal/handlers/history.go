@@ -0,0 +1,86 @@
+package handlers
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strconv"
+    "github.com/gorilla/mux"
+    "todo-app/internal/apierror"
+    "todo-app/internal/models"
+)
+
+// HistoryHandler отдаёт историю изменений задачи и ленту действий
+// пользователя по всем его задачам.
+type HistoryHandler struct {
+    history models.HistoryStore
+    tasks   models.TaskStore
+}
+
+func NewHistoryHandler(history models.HistoryStore, tasks models.TaskStore) *HistoryHandler {
+    return &HistoryHandler{
+        history: history,
+        tasks:   tasks,
+    }
+}
+
+// parseHistoryFilter разбирает параметры cursor и limit.
+func parseHistoryFilter(r *http.Request) (models.HistoryFilter, error) {
+    query := r.URL.Query()
+    filter := models.HistoryFilter{Cursor: query.Get("cursor")}
+    if v := query.Get("limit"); v != "" {
+        limit, err := strconv.Atoi(v)
+        if err != nil || limit < 1 || limit > models.MaxHistoryPageSize {
+            return filter, models.Invalid("limit", fmt.Sprintf("must be between 1 and %d", models.MaxHistoryPageSize))
+        }
+        filter.Limit = limit
+    }
+    return filter, filter.Validate()
+}
+
+// Task отдаёт историю задачи страницами от новых записей к старым. История
+// остаётся доступной и после удаления задачи.
+func (h *HistoryHandler) Task(w http.ResponseWriter, r *http.Request) {
+    taskID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+    if err != nil {
+        apierror.BadRequest(w, r, "Invalid task ID")
+        return
+    }
+    filter, err := parseHistoryFilter(r)
+    if err != nil {
+        apierror.FromError(w, r, err, "", "Could not get task history")
+        return
+    }
+    filter.TaskID = uint(taskID)
+
+    userID := getUserIDFromToken(r)
+    page, err := h.history.ListTaskEvents(r.Context(), userID, filter)
+    if err != nil {
+        apierror.FromError(w, r, err, "", "Could not get task history")
+        return
+    }
+    // Пустая первая страница — у чужой или несуществующей задачи либо у
+    // задачи, созданной до появления истории
+    if len(page.Events) == 0 && filter.Cursor == "" {
+        if _, err := h.tasks.GetTask(r.Context(), filter.TaskID, userID); err != nil {
+            apierror.FromError(w, r, err, "Task not found", "Could not get task history")
+            return
+        }
+    }
+    json.NewEncoder(w).Encode(page)
+}
+
+// Activity отдаёт ленту изменений всех задач пользователя.
+func (h *HistoryHandler) Activity(w http.ResponseWriter, r *http.Request) {
+    filter, err := parseHistoryFilter(r)
+    if err != nil {
+        apierror.FromError(w, r, err, "", "Could not get activity")
+        return
+    }
+    page, err := h.history.ListTaskEvents(r.Context(), getUserIDFromToken(r), filter)
+    if err != nil {
+        apierror.FromError(w, r, err, "", "Could not get activity")
+        return
+    }
+    json.NewEncoder(w).Encode(page)
+}
@@ -2,50 +2,266 @@ package handlers
 
 import (
     "encoding/json"
+    "errors"
+    "fmt"
+    "io"
     "net/http"
     "strconv"
+    "time"
     "github.com/gorilla/mux"
+    "todo-app/internal/apierror"
     "todo-app/internal/models"
 )
 
-type NotificationHandler struct{}
+const (
+    defaultSnoozeMinutes = 10
+    maxSnoozeMinutes     = 7 * 24 * 60
+)
+
+type NotificationHandler struct {
+    notifications models.NotificationStore
+    reminders     models.ReminderStore
+    deliveries    models.DeliveryStore
+    users         models.UserStore
+}
+
+// NotificationSelectionRequest выбирает уведомления для массовых операций:
+// по списку ids и/или созданные до before. Нужно хотя бы одно условие.
+type NotificationSelectionRequest struct {
+    IDs    []uint  `json:"ids"`
+    Before *string `json:"before"`
+}
+
+type UnreadCountResponse struct {
+    Count int `json:"count"`
+}
+
+// BulkNotificationResponse сообщает, сколько уведомлений затронул запрос.
+type BulkNotificationResponse struct {
+    Affected int64 `json:"affected"`
+}
 
-func NewNotificationHandler() *NotificationHandler {
-    return &NotificationHandler{}
+// SnoozeRequest откладывает уведомление на Minutes минут; пустое тело —
+// на defaultSnoozeMinutes.
+type SnoozeRequest struct {
+    Minutes int `json:"minutes"`
+}
+
+func NewNotificationHandler(notifications models.NotificationStore, reminders models.ReminderStore, deliveries models.DeliveryStore, users models.UserStore) *NotificationHandler {
+    return &NotificationHandler{
+        notifications: notifications,
+        reminders:     reminders,
+        deliveries:    deliveries,
+        users:         users,
+    }
 }
 
+// parseNotificationFilter разбирает параметры unread_only, cursor и limit.
+func parseNotificationFilter(r *http.Request) (models.NotificationFilter, error) {
+    query := r.URL.Query()
+    filter := models.NotificationFilter{Cursor: query.Get("cursor")}
+    if v := query.Get("unread_only"); v != "" {
+        unread, err := strconv.ParseBool(v)
+        if err != nil {
+            return filter, models.Invalid("unread_only", "must be true or false")
+        }
+        filter.UnreadOnly = unread
+    }
+    if v := query.Get("limit"); v != "" {
+        limit, err := strconv.Atoi(v)
+        if err != nil || limit < 1 || limit > models.MaxNotificationPageSize {
+            return filter, models.Invalid("limit", fmt.Sprintf("must be between 1 and %d", models.MaxNotificationPageSize))
+        }
+        filter.Limit = limit
+    }
+    return filter, filter.Validate()
+}
+
+// List отдаёт уведомления страницами от новых к старым.
 func (h *NotificationHandler) List(w http.ResponseWriter, r *http.Request) {
+    filter, err := parseNotificationFilter(r)
+    if err != nil {
+        apierror.FromError(w, r, err, "", "Could not get notifications")
+        return
+    }
+    locale, err := requestLocale(r, h.users)
+    if err != nil {
+        apierror.FromError(w, r, err, "User not found", "Could not get notifications")
+        return
+    }
+
     userID := getUserIDFromToken(r)
-    notifications, err := models.GetUserNotifications(userID)
+    page, err := h.notifications.ListNotifications(r.Context(), userID, filter)
     if err != nil {
-        http.Error(w, "Could not get notifications", http.StatusInternalServerError)
+        apierror.FromError(w, r, err, "", "Could not get notifications")
         return
     }
-    json.NewEncoder(w).Encode(notifications)
+    for i := range page.Notifications {
+        page.Notifications[i].Localize(locale)
+    }
+    json.NewEncoder(w).Encode(page)
+}
+
+func (h *NotificationHandler) UnreadCount(w http.ResponseWriter, r *http.Request) {
+    count, err := h.notifications.CountUnreadNotifications(r.Context(), getUserIDFromToken(r))
+    if err != nil {
+        apierror.Internal(w, r, err, "Could not count notifications")
+        return
+    }
+    json.NewEncoder(w).Encode(UnreadCountResponse{Count: count})
+}
+
+// parseSelection читает выбор уведомлений из тела запроса. При ошибке ответ
+// уже записан.
+func (h *NotificationHandler) parseSelection(w http.ResponseWriter, r *http.Request) (models.NotificationSelection, bool) {
+    var sel models.NotificationSelection
+    var req NotificationSelectionRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        apierror.BadRequest(w, r, "Invalid request body")
+        return sel, false
+    }
+    if req.IDs == nil && req.Before == nil {
+        apierror.Invalid(w, r, "ids", "ids or before is required")
+        return sel, false
+    }
+    if len(req.IDs) > models.MaxNotificationSelection {
+        apierror.Invalid(w, r, "ids", fmt.Sprintf("at most %d ids are allowed", models.MaxNotificationSelection))
+        return sel, false
+    }
+    sel.IDs = req.IDs
+    if req.Before != nil {
+        loc, err := userLocation(r, h.users)
+        if err != nil {
+            apierror.FromError(w, r, err, "User not found", "Could not parse selection")
+            return sel, false
+        }
+        before, _, err := parseDate(*req.Before, loc)
+        if err != nil {
+            apierror.Invalid(w, r, "before", err.Error())
+            return sel, false
+        }
+        sel.Before = &before
+    }
+    return sel, true
+}
+
+// ReadAll отмечает прочитанными все уведомления пользователя.
+func (h *NotificationHandler) ReadAll(w http.ResponseWriter, r *http.Request) {
+    updated, err := h.notifications.MarkNotificationsRead(r.Context(), getUserIDFromToken(r), models.NotificationSelection{})
+    if err != nil {
+        apierror.Internal(w, r, err, "Could not mark notifications as read")
+        return
+    }
+    json.NewEncoder(w).Encode(BulkNotificationResponse{Affected: updated})
+}
+
+// MarkManyAsRead отмечает прочитанными уведомления по списку id или дате.
+func (h *NotificationHandler) MarkManyAsRead(w http.ResponseWriter, r *http.Request) {
+    sel, ok := h.parseSelection(w, r)
+    if !ok {
+        return
+    }
+    updated, err := h.notifications.MarkNotificationsRead(r.Context(), getUserIDFromToken(r), sel)
+    if err != nil {
+        apierror.Internal(w, r, err, "Could not mark notifications as read")
+        return
+    }
+    json.NewEncoder(w).Encode(BulkNotificationResponse{Affected: updated})
+}
+
+func (h *NotificationHandler) Delete(w http.ResponseWriter, r *http.Request) {
+    notificationID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+    if err != nil {
+        apierror.BadRequest(w, r, "Invalid notification ID")
+        return
+    }
+
+    err = h.notifications.DeleteNotification(r.Context(), uint(notificationID), getUserIDFromToken(r))
+    if err != nil {
+        apierror.FromError(w, r, err, "Notification not found", "Could not delete notification")
+        return
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *NotificationHandler) DeleteMany(w http.ResponseWriter, r *http.Request) {
+    sel, ok := h.parseSelection(w, r)
+    if !ok {
+        return
+    }
+    deleted, err := h.notifications.DeleteNotifications(r.Context(), getUserIDFromToken(r), sel)
+    if err != nil {
+        apierror.Internal(w, r, err, "Could not delete notifications")
+        return
+    }
+    json.NewEncoder(w).Encode(BulkNotificationResponse{Affected: deleted})
 }
 
 func (h *NotificationHandler) MarkAsRead(w http.ResponseWriter, r *http.Request) {
     vars := mux.Vars(r)
     notificationID, err := strconv.ParseUint(vars["id"], 10, 32)
     if err != nil {
-        http.Error(w, "Invalid notification ID", http.StatusBadRequest)
+        apierror.BadRequest(w, r, "Invalid notification ID")
         return
     }
 
-    err = models.MarkNotificationAsRead(uint(notificationID))
+    userID := getUserIDFromToken(r)
+    err = h.notifications.MarkNotificationAsRead(r.Context(), uint(notificationID), userID)
     if err != nil {
-        http.Error(w, "Could not mark notification as read", http.StatusInternalServerError)
+        apierror.FromError(w, r, err, "Notification not found", "Could not mark notification as read")
         return
     }
 
     w.WriteHeader(http.StatusOK)
 }
 
-func (h *NotificationHandler) CheckDueTasks(w http.ResponseWriter, r *http.Request) {
-    err := models.CheckDueTasks()
+// Snooze отмечает уведомление прочитанным и создаёт напоминание о той же
+// задаче через указанное число минут.
+func (h *NotificationHandler) Snooze(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    notificationID, err := strconv.ParseUint(vars["id"], 10, 32)
     if err != nil {
-        http.Error(w, "Could not check due tasks", http.StatusInternalServerError)
+        apierror.BadRequest(w, r, "Invalid notification ID")
         return
     }
-    w.WriteHeader(http.StatusOK)
-} 
\ No newline at end of file
+
+    var req SnoozeRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+        apierror.BadRequest(w, r, "Invalid request body")
+        return
+    }
+    if req.Minutes == 0 {
+        req.Minutes = defaultSnoozeMinutes
+    }
+    if req.Minutes < 0 || req.Minutes > maxSnoozeMinutes {
+        apierror.Invalid(w, r, "minutes", "minutes must be between 1 and 10080")
+        return
+    }
+
+    userID := getUserIDFromToken(r)
+    until := time.Now().Add(time.Duration(req.Minutes) * time.Minute)
+    reminder, err := h.reminders.SnoozeNotification(r.Context(), uint(notificationID), userID, until)
+    if err != nil {
+        apierror.FromError(w, r, err, "Notification not found", "Could not snooze notification")
+        return
+    }
+
+    w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(reminder)
+}
+
+// Deliveries возвращает состояние доставки уведомления по внешним каналам.
+func (h *NotificationHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+    notificationID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+    if err != nil {
+        apierror.BadRequest(w, r, "Invalid notification ID")
+        return
+    }
+
+    deliveries, err := h.deliveries.GetNotificationDeliveries(r.Context(), uint(notificationID), getUserIDFromToken(r))
+    if err != nil {
+        apierror.FromError(w, r, err, "Notification not found", "Could not get deliveries")
+        return
+    }
+    json.NewEncoder(w).Encode(deliveries)
+}
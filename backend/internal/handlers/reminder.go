@@ -0,0 +1,156 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "strconv"
+    "time"
+    "github.com/gorilla/mux"
+    "todo-app/internal/apierror"
+    "todo-app/internal/models"
+)
+
+type ReminderHandler struct {
+    tasks     models.TaskStore
+    reminders models.ReminderStore
+    users     models.UserStore
+}
+
+// ReminderRequest задаёт ровно одно из полей: абсолютное время или
+// смещение в минутах до срока задачи.
+type ReminderRequest struct {
+    RemindAt      *string `json:"remind_at"`
+    MinutesBefore *int    `json:"minutes_before"`
+}
+
+func NewReminderHandler(tasks models.TaskStore, reminders models.ReminderStore, users models.UserStore) *ReminderHandler {
+    return &ReminderHandler{
+        tasks:     tasks,
+        reminders: reminders,
+        users:     users,
+    }
+}
+
+// parseReminder читает тело запроса и id из пути. При ошибке ответ уже записан.
+func (h *ReminderHandler) parseReminder(w http.ResponseWriter, r *http.Request) (*models.Reminder, bool) {
+    vars := mux.Vars(r)
+    taskID, err := strconv.ParseUint(vars["id"], 10, 32)
+    if err != nil {
+        apierror.BadRequest(w, r, "Invalid task ID")
+        return nil, false
+    }
+
+    var req ReminderRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        apierror.BadRequest(w, r, "Invalid request body")
+        return nil, false
+    }
+
+    reminder := &models.Reminder{
+        TaskID:        uint(taskID),
+        UserID:        getUserIDFromToken(r),
+        MinutesBefore: req.MinutesBefore,
+    }
+    if req.RemindAt != nil {
+        loc, err := userLocation(r, h.users)
+        if err != nil {
+            apierror.FromError(w, r, err, "User not found", "Could not parse reminder")
+            return nil, false
+        }
+        remindAt, _, err := parseDate(*req.RemindAt, loc)
+        if err != nil {
+            apierror.Invalid(w, r, "remind_at", err.Error())
+            return nil, false
+        }
+        if remindAt.Before(time.Now()) {
+            apierror.Invalid(w, r, "remind_at", "remind_at must be in the future")
+            return nil, false
+        }
+        reminder.RemindAt = &remindAt
+    }
+    if err := reminder.Validate(); err != nil {
+        apierror.FromError(w, r, err, "", "Invalid reminder")
+        return nil, false
+    }
+    return reminder, true
+}
+
+func (h *ReminderHandler) List(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    taskID, err := strconv.ParseUint(vars["id"], 10, 32)
+    if err != nil {
+        apierror.BadRequest(w, r, "Invalid task ID")
+        return
+    }
+
+    userID := getUserIDFromToken(r)
+    if _, err := h.tasks.GetTask(r.Context(), uint(taskID), userID); err != nil {
+        apierror.FromError(w, r, err, "Task not found", "Could not get reminders")
+        return
+    }
+
+    reminders, err := h.reminders.GetTaskReminders(r.Context(), uint(taskID), userID)
+    if err != nil {
+        apierror.Internal(w, r, err, "Could not get reminders")
+        return
+    }
+    json.NewEncoder(w).Encode(reminders)
+}
+
+func (h *ReminderHandler) Create(w http.ResponseWriter, r *http.Request) {
+    reminder, ok := h.parseReminder(w, r)
+    if !ok {
+        return
+    }
+
+    created, err := h.reminders.CreateReminder(r.Context(), reminder)
+    if err != nil {
+        apierror.FromError(w, r, err, "Task not found", "Could not create reminder")
+        return
+    }
+
+    w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(created)
+}
+
+func (h *ReminderHandler) Update(w http.ResponseWriter, r *http.Request) {
+    reminderID, err := strconv.ParseUint(mux.Vars(r)["reminderId"], 10, 32)
+    if err != nil {
+        apierror.BadRequest(w, r, "Invalid reminder ID")
+        return
+    }
+    reminder, ok := h.parseReminder(w, r)
+    if !ok {
+        return
+    }
+    reminder.ID = uint(reminderID)
+
+    updated, err := h.reminders.UpdateReminder(r.Context(), reminder)
+    if err != nil {
+        apierror.FromError(w, r, err, "Reminder not found", "Could not update reminder")
+        return
+    }
+    json.NewEncoder(w).Encode(updated)
+}
+
+func (h *ReminderHandler) Delete(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    taskID, err := strconv.ParseUint(vars["id"], 10, 32)
+    if err != nil {
+        apierror.BadRequest(w, r, "Invalid task ID")
+        return
+    }
+    reminderID, err := strconv.ParseUint(vars["reminderId"], 10, 32)
+    if err != nil {
+        apierror.BadRequest(w, r, "Invalid reminder ID")
+        return
+    }
+
+    userID := getUserIDFromToken(r)
+    err = h.reminders.DeleteReminder(r.Context(), uint(reminderID), uint(taskID), userID)
+    if err != nil {
+        apierror.FromError(w, r, err, "Reminder not found", "Could not delete reminder")
+        return
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strconv"
+    "todo-app/internal/apierror"
+    "todo-app/internal/models"
+)
+
+type SearchHandler struct {
+    search models.SearchStore
+}
+
+func NewSearchHandler(search models.SearchStore) *SearchHandler {
+    return &SearchHandler{
+        search: search,
+    }
+}
+
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+    q := r.URL.Query().Get("q")
+    if len(models.SearchTerms(q)) == 0 {
+        apierror.Invalid(w, r, "q", "query parameter q is required")
+        return
+    }
+
+    limit := models.DefaultSearchLimit
+    if v := r.URL.Query().Get("limit"); v != "" {
+        parsed, err := strconv.Atoi(v)
+        if err != nil || parsed < 1 || parsed > models.MaxSearchLimit {
+            apierror.Invalid(w, r, "limit", fmt.Sprintf("limit must be between 1 and %d", models.MaxSearchLimit))
+            return
+        }
+        limit = parsed
+    }
+
+    userID := getUserIDFromToken(r)
+    results, err := h.search.Search(r.Context(), userID, q, limit)
+    if err != nil {
+        apierror.Internal(w, r, err, "Could not search")
+        return
+    }
+
+    json.NewEncoder(w).Encode(results)
+}
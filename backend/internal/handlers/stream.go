@@ -0,0 +1,162 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "net/http"
+    "strconv"
+    "time"
+    "todo-app/internal/apierror"
+    "todo-app/internal/config"
+    "todo-app/internal/events"
+    "todo-app/internal/i18n"
+    "todo-app/internal/models"
+    "todo-app/internal/requestid"
+)
+
+// Через сколько миллисекунд клиенту SSE переподключаться после обрыва.
+const streamRetry = 5000
+
+type StreamHandler struct {
+    heartbeat time.Duration
+    events    models.EventStore
+    users     models.UserStore
+    hub       *events.Hub
+}
+
+func NewStreamHandler(cfg config.StreamConfig, store models.EventStore, users models.UserStore, hub *events.Hub) *StreamHandler {
+    return &StreamHandler{
+        heartbeat: cfg.Heartbeat,
+        events:    store,
+        users:     users,
+        hub:       hub,
+    }
+}
+
+// lastEventID берёт позицию из заголовка Last-Event-ID, который браузер
+// отправляет при переподключении, или из параметра last_event_id.
+func lastEventID(r *http.Request) (int64, bool, error) {
+    value := r.Header.Get("Last-Event-ID")
+    if value == "" {
+        value = r.URL.Query().Get("last_event_id")
+    }
+    if value == "" {
+        return 0, false, nil
+    }
+    id, err := strconv.ParseInt(value, 10, 64)
+    if err != nil || id < 0 {
+        return 0, false, errors.New("invalid event id")
+    }
+    return id, true, nil
+}
+
+// Stream отдаёт события пользователя в формате Server-Sent Events. Без
+// Last-Event-ID поток начинается с текущего момента.
+func (h *StreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
+    flusher, ok := w.(http.Flusher)
+    if !ok {
+        apierror.Internal(w, r, errors.New("response writer does not support flushing"), "Streaming is not supported")
+        return
+    }
+    lastID, resume, err := lastEventID(r)
+    if err != nil {
+        apierror.Invalid(w, r, "Last-Event-ID", "must be a non-negative integer")
+        return
+    }
+
+    // Язык фиксируется на время соединения
+    locale, err := requestLocale(r, h.users)
+    if err != nil {
+        apierror.FromError(w, r, err, "User not found", "Could not open event stream")
+        return
+    }
+
+    ctx := r.Context()
+    userID := getUserIDFromToken(r)
+
+    // Подписываемся до чтения журнала, чтобы не пропустить события между ними
+    wake, unsubscribe := h.hub.Subscribe(userID)
+    defer unsubscribe()
+
+    if !resume {
+        lastID, err = h.events.LatestEventID(ctx, userID)
+        if err != nil {
+            apierror.Internal(w, r, err, "Could not open event stream")
+            return
+        }
+    }
+
+    w.Header().Set("Content-Type", "text/event-stream")
+    w.Header().Set("Cache-Control", "no-cache")
+    w.Header().Set("Connection", "keep-alive")
+    w.Header().Set("X-Accel-Buffering", "no")
+    w.WriteHeader(http.StatusOK)
+    if _, err := fmt.Fprintf(w, "retry: %d\n\n", streamRetry); err != nil {
+        return
+    }
+
+    heartbeat := time.NewTicker(h.heartbeat)
+    defer heartbeat.Stop()
+    for {
+        lastID, err = h.writeEvents(ctx, w, userID, locale, lastID)
+        if err != nil {
+            if ctx.Err() == nil {
+                log.Printf("[%s] Event stream closed: %v", requestid.FromContext(ctx), err)
+            }
+            return
+        }
+        flusher.Flush()
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-wake:
+        case <-heartbeat.C:
+            // Заодно перечитываем журнал на случай потерянного NOTIFY
+            if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+                return
+            }
+        }
+    }
+}
+
+// writeEvents дописывает в поток все события после afterID и возвращает id
+// последнего отправленного. Текст уведомлений рендерится на языке locale.
+func (h *StreamHandler) writeEvents(ctx context.Context, w http.ResponseWriter, userID uint, locale i18n.Locale, afterID int64) (int64, error) {
+    for {
+        batch, err := h.events.ListEvents(ctx, userID, afterID, models.MaxEventBatch)
+        if err != nil {
+            return afterID, err
+        }
+        for _, e := range batch {
+            if e.Type == models.EventNotificationCreated {
+                e.Data = localizeNotification(e.Data, locale)
+            }
+            if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, e.Data); err != nil {
+                return afterID, err
+            }
+            afterID = e.ID
+        }
+        if len(batch) < models.MaxEventBatch {
+            return afterID, nil
+        }
+    }
+}
+
+// localizeNotification подставляет текст в данные события notification.created;
+// нераспознанные данные отдаются как есть.
+func localizeNotification(data json.RawMessage, locale i18n.Locale) json.RawMessage {
+    var n models.Notification
+    if err := json.Unmarshal(data, &n); err != nil {
+        return data
+    }
+    n.Localize(locale)
+    localized, err := json.Marshal(n)
+    if err != nil {
+        return data
+    }
+    return localized
+}
@@ -0,0 +1,449 @@
+package handlers
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+    "todo-app/internal/apierror"
+    "todo-app/internal/models"
+)
+
+const (
+    syncCreate = "create"
+    syncUpdate = "update"
+    syncDelete = "delete"
+
+    syncApplied  = "applied"
+    syncConflict = "conflict"
+    syncRejected = "rejected"
+
+    maxSyncMutations  = 100
+    maxClientIDLength = 64
+)
+
+type SyncHandler struct {
+    taskHandler   *TaskHandler
+    tasks         models.TaskStore
+    categories    models.CategoryStore
+    notifications models.NotificationStore
+    users         models.UserStore
+    sync          models.SyncStore
+}
+
+// SyncMutation — изменение, сделанное клиентом без связи. ClientID у create —
+// идентификатор, который клиент выдал новой записи; повторная отправка той же
+// мутации возвращает уже созданную запись. ID — id записи на сервере или
+// строка с её client_id. Version, если не ноль, — версия, которую клиент
+// менял; при расхождении мутация не применяется.
+type SyncMutation struct {
+    ClientID string          `json:"client_id"`
+    Entity   string          `json:"entity"`
+    Op       string          `json:"op"`
+    ID       json.RawMessage `json:"id"`
+    Version  int             `json:"version"`
+    Data     json.RawMessage `json:"data"`
+}
+
+type SyncRequest struct {
+    Mutations []SyncMutation `json:"mutations"`
+}
+
+// SyncResult — итог одной мутации. При конфликте в Task или Category
+// возвращается текущее состояние записи, в том числе из корзины, если
+// повторно прислано создание уже удалённой записи.
+type SyncResult struct {
+    ClientID string             `json:"client_id,omitempty"`
+    Status   string             `json:"status"`
+    ID       uint               `json:"id,omitempty"`
+    Task     *models.Task       `json:"task,omitempty"`
+    Category *models.Category   `json:"category,omitempty"`
+    Error    *apierror.Response `json:"error,omitempty"`
+}
+
+type SyncResponse struct {
+    Results []SyncResult `json:"results"`
+}
+
+func NewSyncHandler(tasks models.TaskStore, categories models.CategoryStore, notifications models.NotificationStore, users models.UserStore, events models.EventStore, sync models.SyncStore) *SyncHandler {
+    return &SyncHandler{
+        taskHandler:   NewTaskHandler(tasks, notifications, users, events),
+        tasks:         tasks,
+        categories:    categories,
+        notifications: notifications,
+        users:         users,
+        sync:          sync,
+    }
+}
+
+// Changes возвращает записи, изменённые после токена since, и надгробия
+// удалённых. Без since возвращается всё текущее состояние.
+func (h *SyncHandler) Changes(w http.ResponseWriter, r *http.Request) {
+    limit := models.DefaultSyncLimit
+    if v := r.URL.Query().Get("limit"); v != "" {
+        parsed, err := strconv.Atoi(v)
+        if err != nil || parsed < 1 || parsed > models.MaxSyncLimit {
+            apierror.Invalid(w, r, "limit", fmt.Sprintf("limit must be between 1 and %d", models.MaxSyncLimit))
+            return
+        }
+        limit = parsed
+    }
+
+    locale, err := requestLocale(r, h.users)
+    if err != nil {
+        apierror.FromError(w, r, err, "User not found", "Could not get changes")
+        return
+    }
+
+    userID := getUserIDFromToken(r)
+    changes, err := h.sync.GetChanges(r.Context(), userID, r.URL.Query().Get("since"), limit)
+    if err != nil {
+        apierror.FromError(w, r, err, "", "Could not get changes")
+        return
+    }
+    for i := range changes.Notifications {
+        changes.Notifications[i].Localize(locale)
+    }
+    json.NewEncoder(w).Encode(changes)
+}
+
+// Apply применяет пачку мутаций по порядку. Каждая мутация применяется
+// независимо: отклонённая не мешает следующим, поэтому ответ всегда 200 с
+// результатом на каждую мутацию.
+func (h *SyncHandler) Apply(w http.ResponseWriter, r *http.Request) {
+    var req SyncRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        apierror.BadRequest(w, r, "Invalid request body")
+        return
+    }
+    if len(req.Mutations) > maxSyncMutations {
+        apierror.Invalid(w, r, "mutations", fmt.Sprintf("at most %d mutations per request", maxSyncMutations))
+        return
+    }
+
+    loc, err := userLocation(r, h.users)
+    if err != nil {
+        apierror.FromError(w, r, err, "User not found", "Could not apply mutations")
+        return
+    }
+
+    userID := getUserIDFromToken(r)
+    // В истории задач изменения из пакета отмечаются источником sync
+    r = r.WithContext(models.WithActor(r.Context(), models.Actor{UserID: userID, Source: models.SourceSync}))
+    results := make([]SyncResult, 0, len(req.Mutations))
+    for _, m := range req.Mutations {
+        results = append(results, h.apply(r, userID, loc, m))
+    }
+    json.NewEncoder(w).Encode(SyncResponse{Results: results})
+}
+
+func (h *SyncHandler) apply(r *http.Request, userID uint, loc *time.Location, m SyncMutation) SyncResult {
+    result := SyncResult{ClientID: m.ClientID}
+    var err error
+    switch m.Entity {
+    case models.EntityTask:
+        err = h.applyTask(r, userID, loc, m, &result)
+    case models.EntityCategory:
+        err = h.applyCategory(r, userID, m, &result)
+    case models.EntityNotification:
+        err = h.applyNotification(r, userID, m, &result)
+    default:
+        err = models.Invalid("entity", "entity must be task, category or notification")
+    }
+    if err == nil {
+        result.Status = syncApplied
+        return result
+    }
+
+    result.Status = syncRejected
+    if errors.Is(err, models.ErrVersionMismatch) || errors.Is(err, models.ErrClientIDTrashed) {
+        result.Status = syncConflict
+    }
+    _, resp := apierror.Describe(r, err, "Record not found", "Could not apply mutation")
+    result.Error = &resp
+    return result
+}
+
+// resolveID разбирает id записи: число — id на сервере, строка — client_id.
+func (h *SyncHandler) resolveID(r *http.Request, userID uint, entity string, raw json.RawMessage) (uint, error) {
+    var id uint
+    if err := json.Unmarshal(raw, &id); err == nil && id != 0 {
+        return id, nil
+    }
+    var clientID string
+    if err := json.Unmarshal(raw, &clientID); err != nil || clientID == "" {
+        return 0, models.Invalid("id", "id must be a record id or client_id")
+    }
+    return h.sync.ResolveClientID(r.Context(), userID, entity, clientID)
+}
+
+func validateClientID(clientID string) error {
+    if strings.TrimSpace(clientID) == "" {
+        return models.Invalid("client_id", "client_id is required")
+    }
+    if len(clientID) > maxClientIDLength {
+        return models.Invalid("client_id", fmt.Sprintf("client_id must be at most %d characters", maxClientIDLength))
+    }
+    return nil
+}
+
+// decodeFields разбирает data мутации как JSON-объект.
+func decodeFields(data json.RawMessage) (map[string]json.RawMessage, error) {
+    var fields map[string]json.RawMessage
+    if err := json.Unmarshal(data, &fields); err != nil || fields == nil {
+        return nil, models.Invalid("data", "data must be an object")
+    }
+    return fields, nil
+}
+
+// taskReferences заменяет client_id в category_id и parent_id на id записей,
+// чтобы задача могла ссылаться на категорию или родителя, созданных без связи.
+func (h *SyncHandler) taskReferences(r *http.Request, userID uint, fields map[string]json.RawMessage) error {
+    refs := []struct {
+        field   string
+        entity  string
+        invalid error
+    }{
+        {"category_id", models.EntityCategory, models.ErrInvalidCategory},
+        {"parent_id", models.EntityTask, models.ErrInvalidParent},
+    }
+    for _, ref := range refs {
+        var clientID string
+        if err := json.Unmarshal(fields[ref.field], &clientID); err != nil || clientID == "" {
+            continue
+        }
+        id, err := h.sync.ResolveClientID(r.Context(), userID, ref.entity, clientID)
+        if errors.Is(err, models.ErrNotFound) {
+            return ref.invalid
+        }
+        if err != nil {
+            return err
+        }
+        fields[ref.field] = json.RawMessage(strconv.FormatUint(uint64(id), 10))
+    }
+    return nil
+}
+
+func (h *SyncHandler) applyTask(r *http.Request, userID uint, loc *time.Location, m SyncMutation, result *SyncResult) error {
+    if m.Op == syncCreate {
+        task, err := h.createTask(r, userID, loc, m)
+        if err != nil {
+            return err
+        }
+        result.ID, result.Task = task.ID, task
+        if task.DeletedAt != nil {
+            return models.ErrClientIDTrashed
+        }
+        return nil
+    }
+    if m.Op != syncUpdate && m.Op != syncDelete {
+        return models.Invalid("op", "op must be create, update or delete")
+    }
+
+    id, err := h.resolveID(r, userID, models.EntityTask, m.ID)
+    if err == nil {
+        result.ID = id
+        var existing *models.Task
+        if existing, err = h.tasks.GetTask(r.Context(), id, userID); err == nil {
+            if m.Op == syncUpdate {
+                result.Task, err = h.updateTask(r, existing, m, loc)
+            } else {
+                err = h.deleteTask(r, existing, m.Version)
+            }
+        }
+    }
+    switch {
+    case errors.Is(err, models.ErrNotFound) && m.Op == syncDelete:
+        // Задачу уже удалили: удаление достигло цели
+        return nil
+    case errors.Is(err, models.ErrVersionMismatch):
+        result.Task, _ = h.tasks.GetTask(r.Context(), id, userID)
+    }
+    return err
+}
+
+// createTask создаёт задачу с client_id мутации или возвращает созданную с
+// ним раньше, в том числе уже перенесённую в корзину.
+func (h *SyncHandler) createTask(r *http.Request, userID uint, loc *time.Location, m SyncMutation) (*models.Task, error) {
+    if err := validateClientID(m.ClientID); err != nil {
+        return nil, err
+    }
+    created := func() (*models.Task, error) {
+        id, err := h.sync.ResolveClientID(r.Context(), userID, models.EntityTask, m.ClientID)
+        if errors.Is(err, models.ErrNotFound) {
+            return h.sync.TrashedTaskByClientID(r.Context(), userID, m.ClientID)
+        }
+        if err != nil {
+            return nil, err
+        }
+        return h.tasks.GetTask(r.Context(), id, userID)
+    }
+    if task, err := created(); !errors.Is(err, models.ErrNotFound) {
+        return task, err
+    }
+
+    fields, err := decodeFields(m.Data)
+    if err != nil {
+        return nil, err
+    }
+    if err := h.taskReferences(r, userID, fields); err != nil {
+        return nil, err
+    }
+    var req CreateTaskRequest
+    raw, _ := json.Marshal(fields)
+    if err := json.Unmarshal(raw, &req); err != nil {
+        return nil, models.Invalid("data", "invalid task")
+    }
+
+    newTask, err := h.taskHandler.buildTask(r, req, loc)
+    if err != nil {
+        return nil, err
+    }
+    newTask.ClientID = m.ClientID
+    task, err := h.taskHandler.createTask(r, newTask)
+    // Ту же пачку одновременно отправил другой запрос
+    if errors.Is(err, models.ErrClientIDTaken) {
+        return created()
+    }
+    return task, err
+}
+
+// updateTask применяет data мутации к задаче как JSON Merge Patch.
+func (h *SyncHandler) updateTask(r *http.Request, existing *models.Task, m SyncMutation, loc *time.Location) (*models.Task, error) {
+    if m.Version != 0 && m.Version != existing.Version {
+        return nil, models.ErrVersionMismatch
+    }
+    fields, err := decodeFields(m.Data)
+    if err != nil {
+        return nil, err
+    }
+    if err := h.taskReferences(r, existing.UserID, fields); err != nil {
+        return nil, err
+    }
+    patch, err := parseTaskPatch(fields, existing, loc)
+    if err != nil {
+        return nil, err
+    }
+    patch.Version = m.Version
+    if patch.ParentID != nil && *patch.ParentID != 0 {
+        if err := models.ValidateParent(r.Context(), h.tasks, existing.ID, *patch.ParentID, existing.UserID); err != nil {
+            return nil, err
+        }
+    }
+    if len(fields) == 0 {
+        return existing, nil
+    }
+
+    task, err := h.tasks.PatchTask(r.Context(), existing.ID, existing.UserID, patch)
+    if err != nil {
+        return nil, err
+    }
+    return h.taskHandler.completeUpdate(r, existing, task, loc)
+}
+
+// deleteTask удаляет задачу вместе с подзадачами, как DELETE без параметров.
+func (h *SyncHandler) deleteTask(r *http.Request, existing *models.Task, version int) error {
+    err := h.tasks.DeleteTask(r.Context(), existing.ID, existing.UserID, models.CascadeChildren, version)
+    if err != nil {
+        return err
+    }
+    h.taskHandler.syncParent(r, existing.ParentID)
+    return nil
+}
+
+// applyCategory создаёт и удаляет категории; изменять категории API не умеет.
+func (h *SyncHandler) applyCategory(r *http.Request, userID uint, m SyncMutation, result *SyncResult) error {
+    switch m.Op {
+    case syncCreate:
+        category, err := h.createCategory(r, userID, m)
+        if err != nil {
+            return err
+        }
+        result.ID, result.Category = category.ID, category
+        if category.DeletedAt != nil {
+            return models.ErrClientIDTrashed
+        }
+        return nil
+    case syncDelete:
+    default:
+        return models.Invalid("op", "op must be create or delete")
+    }
+
+    id, err := h.resolveID(r, userID, models.EntityCategory, m.ID)
+    if err == nil {
+        result.ID = id
+        err = h.categories.DeleteCategory(r.Context(), id, userID, m.Version)
+    }
+    switch {
+    case errors.Is(err, models.ErrNotFound):
+        return nil
+    case errors.Is(err, models.ErrVersionMismatch):
+        result.Category, _ = h.categories.GetCategory(r.Context(), id, userID)
+    }
+    return err
+}
+
+// createCategory создаёт категорию с client_id мутации или возвращает
+// созданную с ним раньше, в том числе уже перенесённую в корзину.
+func (h *SyncHandler) createCategory(r *http.Request, userID uint, m SyncMutation) (*models.Category, error) {
+    if err := validateClientID(m.ClientID); err != nil {
+        return nil, err
+    }
+    created := func() (*models.Category, error) {
+        id, err := h.sync.ResolveClientID(r.Context(), userID, models.EntityCategory, m.ClientID)
+        if errors.Is(err, models.ErrNotFound) {
+            return h.sync.TrashedCategoryByClientID(r.Context(), userID, m.ClientID)
+        }
+        if err != nil {
+            return nil, err
+        }
+        return h.categories.GetCategory(r.Context(), id, userID)
+    }
+    if category, err := created(); !errors.Is(err, models.ErrNotFound) {
+        return category, err
+    }
+
+    var req CreateCategoryRequest
+    if err := json.Unmarshal(m.Data, &req); err != nil {
+        return nil, models.Invalid("data", "data must be an object")
+    }
+    if strings.TrimSpace(req.Name) == "" {
+        return nil, models.Invalid("name", "name is required")
+    }
+    category, err := h.categories.CreateCategory(r.Context(), &models.Category{Name: req.Name, UserID: userID, ClientID: m.ClientID})
+    if errors.Is(err, models.ErrClientIDTaken) {
+        return created()
+    }
+    return category, err
+}
+
+// applyNotification отмечает уведомление прочитанным ({"read": true}) или
+// удаляет его; создают уведомления только сервер и планировщик.
+func (h *SyncHandler) applyNotification(r *http.Request, userID uint, m SyncMutation, result *SyncResult) error {
+    if m.Op != syncUpdate && m.Op != syncDelete {
+        return models.Invalid("op", "op must be update or delete")
+    }
+    id, err := h.resolveID(r, userID, models.EntityNotification, m.ID)
+    if err != nil {
+        return err
+    }
+    result.ID = id
+
+    if m.Op == syncDelete {
+        err := h.notifications.DeleteNotification(r.Context(), id, userID)
+        if errors.Is(err, models.ErrNotFound) {
+            return nil
+        }
+        return err
+    }
+    var req struct {
+        Read *bool `json:"read"`
+    }
+    if err := json.Unmarshal(m.Data, &req); err != nil || req.Read == nil || !*req.Read {
+        return models.Invalid("read", "only read: true is supported")
+    }
+    return h.notifications.MarkNotificationAsRead(r.Context(), id, userID)
+}
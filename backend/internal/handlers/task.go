@@ -1,162 +1,664 @@
 package handlers
 
 import (
+    "crypto/sha256"
+    "encoding/hex"
     "encoding/json"
+    "strings"
     "net/http"
     "strconv"
     "time"
     "log"
     "fmt"
     "github.com/gorilla/mux"
+    "todo-app/internal/apierror"
+    "todo-app/internal/middleware"
     "todo-app/internal/models"
-    "github.com/golang-jwt/jwt/v5"
+    "todo-app/internal/recurrence"
 )
 
-type TaskHandler struct{}
+const (
+    defaultOccurrenceWindowDays = 30
+    maxOccurrenceWindow         = 366 * 24 * time.Hour
+    defaultOccurrenceLimit      = 100
+    maxOccurrenceLimit          = 1000
+)
+
+type TaskHandler struct {
+    tasks         models.TaskStore
+    notifications models.NotificationStore
+    users         models.UserStore
+    events        models.EventStore
+}
 
 type CreateTaskRequest struct {
-    Title       string `json:"title"`
-    Description string `json:"description"`
-    DueDate     string `json:"due_date"`
-    Priority    int    `json:"priority"`
-    CategoryID  *uint  `json:"category_id"`
+    Title        string `json:"title"`
+    Description  string `json:"description"`
+    DueDate      string `json:"due_date"`
+    Priority     int    `json:"priority"`
+    CategoryID   *uint  `json:"category_id"`
+    Recurrence   string `json:"recurrence"`
+    ParentID     *uint  `json:"parent_id"`
+    AutoComplete bool   `json:"auto_complete"`
+    // AllDay по умолчанию определяется по due_date: дата без времени — задача на весь день.
+    AllDay *bool `json:"all_day"`
 }
 
 type UpdateTaskRequest struct {
-    Title       string `json:"title"`
-    Description string `json:"description"`
-    Completed   bool   `json:"completed"`
-    DueDate     string `json:"due_date"`
-    Priority    int    `json:"priority"`
-    CategoryID  *uint  `json:"category_id"`
+    Title       string  `json:"title"`
+    Description string  `json:"description"`
+    Completed   bool    `json:"completed"`
+    DueDate     string  `json:"due_date"`
+    Priority    int     `json:"priority"`
+    CategoryID  *uint   `json:"category_id"`
+    Recurrence  *string `json:"recurrence"`
+    // ParentID не меняется, если поле не передано; 0 переносит задачу на верхний уровень.
+    ParentID     *uint `json:"parent_id"`
+    AutoComplete *bool `json:"auto_complete"`
+    AllDay       *bool `json:"all_day"`
 }
 
-func NewTaskHandler() *TaskHandler {
-    return &TaskHandler{}
+type ReorderSubtasksRequest struct {
+    IDs []uint `json:"ids"`
+}
+
+func NewTaskHandler(tasks models.TaskStore, notifications models.NotificationStore, users models.UserStore, events models.EventStore) *TaskHandler {
+    return &TaskHandler{
+        tasks:         tasks,
+        notifications: notifications,
+        users:         users,
+        events:        events,
+    }
 }
 
 func getUserIDFromToken(r *http.Request) uint {
-    claims := r.Context().Value("claims").(jwt.MapClaims)
-    userID := uint(claims["user_id"].(float64))
-    return userID
+    claims, ok := middleware.Claims(r.Context())
+    if !ok {
+        return 0
+    }
+    userID, _ := claims["user_id"].(float64)
+    return uint(userID)
 }
 
-func parseDate(dateStr string) (time.Time, error) {
+// parseDate разбирает дату из запроса. Значения без смещения трактуются как
+// местное время в loc; dateOnly сообщает, что время не указано.
+func parseDate(dateStr string, loc *time.Location) (t time.Time, dateOnly bool, err error) {
+    if t, err := time.Parse(time.RFC3339, dateStr); err == nil {
+        return t.UTC(), false, nil
+    }
+
     formats := []string{
-        time.RFC3339,
         "2006-01-02T15:04:05.000",
-        "2006-01-02T15:04:05Z",
         "2006-01-02T15:04:05",
         "2006-01-02",
     }
-
     for _, format := range formats {
-        if t, err := time.Parse(format, dateStr); err == nil {
-            return t, nil
+        if t, err := time.ParseInLocation(format, dateStr, loc); err == nil {
+            return t.UTC(), format == "2006-01-02", nil
         }
     }
 
-    return time.Time{}, fmt.Errorf("unsupported date format: %s", dateStr)
+    return time.Time{}, false, fmt.Errorf("unsupported date format: %s", dateStr)
+}
+
+// parseDueDate разбирает срок задачи. Срок задачи на весь день приводится к
+// началу дня в часовом поясе loc.
+func parseDueDate(value string, allDay *bool, loc *time.Location) (time.Time, bool, error) {
+    due, dateOnly, err := parseDate(value, loc)
+    if err != nil {
+        return time.Time{}, false, err
+    }
+    if allDay != nil {
+        dateOnly = *allDay
+    }
+    if dateOnly {
+        due = models.StartOfDay(due, loc).UTC()
+    }
+    return due, dateOnly, nil
+}
+
+// normalizeRecurrence проверяет правило RRULE и приводит его к каноничному виду.
+func normalizeRecurrence(rule string) (string, error) {
+    if strings.TrimSpace(rule) == "" {
+        return "", nil
+    }
+    parsed, err := recurrence.Parse(rule)
+    if err != nil {
+        return "", err
+    }
+    return parsed.String(), nil
 }
 
 func (h *TaskHandler) Create(w http.ResponseWriter, r *http.Request) {
     var req CreateTaskRequest
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        log.Printf("Error decoding request: %v", err)
-        http.Error(w, "Invalid request", http.StatusBadRequest)
+        apierror.BadRequest(w, r, "Invalid request body")
         return
     }
 
     log.Printf("Creating task: %+v", req)
 
-    dueDate, err := parseDate(req.DueDate)
+    loc, err := userLocation(r, h.users)
     if err != nil {
-        log.Printf("Error parsing date: %v", err)
-        http.Error(w, "Invalid date format", http.StatusBadRequest)
+        apierror.FromError(w, r, err, "User not found", "Could not create task")
         return
     }
-
-    userID := getUserIDFromToken(r)
-    task, err := models.CreateTask(req.Title, req.Description, userID, dueDate, models.Priority(req.Priority), req.CategoryID)
+    newTask, err := h.buildTask(r, req, loc)
     if err != nil {
-        log.Printf("Error creating task: %v", err)
-        http.Error(w, "Could not create task", http.StatusInternalServerError)
+        apierror.FromError(w, r, err, "Parent task not found", "Could not validate parent task")
+        return
+    }
+    task, err := h.createTask(r, newTask)
+    if err != nil {
+        apierror.FromError(w, r, err, "Task not found", "Could not create task")
         return
     }
 
-    models.CheckDueTasks()
     log.Printf("Task created successfully: %+v", task)
+    w.Header().Set("ETag", entityTag(task.Version))
     json.NewEncoder(w).Encode(task)
 }
 
+// buildTask проверяет запрос на создание задачи и собирает из него задачу.
+func (h *TaskHandler) buildTask(r *http.Request, req CreateTaskRequest, loc *time.Location) (*models.Task, error) {
+    if strings.TrimSpace(req.Title) == "" {
+        return nil, models.Invalid("title", "title is required")
+    }
+    dueDate, allDay, err := parseDueDate(req.DueDate, req.AllDay, loc)
+    if err != nil {
+        return nil, models.Invalid("due_date", err.Error())
+    }
+
+    rule, err := normalizeRecurrence(req.Recurrence)
+    if err != nil {
+        return nil, models.Invalid("recurrence", err.Error())
+    }
+
+    userID := getUserIDFromToken(r)
+    if req.ParentID != nil {
+        if err := models.ValidateParent(r.Context(), h.tasks, 0, *req.ParentID, userID); err != nil {
+            return nil, err
+        }
+    }
+
+    task := &models.Task{
+        Title:        req.Title,
+        Description:  req.Description,
+        UserID:       userID,
+        CategoryID:   req.CategoryID,
+        DueDate:      dueDate,
+        AllDay:       allDay,
+        Priority:     models.Priority(req.Priority),
+        Recurrence:   rule,
+        ParentID:     req.ParentID,
+        AutoComplete: req.AutoComplete,
+    }
+    if rule != "" {
+        task.RecurrenceStart = &dueDate
+    }
+    return task, nil
+}
+
+// createTask сохраняет задачу, пересчитывает завершение родителя и создаёт
+// уведомление о создании.
+func (h *TaskHandler) createTask(r *http.Request, newTask *models.Task) (*models.Task, error) {
+    task, err := h.tasks.CreateTask(r.Context(), newTask)
+    if err != nil {
+        return nil, err
+    }
+
+    h.syncParent(r, task.ParentID)
+    // Напоминания о сроке рассылает планировщик, здесь только уведомление о создании
+    if err := h.notifications.CreateNotification(r.Context(), task.UserID, models.NotificationTaskCreated, models.TaskParams(task)); err != nil {
+        log.Printf("Could not create notification: %v", err)
+    }
+    return task, nil
+}
+
 func (h *TaskHandler) List(w http.ResponseWriter, r *http.Request) {
+    loc, err := userLocation(r, h.users)
+    if err != nil {
+        apierror.FromError(w, r, err, "User not found", "Could not get tasks")
+        return
+    }
+    filter, err := parseTaskFilter(r, loc)
+    if err != nil {
+        apierror.FromError(w, r, err, "", "Could not get tasks")
+        return
+    }
+
     userID := getUserIDFromToken(r)
-    tasks, err := models.GetUserTasks(userID)
+    // Отбор просроченных зависит от текущего времени, а не только от записей
+    if filter.Overdue == nil {
+        tag, err := h.listTag(r, userID, loc)
+        if err != nil {
+            apierror.Internal(w, r, err, "Could not get tasks")
+            return
+        }
+        if tag != "" && notModified(w, r, tag) {
+            return
+        }
+    }
+
+    page, err := h.tasks.ListTasks(r.Context(), userID, filter)
+    if err != nil {
+        apierror.FromError(w, r, err, "", "Could not get tasks")
+        return
+    }
+
+    log.Printf("Retrieved %d tasks", len(page.Tasks))
+    json.NewEncoder(w).Encode(page)
+}
+
+// listTag строит слабый ETag списка задач по последнему событию пользователя:
+// любое изменение задач и категорий пишет событие. Без событий тег не
+// выдаётся: после очистки журнала номер 0 повторяется при другом составе задач.
+func (h *TaskHandler) listTag(r *http.Request, userID uint, loc *time.Location) (string, error) {
+    latest, err := h.events.LatestEventID(r.Context(), userID)
+    if err != nil || latest == 0 {
+        return "", err
+    }
+    sum := sha256.Sum256([]byte(fmt.Sprintf("%d\n%s\n%s", latest, r.URL.RawQuery, loc)))
+    return `W/"` + hex.EncodeToString(sum[:8]) + `"`, nil
+}
+
+func (h *TaskHandler) Get(w http.ResponseWriter, r *http.Request) {
+    taskID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
     if err != nil {
-        log.Printf("Error getting tasks: %v", err)
-        http.Error(w, "Could not get tasks", http.StatusInternalServerError)
+        apierror.BadRequest(w, r, "Invalid task ID")
         return
     }
 
-    log.Printf("Retrieved %d tasks", len(tasks))
-    json.NewEncoder(w).Encode(tasks)
+    task, err := h.tasks.GetTask(r.Context(), uint(taskID), getUserIDFromToken(r))
+    if err != nil {
+        apierror.FromError(w, r, err, "Task not found", "Could not get task")
+        return
+    }
+    if notModified(w, r, entityTag(task.Version)) {
+        return
+    }
+    json.NewEncoder(w).Encode(task)
 }
 
 func (h *TaskHandler) Update(w http.ResponseWriter, r *http.Request) {
     vars := mux.Vars(r)
     taskID, err := strconv.ParseUint(vars["id"], 10, 32)
     if err != nil {
-        log.Printf("Error parsing task ID: %v", err)
-        http.Error(w, "Invalid task ID", http.StatusBadRequest)
+        apierror.BadRequest(w, r, "Invalid task ID")
         return
     }
 
     var req UpdateTaskRequest
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        log.Printf("Error decoding request: %v", err)
-        http.Error(w, "Invalid request", http.StatusBadRequest)
+        apierror.BadRequest(w, r, "Invalid request body")
+        return
+    }
+    if strings.TrimSpace(req.Title) == "" {
+        apierror.Invalid(w, r, "title", "title is required")
         return
     }
 
     log.Printf("Updating task %d: %+v", taskID, req)
 
-    dueDate, err := parseDate(req.DueDate)
+    loc, err := userLocation(r, h.users)
+    if err != nil {
+        apierror.FromError(w, r, err, "User not found", "Could not update task")
+        return
+    }
+    dueDate, allDay, err := parseDueDate(req.DueDate, req.AllDay, loc)
+    if err != nil {
+        apierror.Invalid(w, r, "due_date", err.Error())
+        return
+    }
+
+    userID := getUserIDFromToken(r)
+    existing, err := h.tasks.GetTask(r.Context(), uint(taskID), userID)
+    if err != nil {
+        apierror.FromError(w, r, err, "Task not found", "Could not update task")
+        return
+    }
+    version, ok := ifMatch(w, r, existing.Version)
+    if !ok {
+        return
+    }
+
+    update := &models.Task{
+        ID:              uint(taskID),
+        UserID:          userID,
+        Title:           req.Title,
+        Description:     req.Description,
+        Completed:       req.Completed,
+        CategoryID:      req.CategoryID,
+        DueDate:         dueDate,
+        AllDay:          allDay,
+        Priority:        models.Priority(req.Priority),
+        Recurrence:      existing.Recurrence,
+        RecurrenceStart: existing.RecurrenceStart,
+        ParentID:        existing.ParentID,
+        AutoComplete:    existing.AutoComplete,
+    }
+    if req.AutoComplete != nil {
+        update.AutoComplete = *req.AutoComplete
+    }
+    if req.ParentID != nil {
+        update.ParentID = nil
+        if *req.ParentID != 0 {
+            if err := models.ValidateParent(r.Context(), h.tasks, update.ID, *req.ParentID, userID); err != nil {
+                apierror.FromError(w, r, err, "Parent task not found", "Could not validate parent task")
+                return
+            }
+            update.ParentID = req.ParentID
+        }
+    }
+    // Поле recurrence необязательно: его отсутствие оставляет правило без изменений
+    if req.Recurrence != nil {
+        rule, err := normalizeRecurrence(*req.Recurrence)
+        if err != nil {
+            apierror.Invalid(w, r, "recurrence", err.Error())
+            return
+        }
+        if rule != existing.Recurrence {
+            update.Recurrence = rule
+            update.RecurrenceStart = nil
+            if rule != "" {
+                update.RecurrenceStart = &dueDate
+            }
+        }
+    }
+
+    patch := models.NewTaskPatch(update)
+    patch.Version = version
+    task, err := h.tasks.PatchTask(r.Context(), update.ID, userID, patch)
+    if err != nil {
+        apierror.FromError(w, r, err, "Task not found", "Could not update task")
+        return
+    }
+    h.finishUpdate(w, r, existing, task, loc)
+}
+
+// Patch применяет к задаче JSON Merge Patch (RFC 7396): отсутствующие поля
+// не меняются, null убирает категорию, родителя, правило повторения или
+// описание.
+func (h *TaskHandler) Patch(w http.ResponseWriter, r *http.Request) {
+    taskID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+    if err != nil {
+        apierror.BadRequest(w, r, "Invalid task ID")
+        return
+    }
+
+    var fields map[string]json.RawMessage
+    if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+        apierror.BadRequest(w, r, "Invalid request body")
+        return
+    }
+
+    loc, err := userLocation(r, h.users)
+    if err != nil {
+        apierror.FromError(w, r, err, "User not found", "Could not update task")
+        return
+    }
+    userID := getUserIDFromToken(r)
+    existing, err := h.tasks.GetTask(r.Context(), uint(taskID), userID)
+    if err != nil {
+        apierror.FromError(w, r, err, "Task not found", "Could not update task")
+        return
+    }
+    version, ok := ifMatch(w, r, existing.Version)
+    if !ok {
+        return
+    }
+
+    patch, err := parseTaskPatch(fields, existing, loc)
+    if err != nil {
+        apierror.FromError(w, r, err, "", "Invalid task patch")
+        return
+    }
+    patch.Version = version
+    if patch.ParentID != nil && *patch.ParentID != 0 {
+        if err := models.ValidateParent(r.Context(), h.tasks, existing.ID, *patch.ParentID, userID); err != nil {
+            apierror.FromError(w, r, err, "Parent task not found", "Could not validate parent task")
+            return
+        }
+    }
+
+    log.Printf("Patching task %d: %s", taskID, strings.Join(sortedKeys(fields), ", "))
+
+    // Пустой патч ничего не меняет и не сдвигает updated_at
+    if len(fields) == 0 {
+        w.Header().Set("ETag", entityTag(existing.Version))
+        json.NewEncoder(w).Encode(existing)
+        return
+    }
+    task, err := h.tasks.PatchTask(r.Context(), existing.ID, userID, patch)
     if err != nil {
-        log.Printf("Error parsing date: %v", err)
-        http.Error(w, "Invalid date format", http.StatusBadRequest)
+        apierror.FromError(w, r, err, "Task not found", "Could not update task")
         return
     }
+    h.finishUpdate(w, r, existing, task, loc)
+}
 
-    task, err := models.UpdateTask(uint(taskID), req.Title, req.Description, req.Completed, dueDate, models.Priority(req.Priority), req.CategoryID)
+// finishUpdate завершает изменение задачи и отвечает изменённой задачей.
+func (h *TaskHandler) finishUpdate(w http.ResponseWriter, r *http.Request, existing, task *models.Task, loc *time.Location) {
+    task, err := h.completeUpdate(r, existing, task, loc)
     if err != nil {
-        log.Printf("Error updating task: %v", err)
-        http.Error(w, "Could not update task", http.StatusInternalServerError)
+        apierror.Internal(w, r, err, "Could not create next occurrence")
         return
     }
 
-    models.CheckDueTasks()
     log.Printf("Task updated successfully: %+v", task)
+    w.Header().Set("ETag", entityTag(task.Version))
     json.NewEncoder(w).Encode(task)
 }
 
+// completeUpdate создаёт следующее вхождение завершённой повторяющейся задачи
+// и пересчитывает завершение родителей. Ошибка возможна только при создании
+// вхождения.
+func (h *TaskHandler) completeUpdate(r *http.Request, existing, task *models.Task, loc *time.Location) (*models.Task, error) {
+    if task.Completed && !existing.Completed && task.Recurrence != "" {
+        next, err := models.NextOccurrence(task, loc)
+        if err != nil {
+            log.Printf("Error computing next occurrence: %v", err)
+        } else if next != nil {
+            created, err := h.tasks.CreateNextOccurrence(r.Context(), task, next)
+            if err != nil {
+                return nil, err
+            }
+            // Серия перешла к новому вхождению; версию завершённой задачи
+            // берём из базы, а не угадываем
+            completed, err := h.tasks.GetTask(r.Context(), task.ID, task.UserID)
+            if err != nil {
+                return nil, err
+            }
+            completed.NextOccurrence = created
+            task = completed
+        }
+    }
+
+    if task.Completed != existing.Completed || !sameParent(task.ParentID, existing.ParentID) {
+        h.syncParent(r, existing.ParentID)
+        h.syncParent(r, task.ParentID)
+    }
+    if task.AutoComplete && !existing.AutoComplete {
+        h.syncParent(r, &task.ID)
+        // Пересчёт мог завершить саму задачу и сменить её версию
+        if synced, err := h.tasks.GetTask(r.Context(), task.ID, task.UserID); err == nil {
+            synced.NextOccurrence = task.NextOccurrence
+            task = synced
+        }
+    }
+    return task, nil
+}
+
+func (h *TaskHandler) Occurrences(w http.ResponseWriter, r *http.Request) {
+    loc, err := userLocation(r, h.users)
+    if err != nil {
+        apierror.FromError(w, r, err, "User not found", "Could not get occurrences")
+        return
+    }
+
+    from := time.Now()
+    if v := r.URL.Query().Get("from"); v != "" {
+        parsed, _, err := parseDate(v, loc)
+        if err != nil {
+            apierror.Invalid(w, r, "from", err.Error())
+            return
+        }
+        from = parsed
+    }
+
+    to := from.AddDate(0, 0, defaultOccurrenceWindowDays)
+    if v := r.URL.Query().Get("to"); v != "" {
+        parsed, _, err := parseDate(v, loc)
+        if err != nil {
+            apierror.Invalid(w, r, "to", err.Error())
+            return
+        }
+        to = parsed
+    }
+    if to.Before(from) || to.Sub(from) > maxOccurrenceWindow {
+        apierror.Invalid(w, r, "to", fmt.Sprintf("window must end after from and span at most %d days", int(maxOccurrenceWindow.Hours()/24)))
+        return
+    }
+
+    limit := defaultOccurrenceLimit
+    if v := r.URL.Query().Get("limit"); v != "" {
+        parsed, err := strconv.Atoi(v)
+        if err != nil || parsed < 1 || parsed > maxOccurrenceLimit {
+            apierror.Invalid(w, r, "limit", fmt.Sprintf("limit must be between 1 and %d", maxOccurrenceLimit))
+            return
+        }
+        limit = parsed
+    }
+
+    userID := getUserIDFromToken(r)
+    tasks, err := h.tasks.GetUserTasks(r.Context(), userID)
+    if err != nil {
+        apierror.Internal(w, r, err, "Could not get occurrences")
+        return
+    }
+
+    json.NewEncoder(w).Encode(models.ExpandOccurrences(tasks, from, to, limit, loc))
+}
+
 func (h *TaskHandler) Delete(w http.ResponseWriter, r *http.Request) {
     vars := mux.Vars(r)
     taskID, err := strconv.ParseUint(vars["id"], 10, 32)
     if err != nil {
-        log.Printf("Error parsing task ID: %v", err)
-        http.Error(w, "Invalid task ID", http.StatusBadRequest)
+        apierror.BadRequest(w, r, "Invalid task ID")
+        return
+    }
+
+    children := models.DeleteChildren(r.URL.Query().Get("children"))
+    if children == "" {
+        children = models.CascadeChildren
+    }
+    if children != models.CascadeChildren && children != models.PromoteChildren {
+        apierror.Invalid(w, r, "children", "children must be cascade or promote")
         return
     }
 
     log.Printf("Deleting task %d", taskID)
 
-    err = models.DeleteTask(uint(taskID))
+    userID := getUserIDFromToken(r)
+    existing, err := h.tasks.GetTask(r.Context(), uint(taskID), userID)
     if err != nil {
-        log.Printf("Error deleting task: %v", err)
-        http.Error(w, "Could not delete task", http.StatusInternalServerError)
+        apierror.FromError(w, r, err, "Task not found", "Could not delete task")
+        return
+    }
+    version, ok := ifMatch(w, r, existing.Version)
+    if !ok {
         return
     }
 
+    err = h.tasks.DeleteTask(r.Context(), uint(taskID), userID, children, version)
+    if err != nil {
+        apierror.FromError(w, r, err, "Task not found", "Could not delete task")
+        return
+    }
+
+    h.syncParent(r, existing.ParentID)
+
     log.Printf("Task %d deleted successfully", taskID)
     w.WriteHeader(http.StatusOK)
-} 
\ No newline at end of file
+}
+
+func (h *TaskHandler) Subtasks(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    taskID, err := strconv.ParseUint(vars["id"], 10, 32)
+    if err != nil {
+        apierror.BadRequest(w, r, "Invalid task ID")
+        return
+    }
+
+    userID := getUserIDFromToken(r)
+    if _, err := h.tasks.GetTask(r.Context(), uint(taskID), userID); err != nil {
+        apierror.FromError(w, r, err, "Task not found", "Could not get subtasks")
+        return
+    }
+
+    subtasks, err := h.tasks.GetSubtasks(r.Context(), uint(taskID), userID)
+    if err != nil {
+        apierror.Internal(w, r, err, "Could not get subtasks")
+        return
+    }
+
+    if subtasks == nil {
+        subtasks = []models.Task{}
+    }
+    json.NewEncoder(w).Encode(subtasks)
+}
+
+func (h *TaskHandler) ReorderSubtasks(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    taskID, err := strconv.ParseUint(vars["id"], 10, 32)
+    if err != nil {
+        apierror.BadRequest(w, r, "Invalid task ID")
+        return
+    }
+
+    var req ReorderSubtasksRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        apierror.BadRequest(w, r, "Invalid request body")
+        return
+    }
+
+    userID := getUserIDFromToken(r)
+    if _, err := h.tasks.GetTask(r.Context(), uint(taskID), userID); err != nil {
+        apierror.FromError(w, r, err, "Task not found", "Could not reorder subtasks")
+        return
+    }
+
+    err = h.tasks.ReorderSubtasks(r.Context(), uint(taskID), userID, req.IDs)
+    if err != nil {
+        apierror.FromError(w, r, err, "Task not found", "Could not reorder subtasks")
+        return
+    }
+
+    subtasks, err := h.tasks.GetSubtasks(r.Context(), uint(taskID), userID)
+    if err != nil {
+        apierror.Internal(w, r, err, "Could not get subtasks")
+        return
+    }
+
+    if subtasks == nil {
+        subtasks = []models.Task{}
+    }
+    json.NewEncoder(w).Encode(subtasks)
+}
+
+// syncParent пересчитывает автозавершение родителя; ошибка не прерывает запрос.
+func (h *TaskHandler) syncParent(r *http.Request, parentID *uint) {
+    if parentID == nil {
+        return
+    }
+    if err := h.tasks.SyncParentCompletion(r.Context(), *parentID); err != nil {
+        log.Printf("Error syncing parent %d completion: %v", *parentID, err)
+    }
+}
+
+func sameParent(a, b *uint) bool {
+    if a == nil || b == nil {
+        return a == nil && b == nil
+    }
+    return *a == *b
+}
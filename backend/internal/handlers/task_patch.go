@@ -0,0 +1,135 @@
+package handlers
+
+import (
+    "encoding/json"
+    "sort"
+    "strings"
+    "time"
+    "todo-app/internal/models"
+)
+
+// Поля, которым в патче можно передать null.
+var nullableTaskFields = map[string]bool{
+    "description": true,
+    "category_id": true,
+    "parent_id":   true,
+    "recurrence":  true,
+}
+
+// parseTaskPatch переводит поля JSON Merge Patch в models.TaskPatch. Срок и
+// правило повторения согласуются с existing так же, как при PUT.
+func parseTaskPatch(fields map[string]json.RawMessage, existing *models.Task, loc *time.Location) (models.TaskPatch, error) {
+    var patch models.TaskPatch
+    var dueDate *string
+    for _, name := range sortedKeys(fields) {
+        raw := fields[name]
+        null := string(raw) == "null"
+        if null && !nullableTaskFields[name] {
+            return patch, models.Invalid(name, "must not be null")
+        }
+        decode := func(v interface{}) error {
+            if null {
+                return nil
+            }
+            if err := json.Unmarshal(raw, v); err != nil {
+                return models.Invalid(name, "invalid value")
+            }
+            return nil
+        }
+
+        var err error
+        switch name {
+        case "title":
+            var v string
+            if err = decode(&v); err == nil && strings.TrimSpace(v) == "" {
+                err = models.Invalid("title", "title is required")
+            }
+            patch.Title = &v
+        case "description":
+            var v string
+            err = decode(&v)
+            patch.Description = &v
+        case "completed":
+            var v bool
+            err = decode(&v)
+            patch.Completed = &v
+        case "due_date":
+            var v string
+            err = decode(&v)
+            dueDate = &v
+        case "all_day":
+            var v bool
+            err = decode(&v)
+            patch.AllDay = &v
+        case "priority":
+            var v int
+            err = decode(&v)
+            priority := models.Priority(v)
+            patch.Priority = &priority
+        case "category_id":
+            var v uint
+            err = decode(&v)
+            patch.CategoryID = &v
+        case "parent_id":
+            var v uint
+            err = decode(&v)
+            patch.ParentID = &v
+        case "recurrence":
+            var v string
+            err = decode(&v)
+            patch.Recurrence = &v
+        case "auto_complete":
+            var v bool
+            err = decode(&v)
+            patch.AutoComplete = &v
+        default:
+            err = models.Invalid(name, "unknown or read-only field")
+        }
+        if err != nil {
+            return patch, err
+        }
+    }
+
+    switch {
+    case dueDate != nil:
+        due, allDay, err := parseDueDate(*dueDate, patch.AllDay, loc)
+        if err != nil {
+            return patch, models.Invalid("due_date", err.Error())
+        }
+        patch.DueDate, patch.AllDay = &due, &allDay
+    case patch.AllDay != nil && *patch.AllDay && !existing.AllDay:
+        // Срок задачи на весь день — начало дня
+        due := models.StartOfDay(existing.DueDate, loc).UTC()
+        patch.DueDate = &due
+    }
+
+    if patch.Recurrence != nil {
+        rule, err := normalizeRecurrence(*patch.Recurrence)
+        if err != nil {
+            return patch, models.Invalid("recurrence", err.Error())
+        }
+        // Прежнее правило сохраняет начало серии
+        if rule == existing.Recurrence {
+            patch.Recurrence = nil
+        } else {
+            patch.Recurrence = &rule
+            if rule != "" {
+                start := existing.DueDate
+                if patch.DueDate != nil {
+                    start = *patch.DueDate
+                }
+                patch.RecurrenceStart = &start
+            }
+        }
+    }
+    return patch, nil
+}
+
+func sortedKeys(fields map[string]json.RawMessage) []string {
+    keys := make([]string, 0, len(fields))
+    for key := range fields {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
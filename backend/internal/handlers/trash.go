@@ -0,0 +1,77 @@
+package handlers
+
+import (
+    "encoding/json"
+    "log"
+    "net/http"
+    "strconv"
+    "github.com/gorilla/mux"
+    "todo-app/internal/apierror"
+    "todo-app/internal/models"
+)
+
+// TrashHandler показывает корзину и возвращает из неё задачи и категории.
+// Окончательно корзину очищает планировщик.
+type TrashHandler struct {
+    trash models.TrashStore
+    tasks models.TaskStore
+}
+
+func NewTrashHandler(trash models.TrashStore, tasks models.TaskStore) *TrashHandler {
+    return &TrashHandler{
+        trash: trash,
+        tasks: tasks,
+    }
+}
+
+func (h *TrashHandler) List(w http.ResponseWriter, r *http.Request) {
+    trash, err := h.trash.ListTrash(r.Context(), getUserIDFromToken(r))
+    if err != nil {
+        apierror.Internal(w, r, err, "Could not get trash")
+        return
+    }
+    json.NewEncoder(w).Encode(trash)
+}
+
+// RestoreTask возвращает задачу вместе с подзадачами, удалёнными с ней.
+// Родитель, если он не в корзине, пересчитывает выполнение.
+func (h *TrashHandler) RestoreTask(w http.ResponseWriter, r *http.Request) {
+    taskID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+    if err != nil {
+        apierror.BadRequest(w, r, "Invalid task ID")
+        return
+    }
+
+    task, err := h.trash.RestoreTask(r.Context(), uint(taskID), getUserIDFromToken(r))
+    if err != nil {
+        apierror.FromError(w, r, err, "Task not found", "Could not restore task")
+        return
+    }
+    if task.ParentID != nil {
+        if err := h.tasks.SyncParentCompletion(r.Context(), *task.ParentID); err != nil {
+            log.Printf("Error syncing parent %d completion: %v", *task.ParentID, err)
+        }
+    }
+
+    w.Header().Set("ETag", entityTag(task.Version))
+    json.NewEncoder(w).Encode(task)
+}
+
+// RestoreCategory возвращает категорию и задачи, которые были в ней при
+// удалении.
+func (h *TrashHandler) RestoreCategory(w http.ResponseWriter, r *http.Request) {
+    categoryID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+    if err != nil {
+        apierror.BadRequest(w, r, "Invalid category ID")
+        return
+    }
+
+    category, err := h.trash.RestoreCategory(r.Context(), uint(categoryID), getUserIDFromToken(r))
+    if err != nil {
+        apierror.FromError(w, r, err, "Category not found", "Could not restore category")
+        return
+    }
+
+    w.Header().Set("ETag", entityTag(category.Version))
+    json.NewEncoder(w).Encode(category)
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "strings"
+    "time"
+    "todo-app/internal/apierror"
+    "todo-app/internal/i18n"
+    "todo-app/internal/models"
+)
+
+type UserHandler struct {
+    users    models.UserStore
+    settings models.NotificationSettingsStore
+}
+
+// UpdateProfileRequest меняет только переданные поля.
+type UpdateProfileRequest struct {
+    Name     *string `json:"name"`
+    Timezone *string `json:"timezone"`
+    // Locale — "ru" или "en"; пустая строка возвращает выбор по Accept-Language.
+    Locale *string `json:"locale"`
+}
+
+// UpdateNotificationSettingsRequest меняет только переданные поля; в types
+// достаточно перечислить изменяемые типы, а quiet_hours: null выключает
+// тихие часы.
+type UpdateNotificationSettingsRequest struct {
+    Types    map[models.NotificationType]bool `json:"types"`
+    Channels struct {
+        InApp   *bool `json:"in_app"`
+        Email   *bool `json:"email"`
+        Webhook *bool `json:"webhook"`
+    } `json:"channels"`
+    QuietHours    json.RawMessage       `json:"quiet_hours"`
+    EmailDelivery *models.EmailDelivery `json:"email_delivery"`
+}
+
+func NewUserHandler(users models.UserStore, settings models.NotificationSettingsStore) *UserHandler {
+    return &UserHandler{users: users, settings: settings}
+}
+
+// userLocation возвращает часовой пояс автора запроса, в котором
+// трактуются даты без времени и границы «сегодня».
+func userLocation(r *http.Request, users models.UserStore) (*time.Location, error) {
+    user, err := users.GetUserByID(r.Context(), getUserIDFromToken(r))
+    if err != nil {
+        return nil, err
+    }
+    return models.Location(user.Timezone), nil
+}
+
+// requestLocale выбирает язык ответа: настройка пользователя, а без неё —
+// заголовок Accept-Language.
+func requestLocale(r *http.Request, users models.UserStore) (i18n.Locale, error) {
+    user, err := users.GetUserByID(r.Context(), getUserIDFromToken(r))
+    if err != nil {
+        return "", err
+    }
+    return i18n.Resolve(user.Locale, r.Header.Get("Accept-Language")), nil
+}
+
+func (h *UserHandler) Me(w http.ResponseWriter, r *http.Request) {
+    user, err := h.users.GetUserByID(r.Context(), getUserIDFromToken(r))
+    if err != nil {
+        apierror.FromError(w, r, err, "User not found", "Could not get user")
+        return
+    }
+    json.NewEncoder(w).Encode(user)
+}
+
+func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+    var req UpdateProfileRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        apierror.BadRequest(w, r, "Invalid request body")
+        return
+    }
+
+    userID := getUserIDFromToken(r)
+    user, err := h.users.GetUserByID(r.Context(), userID)
+    if err != nil {
+        apierror.FromError(w, r, err, "User not found", "Could not update user")
+        return
+    }
+
+    name, timezone, locale := user.Name, user.Timezone, user.Locale
+    if req.Name != nil {
+        name = strings.TrimSpace(*req.Name)
+    }
+    if req.Timezone != nil {
+        if err := models.ValidateTimezone(*req.Timezone); err != nil {
+            apierror.FromError(w, r, err, "", "Invalid timezone")
+            return
+        }
+        timezone = *req.Timezone
+        if timezone == "" {
+            timezone = models.DefaultTimezone
+        }
+    }
+    if req.Locale != nil {
+        locale = ""
+        if *req.Locale != "" {
+            parsed, ok := i18n.Parse(*req.Locale)
+            if !ok {
+                apierror.Invalid(w, r, "locale", "unsupported locale")
+                return
+            }
+            locale = string(parsed)
+        }
+    }
+
+    updated, err := h.users.UpdateUserProfile(r.Context(), userID, name, timezone, locale)
+    if err != nil {
+        apierror.FromError(w, r, err, "User not found", "Could not update user")
+        return
+    }
+    json.NewEncoder(w).Encode(updated)
+}
+
+func (h *UserHandler) NotificationSettings(w http.ResponseWriter, r *http.Request) {
+    settings, err := h.settings.GetNotificationSettings(r.Context(), getUserIDFromToken(r))
+    if err != nil {
+        apierror.FromError(w, r, err, "User not found", "Could not get notification settings")
+        return
+    }
+    json.NewEncoder(w).Encode(settings)
+}
+
+func (h *UserHandler) UpdateNotificationSettings(w http.ResponseWriter, r *http.Request) {
+    var req UpdateNotificationSettingsRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        apierror.BadRequest(w, r, "Invalid request body")
+        return
+    }
+
+    userID := getUserIDFromToken(r)
+    settings, err := h.settings.GetNotificationSettings(r.Context(), userID)
+    if err != nil {
+        apierror.FromError(w, r, err, "User not found", "Could not update notification settings")
+        return
+    }
+
+    for kind, enabled := range req.Types {
+        settings.Types[kind] = enabled
+    }
+    if req.Channels.InApp != nil {
+        settings.Channels.InApp = *req.Channels.InApp
+    }
+    if req.Channels.Email != nil {
+        settings.Channels.Email = *req.Channels.Email
+    }
+    if req.Channels.Webhook != nil {
+        settings.Channels.Webhook = *req.Channels.Webhook
+    }
+    if len(req.QuietHours) > 0 {
+        settings.QuietHours = nil
+        if string(req.QuietHours) != "null" {
+            var quiet models.QuietHours
+            if err := json.Unmarshal(req.QuietHours, &quiet); err != nil {
+                apierror.Invalid(w, r, "quiet_hours", err.Error())
+                return
+            }
+            settings.QuietHours = &quiet
+        }
+    }
+    if req.EmailDelivery != nil {
+        settings.EmailDelivery = *req.EmailDelivery
+    }
+    if err := settings.Validate(); err != nil {
+        apierror.FromError(w, r, err, "", "Invalid notification settings")
+        return
+    }
+
+    updated, err := h.settings.UpdateNotificationSettings(r.Context(), userID, settings)
+    if err != nil {
+        apierror.FromError(w, r, err, "User not found", "Could not update notification settings")
+        return
+    }
+    json.NewEncoder(w).Encode(updated)
+}
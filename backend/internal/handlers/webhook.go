@@ -0,0 +1,215 @@
+package handlers
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strconv"
+    "strings"
+    "github.com/gorilla/mux"
+    "todo-app/internal/apierror"
+    "todo-app/internal/models"
+)
+
+type WebhookHandler struct {
+    webhooks models.WebhookStore
+}
+
+// WebhookRequest создаёт вебхук или меняет только переданные поля.
+// rotate_secret выдаёт новый ключ подписи.
+type WebhookRequest struct {
+    URL          *string  `json:"url"`
+    Events       []string `json:"events"`
+    Active       *bool    `json:"active"`
+    RotateSecret bool     `json:"rotate_secret"`
+}
+
+func NewWebhookHandler(webhooks models.WebhookStore) *WebhookHandler {
+    return &WebhookHandler{webhooks: webhooks}
+}
+
+func parseWebhookID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+    id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+    if err != nil {
+        apierror.BadRequest(w, r, "Invalid webhook ID")
+        return 0, false
+    }
+    return uint(id), true
+}
+
+// apply переносит поля запроса на вебхук.
+func (req *WebhookRequest) apply(webhook *models.Webhook) {
+    if req.URL != nil {
+        webhook.URL = strings.TrimSpace(*req.URL)
+    }
+    if req.Events != nil {
+        webhook.Events = req.Events
+    }
+    if req.Active != nil {
+        webhook.Active = *req.Active
+    }
+}
+
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+    webhooks, err := h.webhooks.GetUserWebhooks(r.Context(), getUserIDFromToken(r))
+    if err != nil {
+        apierror.Internal(w, r, err, "Could not get webhooks")
+        return
+    }
+    for i := range webhooks {
+        webhooks[i].Secret = ""
+    }
+    json.NewEncoder(w).Encode(webhooks)
+}
+
+// Create возвращает ключ подписи; позже он не показывается.
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+    var req WebhookRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        apierror.BadRequest(w, r, "Invalid request body")
+        return
+    }
+
+    webhook := &models.Webhook{UserID: getUserIDFromToken(r), Active: true}
+    req.apply(webhook)
+    if err := webhook.Validate(); err != nil {
+        apierror.FromError(w, r, err, "", "Invalid webhook")
+        return
+    }
+    secret, err := models.NewWebhookSecret()
+    if err != nil {
+        apierror.Internal(w, r, err, "Could not create webhook")
+        return
+    }
+    webhook.Secret = secret
+
+    created, err := h.webhooks.CreateWebhook(r.Context(), webhook)
+    if err != nil {
+        apierror.FromError(w, r, err, "", "Could not create webhook")
+        return
+    }
+
+    w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(created)
+}
+
+func (h *WebhookHandler) Get(w http.ResponseWriter, r *http.Request) {
+    id, ok := parseWebhookID(w, r)
+    if !ok {
+        return
+    }
+
+    webhook, err := h.webhooks.GetWebhook(r.Context(), id, getUserIDFromToken(r))
+    if err != nil {
+        apierror.FromError(w, r, err, "Webhook not found", "Could not get webhook")
+        return
+    }
+    webhook.Secret = ""
+    json.NewEncoder(w).Encode(webhook)
+}
+
+func (h *WebhookHandler) Update(w http.ResponseWriter, r *http.Request) {
+    id, ok := parseWebhookID(w, r)
+    if !ok {
+        return
+    }
+    var req WebhookRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        apierror.BadRequest(w, r, "Invalid request body")
+        return
+    }
+
+    webhook, err := h.webhooks.GetWebhook(r.Context(), id, getUserIDFromToken(r))
+    if err != nil {
+        apierror.FromError(w, r, err, "Webhook not found", "Could not update webhook")
+        return
+    }
+    req.apply(webhook)
+    if err := webhook.Validate(); err != nil {
+        apierror.FromError(w, r, err, "", "Invalid webhook")
+        return
+    }
+    webhook.Secret = ""
+    if req.RotateSecret {
+        if webhook.Secret, err = models.NewWebhookSecret(); err != nil {
+            apierror.Internal(w, r, err, "Could not update webhook")
+            return
+        }
+    }
+
+    updated, err := h.webhooks.UpdateWebhook(r.Context(), webhook)
+    if err != nil {
+        apierror.FromError(w, r, err, "Webhook not found", "Could not update webhook")
+        return
+    }
+    if !req.RotateSecret {
+        updated.Secret = ""
+    }
+    json.NewEncoder(w).Encode(updated)
+}
+
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+    id, ok := parseWebhookID(w, r)
+    if !ok {
+        return
+    }
+
+    if err := h.webhooks.DeleteWebhook(r.Context(), id, getUserIDFromToken(r)); err != nil {
+        apierror.FromError(w, r, err, "Webhook not found", "Could not delete webhook")
+        return
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// Deliveries возвращает журнал последних доставок; limit — до
+// models.MaxWebhookDeliveries.
+func (h *WebhookHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+    id, ok := parseWebhookID(w, r)
+    if !ok {
+        return
+    }
+    limit := models.DefaultWebhookDeliveries
+    if v := r.URL.Query().Get("limit"); v != "" {
+        n, err := strconv.Atoi(v)
+        if err != nil || n < 1 || n > models.MaxWebhookDeliveries {
+            apierror.Invalid(w, r, "limit", fmt.Sprintf("must be between 1 and %d", models.MaxWebhookDeliveries))
+            return
+        }
+        limit = n
+    }
+
+    deliveries, err := h.webhooks.GetWebhookDeliveries(r.Context(), id, getUserIDFromToken(r), limit)
+    if err != nil {
+        apierror.FromError(w, r, err, "Webhook not found", "Could not get deliveries")
+        return
+    }
+    json.NewEncoder(w).Encode(deliveries)
+}
+
+// Test ставит в очередь событие webhook.test; результат виден в журнале
+// доставок.
+func (h *WebhookHandler) Test(w http.ResponseWriter, r *http.Request) {
+    id, ok := parseWebhookID(w, r)
+    if !ok {
+        return
+    }
+
+    userID := getUserIDFromToken(r)
+    webhook, err := h.webhooks.GetWebhook(r.Context(), id, userID)
+    if err != nil {
+        apierror.FromError(w, r, err, "Webhook not found", "Could not send test event")
+        return
+    }
+    if !webhook.Active {
+        apierror.Invalid(w, r, "active", "webhook is disabled")
+        return
+    }
+
+    delivery, err := h.webhooks.QueueWebhookTest(r.Context(), id, userID)
+    if err != nil {
+        apierror.FromError(w, r, err, "Webhook not found", "Could not send test event")
+        return
+    }
+    w.WriteHeader(http.StatusAccepted)
+    json.NewEncoder(w).Encode(delivery)
+}
@@ -0,0 +1,60 @@
+package i18n
+
+// Ключи уведомлений совпадают с их типами из models; email.*, digest.* и
+// auth.* — тексты писем.
+var catalog = map[Locale]map[string]string{
+    RU: {
+        "task_created":   "Новая задача создана: {title}",
+        "task_due_soon":  "До срока выполнения задачи осталось менее 3 дней: {title}",
+        "task_due_today": "Задача должна быть выполнена сегодня: {title}",
+        "task_overdue":   "Задача просрочена: {title}",
+        "reminder":       "Напоминание: {title}",
+
+        "email.greeting":       "Здравствуйте, {name}!",
+        "email.due":            "Срок: {due}",
+        "email.due_soon_hint":  "Осталось немного времени, чтобы завершить задачу.",
+        "email.due_today_hint": "Срок истекает сегодня.",
+        "email.overdue_hint":   "Срок уже прошёл. Выполните задачу или перенесите срок.",
+        "email.created_hint":   "Задача добавлена в ваш список.",
+        "email.open":           "Открыть список задач",
+        "email.footer":         "Письмо отправлено автоматически, отвечать на него не нужно.",
+
+        "digest.subject":   "Задачи на {date}",
+        "digest.intro":     "Задачи, которые требуют внимания сегодня:",
+        "digest.overdue":   "Просрочено",
+        "digest.due_today": "На сегодня",
+
+        "auth.verify_subject": "Подтвердите адрес почты",
+        "auth.verify_intro":   "Чтобы подтвердить адрес и открыть все возможности, перейдите по ссылке:",
+        "auth.reset_subject":  "Сброс пароля",
+        "auth.reset_intro":    "Чтобы задать новый пароль, перейдите по ссылке:",
+        "auth.link_hint":      "Ссылка одноразовая и действует ограниченное время. Если вы не запрашивали это письмо, просто не обращайте на него внимания.",
+    },
+    EN: {
+        "task_created":   "New task created: {title}",
+        "task_due_soon":  "Less than 3 days left until the task is due: {title}",
+        "task_due_today": "Task is due today: {title}",
+        "task_overdue":   "Task is overdue: {title}",
+        "reminder":       "Reminder: {title}",
+
+        "email.greeting":       "Hello, {name}!",
+        "email.due":            "Due: {due}",
+        "email.due_soon_hint":  "There is not much time left to finish this task.",
+        "email.due_today_hint": "The task is due today.",
+        "email.overdue_hint":   "The due date has passed. Complete the task or move its due date.",
+        "email.created_hint":   "The task has been added to your list.",
+        "email.open":           "Open your tasks",
+        "email.footer":         "This email was sent automatically, please do not reply.",
+
+        "digest.subject":   "Tasks for {date}",
+        "digest.intro":     "Tasks that need your attention today:",
+        "digest.overdue":   "Overdue",
+        "digest.due_today": "Due today",
+
+        "auth.verify_subject": "Confirm your email address",
+        "auth.verify_intro":   "To confirm your address and unlock all features, follow the link:",
+        "auth.reset_subject":  "Password reset",
+        "auth.reset_intro":    "To set a new password, follow the link:",
+        "auth.link_hint":      "The link can be used once and expires soon. If you did not request this email, you can safely ignore it.",
+    },
+}
@@ -0,0 +1,114 @@
+// Package i18n выбирает язык пользователя и переводит сообщения из каталога.
+// Текст не хранится в базе: уведомления рендерятся при выдаче клиенту.
+package i18n
+
+import (
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+type Locale string
+
+const (
+    RU Locale = "ru"
+    EN Locale = "en"
+
+    // Default используется, когда ни настройка, ни Accept-Language не
+    // указывают поддерживаемый язык.
+    Default = RU
+)
+
+// Parse принимает языковой тег вроде "en-US" и возвращает поддерживаемый
+// язык по его основной части.
+func Parse(tag string) (Locale, bool) {
+    tag = strings.ToLower(strings.TrimSpace(tag))
+    if i := strings.IndexAny(tag, "-_"); i >= 0 {
+        tag = tag[:i]
+    }
+    locale := Locale(tag)
+    if _, ok := catalog[locale]; !ok {
+        return "", false
+    }
+    return locale, true
+}
+
+// Negotiate выбирает язык по заголовку Accept-Language с учётом весов q.
+func Negotiate(acceptLanguage string) Locale {
+    type candidate struct {
+        locale Locale
+        q      float64
+    }
+    var candidates []candidate
+    for _, part := range strings.Split(acceptLanguage, ",") {
+        fields := strings.Split(part, ";")
+        locale, ok := Parse(fields[0])
+        if !ok {
+            continue
+        }
+        q := 1.0
+        for _, f := range fields[1:] {
+            f = strings.TrimSpace(f)
+            if strings.HasPrefix(f, "q=") {
+                if v, err := strconv.ParseFloat(f[2:], 64); err == nil {
+                    q = v
+                }
+            }
+        }
+        if q > 0 {
+            candidates = append(candidates, candidate{locale, q})
+        }
+    }
+    if len(candidates) == 0 {
+        return Default
+    }
+    sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+    return candidates[0].locale
+}
+
+// Resolve отдаёт предпочтение настройке пользователя, а без неё — заголовку
+// Accept-Language.
+func Resolve(preferred, acceptLanguage string) Locale {
+    if locale, ok := Parse(preferred); ok {
+        return locale
+    }
+    return Negotiate(acceptLanguage)
+}
+
+// Text переводит сообщение key, подставляя параметры вида {name}. Ключ без
+// перевода берётся из языка по умолчанию, неизвестный возвращается как есть.
+func Text(locale Locale, key string, params map[string]string) string {
+    text, ok := catalog[locale][key]
+    if !ok {
+        if text, ok = catalog[Default][key]; !ok {
+            return key
+        }
+    }
+    if len(params) == 0 {
+        return text
+    }
+    pairs := make([]string, 0, 2*len(params))
+    for name, value := range params {
+        pairs = append(pairs, "{"+name+"}", value)
+    }
+    return strings.NewReplacer(pairs...).Replace(text)
+}
+
+var dateLayouts = map[Locale][2]string{
+    RU: {"02.01.2006", "02.01.2006 15:04"},
+    EN: {"Jan 2, 2006", "Jan 2, 2006 3:04 PM"},
+}
+
+// FormatTime форматирует момент t в привычном для языка виде; dateOnly
+// оставляет только дату. Часовой пояс задаёт сам t.
+func FormatTime(locale Locale, t time.Time, dateOnly bool) string {
+    layouts, ok := dateLayouts[locale]
+    if !ok {
+        layouts = dateLayouts[Default]
+    }
+    if dateOnly {
+        return t.Format(layouts[0])
+    }
+    return t.Format(layouts[1])
+}
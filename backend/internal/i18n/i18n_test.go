@@ -0,0 +1,140 @@
+package i18n
+
+import (
+    "regexp"
+    "sort"
+    "testing"
+    "time"
+)
+
+func TestParse(t *testing.T) {
+    tests := []struct {
+        tag    string
+        locale Locale
+        ok     bool
+    }{
+        {"ru", RU, true},
+        {"en-US", EN, true},
+        {" EN_gb ", EN, true},
+        {"de-DE", "", false},
+        {"", "", false},
+        {"*", "", false},
+    }
+    for _, tt := range tests {
+        locale, ok := Parse(tt.tag)
+        if locale != tt.locale || ok != tt.ok {
+            t.Errorf("Parse(%q) = %q, %v, want %q, %v", tt.tag, locale, ok, tt.locale, tt.ok)
+        }
+    }
+}
+
+func TestNegotiate(t *testing.T) {
+    tests := []struct {
+        header string
+        want   Locale
+    }{
+        {"", Default},
+        {"en", EN},
+        {"en-US,en;q=0.9,ru;q=0.8", EN},
+        {"ru;q=0.5, en;q=0.8", EN},
+        {"de-DE, en;q=0.1", EN},
+        // q=0 означает «не подходит»
+        {"en;q=0, ru;q=0.3", RU},
+        {"en;q=0", Default},
+        {"de, fr;q=0.9", Default},
+        // При равных весах выигрывает первый
+        {"en;q=0.5, ru;q=0.5", EN},
+        {"ru, en", RU},
+        // Некорректный вес считается единицей
+        {"en;q=abc, ru;q=0.9", EN},
+    }
+    for _, tt := range tests {
+        if got := Negotiate(tt.header); got != tt.want {
+            t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+        }
+    }
+}
+
+func TestResolve(t *testing.T) {
+    if got := Resolve("ru", "en-US,en;q=0.9"); got != RU {
+        t.Errorf("Resolve with a preference = %q, want ru", got)
+    }
+    if got := Resolve("", "en-US,en;q=0.9"); got != EN {
+        t.Errorf("Resolve without a preference = %q, want en", got)
+    }
+    if got := Resolve("de", ""); got != Default {
+        t.Errorf("Resolve with an unsupported preference = %q, want %q", got, Default)
+    }
+}
+
+func TestText(t *testing.T) {
+    params := map[string]string{"title": "Купить молоко"}
+    if got := Text(EN, "reminder", params); got != "Reminder: Купить молоко" {
+        t.Errorf("Text(en, reminder) = %q", got)
+    }
+    if got := Text(RU, "reminder", params); got != "Напоминание: Купить молоко" {
+        t.Errorf("Text(ru, reminder) = %q", got)
+    }
+    // Параметры не подставляются повторно
+    if got := Text(EN, "reminder", map[string]string{"title": "{title}"}); got != "Reminder: {title}" {
+        t.Errorf("Text with a placeholder in the value = %q", got)
+    }
+    if got := Text("de", "reminder", params); got != Text(Default, "reminder", params) {
+        t.Errorf("Text for an unknown locale = %q, want the default translation", got)
+    }
+    if got := Text(EN, "no.such.key", params); got != "no.such.key" {
+        t.Errorf("Text for an unknown key = %q, want the key", got)
+    }
+}
+
+var placeholder = regexp.MustCompile(`\{[a-z_]+\}`)
+
+func TestCatalogComplete(t *testing.T) {
+    for locale, messages := range catalog {
+        for key, text := range catalog[Default] {
+            translated, ok := messages[key]
+            if !ok {
+                t.Errorf("%s: no translation for %q", locale, key)
+                continue
+            }
+            want, got := placeholder.FindAllString(text, -1), placeholder.FindAllString(translated, -1)
+            sort.Strings(want)
+            sort.Strings(got)
+            if len(want) != len(got) {
+                t.Errorf("%s: %q has placeholders %v, want %v", locale, key, got, want)
+                continue
+            }
+            for i := range want {
+                if want[i] != got[i] {
+                    t.Errorf("%s: %q has placeholders %v, want %v", locale, key, got, want)
+                    break
+                }
+            }
+        }
+        for key := range messages {
+            if _, ok := catalog[Default][key]; !ok {
+                t.Errorf("%s: %q is missing in the default locale", locale, key)
+            }
+        }
+    }
+}
+
+func TestFormatTime(t *testing.T) {
+    at := time.Date(2024, 3, 5, 14, 7, 0, 0, time.UTC)
+    tests := []struct {
+        locale   Locale
+        dateOnly bool
+        want     string
+    }{
+        {RU, true, "05.03.2024"},
+        {RU, false, "05.03.2024 14:07"},
+        {EN, true, "Mar 5, 2024"},
+        {EN, false, "Mar 5, 2024 2:07 PM"},
+        {"de", true, "05.03.2024"},
+    }
+    for _, tt := range tests {
+        if got := FormatTime(tt.locale, at, tt.dateOnly); got != tt.want {
+            t.Errorf("FormatTime(%s, %v) = %q, want %q", tt.locale, tt.dateOnly, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,85 @@
+package memstore
+
+import (
+    "context"
+    "time"
+    "todo-app/internal/models"
+)
+
+type authToken struct {
+    userID    uint
+    purpose   models.AuthTokenPurpose
+    expiresAt time.Time
+}
+
+// dropAuthTokens удаляет токены пользователя с данным назначением.
+// Вызывается под блокировкой.
+func (s *Store) dropAuthTokens(userID uint, purpose models.AuthTokenPurpose) {
+    for hash, t := range s.authTokens {
+        if t.userID == userID && t.purpose == purpose {
+            delete(s.authTokens, hash)
+        }
+    }
+}
+
+func (s *Store) CreateAuthToken(ctx context.Context, userID uint, purpose models.AuthTokenPurpose, hash string, expiresAt time.Time) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if _, ok := s.users[userID]; !ok {
+        return models.Invalid("user_id", "referenced record does not exist")
+    }
+    s.dropAuthTokens(userID, purpose)
+    s.authTokens[hash] = authToken{userID: userID, purpose: purpose, expiresAt: expiresAt}
+    return nil
+}
+
+// consumeAuthToken повторяет одноимённую функцию PostgresStore. Вызывается
+// под блокировкой.
+func (s *Store) consumeAuthToken(purpose models.AuthTokenPurpose, hash string) (models.User, error) {
+    t, ok := s.authTokens[hash]
+    if !ok || t.purpose != purpose || !s.now().Before(t.expiresAt) {
+        return models.User{}, models.ErrInvalidAuthToken
+    }
+    s.dropAuthTokens(t.userID, purpose)
+    user, ok := s.users[t.userID]
+    if !ok {
+        return models.User{}, models.ErrInvalidAuthToken
+    }
+    if user.EmailVerifiedAt == nil {
+        now := s.now()
+        user.EmailVerifiedAt = &now
+    }
+    return user, nil
+}
+
+func (s *Store) VerifyEmail(ctx context.Context, hash string) (*models.User, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    user, err := s.consumeAuthToken(models.TokenVerifyEmail, hash)
+    if err != nil {
+        return nil, err
+    }
+    s.users[user.ID] = user
+    return &user, nil
+}
+
+func (s *Store) ResetPassword(ctx context.Context, hash, password string) (*models.User, error) {
+    hashedPassword, err := models.HashPassword(password)
+    if err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    user, err := s.consumeAuthToken(models.TokenResetPassword, hash)
+    if err != nil {
+        return nil, err
+    }
+    user.Password = hashedPassword
+    s.users[user.ID] = user
+    s.revokeUserSessions(user.ID)
+    return &user, nil
+}
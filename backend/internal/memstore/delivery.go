@@ -0,0 +1,185 @@
+package memstore
+
+import (
+    "context"
+    "sort"
+    "time"
+    "todo-app/internal/models"
+)
+
+// digestKey повторяет первичный ключ email_digests.
+type digestKey struct {
+    userID uint
+    day    time.Time
+}
+
+// queueDelivery повторяет триггер notifications_delivery. Вызывается под
+// блокировкой.
+func (s *Store) queueDelivery(n models.Notification, now time.Time) {
+    s.nextDeliveryID++
+    s.deliveries[s.nextDeliveryID] = models.Delivery{
+        ID:             s.nextDeliveryID,
+        NotificationID: n.ID,
+        Channel:        models.ChannelEmail,
+        Status:         models.DeliveryPending,
+        NextAttemptAt:  now,
+        CreatedAt:      now,
+    }
+}
+
+// deleteNotification повторяет ON DELETE CASCADE для доставок. Вызывается
+// под блокировкой.
+func (s *Store) deleteNotification(id uint) {
+    if n := s.notifications[id]; n.InApp {
+        s.recordChange(n.UserID, models.EntityNotification, id, true)
+    }
+    delete(s.notifications, id)
+    for dID, d := range s.deliveries {
+        if d.NotificationID == id {
+            delete(s.deliveries, dID)
+        }
+    }
+}
+
+func (s *Store) ClaimDeliveries(ctx context.Context, channel models.DeliveryChannel, limit int, lease time.Duration) ([]models.PendingDelivery, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    now := s.now()
+    var due []models.Delivery
+    for _, d := range s.deliveries {
+        if d.Channel == channel && d.Status == models.DeliveryPending && !d.NextAttemptAt.After(now) {
+            due = append(due, d)
+        }
+    }
+    sort.Slice(due, func(i, j int) bool {
+        if !due[i].NextAttemptAt.Equal(due[j].NextAttemptAt) {
+            return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+        }
+        return due[i].ID < due[j].ID
+    })
+    if len(due) > limit {
+        due = due[:limit]
+    }
+    sort.Slice(due, func(i, j int) bool { return due[i].ID < due[j].ID })
+
+    var claimed []models.PendingDelivery
+    for _, d := range due {
+        d.Attempts++
+        d.NextAttemptAt = now.Add(lease)
+        s.deliveries[d.ID] = d
+
+        n := s.notifications[d.NotificationID]
+        recipient := s.users[n.UserID]
+        recipient.Password = ""
+        claimed = append(claimed, models.PendingDelivery{
+            Delivery:     d,
+            Notification: n,
+            Recipient:    recipient,
+            Settings:     s.notificationSettings(n.UserID),
+        })
+    }
+    return claimed, nil
+}
+
+func (s *Store) CompleteDelivery(ctx context.Context, id uint, result models.DeliveryResult) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    d, ok := s.deliveries[id]
+    if !ok {
+        return models.ErrNotFound
+    }
+    d.Status = result.Status
+    d.LastError = result.Error
+    if result.RetryAt != nil {
+        d.NextAttemptAt = result.RetryAt.UTC()
+    }
+    if result.Status == models.DeliverySent {
+        sentAt := s.now()
+        d.SentAt = &sentAt
+    }
+    s.deliveries[id] = d
+    return nil
+}
+
+func (s *Store) GetNotificationDeliveries(ctx context.Context, notificationID, userID uint) ([]models.Delivery, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    n, ok := s.notifications[notificationID]
+    if !ok || n.UserID != userID {
+        return nil, models.ErrNotFound
+    }
+
+    deliveries := []models.Delivery{}
+    for _, d := range s.deliveries {
+        if d.NotificationID == notificationID {
+            d.SentAt = copyTime(d.SentAt)
+            deliveries = append(deliveries, d)
+        }
+    }
+    sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].Channel < deliveries[j].Channel })
+    return deliveries, nil
+}
+
+func (s *Store) ClaimDigests(ctx context.Context, hour int) ([]models.Digest, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    now := s.now()
+    for key := range s.digests {
+        if key.day.Before(digestCutoff(now)) {
+            delete(s.digests, key)
+        }
+    }
+
+    var digests []models.Digest
+    for id, user := range s.users {
+        loc := models.Location(user.Timezone)
+        key := digestKey{userID: id, day: models.DigestDay(now, loc)}
+        settings := s.notificationSettings(id)
+        if now.In(loc).Hour() < hour || s.digests[key] || !user.Verified() || !settings.Channels.Email ||
+            settings.EmailDelivery != models.EmailDigest || settings.Quiet(now, loc) {
+            continue
+        }
+        s.digests[key] = true
+
+        user.Password = ""
+        digest := models.Digest{User: user, Day: key.day}
+        for _, t := range sortedByDue(s.filterTasks(func(t models.Task) bool { return t.UserID == id && !t.Completed })) {
+            switch {
+            case t.Deadline(loc).Before(now):
+                digest.Overdue = append(digest.Overdue, t)
+            case models.StartOfDay(t.DueDate, loc).Equal(models.StartOfDay(now, loc)):
+                digest.DueToday = append(digest.DueToday, t)
+            }
+        }
+        digests = append(digests, digest)
+    }
+    sort.Slice(digests, func(i, j int) bool { return digests[i].User.ID < digests[j].User.ID })
+    return digests, nil
+}
+
+func (s *Store) ReleaseDigest(ctx context.Context, userID uint, day time.Time) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    delete(s.digests, digestKey{userID: userID, day: day.UTC()})
+    return nil
+}
+
+// digestCutoff повторяет очистку email_digests: строки старше недели.
+func digestCutoff(now time.Time) time.Time {
+    return models.DigestDay(now, time.UTC).AddDate(0, 0, -7)
+}
+
+func sortedByDue(tasks []models.Task) []models.Task {
+    sort.SliceStable(tasks, func(i, j int) bool {
+        if !tasks[i].DueDate.Equal(tasks[j].DueDate) {
+            return tasks[i].DueDate.Before(tasks[j].DueDate)
+        }
+        return tasks[i].ID < tasks[j].ID
+    })
+    return tasks
+}
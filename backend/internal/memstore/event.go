@@ -0,0 +1,83 @@
+package memstore
+
+import (
+    "context"
+    "encoding/json"
+    "time"
+    "todo-app/internal/models"
+)
+
+// recordEvent повторяет триггеры миграций 0006_events и 0014_webhooks:
+// событие, кроме notification.created, сразу ставится в очередь вебхуков.
+func (s *Store) recordEvent(userID uint, eventType string, data interface{}) {
+    raw, _ := json.Marshal(data)
+    s.nextEventID++
+    s.events = append(s.events, models.Event{
+        ID:        s.nextEventID,
+        UserID:    userID,
+        Type:      eventType,
+        Data:      raw,
+        CreatedAt: s.now(),
+    })
+    if eventType != models.EventNotificationCreated {
+        s.queueWebhooks(userID, eventType, raw)
+    }
+    if s.OnEvent != nil {
+        s.OnEvent(userID)
+    }
+}
+
+func (s *Store) taskEvent(eventType string, task models.Task) {
+    s.recordEvent(task.UserID, eventType, models.TaskEventData{
+        ID:        task.ID,
+        ParentID:  task.ParentID,
+        Completed: task.Completed,
+    })
+}
+
+func (s *Store) categoryEvent(eventType string, category models.Category) {
+    s.recordEvent(category.UserID, eventType, models.CategoryEventData{ID: category.ID, Name: category.Name})
+}
+
+func (s *Store) ListEvents(ctx context.Context, userID uint, afterID int64, limit int) ([]models.Event, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    var events []models.Event
+    for _, e := range s.events {
+        if e.UserID == userID && e.ID > afterID {
+            events = append(events, e)
+            if len(events) == limit {
+                break
+            }
+        }
+    }
+    return events, nil
+}
+
+func (s *Store) LatestEventID(ctx context.Context, userID uint) (int64, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    for i := len(s.events) - 1; i >= 0; i-- {
+        if s.events[i].UserID == userID {
+            return s.events[i].ID, nil
+        }
+    }
+    return 0, nil
+}
+
+func (s *Store) PurgeEvents(ctx context.Context, before time.Time) (int64, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    kept := s.events[:0]
+    for _, e := range s.events {
+        if !e.CreatedAt.Before(before) {
+            kept = append(kept, e)
+        }
+    }
+    purged := int64(len(s.events) - len(kept))
+    s.events = kept
+    return purged, nil
+}
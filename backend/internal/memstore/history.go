@@ -0,0 +1,47 @@
+package memstore
+
+import (
+    "context"
+    "todo-app/internal/models"
+)
+
+// recordTaskHistory повторяет триггер миграции 0019_task_events: запись
+// пишется от имени s.actor, изменение без отслеживаемых полей пропускается.
+func (s *Store) recordTaskHistory(action string, task models.Task, changes models.TaskChanges) {
+    if action == models.TaskActionUpdated && len(changes) == 0 {
+        return
+    }
+    var actorID *uint
+    if s.actor.UserID != 0 {
+        id := s.actor.UserID
+        actorID = &id
+    }
+    source := s.actor.Source
+    if source == "" {
+        source = models.SourceAPI
+    }
+    s.nextTaskEventID++
+    s.taskEvents = append(s.taskEvents, models.TaskEvent{
+        ID:        s.nextTaskEventID,
+        TaskID:    task.ID,
+        UserID:    task.UserID,
+        ActorID:   actorID,
+        Source:    source,
+        Action:    action,
+        Changes:   changes,
+        CreatedAt: s.now(),
+    })
+}
+
+func (s *Store) ListTaskEvents(ctx context.Context, userID uint, filter models.HistoryFilter) (*models.HistoryPage, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    var events []models.TaskEvent
+    for i := len(s.taskEvents) - 1; i >= 0; i-- {
+        if s.taskEvents[i].UserID == userID {
+            events = append(events, s.taskEvents[i])
+        }
+    }
+    return models.PageTaskEvents(events, filter)
+}
@@ -0,0 +1,760 @@
+package memstore
+
+import (
+    "context"
+    "encoding/json"
+    "sort"
+    "sync"
+    "time"
+    "todo-app/internal/models"
+)
+
+// Store хранит все данные в памяти процесса и повторяет поведение PostgresStore,
+// включая каскадные удаления и ON DELETE SET NULL для категорий. Записи в
+// корзине лежат отдельно от остальных, поэтому обычные методы их не видят.
+type Store struct {
+    mu sync.RWMutex
+
+    // Now подменяется в тестах; по умолчанию time.Now.
+    Now func() time.Time
+
+    // OnEvent, если задан, вызывается с id пользователя после записи события;
+    // заменяет LISTEN/NOTIFY. Вызывается под блокировкой хранилища.
+    OnEvent func(userID uint)
+
+    users         map[uint]models.User
+    tasks         map[uint]models.Task
+    categories    map[uint]models.Category
+    notifications map[uint]models.Notification
+    sessions      map[uint]models.Session
+    refreshTokens map[string]refreshToken
+    authTokens    map[string]authToken
+    events        []models.Event
+    taskEvents    []models.TaskEvent
+    stages        map[uint]stage
+    reminders     map[uint]reminder
+    deliveries    map[uint]models.Delivery
+    digests       map[digestKey]bool
+    settings      map[uint]models.NotificationSettings
+    webhooks      map[uint]models.Webhook
+    webhookQueue  map[uint]models.WebhookDelivery
+    changes       map[changeKey]models.Change
+
+    trashedTasks      map[uint]models.Task
+    trashedCategories map[uint]models.Category
+    // trashedCategoryIDs заменяет колонку tasks.trashed_category_id.
+    trashedCategoryIDs map[uint]uint
+
+    // actor — автор текущего изменения задач, как set_config в транзакции
+    // PostgresStore; методы, меняющие задачи, задают его под блокировкой.
+    actor models.Actor
+
+    nextUserID            uint
+    nextTaskID            uint
+    nextCategoryID        uint
+    nextNotificationID    uint
+    nextSessionID         uint
+    nextEventID           int64
+    nextReminderID        uint
+    nextDeliveryID        uint
+    nextWebhookID         uint
+    nextWebhookDeliveryID uint
+    nextChangeID          int64
+    nextTaskEventID       int64
+}
+
+var _ models.Store = (*Store)(nil)
+
+func New() *Store {
+    return &Store{
+        Now:           time.Now,
+        users:         map[uint]models.User{},
+        tasks:         map[uint]models.Task{},
+        categories:    map[uint]models.Category{},
+        notifications: map[uint]models.Notification{},
+        sessions:      map[uint]models.Session{},
+        refreshTokens: map[string]refreshToken{},
+        authTokens:    map[string]authToken{},
+        stages:        map[uint]stage{},
+        reminders:     map[uint]reminder{},
+        deliveries:    map[uint]models.Delivery{},
+        digests:       map[digestKey]bool{},
+        settings:      map[uint]models.NotificationSettings{},
+        webhooks:      map[uint]models.Webhook{},
+        webhookQueue:  map[uint]models.WebhookDelivery{},
+        changes:       map[changeKey]models.Change{},
+
+        trashedTasks:       map[uint]models.Task{},
+        trashedCategories:  map[uint]models.Category{},
+        trashedCategoryIDs: map[uint]uint{},
+    }
+}
+
+func (s *Store) now() time.Time {
+    return s.Now().UTC()
+}
+
+// withCategory возвращает копию задачи с заполненными полями Category и Progress.
+func (s *Store) withCategory(task models.Task) models.Task {
+    task.Category = nil
+    if task.CategoryID != nil {
+        if category, ok := s.categories[*task.CategoryID]; ok {
+            task.Category = &category
+        }
+    }
+
+    var progress models.TaskProgress
+    for _, child := range s.tasks {
+        if child.ParentID != nil && *child.ParentID == task.ID {
+            progress.Total++
+            if child.Completed {
+                progress.Completed++
+            }
+        }
+    }
+    task.Progress = nil
+    if progress.Total > 0 {
+        task.Progress = &progress
+    }
+    return task
+}
+
+func sameParent(a, b *uint) bool {
+    if a == nil || b == nil {
+        return a == nil && b == nil
+    }
+    return *a == *b
+}
+
+// nextPosition возвращает позицию для новой последней подзадачи parentID.
+func (s *Store) nextPosition(parentID *uint) int {
+    if parentID == nil {
+        return 0
+    }
+    position := 0
+    for _, t := range s.tasks {
+        if sameParent(t.ParentID, parentID) && t.Position >= position {
+            position = t.Position + 1
+        }
+    }
+    return position
+}
+
+func sortTasks(tasks []models.Task) {
+    sort.SliceStable(tasks, func(i, j int) bool {
+        a, b := tasks[i], tasks[j]
+        if !a.DueDate.Equal(b.DueDate) {
+            return a.DueDate.Before(b.DueDate)
+        }
+        if a.Priority != b.Priority {
+            return a.Priority > b.Priority
+        }
+        if !a.CreatedAt.Equal(b.CreatedAt) {
+            return a.CreatedAt.After(b.CreatedAt)
+        }
+        return a.ID > b.ID
+    })
+}
+
+func (s *Store) CreateUser(ctx context.Context, email, password, name, timezone string) (*models.User, error) {
+    hashedPassword, err := models.HashPassword(password)
+    if err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for _, u := range s.users {
+        if u.Email == email {
+            return nil, models.ErrEmailTaken
+        }
+    }
+
+    if timezone == "" {
+        timezone = models.DefaultTimezone
+    }
+
+    s.nextUserID++
+    user := models.User{ID: s.nextUserID, Email: email, Password: hashedPassword, Name: name, Timezone: timezone}
+    s.users[user.ID] = user
+
+    return &models.User{ID: user.ID, Email: email, Name: name, Timezone: timezone}, nil
+}
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    for _, u := range s.users {
+        if u.Email == email {
+            user := u
+            return &user, nil
+        }
+    }
+    return nil, models.ErrNotFound
+}
+
+func (s *Store) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    user, ok := s.users[id]
+    if !ok {
+        return nil, models.ErrNotFound
+    }
+    return &user, nil
+}
+
+func (s *Store) UpdateUserProfile(ctx context.Context, id uint, name, timezone, locale string) (*models.User, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    user, ok := s.users[id]
+    if !ok {
+        return nil, models.ErrNotFound
+    }
+    user.Name = name
+    user.Timezone = timezone
+    user.Locale = locale
+    s.users[id] = user
+    return &user, nil
+}
+
+// location возвращает часовой пояс пользователя. Вызывается под блокировкой.
+func (s *Store) location(userID uint) *time.Location {
+    return models.Location(s.users[userID].Timezone)
+}
+
+func (s *Store) CreateCategory(ctx context.Context, category *models.Category) (*models.Category, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if s.clientIDTaken(category.UserID, models.EntityCategory, category.ClientID) {
+        return nil, models.ErrClientIDTaken
+    }
+    s.nextCategoryID++
+    created := models.Category{
+        ID:        s.nextCategoryID,
+        Name:      category.Name,
+        UserID:    category.UserID,
+        CreatedAt: s.now(),
+        Version:   1,
+        ClientID:  category.ClientID,
+    }
+    s.categories[created.ID] = created
+    s.categoryEvent(models.EventCategoryCreated, created)
+    s.recordChange(created.UserID, models.EntityCategory, created.ID, false)
+    return &created, nil
+}
+
+func (s *Store) GetCategory(ctx context.Context, id, userID uint) (*models.Category, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    category, ok := s.categories[id]
+    if !ok || category.UserID != userID {
+        return nil, models.ErrNotFound
+    }
+    return &category, nil
+}
+
+func (s *Store) GetUserCategories(ctx context.Context, userID uint) ([]models.Category, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    var categories []models.Category
+    for _, c := range s.categories {
+        if c.UserID == userID {
+            categories = append(categories, c)
+        }
+    }
+    sort.SliceStable(categories, func(i, j int) bool {
+        if !categories[i].CreatedAt.Equal(categories[j].CreatedAt) {
+            return categories[i].CreatedAt.After(categories[j].CreatedAt)
+        }
+        return categories[i].ID > categories[j].ID
+    })
+    return categories, nil
+}
+
+func (s *Store) DeleteCategory(ctx context.Context, id, userID uint, version int) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.actor = models.ActorFrom(ctx)
+
+    category, ok := s.categories[id]
+    if !ok || category.UserID != userID {
+        return models.ErrNotFound
+    }
+    if version != 0 && version != category.Version {
+        return models.ErrVersionMismatch
+    }
+    now := s.now()
+    delete(s.categories, id)
+    category.DeletedAt = &now
+    category.Version++
+    s.trashedCategories[id] = category
+    s.categoryEvent(models.EventCategoryDeleted, category)
+    s.recordChange(userID, models.EntityCategory, id, true)
+
+    for _, task := range s.tasks {
+        if task.CategoryID != nil && *task.CategoryID == id {
+            task.CategoryID = nil
+            task.UpdatedAt = now
+            s.trashedCategoryIDs[task.ID] = id
+            s.saveTask(task)
+        }
+    }
+    for _, task := range s.trashedTasks {
+        if task.CategoryID != nil && *task.CategoryID == id {
+            task.CategoryID = nil
+            task.UpdatedAt = now
+            s.trashedCategoryIDs[task.ID] = id
+            s.saveTrashedTask(task)
+        }
+    }
+    return nil
+}
+
+func (s *Store) CreateTask(ctx context.Context, task *models.Task) (*models.Task, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.actor = models.ActorFrom(ctx)
+
+    if err := s.checkReferences(task); err != nil {
+        return nil, err
+    }
+    if s.clientIDTaken(task.UserID, models.EntityTask, task.ClientID) {
+        return nil, models.ErrClientIDTaken
+    }
+
+    result := s.withCategory(s.insertTask(task))
+    return &result, nil
+}
+
+// checkReferences повторяет проверку владельца категории и родителя из PostgresStore.
+func (s *Store) checkReferences(task *models.Task) error {
+    if task.CategoryID != nil {
+        if category, ok := s.categories[*task.CategoryID]; !ok || category.UserID != task.UserID {
+            return models.ErrInvalidCategory
+        }
+    }
+    if task.ParentID != nil {
+        if parent, ok := s.tasks[*task.ParentID]; !ok || parent.UserID != task.UserID {
+            return models.ErrInvalidParent
+        }
+    }
+    return nil
+}
+
+func (s *Store) insertTask(task *models.Task) models.Task {
+    now := s.now()
+    s.nextTaskID++
+    created := *task
+    created.ID = s.nextTaskID
+    created.Completed = false
+    created.Position = s.nextPosition(task.ParentID)
+    created.Category = nil
+    created.Progress = nil
+    created.NextOccurrence = nil
+    created.CreatedAt = now
+    created.UpdatedAt = now
+    created.Version = 1
+    s.tasks[created.ID] = created
+    s.taskEvent(models.EventTaskCreated, created)
+    s.recordTaskHistory(models.TaskActionCreated, created, models.DiffTask(nil, &created))
+    s.recordChange(created.UserID, models.EntityTask, created.ID, false)
+    return created
+}
+
+func (s *Store) CreateNextOccurrence(ctx context.Context, completed *models.Task, next *models.Task) (*models.Task, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.actor = models.ActorFrom(ctx)
+
+    current, ok := s.tasks[completed.ID]
+    if !ok {
+        return nil, models.ErrNotFound
+    }
+    current.Recurrence = ""
+    current.RecurrenceStart = nil
+    current.UpdatedAt = s.now()
+    s.saveTask(current)
+
+    result := s.withCategory(s.insertTask(next))
+    return &result, nil
+}
+
+// sameTask сообщает, что задачи различаются только версией, временем
+// изменения и вычисляемыми полями, как проверка в триггере bump_version.
+func sameTask(a, b models.Task) bool {
+    for _, t := range []*models.Task{&a, &b} {
+        t.Version = 0
+        t.UpdatedAt = time.Time{}
+        t.Category = nil
+        t.Progress = nil
+        t.NextOccurrence = nil
+    }
+    left, _ := json.Marshal(a)
+    right, _ := json.Marshal(b)
+    return string(left) == string(right)
+}
+
+// saveTask сохраняет изменённую задачу. Как триггеры tasks_version,
+// tasks_event_update, tasks_change и tasks_history, увеличивает версию и
+// пишет событие task.updated, изменение для синхронизации и историю.
+// Задача без изменений остаётся прежней. Вызывается под блокировкой.
+func (s *Store) saveTask(task models.Task) models.Task {
+    old := s.tasks[task.ID]
+    if sameTask(old, task) {
+        return old
+    }
+    task.Version++
+    s.tasks[task.ID] = task
+    s.taskEvent(models.EventTaskUpdated, task)
+    s.recordTaskHistory(models.TaskActionUpdated, task, models.DiffTask(&old, &task))
+    s.recordChange(task.UserID, models.EntityTask, task.ID, false)
+    return task
+}
+
+func (s *Store) GetTask(ctx context.Context, id, userID uint) (*models.Task, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    task, ok := s.tasks[id]
+    if !ok || task.UserID != userID {
+        return nil, models.ErrNotFound
+    }
+    result := s.withCategory(task)
+    return &result, nil
+}
+
+func (s *Store) filterTasks(match func(models.Task) bool) []models.Task {
+    var tasks []models.Task
+    for _, t := range s.tasks {
+        if match(t) {
+            tasks = append(tasks, s.withCategory(t))
+        }
+    }
+    sortTasks(tasks)
+    return tasks
+}
+
+func (s *Store) GetUserTasks(ctx context.Context, userID uint) ([]models.Task, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    return s.filterTasks(func(t models.Task) bool {
+        return t.UserID == userID
+    }), nil
+}
+
+func (s *Store) ListTasks(ctx context.Context, userID uint, filter models.TaskFilter) (*models.TaskPage, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    now := s.now()
+    tasks := s.filterTasks(func(t models.Task) bool {
+        return t.UserID == userID && filter.Matches(&t, now, s.location(userID))
+    })
+    return models.PageTasks(tasks, filter)
+}
+
+func (s *Store) UpdateTask(ctx context.Context, task *models.Task) (*models.Task, error) {
+    return s.PatchTask(ctx, task.ID, task.UserID, models.NewTaskPatch(task))
+}
+
+func (s *Store) PatchTask(ctx context.Context, id, userID uint, patch models.TaskPatch) (*models.Task, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.actor = models.ActorFrom(ctx)
+
+    refs := models.Task{UserID: userID}
+    patch.Apply(&refs)
+    if err := s.checkReferences(&refs); err != nil {
+        return nil, err
+    }
+    existing, ok := s.tasks[id]
+    if !ok || existing.UserID != userID {
+        return nil, models.ErrNotFound
+    }
+    if patch.Version != 0 && patch.Version != existing.Version {
+        return nil, models.ErrVersionMismatch
+    }
+
+    updated := existing
+    patch.Apply(&updated)
+    if patch.CategoryID != nil {
+        delete(s.trashedCategoryIDs, id)
+    }
+    if !sameParent(existing.ParentID, updated.ParentID) {
+        updated.Position = s.nextPosition(updated.ParentID)
+    }
+    updated.UpdatedAt = s.now()
+    updated = s.saveTask(updated)
+    if updated.Completed && !existing.Completed {
+        s.taskEvent(models.EventTaskCompleted, updated)
+    }
+
+    result := s.withCategory(updated)
+    return &result, nil
+}
+
+func (s *Store) UpdateTaskCategory(ctx context.Context, taskID, categoryID, userID uint, version int) (*models.Task, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.actor = models.ActorFrom(ctx)
+
+    task, ok := s.tasks[taskID]
+    if !ok || task.UserID != userID {
+        return nil, models.ErrNotFound
+    }
+    task.CategoryID = nil
+    if categoryID != 0 {
+        task.CategoryID = &categoryID
+        if err := s.checkReferences(&models.Task{UserID: userID, CategoryID: task.CategoryID}); err != nil {
+            return nil, err
+        }
+    }
+    if version != 0 && version != task.Version {
+        return nil, models.ErrVersionMismatch
+    }
+    delete(s.trashedCategoryIDs, taskID)
+    task.UpdatedAt = s.now()
+    result := s.withCategory(s.saveTask(task))
+    return &result, nil
+}
+
+func (s *Store) DeleteTask(ctx context.Context, id, userID uint, children models.DeleteChildren, version int) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.actor = models.ActorFrom(ctx)
+
+    task, ok := s.tasks[id]
+    if !ok || task.UserID != userID {
+        return models.ErrNotFound
+    }
+    if version != 0 && version != task.Version {
+        return models.ErrVersionMismatch
+    }
+    now := s.now()
+    if children == models.PromoteChildren {
+        for _, child := range s.tasks {
+            if child.ParentID != nil && *child.ParentID == id {
+                child.ParentID = task.ParentID
+                child.UpdatedAt = now
+                s.saveTask(child)
+            }
+        }
+    }
+    s.trashTask(id, now)
+    return nil
+}
+
+// trashTask переносит задачу и её подзадачи в корзину с общим временем
+// удаления. Вызывается под блокировкой.
+func (s *Store) trashTask(id uint, now time.Time) {
+    task := s.tasks[id]
+    delete(s.tasks, id)
+    task.DeletedAt = &now
+    task.UpdatedAt = now
+    task.Version++
+    s.trashedTasks[id] = task
+    s.taskEvent(models.EventTaskDeleted, task)
+    s.recordTaskHistory(models.TaskActionDeleted, task, models.TaskChanges{})
+    s.recordChange(task.UserID, models.EntityTask, id, true)
+    for childID, child := range s.tasks {
+        if child.ParentID != nil && *child.ParentID == id {
+            s.trashTask(childID, now)
+        }
+    }
+}
+
+// saveTrashedTask сохраняет изменённую задачу из корзины: версия растёт, в
+// журнале изменений остаётся надгробие, событий нет, но история пишется.
+// Вызывается под блокировкой.
+func (s *Store) saveTrashedTask(task models.Task) {
+    old := s.trashedTasks[task.ID]
+    if sameTask(old, task) {
+        return
+    }
+    task.Version++
+    s.trashedTasks[task.ID] = task
+    s.recordTaskHistory(models.TaskActionUpdated, task, models.DiffTask(&old, &task))
+    s.recordChange(task.UserID, models.EntityTask, task.ID, true)
+}
+
+// deleteTask окончательно удаляет задачу из корзины и повторяет ON DELETE
+// CASCADE для подзадач и уведомлений.
+func (s *Store) deleteTask(id uint) {
+    task := s.trashedTasks[id]
+    s.recordChange(task.UserID, models.EntityTask, id, true)
+    s.recordTaskHistory(models.TaskActionPurged, task, models.TaskChanges{})
+    delete(s.trashedTasks, id)
+    delete(s.trashedCategoryIDs, id)
+    delete(s.stages, id)
+    for rID, r := range s.reminders {
+        if r.TaskID == id {
+            delete(s.reminders, rID)
+        }
+    }
+    for nID, n := range s.notifications {
+        if n.TaskID == id {
+            s.deleteNotification(nID)
+        }
+    }
+    for childID, child := range s.trashedTasks {
+        if child.ParentID != nil && *child.ParentID == id {
+            s.deleteTask(childID)
+        }
+    }
+}
+
+func (s *Store) GetSubtasks(ctx context.Context, parentID, userID uint) ([]models.Task, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    var tasks []models.Task
+    for _, t := range s.tasks {
+        if t.UserID == userID && t.ParentID != nil && *t.ParentID == parentID {
+            tasks = append(tasks, s.withCategory(t))
+        }
+    }
+    sort.Slice(tasks, func(i, j int) bool {
+        if tasks[i].Position != tasks[j].Position {
+            return tasks[i].Position < tasks[j].Position
+        }
+        return tasks[i].ID < tasks[j].ID
+    })
+    return tasks, nil
+}
+
+func (s *Store) ReorderSubtasks(ctx context.Context, parentID, userID uint, ids []uint) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.actor = models.ActorFrom(ctx)
+
+    children := map[uint]bool{}
+    for _, t := range s.tasks {
+        if t.UserID == userID && t.ParentID != nil && *t.ParentID == parentID {
+            children[t.ID] = true
+        }
+    }
+    if err := models.CheckOrder(children, ids); err != nil {
+        return err
+    }
+
+    now := s.now()
+    for position, id := range ids {
+        task := s.tasks[id]
+        task.Position = position
+        task.UpdatedAt = now
+        s.saveTask(task)
+    }
+    return nil
+}
+
+func (s *Store) SyncParentCompletion(ctx context.Context, parentID uint) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.actor = models.ActorFrom(ctx)
+
+    id := parentID
+    for {
+        parent, ok := s.tasks[id]
+        if !ok || !parent.AutoComplete {
+            return nil
+        }
+
+        allDone := true
+        for _, child := range s.tasks {
+            if child.ParentID != nil && *child.ParentID == parent.ID && !child.Completed {
+                allDone = false
+                break
+            }
+        }
+        if parent.Completed == allDone {
+            return nil
+        }
+        parent.Completed = allDone
+        parent.UpdatedAt = s.now()
+        parent = s.saveTask(parent)
+        if allDone {
+            s.taskEvent(models.EventTaskCompleted, parent)
+        }
+
+        if parent.ParentID == nil {
+            return nil
+        }
+        id = *parent.ParentID
+    }
+}
+
+func (s *Store) CreateNotification(ctx context.Context, userID uint, kind models.NotificationType, params models.NotificationParams) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.createNotification(userID, kind, params, s.now())
+    return nil
+}
+
+// createNotification создаёт уведомление, если настройки пользователя его
+// допускают.
+func (s *Store) createNotification(userID uint, kind models.NotificationType, params models.NotificationParams, now time.Time) {
+    settings := s.notificationSettings(userID)
+    if !settings.Allows(kind) {
+        return
+    }
+    s.nextNotificationID++
+    n := models.Notification{
+        ID:        s.nextNotificationID,
+        UserID:    userID,
+        TaskID:    params.TaskID,
+        Type:      kind,
+        Params:    params,
+        CreatedAt: now,
+        InApp:     settings.Channels.InApp,
+    }
+    s.saveNotification(n)
+    if settings.Channels.Webhook {
+        raw, _ := json.Marshal(n)
+        s.queueWebhooks(userID, models.EventNotificationCreated, raw)
+    }
+    if n.InApp {
+        s.recordEvent(userID, models.EventNotificationCreated, n)
+    }
+    s.queueDelivery(n, now)
+}
+
+func (s *Store) GetUserNotifications(ctx context.Context, userID uint) ([]models.Notification, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    var notifications []models.Notification
+    for _, n := range s.notifications {
+        if n.UserID == userID && n.InApp {
+            notifications = append(notifications, n)
+        }
+    }
+    sort.SliceStable(notifications, func(i, j int) bool {
+        if !notifications[i].CreatedAt.Equal(notifications[j].CreatedAt) {
+            return notifications[i].CreatedAt.After(notifications[j].CreatedAt)
+        }
+        return notifications[i].ID > notifications[j].ID
+    })
+    return notifications, nil
+}
+
+func (s *Store) MarkNotificationAsRead(ctx context.Context, id, userID uint) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    n, ok := s.notifications[id]
+    if !ok || n.UserID != userID {
+        return models.ErrNotFound
+    }
+    n.Read = true
+    s.saveNotification(n)
+    return nil
+}
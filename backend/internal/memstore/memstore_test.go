@@ -0,0 +1,11 @@
+package memstore
+
+import (
+    "testing"
+    "todo-app/internal/models"
+    "todo-app/internal/models/storetest"
+)
+
+func TestConformance(t *testing.T) {
+    storetest.Run(t, func(t *testing.T) models.Store { return New() })
+}
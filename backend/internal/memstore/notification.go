@@ -0,0 +1,90 @@
+package memstore
+
+import (
+    "context"
+    "sort"
+    "time"
+    "todo-app/internal/models"
+)
+
+func (s *Store) ListNotifications(ctx context.Context, userID uint, filter models.NotificationFilter) (*models.NotificationPage, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    var notifications []models.Notification
+    for _, n := range s.notifications {
+        if n.UserID == userID && n.InApp {
+            notifications = append(notifications, n)
+        }
+    }
+    sort.Slice(notifications, func(i, j int) bool { return notifications[i].ID > notifications[j].ID })
+    return models.PageNotifications(notifications, filter)
+}
+
+func (s *Store) CountUnreadNotifications(ctx context.Context, userID uint) (int, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    count := 0
+    for _, n := range s.notifications {
+        if n.UserID == userID && n.InApp && !n.Read {
+            count++
+        }
+    }
+    return count, nil
+}
+
+func (s *Store) MarkNotificationsRead(ctx context.Context, userID uint, sel models.NotificationSelection) (int64, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    var updated int64
+    for _, n := range s.notifications {
+        if n.UserID == userID && n.InApp && !n.Read && sel.Matches(&n) {
+            n.Read = true
+            s.saveNotification(n)
+            updated++
+        }
+    }
+    return updated, nil
+}
+
+func (s *Store) DeleteNotification(ctx context.Context, id, userID uint) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    n, ok := s.notifications[id]
+    if !ok || n.UserID != userID {
+        return models.ErrNotFound
+    }
+    s.deleteNotification(id)
+    return nil
+}
+
+func (s *Store) DeleteNotifications(ctx context.Context, userID uint, sel models.NotificationSelection) (int64, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    var deleted int64
+    for id, n := range s.notifications {
+        if n.UserID == userID && sel.Matches(&n) {
+            s.deleteNotification(id)
+            deleted++
+        }
+    }
+    return deleted, nil
+}
+
+func (s *Store) PurgeNotifications(ctx context.Context, before time.Time) (int64, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    var purged int64
+    for id, n := range s.notifications {
+        if (n.Read || !n.InApp) && n.CreatedAt.Before(before) {
+            s.deleteNotification(id)
+            purged++
+        }
+    }
+    return purged, nil
+}
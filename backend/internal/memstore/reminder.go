@@ -0,0 +1,193 @@
+package memstore
+
+import (
+    "context"
+    "sort"
+    "time"
+    "todo-app/internal/models"
+)
+
+// stage повторяет строку task_reminders.
+type stage struct {
+    dueDate time.Time
+    stage   models.ReminderStage
+}
+
+// reminder повторяет строку reminders; firedFor нулевой, пока напоминание
+// не сработало.
+type reminder struct {
+    models.Reminder
+    firedFor time.Time
+}
+
+func (s *Store) FireReminders(ctx context.Context) (int, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    now := s.now()
+    before := s.nextNotificationID
+    var due []models.Task
+    for _, t := range s.tasks {
+        if t.Completed || s.quiet(t.UserID, now) {
+            continue
+        }
+        current := models.ReminderStageAt(&t, now, s.location(t.UserID))
+        if current == models.StageNone {
+            continue
+        }
+        if st, ok := s.stages[t.ID]; ok && st.dueDate.Equal(t.DueDate) && st.stage >= current {
+            continue
+        }
+        due = append(due, t)
+    }
+    sort.Slice(due, func(i, j int) bool { return due[i].ID < due[j].ID })
+
+    for _, t := range due {
+        current := models.ReminderStageAt(&t, now, s.location(t.UserID))
+        s.stages[t.ID] = stage{dueDate: t.DueDate, stage: current}
+        s.createNotification(t.UserID, current.NotificationType(), models.TaskParams(&t), now)
+    }
+
+    var ids []uint
+    for id, r := range s.reminders {
+        task, ok := s.tasks[r.TaskID]
+        trigger := r.TriggerTime(task.DueDate)
+        if ok && !task.Completed && !trigger.After(now) && !r.firedFor.Equal(trigger) && !s.quiet(task.UserID, now) {
+            ids = append(ids, id)
+        }
+    }
+    sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+    for _, id := range ids {
+        r := s.reminders[id]
+        task := s.tasks[r.TaskID]
+        firedAt := now
+        r.FiredAt = &firedAt
+        r.firedFor = r.TriggerTime(task.DueDate)
+        s.reminders[id] = r
+        s.createNotification(task.UserID, models.NotificationReminder, models.TaskParams(&task), now)
+    }
+    return int(s.nextNotificationID - before), nil
+}
+
+// withTrigger возвращает копию напоминания с вычисленным TriggerAt.
+func (s *Store) withTrigger(r reminder) models.Reminder {
+    result := r.Reminder
+    result.TriggerAt = r.TriggerTime(s.tasks[r.TaskID].DueDate)
+    return result
+}
+
+func (s *Store) CreateReminder(ctx context.Context, r *models.Reminder) (*models.Reminder, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    task, ok := s.tasks[r.TaskID]
+    if !ok || task.UserID != r.UserID {
+        return nil, models.ErrNotFound
+    }
+    result := s.insertReminder(r.TaskID, r.UserID, r.RemindAt, r.MinutesBefore)
+    return &result, nil
+}
+
+// insertReminder копирует remindAt и minutesBefore, чтобы вызывающий код не
+// мог изменить сохранённое напоминание.
+func (s *Store) insertReminder(taskID, userID uint, remindAt *time.Time, minutesBefore *int) models.Reminder {
+    remindAt, minutesBefore = copyTime(remindAt), copyInt(minutesBefore)
+    s.nextReminderID++
+    created := reminder{Reminder: models.Reminder{
+        ID:            s.nextReminderID,
+        TaskID:        taskID,
+        UserID:        userID,
+        RemindAt:      remindAt,
+        MinutesBefore: minutesBefore,
+        CreatedAt:     s.now(),
+    }}
+    s.reminders[created.ID] = created
+    return s.withTrigger(created)
+}
+
+func (s *Store) GetTaskReminders(ctx context.Context, taskID, userID uint) ([]models.Reminder, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    reminders := []models.Reminder{}
+    for _, r := range s.reminders {
+        if _, ok := s.tasks[r.TaskID]; ok && r.TaskID == taskID && r.UserID == userID {
+            reminders = append(reminders, s.withTrigger(r))
+        }
+    }
+    sort.Slice(reminders, func(i, j int) bool {
+        if !reminders[i].TriggerAt.Equal(reminders[j].TriggerAt) {
+            return reminders[i].TriggerAt.Before(reminders[j].TriggerAt)
+        }
+        return reminders[i].ID < reminders[j].ID
+    })
+    return reminders, nil
+}
+
+func (s *Store) UpdateReminder(ctx context.Context, r *models.Reminder) (*models.Reminder, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    existing, ok := s.reminders[r.ID]
+    if _, live := s.tasks[r.TaskID]; !ok || !live || existing.TaskID != r.TaskID || existing.UserID != r.UserID {
+        return nil, models.ErrNotFound
+    }
+    existing.RemindAt = copyTime(r.RemindAt)
+    existing.MinutesBefore = copyInt(r.MinutesBefore)
+    existing.FiredAt = nil
+    existing.firedFor = time.Time{}
+    s.reminders[r.ID] = existing
+
+    result := s.withTrigger(existing)
+    return &result, nil
+}
+
+func (s *Store) DeleteReminder(ctx context.Context, id, taskID, userID uint) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    r, ok := s.reminders[id]
+    if _, live := s.tasks[taskID]; !ok || !live || r.TaskID != taskID || r.UserID != userID {
+        return models.ErrNotFound
+    }
+    delete(s.reminders, id)
+    return nil
+}
+
+func (s *Store) SnoozeNotification(ctx context.Context, notificationID, userID uint, until time.Time) (*models.Reminder, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    n, ok := s.notifications[notificationID]
+    if !ok || n.UserID != userID {
+        return nil, models.ErrNotFound
+    }
+    if _, ok := s.trashedTasks[n.TaskID]; ok {
+        return nil, models.ErrNotFound
+    }
+    if _, ok := s.tasks[n.TaskID]; !ok {
+        return nil, models.Invalid("task_id", "referenced record does not exist")
+    }
+    n.Read = true
+    s.saveNotification(n)
+
+    result := s.insertReminder(n.TaskID, userID, &until, nil)
+    return &result, nil
+}
+
+func copyTime(t *time.Time) *time.Time {
+    if t == nil {
+        return nil
+    }
+    v := t.UTC()
+    return &v
+}
+
+func copyInt(n *int) *int {
+    if n == nil {
+        return nil
+    }
+    v := *n
+    return &v
+}
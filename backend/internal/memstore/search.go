@@ -0,0 +1,65 @@
+package memstore
+
+import (
+    "context"
+    "sort"
+    "todo-app/internal/models"
+)
+
+// Search ищет по префиксам слов без стемминга; ранжирование приближённо
+// повторяет веса A (заголовок) и B (описание) из PostgresStore.
+func (s *Store) Search(ctx context.Context, userID uint, q string, limit int) (*models.SearchResults, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    results := &models.SearchResults{Tasks: []models.TaskHit{}, Categories: []models.CategoryHit{}}
+    terms := models.SearchTerms(q)
+    if len(terms) == 0 {
+        return results, nil
+    }
+
+    for _, task := range s.tasks {
+        if task.UserID != userID || !models.MatchesAllTerms(terms, task.Title, task.Description) {
+            continue
+        }
+        title, titleMatches := models.HighlightTerms(task.Title, terms)
+        snippet, descriptionMatches := models.HighlightTerms(task.Description, terms)
+        results.Tasks = append(results.Tasks, models.TaskHit{
+            Task:           s.withCategory(task),
+            Rank:           float64(titleMatches) + 0.4*float64(descriptionMatches),
+            TitleHighlight: title,
+            Snippet:        snippet,
+        })
+    }
+    sort.Slice(results.Tasks, func(i, j int) bool {
+        if results.Tasks[i].Rank != results.Tasks[j].Rank {
+            return results.Tasks[i].Rank > results.Tasks[j].Rank
+        }
+        return results.Tasks[i].Task.ID > results.Tasks[j].Task.ID
+    })
+    if len(results.Tasks) > limit {
+        results.Tasks = results.Tasks[:limit]
+    }
+
+    for _, category := range s.categories {
+        if category.UserID != userID || !models.MatchesAllTerms(terms, category.Name) {
+            continue
+        }
+        name, matches := models.HighlightTerms(category.Name, terms)
+        results.Categories = append(results.Categories, models.CategoryHit{
+            Category:      category,
+            Rank:          float64(matches),
+            NameHighlight: name,
+        })
+    }
+    sort.Slice(results.Categories, func(i, j int) bool {
+        if results.Categories[i].Rank != results.Categories[j].Rank {
+            return results.Categories[i].Rank > results.Categories[j].Rank
+        }
+        return results.Categories[i].Category.ID > results.Categories[j].Category.ID
+    })
+    if len(results.Categories) > limit {
+        results.Categories = results.Categories[:limit]
+    }
+    return results, nil
+}
@@ -0,0 +1,106 @@
+package memstore
+
+import (
+    "context"
+    "time"
+    "todo-app/internal/models"
+)
+
+type refreshToken struct {
+    sessionID uint
+    expiresAt time.Time
+    used      bool
+}
+
+func (s *Store) CreateSession(ctx context.Context, userID uint, userAgent, refreshHash string, expiresAt time.Time) (*models.Session, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    now := s.now()
+    s.nextSessionID++
+    session := models.Session{
+        ID:         s.nextSessionID,
+        UserID:     userID,
+        UserAgent:  userAgent,
+        CreatedAt:  now,
+        LastUsedAt: now,
+    }
+    s.sessions[session.ID] = session
+    s.refreshTokens[refreshHash] = refreshToken{sessionID: session.ID, expiresAt: expiresAt}
+    return &session, nil
+}
+
+func (s *Store) GetSession(ctx context.Context, id uint) (*models.Session, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    session, ok := s.sessions[id]
+    if !ok {
+        return nil, models.ErrNotFound
+    }
+    return &session, nil
+}
+
+func (s *Store) RotateRefreshToken(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (*models.Session, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    token, ok := s.refreshTokens[oldHash]
+    if !ok {
+        return nil, models.ErrNotFound
+    }
+    session := s.sessions[token.sessionID]
+    now := s.now()
+
+    if session.RevokedAt != nil {
+        return nil, models.ErrSessionRevoked
+    }
+    if token.used {
+        session.RevokedAt = &now
+        s.sessions[session.ID] = session
+        return nil, models.ErrRefreshTokenReused
+    }
+    if now.After(token.expiresAt) {
+        return nil, models.ErrRefreshTokenExpired
+    }
+
+    token.used = true
+    s.refreshTokens[oldHash] = token
+    s.refreshTokens[newHash] = refreshToken{sessionID: session.ID, expiresAt: expiresAt}
+    session.LastUsedAt = now
+    s.sessions[session.ID] = session
+    return &session, nil
+}
+
+func (s *Store) RevokeSession(ctx context.Context, id, userID uint) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    session, ok := s.sessions[id]
+    if !ok || session.UserID != userID || session.RevokedAt != nil {
+        return models.ErrNotFound
+    }
+    now := s.now()
+    session.RevokedAt = &now
+    s.sessions[id] = session
+    return nil
+}
+
+func (s *Store) RevokeUserSessions(ctx context.Context, userID uint) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.revokeUserSessions(userID)
+    return nil
+}
+
+// revokeUserSessions вызывается под блокировкой.
+func (s *Store) revokeUserSessions(userID uint) {
+    now := s.now()
+    for id, session := range s.sessions {
+        if session.UserID == userID && session.RevokedAt == nil {
+            session.RevokedAt = &now
+            s.sessions[id] = session
+        }
+    }
+}
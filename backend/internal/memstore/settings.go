@@ -0,0 +1,69 @@
+package memstore
+
+import (
+    "context"
+    "time"
+    "todo-app/internal/models"
+)
+
+// notificationSettings возвращает копию настроек пользователя или значения
+// по умолчанию. Вызывается под блокировкой.
+func (s *Store) notificationSettings(userID uint) models.NotificationSettings {
+    settings, ok := s.settings[userID]
+    if !ok {
+        return *models.DefaultNotificationSettings()
+    }
+    return copySettings(settings)
+}
+
+// quiet сообщает, идут ли у пользователя тихие часы. Вызывается под
+// блокировкой.
+func (s *Store) quiet(userID uint, now time.Time) bool {
+    settings := s.notificationSettings(userID)
+    return settings.Quiet(now, s.location(userID))
+}
+
+func copySettings(settings models.NotificationSettings) models.NotificationSettings {
+    types := make(map[models.NotificationType]bool, len(settings.Types))
+    for kind, enabled := range settings.Types {
+        types[kind] = enabled
+    }
+    settings.Types = types
+    if settings.QuietHours != nil {
+        quiet := *settings.QuietHours
+        settings.QuietHours = &quiet
+    }
+    return settings
+}
+
+func (s *Store) GetNotificationSettings(ctx context.Context, userID uint) (*models.NotificationSettings, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    if _, ok := s.users[userID]; !ok {
+        return nil, models.ErrNotFound
+    }
+    settings := s.notificationSettings(userID)
+    return &settings, nil
+}
+
+func (s *Store) UpdateNotificationSettings(ctx context.Context, userID uint, settings *models.NotificationSettings) (*models.NotificationSettings, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if _, ok := s.users[userID]; !ok {
+        return nil, models.Invalid("user_id", "referenced record does not exist")
+    }
+    // Как и в таблице, хранятся только выключенные типы из известных
+    stored := *models.DefaultNotificationSettings()
+    for _, kind := range models.ConfigurableTypes {
+        stored.Types[kind] = settings.TypeEnabled(kind)
+    }
+    stored.Channels = settings.Channels
+    stored.QuietHours = settings.QuietHours
+    stored.EmailDelivery = settings.EmailDelivery
+    s.settings[userID] = copySettings(stored)
+
+    result := s.notificationSettings(userID)
+    return &result, nil
+}
@@ -0,0 +1,164 @@
+package memstore
+
+import (
+    "context"
+    "sort"
+    "todo-app/internal/models"
+)
+
+type changeKey struct {
+    entity string
+    id     uint
+}
+
+// recordChange повторяет триггеры миграций 0017_changes и 0018_trash: запись
+// получает новый номер изменения, удалённая или перенесённая в корзину
+// становится надгробием. Вызывается под блокировкой.
+func (s *Store) recordChange(userID uint, entity string, id uint, deleted bool) {
+    s.nextChangeID++
+    s.changes[changeKey{entity, id}] = models.Change{
+        ID:       s.nextChangeID,
+        UserID:   userID,
+        Entity:   entity,
+        EntityID: id,
+        Deleted:  deleted,
+    }
+}
+
+// saveNotification сохраняет изменённое уведомление. Вызывается под
+// блокировкой.
+func (s *Store) saveNotification(n models.Notification) {
+    s.notifications[n.ID] = n
+    if n.InApp {
+        s.recordChange(n.UserID, models.EntityNotification, n.ID, false)
+    }
+}
+
+func (s *Store) GetChanges(ctx context.Context, userID uint, since string, limit int) (*models.ChangeSet, error) {
+    after, err := models.DecodeSyncToken(since)
+    if err != nil {
+        return nil, err
+    }
+
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    var changes []models.Change
+    for _, c := range s.changes {
+        if c.UserID == userID && c.ID > after {
+            changes = append(changes, c)
+        }
+    }
+    sort.Slice(changes, func(i, j int) bool { return changes[i].ID < changes[j].ID })
+    if len(changes) > limit+1 {
+        changes = changes[:limit+1]
+    }
+    set := models.NewChangeSet(after, changes, limit)
+
+    var tasks []models.Task
+    for _, id := range set.Changed(models.EntityTask) {
+        if task, ok := s.tasks[id]; ok && task.UserID == userID {
+            tasks = append(tasks, s.withCategory(task))
+        }
+    }
+    var categories []models.Category
+    for _, id := range set.Changed(models.EntityCategory) {
+        if category, ok := s.categories[id]; ok && category.UserID == userID {
+            categories = append(categories, category)
+        }
+    }
+    var notifications []models.Notification
+    for _, id := range set.Changed(models.EntityNotification) {
+        if n, ok := s.notifications[id]; ok && n.UserID == userID && n.InApp {
+            notifications = append(notifications, n)
+        }
+    }
+    set.Fill(tasks, categories, notifications)
+    return set, nil
+}
+
+func (s *Store) ResolveClientID(ctx context.Context, userID uint, entity, clientID string) (uint, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    if id := s.clientIDOwner(userID, entity, clientID); id != 0 {
+        return id, nil
+    }
+    return 0, models.ErrNotFound
+}
+
+func (s *Store) TrashedTaskByClientID(ctx context.Context, userID uint, clientID string) (*models.Task, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    for _, t := range s.trashedTasks {
+        if t.UserID == userID && clientID != "" && t.ClientID == clientID {
+            result := s.withCategory(t)
+            return &result, nil
+        }
+    }
+    return nil, models.ErrNotFound
+}
+
+func (s *Store) TrashedCategoryByClientID(ctx context.Context, userID uint, clientID string) (*models.Category, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    for _, c := range s.trashedCategories {
+        if c.UserID == userID && clientID != "" && c.ClientID == clientID {
+            result := c
+            return &result, nil
+        }
+    }
+    return nil, models.ErrNotFound
+}
+
+// clientIDOwner находит запись вне корзины по client_id. Вызывается под
+// блокировкой.
+func (s *Store) clientIDOwner(userID uint, entity, clientID string) uint {
+    if clientID == "" {
+        return 0
+    }
+    switch entity {
+    case models.EntityTask:
+        for _, t := range s.tasks {
+            if t.UserID == userID && t.ClientID == clientID {
+                return t.ID
+            }
+        }
+    case models.EntityCategory:
+        for _, c := range s.categories {
+            if c.UserID == userID && c.ClientID == clientID {
+                return c.ID
+            }
+        }
+    }
+    return 0
+}
+
+// clientIDTaken заменяет уникальные индексы tasks_client_id_idx и
+// categories_client_id_idx: они действуют и на записи в корзине.
+// Вызывается под блокировкой.
+func (s *Store) clientIDTaken(userID uint, entity, clientID string) bool {
+    if s.clientIDOwner(userID, entity, clientID) != 0 {
+        return true
+    }
+    if clientID == "" {
+        return false
+    }
+    switch entity {
+    case models.EntityTask:
+        for _, t := range s.trashedTasks {
+            if t.UserID == userID && t.ClientID == clientID {
+                return true
+            }
+        }
+    case models.EntityCategory:
+        for _, c := range s.trashedCategories {
+            if c.UserID == userID && c.ClientID == clientID {
+                return true
+            }
+        }
+    }
+    return false
+}
@@ -0,0 +1,160 @@
+package memstore
+
+import (
+    "context"
+    "sort"
+    "time"
+    "todo-app/internal/models"
+)
+
+func (s *Store) ListTrash(ctx context.Context, userID uint) (*models.Trash, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    trash := &models.Trash{Tasks: []models.Task{}, Categories: []models.Category{}}
+    for _, t := range s.trashedTasks {
+        if t.UserID == userID {
+            trash.Tasks = append(trash.Tasks, s.withCategory(t))
+        }
+    }
+    sort.Slice(trash.Tasks, func(i, j int) bool {
+        a, b := trash.Tasks[i], trash.Tasks[j]
+        if !a.DeletedAt.Equal(*b.DeletedAt) {
+            return a.DeletedAt.After(*b.DeletedAt)
+        }
+        return a.ID < b.ID
+    })
+    for _, c := range s.trashedCategories {
+        if c.UserID == userID {
+            trash.Categories = append(trash.Categories, c)
+        }
+    }
+    sort.Slice(trash.Categories, func(i, j int) bool {
+        a, b := trash.Categories[i], trash.Categories[j]
+        if !a.DeletedAt.Equal(*b.DeletedAt) {
+            return a.DeletedAt.After(*b.DeletedAt)
+        }
+        return a.ID < b.ID
+    })
+    return trash, nil
+}
+
+func (s *Store) RestoreTask(ctx context.Context, id, userID uint) (*models.Task, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.actor = models.ActorFrom(ctx)
+
+    task, ok := s.trashedTasks[id]
+    if !ok || task.UserID != userID {
+        return nil, models.ErrNotFound
+    }
+    detach := false
+    if task.ParentID != nil {
+        _, detach = s.trashedTasks[*task.ParentID]
+    }
+    s.restoreTask(id, *task.DeletedAt, s.now(), detach)
+
+    result := s.withCategory(s.tasks[id])
+    return &result, nil
+}
+
+// restoreTask возвращает из корзины задачу и подзадачи, удалённые вместе с
+// ней в момент deletedAt; detach поднимает задачу на верхний уровень.
+// Вызывается под блокировкой.
+func (s *Store) restoreTask(id uint, deletedAt, now time.Time, detach bool) {
+    old := s.trashedTasks[id]
+    task := old
+    delete(s.trashedTasks, id)
+    if detach {
+        task.ParentID = nil
+        task.Position = 0
+    }
+    task.DeletedAt = nil
+    task.UpdatedAt = now
+    task.Version++
+    s.tasks[id] = task
+    s.taskEvent(models.EventTaskRestored, task)
+    s.recordTaskHistory(models.TaskActionRestored, task, models.DiffTask(&old, &task))
+    s.recordChange(task.UserID, models.EntityTask, id, false)
+    for childID, child := range s.trashedTasks {
+        if child.ParentID != nil && *child.ParentID == id && child.DeletedAt.Equal(deletedAt) {
+            s.restoreTask(childID, deletedAt, now, false)
+        }
+    }
+}
+
+func (s *Store) RestoreCategory(ctx context.Context, id, userID uint) (*models.Category, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.actor = models.ActorFrom(ctx)
+
+    category, ok := s.trashedCategories[id]
+    if !ok || category.UserID != userID {
+        return nil, models.ErrNotFound
+    }
+    delete(s.trashedCategories, id)
+    category.DeletedAt = nil
+    category.Version++
+    s.categories[id] = category
+    s.categoryEvent(models.EventCategoryRestored, category)
+    s.recordChange(userID, models.EntityCategory, id, false)
+
+    now := s.now()
+    for taskID, categoryID := range s.trashedCategoryIDs {
+        if categoryID != id {
+            continue
+        }
+        delete(s.trashedCategoryIDs, taskID)
+        if task, ok := s.tasks[taskID]; ok && task.CategoryID == nil {
+            task.CategoryID = &id
+            task.UpdatedAt = now
+            s.saveTask(task)
+        } else if task, ok := s.trashedTasks[taskID]; ok && task.CategoryID == nil {
+            task.CategoryID = &id
+            task.UpdatedAt = now
+            s.saveTrashedTask(task)
+        }
+    }
+    return &category, nil
+}
+
+func (s *Store) PurgeTrash(ctx context.Context, before time.Time) (int64, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.actor = models.ActorFrom(ctx)
+
+    var expired []uint
+    for id, t := range s.trashedTasks {
+        if t.DeletedAt.Before(before) {
+            expired = append(expired, id)
+        }
+    }
+    purged := int64(len(expired))
+    for _, id := range expired {
+        if _, ok := s.trashedTasks[id]; ok {
+            s.deleteTask(id)
+        }
+    }
+
+    for id, c := range s.trashedCategories {
+        if !c.DeletedAt.Before(before) {
+            continue
+        }
+        delete(s.trashedCategories, id)
+        s.recordChange(c.UserID, models.EntityCategory, id, true)
+        purged++
+        // ON DELETE SET NULL для tasks.trashed_category_id
+        for taskID, categoryID := range s.trashedCategoryIDs {
+            if categoryID != id {
+                continue
+            }
+            delete(s.trashedCategoryIDs, taskID)
+            if task, ok := s.tasks[taskID]; ok {
+                s.saveTask(task)
+            } else if task, ok := s.trashedTasks[taskID]; ok {
+                s.saveTrashedTask(task)
+            }
+        }
+    }
+    return purged, nil
+}
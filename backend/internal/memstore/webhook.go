@@ -0,0 +1,242 @@
+package memstore
+
+import (
+    "context"
+    "encoding/json"
+    "sort"
+    "time"
+    "todo-app/internal/models"
+)
+
+// queueWebhooks повторяет функцию queue_webhooks. Вызывается под
+// блокировкой.
+func (s *Store) queueWebhooks(userID uint, event string, data json.RawMessage) {
+    now := s.now()
+    for _, w := range s.webhooks {
+        if w.UserID == userID && w.Active && w.Subscribed(event) {
+            s.queueWebhookDelivery(w.ID, event, data, now)
+        }
+    }
+}
+
+func (s *Store) queueWebhookDelivery(webhookID uint, event string, data json.RawMessage, now time.Time) models.WebhookDelivery {
+    s.nextWebhookDeliveryID++
+    d := models.WebhookDelivery{
+        ID:            s.nextWebhookDeliveryID,
+        WebhookID:     webhookID,
+        Event:         event,
+        Data:          data,
+        Status:        models.DeliveryPending,
+        NextAttemptAt: now,
+        CreatedAt:     now,
+    }
+    s.webhookQueue[d.ID] = d
+    return d
+}
+
+func copyWebhook(w models.Webhook) models.Webhook {
+    w.Events = append([]string(nil), w.Events...)
+    return w
+}
+
+func copyWebhookDelivery(d models.WebhookDelivery) models.WebhookDelivery {
+    d.Data = append(json.RawMessage(nil), d.Data...)
+    d.ResponseStatus = copyInt(d.ResponseStatus)
+    d.DeliveredAt = copyTime(d.DeliveredAt)
+    return d
+}
+
+func (s *Store) CreateWebhook(ctx context.Context, webhook *models.Webhook) (*models.Webhook, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if _, ok := s.users[webhook.UserID]; !ok {
+        return nil, models.Invalid("user_id", "referenced record does not exist")
+    }
+    now := s.now()
+    s.nextWebhookID++
+    created := copyWebhook(*webhook)
+    created.ID = s.nextWebhookID
+    created.CreatedAt = now
+    created.UpdatedAt = now
+    s.webhooks[created.ID] = created
+
+    result := copyWebhook(created)
+    return &result, nil
+}
+
+func (s *Store) GetWebhook(ctx context.Context, id, userID uint) (*models.Webhook, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    w, ok := s.webhooks[id]
+    if !ok || w.UserID != userID {
+        return nil, models.ErrNotFound
+    }
+    result := copyWebhook(w)
+    return &result, nil
+}
+
+func (s *Store) GetUserWebhooks(ctx context.Context, userID uint) ([]models.Webhook, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    webhooks := []models.Webhook{}
+    for _, w := range s.webhooks {
+        if w.UserID == userID {
+            webhooks = append(webhooks, copyWebhook(w))
+        }
+    }
+    sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].ID < webhooks[j].ID })
+    return webhooks, nil
+}
+
+func (s *Store) UpdateWebhook(ctx context.Context, webhook *models.Webhook) (*models.Webhook, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    existing, ok := s.webhooks[webhook.ID]
+    if !ok || existing.UserID != webhook.UserID {
+        return nil, models.ErrNotFound
+    }
+    existing.URL = webhook.URL
+    existing.Events = append([]string(nil), webhook.Events...)
+    existing.Active = webhook.Active
+    if webhook.Secret != "" {
+        existing.Secret = webhook.Secret
+    }
+    existing.UpdatedAt = s.now()
+    s.webhooks[existing.ID] = existing
+
+    result := copyWebhook(existing)
+    return &result, nil
+}
+
+func (s *Store) DeleteWebhook(ctx context.Context, id, userID uint) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    w, ok := s.webhooks[id]
+    if !ok || w.UserID != userID {
+        return models.ErrNotFound
+    }
+    delete(s.webhooks, id)
+    for dID, d := range s.webhookQueue {
+        if d.WebhookID == id {
+            delete(s.webhookQueue, dID)
+        }
+    }
+    return nil
+}
+
+func (s *Store) GetWebhookDeliveries(ctx context.Context, webhookID, userID uint, limit int) ([]models.WebhookDelivery, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    w, ok := s.webhooks[webhookID]
+    if !ok || w.UserID != userID {
+        return nil, models.ErrNotFound
+    }
+
+    deliveries := []models.WebhookDelivery{}
+    for _, d := range s.webhookQueue {
+        if d.WebhookID == webhookID {
+            deliveries = append(deliveries, copyWebhookDelivery(d))
+        }
+    }
+    sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].ID > deliveries[j].ID })
+    if len(deliveries) > limit {
+        deliveries = deliveries[:limit]
+    }
+    return deliveries, nil
+}
+
+func (s *Store) QueueWebhookTest(ctx context.Context, webhookID, userID uint) (*models.WebhookDelivery, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    w, ok := s.webhooks[webhookID]
+    if !ok || w.UserID != userID {
+        return nil, models.ErrNotFound
+    }
+    data, _ := json.Marshal(map[string]uint{"webhook_id": webhookID})
+    d := copyWebhookDelivery(s.queueWebhookDelivery(webhookID, models.EventWebhookTest, data, s.now()))
+    return &d, nil
+}
+
+func (s *Store) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.PendingWebhookDelivery, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    now := s.now()
+    var due []models.WebhookDelivery
+    for _, d := range s.webhookQueue {
+        if d.Status == models.DeliveryPending && !d.NextAttemptAt.After(now) && s.webhooks[d.WebhookID].Active {
+            due = append(due, d)
+        }
+    }
+    sort.Slice(due, func(i, j int) bool {
+        if !due[i].NextAttemptAt.Equal(due[j].NextAttemptAt) {
+            return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+        }
+        return due[i].ID < due[j].ID
+    })
+    if len(due) > limit {
+        due = due[:limit]
+    }
+    sort.Slice(due, func(i, j int) bool { return due[i].ID < due[j].ID })
+
+    var claimed []models.PendingWebhookDelivery
+    for _, d := range due {
+        d.Attempts++
+        d.NextAttemptAt = now.Add(lease)
+        s.webhookQueue[d.ID] = d
+
+        w := s.webhooks[d.WebhookID]
+        claimed = append(claimed, models.PendingWebhookDelivery{
+            WebhookDelivery: copyWebhookDelivery(d),
+            URL:             w.URL,
+            Secret:          w.Secret,
+        })
+    }
+    return claimed, nil
+}
+
+func (s *Store) CompleteWebhookDelivery(ctx context.Context, id uint, result models.WebhookResult) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    d, ok := s.webhookQueue[id]
+    if !ok {
+        return models.ErrNotFound
+    }
+    d.Status = result.Status
+    d.LastError = result.Error
+    d.ResponseStatus = nil
+    if result.ResponseStatus != 0 {
+        d.ResponseStatus = copyInt(&result.ResponseStatus)
+    }
+    if result.RetryAt != nil {
+        d.NextAttemptAt = result.RetryAt.UTC()
+    }
+    if result.Status == models.DeliverySent {
+        deliveredAt := s.now()
+        d.DeliveredAt = &deliveredAt
+    }
+    s.webhookQueue[id] = d
+    return nil
+}
+
+func (s *Store) PurgeWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    var purged int64
+    for id, d := range s.webhookQueue {
+        if d.CreatedAt.Before(before) && d.Status != models.DeliveryPending {
+            delete(s.webhookQueue, id)
+            purged++
+        }
+    }
+    return purged, nil
+}
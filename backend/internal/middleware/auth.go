@@ -2,33 +2,100 @@ package middleware
 
 import (
     "context"
+    "errors"
     "net/http"
     "strings"
     "github.com/golang-jwt/jwt/v5"
+    "todo-app/internal/apierror"
+    "todo-app/internal/models"
 )
 
-func AuthMiddleware(jwtSecret []byte) func(http.Handler) http.Handler {
+type claimsKey struct{}
+
+// WithClaims кладёт в контекст утверждения проверенного токена.
+func WithClaims(ctx context.Context, claims jwt.MapClaims) context.Context {
+    return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// Claims возвращает утверждения токена, проверенного AuthMiddleware; ok
+// ложно, если запрос через него не прошёл.
+func Claims(ctx context.Context) (jwt.MapClaims, bool) {
+    claims, ok := ctx.Value(claimsKey{}).(jwt.MapClaims)
+    return claims, ok
+}
+
+func AuthMiddleware(jwtSecret []byte, sessions models.SessionStore) func(http.Handler) http.Handler {
     return func(next http.Handler) http.Handler {
         return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
             authHeader := r.Header.Get("Authorization")
             if authHeader == "" {
-                http.Error(w, "Authorization header required", http.StatusUnauthorized)
+                apierror.Unauthorized(w, r, "Authorization header required")
                 return
             }
 
             tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
             token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
                 return jwtSecret, nil
-            })
+            }, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
             if err != nil || !token.Valid {
-                http.Error(w, "Invalid token", http.StatusUnauthorized)
+                apierror.Unauthorized(w, r, "Invalid token")
                 return
             }
 
             claims := token.Claims.(jwt.MapClaims)
-            ctx := context.WithValue(r.Context(), "claims", claims)
+
+            // Токены без сессии (выданные до появления refresh-токенов) отозвать
+            // нельзя, поэтому они не принимаются.
+            sid, ok := claims["sid"].(float64)
+            if !ok {
+                apierror.Unauthorized(w, r, "Invalid token")
+                return
+            }
+            // Сбой базы не должен разлогинивать клиентов: 401 только для
+            // удалённой или отозванной сессии
+            session, err := sessions.GetSession(r.Context(), uint(sid))
+            if err != nil && !errors.Is(err, models.ErrNotFound) {
+                apierror.Internal(w, r, err, "Could not check session")
+                return
+            }
+            if err != nil || session.RevokedAt != nil {
+                apierror.Unauthorized(w, r, "Session revoked")
+                return
+            }
+            if userID, _ := claims["user_id"].(float64); uint(userID) != session.UserID {
+                apierror.Unauthorized(w, r, "Invalid token")
+                return
+            }
+
+            ctx := WithClaims(r.Context(), claims)
+            ctx = models.WithActor(ctx, models.Actor{UserID: session.UserID, Source: models.SourceAPI})
             next.ServeHTTP(w, r.WithContext(ctx))
         })
     }
-} 
\ No newline at end of file
+}
+
+// RequireVerified пропускает только пользователей с подтверждённым адресом.
+// Ставится после AuthMiddleware.
+func RequireVerified(users models.UserStore) func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            claims, ok := Claims(r.Context())
+            if !ok {
+                apierror.Unauthorized(w, r, "Authorization header required")
+                return
+            }
+            userID, _ := claims["user_id"].(float64)
+            user, err := users.GetUserByID(r.Context(), uint(userID))
+            if err != nil {
+                apierror.FromError(w, r, err, "User not found", "Could not get user")
+                return
+            }
+            if !user.Verified() {
+                apierror.Write(w, r, http.StatusForbidden, apierror.CodeEmailNotVerified, "Email address is not verified", nil)
+                return
+            }
+            next.ServeHTTP(w, r)
+        })
+    }
+}
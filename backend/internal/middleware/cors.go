@@ -0,0 +1,28 @@
+package middleware
+
+import (
+    "net/http"
+    "todo-app/internal/config"
+)
+
+// CORS отражает Origin запроса, только если он есть в списке разрешённых.
+func CORS(cfg config.CORSConfig) func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            w.Header().Add("Vary", "Origin")
+            if origin := r.Header.Get("Origin"); origin != "" && cfg.AllowsOrigin(origin) {
+                w.Header().Set("Access-Control-Allow-Origin", origin)
+                w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+                w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Accept-Language, X-Request-ID, Last-Event-ID, If-Match, If-None-Match")
+                w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, ETag")
+                w.Header().Set("Access-Control-Allow-Credentials", "true")
+            }
+
+            if r.Method == "OPTIONS" {
+                w.WriteHeader(http.StatusOK)
+                return
+            }
+            next.ServeHTTP(w, r)
+        })
+    }
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+    "net/http"
+    "todo-app/internal/requestid"
+)
+
+// RequestID берёт идентификатор из заголовка X-Request-ID или создаёт новый
+// и возвращает его в ответе.
+func RequestID(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        id := r.Header.Get(requestid.Header)
+        if !requestid.Valid(id) {
+            id = requestid.New()
+        }
+        w.Header().Set(requestid.Header, id)
+        next.ServeHTTP(w, r.WithContext(requestid.WithID(r.Context(), id)))
+    })
+}
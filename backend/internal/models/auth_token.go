@@ -0,0 +1,127 @@
+package models
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "time"
+)
+
+// AuthTokenPurpose — назначение одноразового токена из письма.
+type AuthTokenPurpose string
+
+const (
+    TokenVerifyEmail   AuthTokenPurpose = "verify_email"
+    TokenResetPassword AuthTokenPurpose = "reset_password"
+)
+
+// CreateAuthToken сохраняет хеш нового токена; выданные раньше токены того же
+// назначения перестают действовать.
+func (s *PostgresStore) CreateAuthToken(ctx context.Context, userID uint, purpose AuthTokenPurpose, hash string, expiresAt time.Time) error {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    if _, err := tx.ExecContext(ctx, "DELETE FROM auth_tokens WHERE user_id = $1 AND purpose = $2", userID, purpose); err != nil {
+        return err
+    }
+    _, err = tx.ExecContext(ctx,
+        "INSERT INTO auth_tokens (user_id, purpose, token_hash, expires_at) VALUES ($1, $2, $3, $4)",
+        userID, purpose, hash, expiresAt,
+    )
+    if err != nil {
+        return dbError(err)
+    }
+    return tx.Commit()
+}
+
+// consumeAuthToken удаляет действующий токен и возвращает его владельца.
+// Удаление атомарно, поэтому из двух одновременных запросов с одним токеном
+// успешен только один.
+func consumeAuthToken(ctx context.Context, tx *sql.Tx, purpose AuthTokenPurpose, hash string) (uint, error) {
+    var userID uint
+    err := tx.QueryRowContext(ctx,
+        "DELETE FROM auth_tokens WHERE token_hash = $1 AND purpose = $2 AND expires_at > NOW() RETURNING user_id",
+        hash, purpose,
+    ).Scan(&userID)
+    if errors.Is(err, sql.ErrNoRows) {
+        return 0, ErrInvalidAuthToken
+    }
+    if err != nil {
+        return 0, err
+    }
+
+    // Просроченные и вытесненные токены того же назначения больше не нужны
+    if _, err := tx.ExecContext(ctx, "DELETE FROM auth_tokens WHERE user_id = $1 AND purpose = $2", userID, purpose); err != nil {
+        return 0, err
+    }
+    return userID, nil
+}
+
+// VerifyEmail отмечает адрес владельца токена подтверждённым.
+func (s *PostgresStore) VerifyEmail(ctx context.Context, hash string) (*User, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+
+    userID, err := consumeAuthToken(ctx, tx, TokenVerifyEmail, hash)
+    if err != nil {
+        return nil, err
+    }
+    _, err = tx.ExecContext(ctx,
+        "UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = $1",
+        userID,
+    )
+    if err != nil {
+        return nil, err
+    }
+
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+    return s.GetUserByID(ctx, userID)
+}
+
+// ResetPassword меняет пароль владельца токена и отзывает все его сессии.
+// Письмо со ссылкой дошло до владельца адреса, поэтому адрес заодно
+// считается подтверждённым.
+func (s *PostgresStore) ResetPassword(ctx context.Context, hash, password string) (*User, error) {
+    hashedPassword, err := HashPassword(password)
+    if err != nil {
+        return nil, err
+    }
+
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+
+    userID, err := consumeAuthToken(ctx, tx, TokenResetPassword, hash)
+    if err != nil {
+        return nil, err
+    }
+    _, err = tx.ExecContext(ctx,
+        "UPDATE users SET password = $1, email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = $2",
+        hashedPassword, userID,
+    )
+    if err != nil {
+        return nil, err
+    }
+    _, err = tx.ExecContext(ctx,
+        "UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL",
+        userID,
+    )
+    if err != nil {
+        return nil, err
+    }
+
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+    return s.GetUserByID(ctx, userID)
+}
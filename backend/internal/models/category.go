@@ -1,8 +1,8 @@
 package models
 
 import (
+    "context"
     "time"
-    "todo-app/internal/db"
 )
 
 type Category struct {
@@ -10,46 +10,62 @@ type Category struct {
     Name      string    `json:"name"`
     UserID    uint      `json:"user_id"`
     CreatedAt time.Time `json:"created_at"`
+    Version   int       `json:"version"`
+    ClientID  string    `json:"client_id,omitempty"`
+    // DeletedAt задан у категории в корзине.
+    DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
-func GetCategory(id, userID uint) (*Category, error) {
-    var category Category
-    err := db.DB.QueryRow(
-        `SELECT id, name, user_id, created_at 
-         FROM categories 
-         WHERE id = $1 AND user_id = $2`,
-        id, userID,
-    ).Scan(&category.ID, &category.Name, &category.UserID, &category.CreatedAt)
+const categoryColumns = "id, name, user_id, created_at, version, COALESCE(client_id, ''), deleted_at"
 
+func scanCategory(row rowScanner) (*Category, error) {
+    var category Category
+    err := row.Scan(&category.ID, &category.Name, &category.UserID, &category.CreatedAt, &category.Version, &category.ClientID, &category.DeletedAt)
     if err != nil {
         return nil, err
     }
     return &category, nil
 }
 
-func CreateCategory(name string, userID uint) (*Category, error) {
-    var category Category
-    err := db.DB.QueryRow(
-        `INSERT INTO categories (name, user_id, created_at) 
-         VALUES ($1, $2, NOW()) 
-         RETURNING id, name, user_id, created_at`,
-        name, userID,
-    ).Scan(&category.ID, &category.Name, &category.UserID, &category.CreatedAt)
+func (s *PostgresStore) GetCategory(ctx context.Context, id, userID uint) (*Category, error) {
+    category, err := scanCategory(s.db.QueryRowContext(ctx,
+        `SELECT `+categoryColumns+` 
+         FROM categories 
+         WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`,
+        id, userID,
+    ))
+    if err != nil {
+        return nil, notFound(err)
+    }
+    return category, nil
+}
 
+// CreateCategory создаёт категорию category.UserID; ClientID необязателен.
+func (s *PostgresStore) CreateCategory(ctx context.Context, category *Category) (*Category, error) {
+    created, err := scanCategory(s.db.QueryRowContext(ctx,
+        `INSERT INTO categories (name, user_id, client_id, created_at) 
+         VALUES ($1, $2, NULLIF($3, ''), NOW()) 
+         RETURNING `+categoryColumns,
+        category.Name, category.UserID, category.ClientID,
+    ))
     if err != nil {
-        return nil, err
+        return nil, dbError(err)
     }
-    return &category, nil
+    return created, nil
 }
 
-func GetUserCategories(userID uint) ([]Category, error) {
-    rows, err := db.DB.Query(
-        `SELECT id, name, user_id, created_at 
+func (s *PostgresStore) GetUserCategories(ctx context.Context, userID uint) ([]Category, error) {
+    return s.queryCategories(ctx,
+        `SELECT `+categoryColumns+` 
          FROM categories 
-         WHERE user_id = $1 
+         WHERE user_id = $1 AND deleted_at IS NULL 
          ORDER BY created_at DESC`,
         userID,
     )
+}
+
+func (s *PostgresStore) queryCategories(ctx context.Context, query string, args ...interface{}) ([]Category, error) {
+    rows, err := s.db.QueryContext(ctx, query, args...)
     if err != nil {
         return nil, err
     }
@@ -57,78 +73,29 @@ func GetUserCategories(userID uint) ([]Category, error) {
 
     var categories []Category
     for rows.Next() {
-        var category Category
-        err := rows.Scan(&category.ID, &category.Name, &category.UserID, &category.CreatedAt)
+        category, err := scanCategory(rows)
         if err != nil {
             return nil, err
         }
-        categories = append(categories, category)
+        categories = append(categories, *category)
     }
-    return categories, nil
+    return categories, rows.Err()
 }
 
-func DeleteCategory(id, userID uint) error {
-    result, err := db.DB.Exec(
-        "DELETE FROM categories WHERE id = $1 AND user_id = $2",
-        id, userID,
-    )
+// DeleteCategory переносит категорию пользователя в корзину; version, если
+// не ноль, — ожидаемая версия категории. Задачи остаются без категории, но
+// запоминают её в trashed_category_id, чтобы RestoreCategory их вернул.
+func (s *PostgresStore) DeleteCategory(ctx context.Context, id, userID uint, version int) error {
+    tx, err := s.beginTx(ctx)
     if err != nil {
         return err
     }
+    defer tx.Rollback()
 
-    rowsAffected, err := result.RowsAffected()
-    if err != nil {
-        return err
-    }
-
-    if rowsAffected == 0 {
-        return ErrNotFound
-    }
-
-    return nil
-}
-
-func GetTasksByCategory(categoryID, userID uint) ([]Task, error) {
-    rows, err := db.DB.Query(
-        `SELECT t.id, t.title, t.description, t.completed, t.user_id, t.category_id, t.due_date, t.priority, t.created_at, t.updated_at,
-                c.id, c.name, c.user_id, c.created_at
-         FROM tasks t
-         LEFT JOIN categories c ON t.category_id = c.id
-         WHERE t.category_id = $1 AND t.user_id = $2 
-         ORDER BY t.due_date ASC, t.priority DESC, t.created_at DESC`,
-        categoryID, userID,
-    )
-    if err != nil {
-        return nil, err
-    }
-    defer rows.Close()
-
-    var tasks []Task
-    for rows.Next() {
-        var task Task
-        var category Category
-        var categoryID *uint
-        err := rows.Scan(
-            &task.ID, &task.Title, &task.Description, &task.Completed, &task.UserID, &categoryID,
-            &task.DueDate, &task.Priority, &task.CreatedAt, &task.UpdatedAt,
-            &category.ID, &category.Name, &category.UserID, &category.CreatedAt,
-        )
-        if err != nil {
-            return nil, err
-        }
-        task.CategoryID = categoryID
-        if categoryID != nil {
-            task.Category = &category
-        }
-        tasks = append(tasks, task)
-    }
-    return tasks, nil
-}
-
-func UpdateTaskCategory(taskID, categoryID, userID uint) error {
-    result, err := db.DB.Exec(
-        "UPDATE tasks SET category_id = $1 WHERE id = $2 AND user_id = $3",
-        categoryID, taskID, userID,
+    result, err := tx.ExecContext(ctx,
+        `UPDATE categories SET deleted_at = NOW() 
+         WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL AND ($3 = 0 OR version = $3)`,
+        id, userID, version,
     )
     if err != nil {
         return err
@@ -140,8 +107,16 @@ func UpdateTaskCategory(taskID, categoryID, userID uint) error {
     }
 
     if rowsAffected == 0 {
-        return ErrNotFound
+        return versionConflict(ctx, tx, "categories", id, userID, version)
     }
 
-    return nil
-} 
\ No newline at end of file
+    _, err = tx.ExecContext(ctx,
+        `UPDATE tasks SET trashed_category_id = category_id, category_id = NULL, updated_at = NOW() 
+         WHERE category_id = $1`,
+        id,
+    )
+    if err != nil {
+        return err
+    }
+    return tx.Commit()
+}
@@ -0,0 +1,289 @@
+package models
+
+import (
+    "context"
+    "time"
+    "github.com/lib/pq"
+)
+
+// DeliveryChannel — внешний канал доставки уведомлений.
+type DeliveryChannel string
+
+const ChannelEmail DeliveryChannel = "email"
+
+type DeliveryStatus string
+
+const (
+    DeliveryPending DeliveryStatus = "pending"
+    DeliverySent    DeliveryStatus = "sent"
+    // DeliveryFailed — попытки исчерпаны или письмо не удалось собрать.
+    DeliveryFailed DeliveryStatus = "failed"
+    // DeliverySkipped — уведомление решено не отправлять по этому каналу.
+    DeliverySkipped DeliveryStatus = "skipped"
+)
+
+// Delivery — состояние доставки одного уведомления по одному каналу. Строку
+// ставит в очередь само хранилище при создании уведомления.
+type Delivery struct {
+    ID             uint            `json:"id"`
+    NotificationID uint            `json:"notification_id"`
+    Channel        DeliveryChannel `json:"channel"`
+    Status         DeliveryStatus  `json:"status"`
+    Attempts       int             `json:"attempts"`
+    LastError      string          `json:"last_error,omitempty"`
+    NextAttemptAt  time.Time       `json:"next_attempt_at"`
+    SentAt         *time.Time      `json:"sent_at,omitempty"`
+    CreatedAt      time.Time       `json:"created_at"`
+}
+
+// PendingDelivery — доставка, захваченная отправителем, вместе с данными
+// для письма.
+type PendingDelivery struct {
+    Delivery
+    Notification Notification
+    Recipient    User
+    Settings     NotificationSettings
+}
+
+// DeliveryResult — итог попытки. Статус DeliveryPending с RetryAt
+// откладывает следующую попытку.
+type DeliveryResult struct {
+    Status  DeliveryStatus
+    Error   string
+    RetryAt *time.Time
+}
+
+// Digest — ежедневная сводка пользователя за его локальную дату Day.
+// Просроченная задача попадает только в Overdue.
+type Digest struct {
+    User     User
+    Day      time.Time
+    Overdue  []Task
+    DueToday []Task
+}
+
+// DigestDay возвращает локальную дату now в loc как полночь UTC — так
+// драйвер читает колонку DATE.
+func DigestDay(now time.Time, loc *time.Location) time.Time {
+    y, m, d := now.In(loc).Date()
+    return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+const deliveryColumns = `d.id, d.notification_id, d.channel, d.status, d.attempts, d.last_error, d.next_attempt_at, d.sent_at, d.created_at`
+
+func scanDelivery(row rowScanner, extra ...interface{}) (*Delivery, error) {
+    var d Delivery
+    dest := []interface{}{
+        &d.ID, &d.NotificationID, &d.Channel, &d.Status, &d.Attempts, &d.LastError, &d.NextAttemptAt, &d.SentAt, &d.CreatedAt,
+    }
+    if err := row.Scan(append(dest, extra...)...); err != nil {
+        return nil, err
+    }
+    return &d, nil
+}
+
+// ClaimDeliveries забирает до limit доставок канала, время попытки которых
+// наступило, засчитывает им попытку и откладывает их на lease: если
+// отправитель не запишет результат, доставка вернётся в очередь. Реплики
+// не забирают одну строку дважды.
+func (s *PostgresStore) ClaimDeliveries(ctx context.Context, channel DeliveryChannel, limit int, lease time.Duration) ([]PendingDelivery, error) {
+    rows, err := s.db.QueryContext(ctx,
+        `WITH claimed AS (
+             UPDATE notification_deliveries
+             SET attempts = attempts + 1, next_attempt_at = NOW() + $3 * INTERVAL '1 millisecond'
+             WHERE id IN (
+                 SELECT id FROM notification_deliveries
+                 WHERE channel = $1 AND status = 'pending' AND next_attempt_at <= NOW()
+                 ORDER BY next_attempt_at, id
+                 LIMIT $2
+                 FOR UPDATE SKIP LOCKED
+             )
+             RETURNING *
+         )
+         SELECT `+deliveryColumns+`,
+             n.id, n.user_id, n.task_id, n.type, n.params, n.message, n.created_at, n.read, n.in_app,
+             u.id, u.email, u.name, u.timezone, u.locale, u.email_verified_at, `+settingsColumns+`
+         FROM claimed d
+         JOIN notifications n ON n.id = d.notification_id
+         JOIN users u ON u.id = n.user_id
+         `+settingsJoin+`
+         ORDER BY d.id`,
+        channel, limit, lease.Milliseconds(),
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var claimed []PendingDelivery
+    for rows.Next() {
+        var p PendingDelivery
+        var settings settingsRow
+        n, u := &p.Notification, &p.Recipient
+        extra := []interface{}{
+            &n.ID, &n.UserID, &n.TaskID, &n.Type, &n.Params, &n.Message, &n.CreatedAt, &n.Read, &n.InApp,
+            &u.ID, &u.Email, &u.Name, &u.Timezone, &u.Locale, &u.EmailVerifiedAt,
+        }
+        d, err := scanDelivery(rows, append(extra, settings.dest()...)...)
+        if err != nil {
+            return nil, err
+        }
+        p.Delivery = *d
+        p.Settings = *settings.result()
+        claimed = append(claimed, p)
+    }
+    return claimed, rows.Err()
+}
+
+// CompleteDelivery записывает итог попытки доставки id.
+func (s *PostgresStore) CompleteDelivery(ctx context.Context, id uint, result DeliveryResult) error {
+    res, err := s.db.ExecContext(ctx,
+        `UPDATE notification_deliveries
+         SET status = $2, last_error = $3, next_attempt_at = COALESCE($4, next_attempt_at),
+             sent_at = CASE WHEN $5 THEN NOW() ELSE sent_at END
+         WHERE id = $1`,
+        id, result.Status, result.Error, result.RetryAt, result.Status == DeliverySent,
+    )
+    if err != nil {
+        return err
+    }
+    rowsAffected, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if rowsAffected == 0 {
+        return ErrNotFound
+    }
+    return nil
+}
+
+// GetNotificationDeliveries возвращает доставки уведомления пользователя
+// userID; чужое или несуществующее уведомление — ErrNotFound.
+func (s *PostgresStore) GetNotificationDeliveries(ctx context.Context, notificationID, userID uint) ([]Delivery, error) {
+    var exists bool
+    err := s.db.QueryRowContext(ctx,
+        "SELECT EXISTS (SELECT 1 FROM notifications WHERE id = $1 AND user_id = $2)",
+        notificationID, userID,
+    ).Scan(&exists)
+    if err != nil {
+        return nil, err
+    }
+    if !exists {
+        return nil, ErrNotFound
+    }
+
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT `+deliveryColumns+`
+         FROM notification_deliveries d
+         WHERE d.notification_id = $1
+         ORDER BY d.channel`,
+        notificationID,
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    deliveries := []Delivery{}
+    for rows.Next() {
+        d, err := scanDelivery(rows)
+        if err != nil {
+            return nil, err
+        }
+        deliveries = append(deliveries, *d)
+    }
+    return deliveries, rows.Err()
+}
+
+// ClaimDigests захватывает сводки за текущую локальную дату для
+// пользователей с подтверждённым адресом, выбравших сводку вместо отдельных
+// писем, у которых уже наступил час hour и не идут тихие часы, и собирает
+// их задачи.
+// Каждая сводка захватывается один раз; ReleaseDigest возвращает её, если
+// отправить не удалось.
+func (s *PostgresStore) ClaimDigests(ctx context.Context, hour int) ([]Digest, error) {
+    if _, err := s.db.ExecContext(ctx, "DELETE FROM email_digests WHERE day < CURRENT_DATE - 7"); err != nil {
+        return nil, err
+    }
+
+    rows, err := s.db.QueryContext(ctx,
+        `WITH claimed AS (
+             INSERT INTO email_digests (user_id, day)
+             SELECT u.id, (NOW() AT TIME ZONE u.timezone)::date
+             FROM users u `+settingsJoin+`
+             WHERE EXTRACT(HOUR FROM NOW() AT TIME ZONE u.timezone) >= $1
+                 AND u.email_verified_at IS NOT NULL AND COALESCE(ns.email, true) AND ns.email_delivery = $2
+                 AND NOT `+settingsQuiet("u.timezone")+`
+             ON CONFLICT DO NOTHING
+             RETURNING user_id, day
+         )
+         SELECT c.day, u.id, u.email, u.name, u.timezone, u.locale
+         FROM claimed c
+         JOIN users u ON u.id = c.user_id
+         ORDER BY u.id`,
+        hour, EmailDigest,
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var digests []Digest
+    index := map[uint]int{}
+    var ids []int64
+    for rows.Next() {
+        var d Digest
+        u := &d.User
+        if err := rows.Scan(&d.Day, &u.ID, &u.Email, &u.Name, &u.Timezone, &u.Locale); err != nil {
+            return nil, err
+        }
+        index[u.ID] = len(digests)
+        ids = append(ids, int64(u.ID))
+        digests = append(digests, d)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+    if len(digests) == 0 {
+        return nil, nil
+    }
+
+    taskRows, err := s.db.QueryContext(ctx,
+        `SELECT `+taskColumns+`, `+taskDeadline("u.timezone")+` < NOW()
+         FROM tasks t
+         JOIN users u ON u.id = t.user_id
+         LEFT JOIN categories c ON t.category_id = c.id
+         WHERE t.user_id = ANY($1) AND t.deleted_at IS NULL AND t.completed = false AND t.due_date IS NOT NULL
+             AND (`+taskDeadline("u.timezone")+` < NOW()
+                  OR (t.due_date AT TIME ZONE u.timezone)::date = (NOW() AT TIME ZONE u.timezone)::date)
+         ORDER BY t.due_date, t.id`,
+        pq.Array(ids),
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer taskRows.Close()
+
+    for taskRows.Next() {
+        var overdue bool
+        task, err := scanTask(taskRows, &overdue)
+        if err != nil {
+            return nil, err
+        }
+        d := &digests[index[task.UserID]]
+        if overdue {
+            d.Overdue = append(d.Overdue, *task)
+        } else {
+            d.DueToday = append(d.DueToday, *task)
+        }
+    }
+    return digests, taskRows.Err()
+}
+
+func (s *PostgresStore) ReleaseDigest(ctx context.Context, userID uint, day time.Time) error {
+    _, err := s.db.ExecContext(ctx,
+        "DELETE FROM email_digests WHERE user_id = $1 AND day = $2",
+        userID, day.Format("2006-01-02"),
+    )
+    return err
+}
@@ -2,6 +2,64 @@ package models
 
 import "errors"
 
+// Виды доменных ошибок; конкретные ошибки оборачивают один из них, и
+// обработчики выбирают HTTP-статус через errors.Is.
 var (
-    ErrNotFound = errors.New("not found")
-) 
\ No newline at end of file
+    ErrNotFound        = errors.New("not found")
+    ErrValidation      = errors.New("validation failed")
+    ErrConflict        = errors.New("conflict")
+    ErrForbidden       = errors.New("forbidden")
+    // Запись изменилась после того, как клиент получил её версию
+    ErrVersionMismatch = errors.New("version mismatch")
+)
+
+var (
+    ErrInvalidParent    = Invalid("parent_id", "invalid parent task")
+    ErrInvalidCategory  = errors.New("invalid category")
+    ErrInvalidOrder     = Invalid("ids", "order must list every subtask exactly once")
+    ErrEmailTaken       = &ConflictError{Field: "email", Message: "email is already registered"}
+    ErrClientIDTaken    = &ConflictError{Field: "client_id", Message: "client_id is already used"}
+    // Запись с этим client_id уже удалена в корзину
+    ErrClientIDTrashed  = &ConflictError{Field: "client_id", Message: "record with this client_id is in the trash"}
+    // Токен из письма неизвестен, уже использован или истёк
+    ErrInvalidAuthToken = Invalid("token", "token is invalid or expired")
+
+    ErrSessionRevoked      = errors.New("session revoked")
+    ErrRefreshTokenExpired = errors.New("refresh token expired")
+    ErrRefreshTokenReused  = errors.New("refresh token reused")
+)
+
+// ValidationError сообщает, какое поле запроса не прошло проверку.
+type ValidationError struct {
+    Field   string
+    Message string
+}
+
+func Invalid(field, message string) *ValidationError {
+    return &ValidationError{Field: field, Message: message}
+}
+
+func (e *ValidationError) Error() string {
+    if e.Field == "" {
+        return e.Message
+    }
+    return e.Field + ": " + e.Message
+}
+
+func (e *ValidationError) Unwrap() error {
+    return ErrValidation
+}
+
+// ConflictError означает, что запись противоречит уже существующей.
+type ConflictError struct {
+    Field   string
+    Message string
+}
+
+func (e *ConflictError) Error() string {
+    return e.Message
+}
+
+func (e *ConflictError) Unwrap() error {
+    return ErrConflict
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+    "context"
+    "encoding/json"
+    "time"
+)
+
+const (
+    EventNotificationCreated = "notification.created"
+    EventTaskCreated         = "task.created"
+    EventTaskUpdated         = "task.updated"
+    // EventTaskCompleted следует за task.updated, когда задача выполнена.
+    EventTaskCompleted       = "task.completed"
+    EventTaskDeleted         = "task.deleted"
+    EventTaskRestored        = "task.restored"
+    EventCategoryCreated     = "category.created"
+    EventCategoryUpdated     = "category.updated"
+    EventCategoryDeleted     = "category.deleted"
+    EventCategoryRestored    = "category.restored"
+
+    MaxEventBatch = 100
+)
+
+// Event — запись журнала изменений для потока уведомлений. ID растёт
+// монотонно и передаётся клиенту как id события SSE.
+type Event struct {
+    ID        int64           `json:"id"`
+    UserID    uint            `json:"-"`
+    Type      string          `json:"type"`
+    Data      json.RawMessage `json:"data"`
+    CreatedAt time.Time       `json:"created_at"`
+}
+
+// TaskEventData — содержимое событий task.*; полную задачу клиент
+// запрашивает сам, если она ему нужна.
+type TaskEventData struct {
+    ID        uint  `json:"id"`
+    ParentID  *uint `json:"parent_id"`
+    Completed bool  `json:"completed"`
+}
+
+type CategoryEventData struct {
+    ID   uint   `json:"id"`
+    Name string `json:"name"`
+}
+
+// События пишут триггеры из миграций 0006_events и 0014_webhooks.
+
+func (s *PostgresStore) ListEvents(ctx context.Context, userID uint, afterID int64, limit int) ([]Event, error) {
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT id, user_id, type, data, created_at
+         FROM events
+         WHERE user_id = $1 AND id > $2
+         ORDER BY id
+         LIMIT $3`,
+        userID, afterID, limit,
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var events []Event
+    for rows.Next() {
+        var e Event
+        var data []byte
+        if err := rows.Scan(&e.ID, &e.UserID, &e.Type, &data, &e.CreatedAt); err != nil {
+            return nil, err
+        }
+        e.Data = data
+        events = append(events, e)
+    }
+    return events, rows.Err()
+}
+
+func (s *PostgresStore) LatestEventID(ctx context.Context, userID uint) (int64, error) {
+    var id int64
+    err := s.db.QueryRowContext(ctx,
+        "SELECT COALESCE(MAX(id), 0) FROM events WHERE user_id = $1",
+        userID,
+    ).Scan(&id)
+    return id, err
+}
+
+func (s *PostgresStore) PurgeEvents(ctx context.Context, before time.Time) (int64, error) {
+    result, err := s.db.ExecContext(ctx, "DELETE FROM events WHERE created_at < $1", before)
+    if err != nil {
+        return 0, err
+    }
+    return result.RowsAffected()
+}
@@ -0,0 +1,289 @@
+package models
+
+import (
+    "encoding/base64"
+    "encoding/json"
+    "fmt"
+    "sort"
+    "strings"
+    "time"
+)
+
+const (
+    DefaultTaskPageSize = 100
+    MaxTaskPageSize     = 500
+)
+
+var (
+    ErrInvalidCursor = Invalid("cursor", "invalid cursor")
+    ErrInvalidSort   = Invalid("sort", "unknown sort order")
+)
+
+type TaskFilter struct {
+    Completed  *bool
+    Priority   *Priority
+    CategoryID *uint
+    DueFrom    *time.Time
+    DueTo      *time.Time
+    Overdue    *bool
+    Query      string
+    Sort       string
+    Cursor     string
+    Limit      int
+}
+
+type TaskPage struct {
+    Tasks      []Task `json:"tasks"`
+    NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type sortKey struct {
+    column string
+    desc   bool
+}
+
+// Каждый вариант сортировки заканчивается по id, чтобы порядок был полным
+// и курсор однозначно указывал на позицию.
+var taskSorts = map[string][]sortKey{
+    "due_date":    {{"due_date", false}, {"priority", true}, {"created_at", true}, {"id", true}},
+    "-due_date":   {{"due_date", true}, {"priority", true}, {"created_at", true}, {"id", true}},
+    "priority":    {{"priority", false}, {"due_date", false}, {"id", false}},
+    "-priority":   {{"priority", true}, {"due_date", false}, {"id", false}},
+    "created_at":  {{"created_at", false}, {"id", false}},
+    "-created_at": {{"created_at", true}, {"id", true}},
+    "updated_at":  {{"updated_at", false}, {"id", false}},
+    "-updated_at": {{"updated_at", true}, {"id", true}},
+    "title":       {{"title", false}, {"id", false}},
+    "-title":      {{"title", true}, {"id", true}},
+}
+
+const DefaultTaskSort = "due_date"
+
+func (f *TaskFilter) sortKeys() ([]sortKey, error) {
+    if f.Sort == "" {
+        f.Sort = DefaultTaskSort
+    }
+    keys, ok := taskSorts[f.Sort]
+    if !ok {
+        return nil, fmt.Errorf("%w %q", ErrInvalidSort, f.Sort)
+    }
+    return keys, nil
+}
+
+func (f *TaskFilter) limit() int {
+    if f.Limit <= 0 {
+        return DefaultTaskPageSize
+    }
+    if f.Limit > MaxTaskPageSize {
+        return MaxTaskPageSize
+    }
+    return f.Limit
+}
+
+// Validate нормализует сортировку и проверяет курсор до обращения к хранилищу.
+func (f *TaskFilter) Validate() error {
+    keys, err := f.sortKeys()
+    if err != nil {
+        return err
+    }
+    _, err = decodeCursor(f.Cursor, f.Sort, keys)
+    return err
+}
+
+type cursor struct {
+    Sort   string            `json:"s"`
+    Values []json.RawMessage `json:"v"`
+}
+
+func taskSortValue(task *Task, column string) interface{} {
+    switch column {
+    case "due_date":
+        return task.DueDate
+    case "priority":
+        return int(task.Priority)
+    case "created_at":
+        return task.CreatedAt
+    case "updated_at":
+        return task.UpdatedAt
+    case "title":
+        return task.Title
+    default:
+        return task.ID
+    }
+}
+
+func encodeCursor(sortName string, keys []sortKey, last *Task) string {
+    c := cursor{Sort: sortName}
+    for _, key := range keys {
+        raw, _ := json.Marshal(taskSortValue(last, key.column))
+        c.Values = append(c.Values, raw)
+    }
+    data, _ := json.Marshal(c)
+    return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor возвращает значения ключей сортировки последней задачи
+// предыдущей страницы в типах, пригодных для передачи в SQL.
+func decodeCursor(token, sortName string, keys []sortKey) ([]interface{}, error) {
+    if token == "" {
+        return nil, nil
+    }
+    data, err := base64.RawURLEncoding.DecodeString(token)
+    if err != nil {
+        return nil, ErrInvalidCursor
+    }
+    var c cursor
+    if err := json.Unmarshal(data, &c); err != nil {
+        return nil, ErrInvalidCursor
+    }
+    if c.Sort != sortName || len(c.Values) != len(keys) {
+        return nil, ErrInvalidCursor
+    }
+
+    values := make([]interface{}, len(keys))
+    for i, key := range keys {
+        var err error
+        switch key.column {
+        case "due_date", "created_at", "updated_at":
+            var v time.Time
+            err = json.Unmarshal(c.Values[i], &v)
+            values[i] = v
+        case "priority":
+            var v int
+            err = json.Unmarshal(c.Values[i], &v)
+            values[i] = v
+        case "title":
+            var v string
+            err = json.Unmarshal(c.Values[i], &v)
+            values[i] = v
+        default:
+            var v uint
+            err = json.Unmarshal(c.Values[i], &v)
+            values[i] = v
+        }
+        if err != nil {
+            return nil, ErrInvalidCursor
+        }
+    }
+    return values, nil
+}
+
+func compareValues(a, b interface{}) int {
+    switch av := a.(type) {
+    case time.Time:
+        bv := b.(time.Time)
+        switch {
+        case av.Before(bv):
+            return -1
+        case av.After(bv):
+            return 1
+        }
+        return 0
+    case int:
+        bv := b.(int)
+        switch {
+        case av < bv:
+            return -1
+        case av > bv:
+            return 1
+        }
+        return 0
+    case uint:
+        bv := b.(uint)
+        switch {
+        case av < bv:
+            return -1
+        case av > bv:
+            return 1
+        }
+        return 0
+    case string:
+        return strings.Compare(av, b.(string))
+    }
+    return 0
+}
+
+// compareToKeys сравнивает задачу с набором значений ключей с учётом направления сортировки.
+func compareToKeys(task *Task, keys []sortKey, values []interface{}) int {
+    for i, key := range keys {
+        c := compareValues(taskSortValue(task, key.column), values[i])
+        if key.desc {
+            c = -c
+        }
+        if c != 0 {
+            return c
+        }
+    }
+    return 0
+}
+
+func taskKeyValues(task *Task, keys []sortKey) []interface{} {
+    values := make([]interface{}, len(keys))
+    for i, key := range keys {
+        values[i] = taskSortValue(task, key.column)
+    }
+    return values
+}
+
+// Matches применяет фильтр к задаче; используется хранилищами без SQL.
+// loc — часовой пояс владельца задачи.
+func (f *TaskFilter) Matches(task *Task, now time.Time, loc *time.Location) bool {
+    if f.Completed != nil && task.Completed != *f.Completed {
+        return false
+    }
+    if f.Priority != nil && task.Priority != *f.Priority {
+        return false
+    }
+    if f.CategoryID != nil && (task.CategoryID == nil || *task.CategoryID != *f.CategoryID) {
+        return false
+    }
+    if f.DueFrom != nil && task.DueDate.Before(*f.DueFrom) {
+        return false
+    }
+    if f.DueTo != nil && task.DueDate.After(*f.DueTo) {
+        return false
+    }
+    if f.Overdue != nil {
+        overdue := !task.Completed && task.Deadline(loc).Before(now)
+        if overdue != *f.Overdue {
+            return false
+        }
+    }
+    if f.Query != "" {
+        q := strings.ToLower(f.Query)
+        if !strings.Contains(strings.ToLower(task.Title), q) && !strings.Contains(strings.ToLower(task.Description), q) {
+            return false
+        }
+    }
+    return true
+}
+
+// PageTasks сортирует уже отфильтрованные задачи и вырезает страницу после курсора.
+func PageTasks(tasks []Task, filter TaskFilter) (*TaskPage, error) {
+    keys, err := filter.sortKeys()
+    if err != nil {
+        return nil, err
+    }
+    after, err := decodeCursor(filter.Cursor, filter.Sort, keys)
+    if err != nil {
+        return nil, err
+    }
+
+    sort.SliceStable(tasks, func(i, j int) bool {
+        return compareToKeys(&tasks[i], keys, taskKeyValues(&tasks[j], keys)) < 0
+    })
+
+    page := &TaskPage{Tasks: []Task{}}
+    limit := filter.limit()
+    for i := range tasks {
+        if after != nil && compareToKeys(&tasks[i], keys, after) <= 0 {
+            continue
+        }
+        if len(page.Tasks) == limit {
+            page.NextCursor = encodeCursor(filter.Sort, keys, &page.Tasks[limit-1])
+            break
+        }
+        page.Tasks = append(page.Tasks, tasks[i])
+    }
+    return page, nil
+}
@@ -0,0 +1,277 @@
+package models
+
+import (
+    "bytes"
+    "context"
+    "database/sql"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "strconv"
+    "time"
+)
+
+// ChangeSource — откуда пришло изменение задачи.
+type ChangeSource string
+
+const (
+    SourceAPI       ChangeSource = "api"
+    SourceScheduler ChangeSource = "scheduler"
+    SourceSync      ChangeSource = "sync"
+)
+
+// Действия в истории задачи. Deleted — перенос в корзину, Purged —
+// окончательное удаление.
+const (
+    TaskActionCreated  = "created"
+    TaskActionUpdated  = "updated"
+    TaskActionDeleted  = "deleted"
+    TaskActionRestored = "restored"
+    TaskActionPurged   = "purged"
+)
+
+// Actor — кто меняет задачи: пользователь (ноль для планировщика) и
+// источник. Хранилища берут его из контекста и записывают в историю.
+type Actor struct {
+    UserID uint
+    Source ChangeSource
+}
+
+type actorKey struct{}
+
+func WithActor(ctx context.Context, actor Actor) context.Context {
+    return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom возвращает автора изменений из контекста; без него изменение
+// считается пришедшим через API.
+func ActorFrom(ctx context.Context) Actor {
+    actor, _ := ctx.Value(actorKey{}).(Actor)
+    if actor.Source == "" {
+        actor.Source = SourceAPI
+    }
+    return actor
+}
+
+// FieldChange — значения поля в JSON до и после изменения.
+type FieldChange struct {
+    Old json.RawMessage `json:"old"`
+    New json.RawMessage `json:"new"`
+}
+
+// TaskChanges — изменённые поля задачи по их именам в JSON.
+type TaskChanges map[string]FieldChange
+
+func (c *TaskChanges) Scan(src interface{}) error {
+    switch v := src.(type) {
+    case []byte:
+        return json.Unmarshal(v, c)
+    case string:
+        return json.Unmarshal([]byte(v), c)
+    default:
+        return errors.New("unsupported task changes")
+    }
+}
+
+type TaskEvent struct {
+    ID        int64        `json:"id"`
+    TaskID    uint         `json:"task_id"`
+    UserID    uint         `json:"user_id"`
+    ActorID   *uint        `json:"actor_id"`
+    Source    ChangeSource `json:"source"`
+    Action    string       `json:"action"`
+    Changes   TaskChanges  `json:"changes"`
+    CreatedAt time.Time    `json:"created_at"`
+}
+
+// historyFields — поля, изменения которых попадают в историю; тот же список
+// у триггера record_task_history.
+var historyFields = []string{
+    "title", "description", "completed", "due_date", "all_day", "priority",
+    "category_id", "parent_id", "recurrence", "recurrence_start", "auto_complete",
+}
+
+func historyValues(task *Task) map[string]interface{} {
+    if task == nil {
+        return map[string]interface{}{}
+    }
+    return map[string]interface{}{
+        "title":            task.Title,
+        "description":      task.Description,
+        "completed":        task.Completed,
+        "due_date":         task.DueDate,
+        "all_day":          task.AllDay,
+        "priority":         task.Priority,
+        "category_id":      task.CategoryID,
+        "parent_id":        task.ParentID,
+        "recurrence":       task.Recurrence,
+        "recurrence_start": task.RecurrenceStart,
+        "auto_complete":    task.AutoComplete,
+    }
+}
+
+// DiffTask сравнивает отслеживаемые поля задачи; old равен nil для новой
+// задачи. Используется хранилищами без SQL, в Postgres то же делает триггер.
+func DiffTask(old, task *Task) TaskChanges {
+    before, after := historyValues(old), historyValues(task)
+    changes := TaskChanges{}
+    for _, field := range historyFields {
+        from, to := jsonValue(before[field]), jsonValue(after[field])
+        if !bytes.Equal(from, to) {
+            changes[field] = FieldChange{Old: from, New: to}
+        }
+    }
+    return changes
+}
+
+func jsonValue(v interface{}) json.RawMessage {
+    data, err := json.Marshal(v)
+    if err != nil {
+        return json.RawMessage("null")
+    }
+    return data
+}
+
+const (
+    DefaultHistoryPageSize = 50
+    MaxHistoryPageSize     = 200
+)
+
+// HistoryFilter выбирает историю одной задачи или, при нулевом TaskID, ленту
+// всех задач пользователя.
+type HistoryFilter struct {
+    TaskID uint
+    Cursor string
+    Limit  int
+}
+
+type HistoryPage struct {
+    Events     []TaskEvent `json:"events"`
+    NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+func (f *HistoryFilter) limit() int {
+    if f.Limit <= 0 {
+        return DefaultHistoryPageSize
+    }
+    if f.Limit > MaxHistoryPageSize {
+        return MaxHistoryPageSize
+    }
+    return f.Limit
+}
+
+// История идёт от новых записей к старым, курсор — id последней записи
+// предыдущей страницы.
+func encodeHistoryCursor(id int64) string {
+    return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func decodeHistoryCursor(token string) (int64, error) {
+    if token == "" {
+        return 0, nil
+    }
+    data, err := base64.RawURLEncoding.DecodeString(token)
+    if err != nil {
+        return 0, ErrInvalidCursor
+    }
+    id, err := strconv.ParseInt(string(data), 10, 64)
+    if err != nil || id <= 0 {
+        return 0, ErrInvalidCursor
+    }
+    return id, nil
+}
+
+// Validate проверяет курсор до обращения к хранилищу.
+func (f *HistoryFilter) Validate() error {
+    _, err := decodeHistoryCursor(f.Cursor)
+    return err
+}
+
+// PageTaskEvents фильтрует записи истории, уже упорядоченные от новых к
+// старым, и вырезает страницу; используется хранилищами без SQL.
+func PageTaskEvents(events []TaskEvent, filter HistoryFilter) (*HistoryPage, error) {
+    after, err := decodeHistoryCursor(filter.Cursor)
+    if err != nil {
+        return nil, err
+    }
+
+    page := &HistoryPage{Events: []TaskEvent{}}
+    limit := filter.limit()
+    for _, e := range events {
+        if (after != 0 && e.ID >= after) || (filter.TaskID != 0 && e.TaskID != filter.TaskID) {
+            continue
+        }
+        if len(page.Events) == limit {
+            page.NextCursor = encodeHistoryCursor(page.Events[limit-1].ID)
+            break
+        }
+        page.Events = append(page.Events, e)
+    }
+    return page, nil
+}
+
+// beginTx начинает транзакцию и передаёт триггеру истории автора изменений
+// из контекста; set_config с is_local действует до конца транзакции.
+func (s *PostgresStore) beginTx(ctx context.Context) (*sql.Tx, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, err
+    }
+    actor := ActorFrom(ctx)
+    var actorID string
+    if actor.UserID != 0 {
+        actorID = strconv.FormatUint(uint64(actor.UserID), 10)
+    }
+    _, err = tx.ExecContext(ctx,
+        "SELECT set_config('app.actor_id', $1, true), set_config('app.source', $2, true)",
+        actorID, string(actor.Source),
+    )
+    if err != nil {
+        tx.Rollback()
+        return nil, err
+    }
+    return tx, nil
+}
+
+func (s *PostgresStore) ListTaskEvents(ctx context.Context, userID uint, filter HistoryFilter) (*HistoryPage, error) {
+    after, err := decodeHistoryCursor(filter.Cursor)
+    if err != nil {
+        return nil, err
+    }
+    limit := filter.limit()
+
+    // Без явных приведений Postgres выводит тип $3 как integer, а id —
+    // BIGSERIAL
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT id, task_id, user_id, actor_id, source, action, changes, created_at
+         FROM task_events
+         WHERE user_id = $1 AND ($2::integer = 0 OR task_id = $2::integer) AND ($3::bigint = 0 OR id < $3::bigint)
+         ORDER BY id DESC
+         LIMIT $4`,
+        userID, filter.TaskID, after, limit+1,
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    page := &HistoryPage{Events: []TaskEvent{}}
+    for rows.Next() {
+        var e TaskEvent
+        var actorID sql.NullInt64
+        err := rows.Scan(&e.ID, &e.TaskID, &e.UserID, &actorID, &e.Source, &e.Action, &e.Changes, &e.CreatedAt)
+        if err != nil {
+            return nil, err
+        }
+        if actorID.Valid {
+            id := uint(actorID.Int64)
+            e.ActorID = &id
+        }
+        if len(page.Events) == limit {
+            page.NextCursor = encodeHistoryCursor(page.Events[limit-1].ID)
+            break
+        }
+        page.Events = append(page.Events, e)
+    }
+    return page, rows.Err()
+}
@@ -1,33 +1,107 @@
 package models
 
 import (
+    "context"
+    "database/sql/driver"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "strconv"
+    "strings"
     "time"
-    "todo-app/internal/db"
+    "github.com/lib/pq"
+    "todo-app/internal/i18n"
 )
 
+// NotificationType определяет шаблон текста уведомления в каталоге i18n.
+type NotificationType string
+
+const (
+    NotificationTaskCreated  NotificationType = "task_created"
+    NotificationTaskDueSoon  NotificationType = "task_due_soon"
+    NotificationTaskDueToday NotificationType = "task_due_today"
+    NotificationTaskOverdue  NotificationType = "task_overdue"
+    NotificationReminder     NotificationType = "reminder"
+    // NotificationMessage — уведомления, созданные до появления типов; их
+    // текст хранится в message как есть.
+    NotificationMessage NotificationType = "message"
+)
+
+// NotificationParams — данные для подстановки в шаблон. Хранятся в JSONB,
+// чтобы клиент мог сам перерисовать уведомление.
+type NotificationParams struct {
+    TaskID  uint       `json:"task_id"`
+    Title   string     `json:"title"`
+    DueDate *time.Time `json:"due_date,omitempty"`
+    AllDay  bool       `json:"all_day,omitempty"`
+}
+
+// Value отдаёт JSON строкой: []byte драйвер передал бы как bytea.
+func (p NotificationParams) Value() (driver.Value, error) {
+    data, err := json.Marshal(p)
+    return string(data), err
+}
+
+func (p *NotificationParams) Scan(src interface{}) error {
+    switch v := src.(type) {
+    case []byte:
+        return json.Unmarshal(v, p)
+    case string:
+        return json.Unmarshal([]byte(v), p)
+    default:
+        return errors.New("unsupported notification params")
+    }
+}
+
+// TaskParams возвращает параметры уведомления о задаче.
+func TaskParams(task *Task) NotificationParams {
+    due := task.DueDate
+    return NotificationParams{TaskID: task.ID, Title: task.Title, DueDate: &due, AllDay: task.AllDay}
+}
+
 type Notification struct {
-    ID        uint      `json:"id"`
-    UserID    uint      `json:"user_id"`
-    TaskID    uint      `json:"task_id"`
+    ID     uint               `json:"id"`
+    UserID uint               `json:"user_id"`
+    TaskID uint               `json:"task_id"`
+    Type   NotificationType   `json:"type"`
+    Params NotificationParams `json:"params"`
+    // Message заполняется при выдаче клиенту на его языке, см. Localize.
     Message   string    `json:"message"`
     CreatedAt time.Time `json:"created_at"`
     Read      bool      `json:"read"`
+    // InApp ложно, если пользователь выключил канал in_app: такое уведомление
+    // нужно только внешним каналам.
+    InApp bool `json:"-"`
+}
+
+// Localize рендерит Message на языке locale.
+func (n *Notification) Localize(locale i18n.Locale) {
+    if n.Type == NotificationMessage || n.Type == "" {
+        return
+    }
+    n.Message = i18n.Text(locale, string(n.Type), map[string]string{"title": n.Params.Title})
 }
 
-func CreateNotification(userID, taskID uint, message string) error {
-    _, err := db.DB.Exec(
-        `INSERT INTO notifications (user_id, task_id, message, created_at, read) 
-         VALUES ($1, $2, $3, NOW(), false)`,
-        userID, taskID, message,
+// CreateNotification создаёт уведомление о задаче params.TaskID, если
+// настройки пользователя его допускают. Тихие часы здесь не действуют:
+// такие уведомления вызваны действиями самого пользователя.
+func (s *PostgresStore) CreateNotification(ctx context.Context, userID uint, kind NotificationType, params NotificationParams) error {
+    _, err := s.db.ExecContext(ctx,
+        `INSERT INTO notifications (user_id, task_id, type, params, in_app, created_at, read) 
+         SELECT u.id, $2, $3::text, $4, `+settingsInApp+`, NOW(), false
+         FROM users u `+settingsJoin+`
+         WHERE u.id = $1 AND `+settingsTypeEnabled("$3::text")+` AND `+settingsAnyChannel,
+        userID, params.TaskID, kind, params,
     )
     return err
 }
 
-func GetUserNotifications(userID uint) ([]Notification, error) {
-    rows, err := db.DB.Query(
-        `SELECT id, user_id, task_id, message, created_at, read 
+func (s *PostgresStore) GetUserNotifications(ctx context.Context, userID uint) ([]Notification, error) {
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT id, user_id, task_id, type, params, message, created_at, read 
          FROM notifications 
-         WHERE user_id = $1 
+         WHERE user_id = $1 AND in_app
          ORDER BY created_at DESC`,
         userID,
     )
@@ -39,7 +113,7 @@ func GetUserNotifications(userID uint) ([]Notification, error) {
     var notifications []Notification
     for rows.Next() {
         var n Notification
-        err := rows.Scan(&n.ID, &n.UserID, &n.TaskID, &n.Message, &n.CreatedAt, &n.Read)
+        err := rows.Scan(&n.ID, &n.UserID, &n.TaskID, &n.Type, &n.Params, &n.Message, &n.CreatedAt, &n.Read)
         if err != nil {
             return nil, err
         }
@@ -48,42 +122,241 @@ func GetUserNotifications(userID uint) ([]Notification, error) {
     return notifications, nil
 }
 
-func MarkNotificationAsRead(id uint) error {
-    _, err := db.DB.Exec(
-        "UPDATE notifications SET read = true WHERE id = $1",
-        id,
+func (s *PostgresStore) MarkNotificationAsRead(ctx context.Context, id, userID uint) error {
+    result, err := s.db.ExecContext(ctx,
+        "UPDATE notifications SET read = true WHERE id = $1 AND user_id = $2",
+        id, userID,
     )
-    return err
+    if err != nil {
+        return err
+    }
+
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+
+    if rowsAffected == 0 {
+        return ErrNotFound
+    }
+
+    return nil
 }
 
-func CheckDueTasks() error {
-    _, err := db.DB.Exec(`
-        INSERT INTO notifications (user_id, task_id, message, created_at, read)
-        SELECT 
-            user_id,
-            id as task_id,
-            CASE 
-                WHEN due_date < NOW() THEN 'Задача просрочена: ' || title
-                WHEN due_date < NOW() + INTERVAL '1 day' THEN 'Задача должна быть выполнена сегодня: ' || title
-                WHEN due_date < NOW() + INTERVAL '3 days' THEN 'До срока выполнения задачи осталось менее 3 дней: ' || title
-                ELSE 'Новая задача создана: ' || title
-            END as message,
-            NOW(),
-            false
-        FROM tasks
-        WHERE 
-            completed = false 
-            AND (
-                due_date < NOW() 
-                OR due_date < NOW() + INTERVAL '1 day'
-                OR due_date < NOW() + INTERVAL '3 days'
-                OR id NOT IN (SELECT task_id FROM notifications)
-            )
-            AND NOT EXISTS (
-                SELECT 1 FROM notifications n 
-                WHERE n.task_id = tasks.id 
-                AND n.created_at > NOW() - INTERVAL '1 hour'
-            )
-    `)
+const (
+    DefaultNotificationPageSize = 50
+    MaxNotificationPageSize     = 200
+
+    // MaxNotificationSelection ограничивает число id в одном массовом запросе.
+    MaxNotificationSelection = 500
+)
+
+type NotificationFilter struct {
+    UnreadOnly bool
+    Cursor     string
+    Limit      int
+}
+
+type NotificationPage struct {
+    Notifications []Notification `json:"notifications"`
+    NextCursor    string         `json:"next_cursor,omitempty"`
+}
+
+func (f *NotificationFilter) limit() int {
+    if f.Limit <= 0 {
+        return DefaultNotificationPageSize
+    }
+    if f.Limit > MaxNotificationPageSize {
+        return MaxNotificationPageSize
+    }
+    return f.Limit
+}
+
+// Страницы уведомлений идут от новых к старым по id, поэтому курсор — id
+// последнего уведомления предыдущей страницы.
+func encodeNotificationCursor(id uint) string {
+    return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(uint64(id), 10)))
+}
+
+func decodeNotificationCursor(token string) (uint, error) {
+    if token == "" {
+        return 0, nil
+    }
+    data, err := base64.RawURLEncoding.DecodeString(token)
+    if err != nil {
+        return 0, ErrInvalidCursor
+    }
+    id, err := strconv.ParseUint(string(data), 10, 32)
+    if err != nil || id == 0 {
+        return 0, ErrInvalidCursor
+    }
+    return uint(id), nil
+}
+
+// Validate проверяет курсор до обращения к хранилищу.
+func (f *NotificationFilter) Validate() error {
+    _, err := decodeNotificationCursor(f.Cursor)
     return err
-} 
\ No newline at end of file
+}
+
+// PageNotifications фильтрует уведомления, уже упорядоченные от новых к
+// старым по id, и вырезает страницу; используется хранилищами без SQL.
+func PageNotifications(notifications []Notification, filter NotificationFilter) (*NotificationPage, error) {
+    after, err := decodeNotificationCursor(filter.Cursor)
+    if err != nil {
+        return nil, err
+    }
+
+    page := &NotificationPage{Notifications: []Notification{}}
+    limit := filter.limit()
+    for _, n := range notifications {
+        if (after != 0 && n.ID >= after) || (filter.UnreadOnly && n.Read) {
+            continue
+        }
+        if len(page.Notifications) == limit {
+            page.NextCursor = encodeNotificationCursor(page.Notifications[limit-1].ID)
+            break
+        }
+        page.Notifications = append(page.Notifications, n)
+    }
+    return page, nil
+}
+
+// NotificationSelection выбирает уведомления для массовых операций: по списку
+// id и по времени создания строго до Before. Условия объединяются через И;
+// пустой выбор означает все уведомления пользователя.
+type NotificationSelection struct {
+    IDs    []uint
+    Before *time.Time
+}
+
+// Matches применяет выбор к уведомлению; используется хранилищами без SQL.
+func (sel NotificationSelection) Matches(n *Notification) bool {
+    if sel.Before != nil && !n.CreatedAt.Before(*sel.Before) {
+        return false
+    }
+    if sel.IDs == nil {
+        return true
+    }
+    for _, id := range sel.IDs {
+        if id == n.ID {
+            return true
+        }
+    }
+    return false
+}
+
+// where возвращает условие выбора для уведомлений пользователя $1.
+func (sel NotificationSelection) where(userID uint) (string, []interface{}) {
+    args := []interface{}{userID}
+    conditions := []string{"user_id = $1"}
+    if sel.IDs != nil {
+        ids := make([]int64, len(sel.IDs))
+        for i, id := range sel.IDs {
+            ids[i] = int64(id)
+        }
+        args = append(args, pq.Array(ids))
+        conditions = append(conditions, fmt.Sprintf("id = ANY($%d)", len(args)))
+    }
+    if sel.Before != nil {
+        args = append(args, *sel.Before)
+        conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+    }
+    return strings.Join(conditions, " AND "), args
+}
+
+func (s *PostgresStore) ListNotifications(ctx context.Context, userID uint, filter NotificationFilter) (*NotificationPage, error) {
+    after, err := decodeNotificationCursor(filter.Cursor)
+    if err != nil {
+        return nil, err
+    }
+    limit := filter.limit()
+
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT id, user_id, task_id, type, params, message, created_at, read 
+         FROM notifications 
+         WHERE user_id = $1 AND in_app AND (NOT $2 OR NOT read) AND ($3 = 0 OR id < $3)
+         ORDER BY id DESC
+         LIMIT $4`,
+        userID, filter.UnreadOnly, after, limit+1,
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    page := &NotificationPage{Notifications: []Notification{}}
+    for rows.Next() {
+        var n Notification
+        err := rows.Scan(&n.ID, &n.UserID, &n.TaskID, &n.Type, &n.Params, &n.Message, &n.CreatedAt, &n.Read)
+        if err != nil {
+            return nil, err
+        }
+        if len(page.Notifications) == limit {
+            page.NextCursor = encodeNotificationCursor(page.Notifications[limit-1].ID)
+            break
+        }
+        page.Notifications = append(page.Notifications, n)
+    }
+    return page, rows.Err()
+}
+
+func (s *PostgresStore) CountUnreadNotifications(ctx context.Context, userID uint) (int, error) {
+    var count int
+    err := s.db.QueryRowContext(ctx,
+        "SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND in_app AND NOT read",
+        userID,
+    ).Scan(&count)
+    return count, err
+}
+
+// MarkNotificationsRead отмечает прочитанными выбранные уведомления и
+// возвращает число изменённых.
+func (s *PostgresStore) MarkNotificationsRead(ctx context.Context, userID uint, sel NotificationSelection) (int64, error) {
+    where, args := sel.where(userID)
+    result, err := s.db.ExecContext(ctx, "UPDATE notifications SET read = true WHERE in_app AND NOT read AND "+where, args...)
+    if err != nil {
+        return 0, err
+    }
+    return result.RowsAffected()
+}
+
+func (s *PostgresStore) DeleteNotification(ctx context.Context, id, userID uint) error {
+    result, err := s.db.ExecContext(ctx,
+        "DELETE FROM notifications WHERE id = $1 AND user_id = $2",
+        id, userID,
+    )
+    if err != nil {
+        return err
+    }
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if rowsAffected == 0 {
+        return ErrNotFound
+    }
+    return nil
+}
+
+func (s *PostgresStore) DeleteNotifications(ctx context.Context, userID uint, sel NotificationSelection) (int64, error) {
+    where, args := sel.where(userID)
+    result, err := s.db.ExecContext(ctx, "DELETE FROM notifications WHERE "+where, args...)
+    if err != nil {
+        return 0, err
+    }
+    return result.RowsAffected()
+}
+
+// PurgeNotifications удаляет прочитанные и скрытые из приложения
+// уведомления, созданные до before.
+func (s *PostgresStore) PurgeNotifications(ctx context.Context, before time.Time) (int64, error) {
+    result, err := s.db.ExecContext(ctx,
+        "DELETE FROM notifications WHERE (read OR NOT in_app) AND created_at < $1",
+        before,
+    )
+    if err != nil {
+        return 0, err
+    }
+    return result.RowsAffected()
+}
@@ -0,0 +1,184 @@
+package models
+
+import (
+    "context"
+    "fmt"
+    "strings"
+    "time"
+)
+
+// TaskPatch — частичное изменение задачи: поля со значением nil не меняются.
+// CategoryID и ParentID, равные нулю, убирают задачу из категории и переносят
+// на верхний уровень. RecurrenceStart учитывается только вместе с Recurrence.
+// Version, если не ноль, — ожидаемая версия задачи: при расхождении изменение
+// не применяется и возвращается ErrVersionMismatch.
+type TaskPatch struct {
+    Title           *string
+    Description     *string
+    Completed       *bool
+    DueDate         *time.Time
+    AllDay          *bool
+    Priority        *Priority
+    CategoryID      *uint
+    ParentID        *uint
+    Recurrence      *string
+    RecurrenceStart *time.Time
+    AutoComplete    *bool
+    Version         int
+}
+
+// NewTaskPatch возвращает изменение, заменяющее все изменяемые поля задачи.
+func NewTaskPatch(task *Task) TaskPatch {
+    orZero := func(id *uint) *uint {
+        var v uint
+        if id != nil {
+            v = *id
+        }
+        return &v
+    }
+    return TaskPatch{
+        Title:           &task.Title,
+        Description:     &task.Description,
+        Completed:       &task.Completed,
+        DueDate:         &task.DueDate,
+        AllDay:          &task.AllDay,
+        Priority:        &task.Priority,
+        CategoryID:      orZero(task.CategoryID),
+        ParentID:        orZero(task.ParentID),
+        Recurrence:      &task.Recurrence,
+        RecurrenceStart: task.RecurrenceStart,
+        AutoComplete:    &task.AutoComplete,
+    }
+}
+
+// Apply переносит изменение на задачу. Применённое к пустой задаче, оно
+// оставляет в ней только ссылки, которые нужно проверить.
+func (p TaskPatch) Apply(task *Task) {
+    nonZero := func(id uint) *uint {
+        if id == 0 {
+            return nil
+        }
+        return &id
+    }
+    if p.Title != nil {
+        task.Title = *p.Title
+    }
+    if p.Description != nil {
+        task.Description = *p.Description
+    }
+    if p.Completed != nil {
+        task.Completed = *p.Completed
+    }
+    if p.DueDate != nil {
+        task.DueDate = *p.DueDate
+    }
+    if p.AllDay != nil {
+        task.AllDay = *p.AllDay
+    }
+    if p.Priority != nil {
+        task.Priority = *p.Priority
+    }
+    if p.CategoryID != nil {
+        task.CategoryID = nonZero(*p.CategoryID)
+    }
+    if p.ParentID != nil {
+        task.ParentID = nonZero(*p.ParentID)
+    }
+    if p.Recurrence != nil {
+        task.Recurrence = *p.Recurrence
+        task.RecurrenceStart = nil
+        if p.RecurrenceStart != nil {
+            start := *p.RecurrenceStart
+            task.RecurrenceStart = &start
+        }
+    }
+    if p.AutoComplete != nil {
+        task.AutoComplete = *p.AutoComplete
+    }
+}
+
+// PatchTask меняет только переданные поля задачи пользователя; UPDATE
+// собирается из них же.
+func (s *PostgresStore) PatchTask(ctx context.Context, id, userID uint, patch TaskPatch) (*Task, error) {
+    tx, err := s.beginTx(ctx)
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+
+    refs := Task{UserID: userID}
+    patch.Apply(&refs)
+    if err := checkReferences(ctx, tx, &refs); err != nil {
+        return nil, err
+    }
+
+    var sets []string
+    var args []interface{}
+    set := func(column string, value interface{}) {
+        args = append(args, value)
+        sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+    }
+    if patch.Title != nil {
+        set("title", *patch.Title)
+    }
+    if patch.Description != nil {
+        set("description", *patch.Description)
+    }
+    if patch.Completed != nil {
+        set("completed", *patch.Completed)
+    }
+    if patch.DueDate != nil {
+        set("due_date", *patch.DueDate)
+    }
+    if patch.AllDay != nil {
+        set("all_day", *patch.AllDay)
+    }
+    if patch.Priority != nil {
+        set("priority", *patch.Priority)
+    }
+    if patch.CategoryID != nil {
+        set("category_id", refs.CategoryID)
+        sets = append(sets, "trashed_category_id = NULL")
+    }
+    if patch.ParentID != nil {
+        set("parent_id", refs.ParentID)
+        // Выражения SET видят строку до изменения, поэтому parent_id здесь
+        // ещё старый
+        n := len(args)
+        sets = append(sets, fmt.Sprintf(
+            `position = CASE WHEN parent_id IS DISTINCT FROM $%d::integer
+                             THEN COALESCE((SELECT MAX(position) + 1 FROM tasks WHERE parent_id = $%d::integer AND deleted_at IS NULL), 0)
+                             ELSE position END`, n, n))
+    }
+    if patch.Recurrence != nil {
+        set("recurrence", *patch.Recurrence)
+        set("recurrence_start", patch.RecurrenceStart)
+    }
+    if patch.AutoComplete != nil {
+        set("auto_complete", *patch.AutoComplete)
+    }
+    sets = append(sets, "updated_at = NOW()")
+
+    args = append(args, id, userID, patch.Version)
+    result, err := tx.ExecContext(ctx,
+        fmt.Sprintf("UPDATE tasks SET %s WHERE id = $%d AND user_id = $%d AND deleted_at IS NULL AND ($%d = 0 OR version = $%d)",
+            strings.Join(sets, ", "), len(args)-2, len(args)-1, len(args), len(args)),
+        args...,
+    )
+    if err != nil {
+        return nil, dbError(err)
+    }
+
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return nil, err
+    }
+    if rowsAffected == 0 {
+        return nil, versionConflict(ctx, tx, "tasks", id, userID, patch.Version)
+    }
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+
+    return s.GetTask(ctx, id, userID)
+}
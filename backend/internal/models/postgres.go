@@ -0,0 +1,69 @@
+package models
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "github.com/lib/pq"
+)
+
+type PostgresStore struct {
+    db *sql.DB
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+    return &PostgresStore{db: db}
+}
+
+var _ Store = (*PostgresStore)(nil)
+
+func notFound(err error) error {
+    if errors.Is(err, sql.ErrNoRows) {
+        return ErrNotFound
+    }
+    return err
+}
+
+// dbError переводит нарушения ограничений Postgres в доменные ошибки.
+func dbError(err error) error {
+    var pqErr *pq.Error
+    if !errors.As(err, &pqErr) {
+        return notFound(err)
+    }
+    switch pqErr.Code.Name() {
+    case "unique_violation":
+        switch pqErr.Constraint {
+        case "users_email_key":
+            return ErrEmailTaken
+        case "tasks_client_id_idx", "categories_client_id_idx":
+            return ErrClientIDTaken
+        }
+        return &ConflictError{Field: pqErr.Column, Message: "record already exists"}
+    case "foreign_key_violation":
+        return Invalid(pqErr.Column, "referenced record does not exist")
+    case "check_violation", "not_null_violation", "string_data_right_truncation", "invalid_datetime_format":
+        return Invalid(pqErr.Column, pqErr.Message)
+    }
+    return err
+}
+
+// versionConflict объясняет, почему условная запись в table не затронула
+// строк: при заданной версии и существующей записи — ErrVersionMismatch,
+// иначе ErrNotFound.
+func versionConflict(ctx context.Context, q execer, table string, id, userID uint, version int) error {
+    if version == 0 {
+        return ErrNotFound
+    }
+    var exists bool
+    err := q.QueryRowContext(ctx,
+        "SELECT EXISTS (SELECT 1 FROM "+table+" WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL)",
+        id, userID,
+    ).Scan(&exists)
+    if err != nil {
+        return err
+    }
+    if exists {
+        return ErrVersionMismatch
+    }
+    return ErrNotFound
+}
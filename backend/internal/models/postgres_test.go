@@ -0,0 +1,114 @@
+package models_test
+
+import (
+    "context"
+    "database/sql"
+    "os"
+    "strings"
+    "testing"
+    "time"
+    _ "github.com/lib/pq"
+    "todo-app/internal/db"
+    "todo-app/internal/models"
+    "todo-app/internal/models/storetest"
+)
+
+// openTestDB подключается к базе из TEST_DATABASE_URL и накатывает
+// миграции; без переменной тест пропускается. Все данные в базе удаляются
+// тестами, поэтому нужна отдельная тестовая база.
+func openTestDB(t *testing.T) *sql.DB {
+    t.Helper()
+    dsn := os.Getenv("TEST_DATABASE_URL")
+    if dsn == "" {
+        t.Skip("TEST_DATABASE_URL is not set")
+    }
+
+    conn, err := sql.Open("postgres", dsn)
+    if err != nil {
+        t.Fatalf("open: %v", err)
+    }
+    t.Cleanup(func() { conn.Close() })
+    if err := conn.Ping(); err != nil {
+        t.Fatalf("ping: %v", err)
+    }
+
+    migrator, err := db.NewMigrator(conn)
+    if err != nil {
+        t.Fatalf("NewMigrator: %v", err)
+    }
+    if _, err := migrator.Up(context.Background()); err != nil {
+        t.Fatalf("migrate: %v", err)
+    }
+    return conn
+}
+
+// TestPostgresConformance прогоняет общий набор проверок, данные очищаются
+// перед каждым подтестом.
+func TestPostgresConformance(t *testing.T) {
+    conn := openTestDB(t)
+    storetest.Run(t, func(t *testing.T) models.Store {
+        truncateAll(t, conn)
+        return models.NewPostgresStore(conn)
+    })
+}
+
+// TestPostgresTaskEventsAppendOnly проверяет, что историю задач нельзя ни
+// изменить, ни удалить в обход хранилища.
+func TestPostgresTaskEventsAppendOnly(t *testing.T) {
+    conn := openTestDB(t)
+    truncateAll(t, conn)
+    s := models.NewPostgresStore(conn)
+    ctx := context.Background()
+
+    user, err := s.CreateUser(ctx, "alice@example.com", "secret", "Alice", "")
+    if err != nil {
+        t.Fatalf("CreateUser: %v", err)
+    }
+    task, err := s.CreateTask(ctx, &models.Task{Title: "draft", UserID: user.ID, DueDate: time.Now().Add(time.Hour)})
+    if err != nil {
+        t.Fatalf("CreateTask: %v", err)
+    }
+
+    if _, err := conn.Exec("UPDATE task_events SET action = 'purged' WHERE task_id = $1", task.ID); err == nil {
+        t.Error("UPDATE task_events succeeded")
+    }
+    if _, err := conn.Exec("DELETE FROM task_events WHERE task_id = $1", task.ID); err == nil {
+        t.Error("DELETE FROM task_events succeeded")
+    }
+    page, err := s.ListTaskEvents(ctx, user.ID, models.HistoryFilter{TaskID: task.ID})
+    if err != nil {
+        t.Fatalf("ListTaskEvents: %v", err)
+    }
+    if len(page.Events) != 1 || page.Events[0].Action != models.TaskActionCreated {
+        t.Errorf("history = %+v, want the created event intact", page.Events)
+    }
+}
+
+// truncateAll очищает все таблицы, кроме журнала миграций, и сбрасывает
+// последовательности.
+func truncateAll(t *testing.T, conn *sql.DB) {
+    t.Helper()
+    rows, err := conn.Query(
+        `SELECT quote_ident(tablename) FROM pg_tables 
+         WHERE schemaname = current_schema() AND tablename <> 'schema_migrations'`,
+    )
+    if err != nil {
+        t.Fatalf("list tables: %v", err)
+    }
+    var tables []string
+    for rows.Next() {
+        var name string
+        if err := rows.Scan(&name); err != nil {
+            rows.Close()
+            t.Fatalf("list tables: %v", err)
+        }
+        tables = append(tables, name)
+    }
+    rows.Close()
+    if len(tables) == 0 {
+        return
+    }
+    if _, err := conn.Exec("TRUNCATE " + strings.Join(tables, ", ") + " RESTART IDENTITY CASCADE"); err != nil {
+        t.Fatalf("truncate: %v", err)
+    }
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+    "sort"
+    "time"
+    "todo-app/internal/recurrence"
+)
+
+type Occurrence struct {
+    TaskID     uint      `json:"task_id"`
+    Title      string    `json:"title"`
+    DueDate    time.Time `json:"due_date"`
+    AllDay     bool      `json:"all_day"`
+    Priority   Priority  `json:"priority"`
+    CategoryID *uint     `json:"category_id"`
+}
+
+func (t *Task) recurrenceStart() time.Time {
+    if t.RecurrenceStart != nil {
+        return *t.RecurrenceStart
+    }
+    return t.DueDate
+}
+
+// NextOccurrence возвращает задачу для следующего вхождения серии или nil,
+// если задача не повторяется либо серия закончилась. Правило разворачивается
+// в часовом поясе владельца loc, так что «каждый день в 9:00» не сдвигается
+// при переходе на летнее время.
+func NextOccurrence(task *Task, loc *time.Location) (*Task, error) {
+    if task.Recurrence == "" {
+        return nil, nil
+    }
+    rule, err := recurrence.Parse(task.Recurrence)
+    if err != nil {
+        return nil, err
+    }
+
+    start := task.recurrenceStart()
+    due, ok := rule.After(start.In(loc), task.DueDate)
+    if !ok {
+        return nil, nil
+    }
+
+    return &Task{
+        Title:           task.Title,
+        Description:     task.Description,
+        UserID:          task.UserID,
+        CategoryID:      task.CategoryID,
+        DueDate:         due.UTC(),
+        AllDay:          task.AllDay,
+        Priority:        task.Priority,
+        Recurrence:      task.Recurrence,
+        RecurrenceStart: &start,
+    }, nil
+}
+
+// ExpandOccurrences разворачивает незавершённые повторяющиеся задачи в список
+// вхождений внутри [from, to], отсортированный по сроку.
+func ExpandOccurrences(tasks []Task, from, to time.Time, limit int, loc *time.Location) []Occurrence {
+    occurrences := []Occurrence{}
+    for _, task := range tasks {
+        if task.Completed || task.Recurrence == "" {
+            continue
+        }
+        rule, err := recurrence.Parse(task.Recurrence)
+        if err != nil {
+            continue
+        }
+
+        windowStart := from
+        if task.DueDate.After(windowStart) {
+            windowStart = task.DueDate
+        }
+        for _, due := range rule.Between(task.recurrenceStart().In(loc), windowStart, to, limit) {
+            occurrences = append(occurrences, Occurrence{
+                TaskID:     task.ID,
+                Title:      task.Title,
+                DueDate:    due.UTC(),
+                AllDay:     task.AllDay,
+                Priority:   task.Priority,
+                CategoryID: task.CategoryID,
+            })
+        }
+    }
+
+    sort.SliceStable(occurrences, func(i, j int) bool {
+        return occurrences[i].DueDate.Before(occurrences[j].DueDate)
+    })
+    if limit > 0 && len(occurrences) > limit {
+        occurrences = occurrences[:limit]
+    }
+    return occurrences
+}
@@ -0,0 +1,337 @@
+package models
+
+import (
+    "context"
+    "database/sql"
+    "time"
+)
+
+// ReminderStage — ступень напоминания о сроке задачи. Ступени упорядочены:
+// каждая срабатывает не больше одного раза на срок задачи, а более поздняя
+// ступень поглощает пропущенные ранние.
+type ReminderStage int
+
+const (
+    StageNone ReminderStage = iota
+    StageDueSoon
+    StageDueToday
+    StageOverdue
+)
+
+// Ключ advisory lock планировщика: напоминания рассылает одна реплика.
+const reminderLockKey int64 = 0x746f646f72656d
+
+// ReminderStageAt возвращает ступень, которой задача достигла к now. Границы
+// «сегодня» берутся в часовом поясе владельца loc.
+func ReminderStageAt(task *Task, now time.Time, loc *time.Location) ReminderStage {
+    deadline := task.Deadline(loc)
+    switch {
+    case deadline.Before(now):
+        return StageOverdue
+    case StartOfDay(task.DueDate, loc).Equal(StartOfDay(now, loc)):
+        return StageDueToday
+    case deadline.Before(now.Add(3 * 24 * time.Hour)):
+        return StageDueSoon
+    default:
+        return StageNone
+    }
+}
+
+// NotificationType возвращает тип уведомления для ступени.
+func (s ReminderStage) NotificationType() NotificationType {
+    switch s {
+    case StageOverdue:
+        return NotificationTaskOverdue
+    case StageDueToday:
+        return NotificationTaskDueToday
+    default:
+        return NotificationTaskDueSoon
+    }
+}
+
+// FireReminders создаёт уведомления для незавершённых задач, достигших новой
+// ступени, и для наступивших пользовательских напоминаний. В task_reminders
+// запоминается последняя ступень вместе со сроком, для которого она
+// сработала, так что перенос срока планирует напоминания заново. Если
+// блокировку держит другая реплика, ничего не делает.
+//
+// В тихие часы пользователя напоминания не срабатывают и ждут их окончания.
+// Ступень выключенного в настройках типа отмечается сработавшей без
+// уведомления.
+func (s *PostgresStore) FireReminders(ctx context.Context) (int, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return 0, err
+    }
+    defer tx.Rollback()
+
+    var locked bool
+    if err := tx.QueryRowContext(ctx, "SELECT pg_try_advisory_xact_lock($1)", reminderLockKey).Scan(&locked); err != nil {
+        return 0, err
+    }
+    if !locked {
+        return 0, nil
+    }
+
+    result, err := tx.ExecContext(ctx, `
+        WITH deadlines AS (
+            SELECT t.id, t.user_id, t.title, t.due_date, t.all_day, `+taskDeadline("u.timezone")+` AS deadline,
+                (t.due_date AT TIME ZONE u.timezone)::date = (NOW() AT TIME ZONE u.timezone)::date AS today,
+                ns.disabled_types, `+settingsInApp+` AS in_app, `+settingsAnyChannel+` AS any_channel
+            FROM tasks t
+            JOIN users u ON u.id = t.user_id
+            `+settingsJoin+`
+            WHERE t.deleted_at IS NULL AND t.completed = false AND t.due_date IS NOT NULL AND NOT `+settingsQuiet("u.timezone")+`
+        ), due AS (
+            SELECT id, user_id, title, due_date, all_day, disabled_types, in_app, any_channel,
+                CASE
+                    WHEN deadline < NOW() THEN $1::smallint
+                    WHEN today THEN $2::smallint
+                    ELSE $3::smallint
+                END AS stage
+            FROM deadlines
+            WHERE deadline < NOW() + INTERVAL '3 days' OR today
+        ), fired AS (
+            INSERT INTO task_reminders (task_id, due_date, stage, fired_at)
+            SELECT d.id, d.due_date, d.stage, NOW()
+            FROM due d
+            LEFT JOIN task_reminders r ON r.task_id = d.id
+            WHERE r.task_id IS NULL OR r.due_date <> d.due_date OR r.stage < d.stage
+            ON CONFLICT (task_id) DO UPDATE
+                SET due_date = EXCLUDED.due_date, stage = EXCLUDED.stage, fired_at = EXCLUDED.fired_at
+            RETURNING task_id, stage
+        ), typed AS (
+            SELECT d.*, (CASE f.stage WHEN $1 THEN $4 WHEN $2 THEN $5 ELSE $6 END)::text AS type
+            FROM fired f
+            JOIN due d ON d.id = f.task_id
+        )
+        INSERT INTO notifications (user_id, task_id, type, params, in_app, created_at, read)
+        SELECT user_id, id, type,
+            jsonb_build_object('task_id', id, 'title', title, 'due_date', due_date, 'all_day', all_day),
+            in_app, NOW(), false
+        FROM typed
+        WHERE any_channel AND NOT (type = ANY(COALESCE(disabled_types, '{}')))`,
+        StageOverdue, StageDueToday, StageDueSoon,
+        NotificationTaskOverdue, NotificationTaskDueToday, NotificationTaskDueSoon,
+    )
+    if err != nil {
+        return 0, err
+    }
+    fired, err := result.RowsAffected()
+    if err != nil {
+        return 0, err
+    }
+
+    result, err = tx.ExecContext(ctx, `
+        WITH due AS (
+            SELECT r.id, t.id AS task_id, t.user_id, t.title, t.due_date, t.all_day,
+                `+reminderTriggerAt+` AS trigger_at,
+                `+settingsTypeEnabled("$1::text")+` AND `+settingsAnyChannel+` AS enabled, `+settingsInApp+` AS in_app
+            FROM reminders r
+            JOIN tasks t ON t.id = r.task_id
+            JOIN users u ON u.id = t.user_id
+            `+settingsJoin+`
+            WHERE t.deleted_at IS NULL AND t.completed = false AND NOT `+settingsQuiet("u.timezone")+`
+        ), fired AS (
+            UPDATE reminders r
+            SET fired_at = NOW(), fired_for = d.trigger_at
+            FROM due d
+            WHERE r.id = d.id AND d.trigger_at <= NOW() AND r.fired_for IS DISTINCT FROM d.trigger_at
+            RETURNING r.id
+        )
+        INSERT INTO notifications (user_id, task_id, type, params, in_app, created_at, read)
+        SELECT d.user_id, d.task_id, $1::text,
+            jsonb_build_object('task_id', d.task_id, 'title', d.title, 'due_date', d.due_date, 'all_day', d.all_day),
+            d.in_app, NOW(), false
+        FROM fired f
+        JOIN due d ON d.id = f.id
+        WHERE d.enabled`,
+        NotificationReminder,
+    )
+    if err != nil {
+        return 0, err
+    }
+    custom, err := result.RowsAffected()
+    if err != nil {
+        return 0, err
+    }
+    return int(fired + custom), tx.Commit()
+}
+
+// MaxMinutesBefore ограничивает смещение напоминания годом до срока.
+const MaxMinutesBefore = 366 * 24 * 60
+
+// Reminder — пользовательское напоминание о задаче: либо на момент RemindAt,
+// либо за MinutesBefore минут до срока. TriggerAt вычисляется хранилищем.
+type Reminder struct {
+    ID            uint       `json:"id"`
+    TaskID        uint       `json:"task_id"`
+    UserID        uint       `json:"user_id"`
+    RemindAt      *time.Time `json:"remind_at,omitempty"`
+    MinutesBefore *int       `json:"minutes_before,omitempty"`
+    TriggerAt     time.Time  `json:"trigger_at"`
+    FiredAt       *time.Time `json:"fired_at,omitempty"`
+    CreatedAt     time.Time  `json:"created_at"`
+}
+
+func (r *Reminder) Validate() error {
+    if (r.RemindAt == nil) == (r.MinutesBefore == nil) {
+        return Invalid("remind_at", "exactly one of remind_at and minutes_before is required")
+    }
+    if r.MinutesBefore != nil && (*r.MinutesBefore < 0 || *r.MinutesBefore > MaxMinutesBefore) {
+        return Invalid("minutes_before", "minutes_before must be between 0 and 527040")
+    }
+    return nil
+}
+
+// TriggerTime возвращает момент срабатывания для задачи со сроком due.
+func (r *Reminder) TriggerTime(due time.Time) time.Time {
+    if r.RemindAt != nil {
+        return *r.RemindAt
+    }
+    return due.Add(-time.Duration(*r.MinutesBefore) * time.Minute)
+}
+
+const reminderTriggerAt = `COALESCE(r.remind_at, t.due_date - r.minutes_before * INTERVAL '1 minute')`
+
+const reminderColumns = `r.id, r.task_id, r.user_id, r.remind_at, r.minutes_before, ` + reminderTriggerAt + `, r.fired_at, r.created_at`
+
+func scanReminder(row rowScanner) (*Reminder, error) {
+    var r Reminder
+    var minutes sql.NullInt64
+    err := row.Scan(&r.ID, &r.TaskID, &r.UserID, &r.RemindAt, &minutes, &r.TriggerAt, &r.FiredAt, &r.CreatedAt)
+    if err != nil {
+        return nil, err
+    }
+    if minutes.Valid {
+        m := int(minutes.Int64)
+        r.MinutesBefore = &m
+    }
+    return &r, nil
+}
+
+// CreateReminder добавляет напоминание к задаче reminder.TaskID, если она
+// принадлежит reminder.UserID.
+func (s *PostgresStore) CreateReminder(ctx context.Context, reminder *Reminder) (*Reminder, error) {
+    var id uint
+    err := s.db.QueryRowContext(ctx,
+        `INSERT INTO reminders (task_id, user_id, remind_at, minutes_before, created_at)
+         SELECT id, user_id, $3, $4, NOW() FROM tasks WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+         RETURNING id`,
+        reminder.TaskID, reminder.UserID, reminder.RemindAt, reminder.MinutesBefore,
+    ).Scan(&id)
+    if err != nil {
+        return nil, dbError(err)
+    }
+    return s.getReminder(ctx, s.db, id, reminder.UserID)
+}
+
+func (s *PostgresStore) getReminder(ctx context.Context, q execer, id, userID uint) (*Reminder, error) {
+    reminder, err := scanReminder(q.QueryRowContext(ctx,
+        `SELECT `+reminderColumns+`
+         FROM reminders r
+         JOIN tasks t ON t.id = r.task_id
+         WHERE r.id = $1 AND r.user_id = $2 AND t.deleted_at IS NULL`,
+        id, userID,
+    ))
+    return reminder, notFound(err)
+}
+
+func (s *PostgresStore) GetTaskReminders(ctx context.Context, taskID, userID uint) ([]Reminder, error) {
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT `+reminderColumns+`
+         FROM reminders r
+         JOIN tasks t ON t.id = r.task_id
+         WHERE r.task_id = $1 AND r.user_id = $2 AND t.deleted_at IS NULL
+         ORDER BY 6, r.id`,
+        taskID, userID,
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    reminders := []Reminder{}
+    for rows.Next() {
+        reminder, err := scanReminder(rows)
+        if err != nil {
+            return nil, err
+        }
+        reminders = append(reminders, *reminder)
+    }
+    return reminders, rows.Err()
+}
+
+// UpdateReminder меняет время напоминания и снова взводит его.
+func (s *PostgresStore) UpdateReminder(ctx context.Context, reminder *Reminder) (*Reminder, error) {
+    result, err := s.db.ExecContext(ctx,
+        `UPDATE reminders
+         SET remind_at = $1, minutes_before = $2, fired_at = NULL, fired_for = NULL
+         WHERE id = $3 AND task_id = $4 AND user_id = $5
+           AND EXISTS (SELECT 1 FROM tasks WHERE id = $4 AND deleted_at IS NULL)`,
+        reminder.RemindAt, reminder.MinutesBefore, reminder.ID, reminder.TaskID, reminder.UserID,
+    )
+    if err != nil {
+        return nil, dbError(err)
+    }
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return nil, err
+    }
+    if rowsAffected == 0 {
+        return nil, ErrNotFound
+    }
+    return s.getReminder(ctx, s.db, reminder.ID, reminder.UserID)
+}
+
+func (s *PostgresStore) DeleteReminder(ctx context.Context, id, taskID, userID uint) error {
+    result, err := s.db.ExecContext(ctx,
+        `DELETE FROM reminders 
+         WHERE id = $1 AND task_id = $2 AND user_id = $3 
+           AND EXISTS (SELECT 1 FROM tasks WHERE id = $2 AND deleted_at IS NULL)`,
+        id, taskID, userID,
+    )
+    if err != nil {
+        return err
+    }
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if rowsAffected == 0 {
+        return ErrNotFound
+    }
+    return nil
+}
+
+// SnoozeNotification отмечает уведомление прочитанным и создаёт новое
+// напоминание о той же задаче на момент until.
+func (s *PostgresStore) SnoozeNotification(ctx context.Context, notificationID, userID uint, until time.Time) (*Reminder, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+
+    var id uint
+    err = tx.QueryRowContext(ctx,
+        `WITH snoozed AS (
+             UPDATE notifications SET read = true
+             WHERE id = $1 AND user_id = $2
+             RETURNING task_id, user_id
+         )
+         INSERT INTO reminders (task_id, user_id, remind_at, created_at)
+         SELECT task_id, user_id, $3, NOW() FROM snoozed
+         RETURNING id`,
+        notificationID, userID, until,
+    ).Scan(&id)
+    if err != nil {
+        return nil, dbError(err)
+    }
+
+    reminder, err := s.getReminder(ctx, tx, id, userID)
+    if err != nil {
+        return nil, err
+    }
+    return reminder, tx.Commit()
+}
@@ -0,0 +1,196 @@
+package models
+
+import (
+    "context"
+    "html"
+    "strings"
+    "unicode"
+)
+
+const (
+    DefaultSearchLimit = 20
+    MaxSearchLimit     = 100
+
+    // Подсветка отдаётся как HTML: текст экранирован, совпадения в тегах mark.
+    HighlightStart = "<mark>"
+    HighlightStop  = "</mark>"
+)
+
+type TaskHit struct {
+    Task           Task    `json:"task"`
+    Rank           float64 `json:"rank"`
+    TitleHighlight string  `json:"title_highlight"`
+    Snippet        string  `json:"snippet"`
+}
+
+type CategoryHit struct {
+    Category      Category `json:"category"`
+    Rank          float64  `json:"rank"`
+    NameHighlight string   `json:"name_highlight"`
+}
+
+type SearchResults struct {
+    Tasks      []TaskHit     `json:"tasks"`
+    Categories []CategoryHit `json:"categories"`
+}
+
+// SearchTerms разбивает пользовательский ввод на слова, отбрасывая операторы
+// и знаки препинания, чтобы их нельзя было подставить в tsquery.
+func SearchTerms(q string) []string {
+    return strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+        return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+    })
+}
+
+// prefixQuery строит запрос для to_tsquery, где каждое слово ищется по префиксу.
+func prefixQuery(terms []string) string {
+    parts := make([]string, len(terms))
+    for i, term := range terms {
+        parts[i] = term + ":*"
+    }
+    return strings.Join(parts, " & ")
+}
+
+// ts_headline отмечает совпадения символами из области частного
+// использования Unicode, чтобы после экранирования текста заменить их тегами.
+const (
+    headlineStart = "\uE000"
+    headlineStop  = "\uE001"
+)
+
+const headlineOptions = "StartSel=" + headlineStart + ", StopSel=" + headlineStop
+
+var headlineMarks = strings.NewReplacer(headlineStart, HighlightStart, headlineStop, HighlightStop)
+
+// renderHeadline экранирует результат ts_headline для HTML и ставит теги подсветки.
+func renderHeadline(headline string) string {
+    return headlineMarks.Replace(html.EscapeString(headline))
+}
+
+func (s *PostgresStore) Search(ctx context.Context, userID uint, q string, limit int) (*SearchResults, error) {
+    results := &SearchResults{Tasks: []TaskHit{}, Categories: []CategoryHit{}}
+    terms := SearchTerms(q)
+    if len(terms) == 0 {
+        return results, nil
+    }
+    query := prefixQuery(terms)
+
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT `+taskColumns+`,
+                ts_rank_cd(t.search_vector, q.query) AS search_rank,
+                ts_headline('russian', t.title, q.query, $3),
+                ts_headline('russian', COALESCE(t.description, ''), q.query, $4)
+         FROM tasks t
+         CROSS JOIN to_tsquery('russian', $2) AS q(query)
+         LEFT JOIN categories c ON t.category_id = c.id
+         WHERE t.user_id = $1 AND t.deleted_at IS NULL AND t.search_vector @@ q.query
+         ORDER BY search_rank DESC, t.id DESC
+         LIMIT $5`,
+        userID, query,
+        headlineOptions+", HighlightAll=true",
+        headlineOptions+", MaxFragments=2, MaxWords=20, MinWords=5, FragmentDelimiter=\" … \"",
+        limit,
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var hit TaskHit
+        task, err := scanTask(rows, &hit.Rank, &hit.TitleHighlight, &hit.Snippet)
+        if err != nil {
+            return nil, err
+        }
+        hit.Task = *task
+        hit.TitleHighlight = renderHeadline(hit.TitleHighlight)
+        hit.Snippet = renderHeadline(hit.Snippet)
+        results.Tasks = append(results.Tasks, hit)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+
+    categoryRows, err := s.db.QueryContext(ctx,
+        `SELECT c.id, c.name, c.user_id, c.created_at, c.version,
+                ts_rank_cd(c.search_vector, q.query) AS search_rank,
+                ts_headline('russian', c.name, q.query, $3)
+         FROM categories c
+         CROSS JOIN to_tsquery('russian', $2) AS q(query)
+         WHERE c.user_id = $1 AND c.deleted_at IS NULL AND c.search_vector @@ q.query
+         ORDER BY search_rank DESC, c.id DESC
+         LIMIT $4`,
+        userID, query, headlineOptions+", HighlightAll=true", limit,
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer categoryRows.Close()
+
+    for categoryRows.Next() {
+        var hit CategoryHit
+        err := categoryRows.Scan(&hit.Category.ID, &hit.Category.Name, &hit.Category.UserID, &hit.Category.CreatedAt,
+            &hit.Category.Version, &hit.Rank, &hit.NameHighlight)
+        if err != nil {
+            return nil, err
+        }
+        hit.NameHighlight = renderHeadline(hit.NameHighlight)
+        results.Categories = append(results.Categories, hit)
+    }
+    return results, categoryRows.Err()
+}
+
+// HighlightTerms экранирует text для HTML, оборачивает слова, начинающиеся с
+// одного из terms, в HighlightStart/HighlightStop и возвращает число таких
+// слов. Используется хранилищами без полнотекстового поиска.
+func HighlightTerms(text string, terms []string) (string, int) {
+    var b strings.Builder
+    matches := 0
+    runes := []rune(text)
+    for i := 0; i < len(runes); {
+        if !unicode.IsLetter(runes[i]) && !unicode.IsDigit(runes[i]) {
+            b.WriteString(html.EscapeString(string(runes[i])))
+            i++
+            continue
+        }
+        j := i
+        for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j])) {
+            j++
+        }
+        word := string(runes[i:j])
+        lower := strings.ToLower(word)
+        matched := false
+        for _, term := range terms {
+            if strings.HasPrefix(lower, term) {
+                matched = true
+                break
+            }
+        }
+        if matched {
+            matches++
+            b.WriteString(HighlightStart + word + HighlightStop)
+        } else {
+            b.WriteString(word)
+        }
+        i = j
+    }
+    return b.String(), matches
+}
+
+// MatchesAllTerms сообщает, что каждое слово запроса встречается как префикс
+// какого-либо слова в одном из текстов.
+func MatchesAllTerms(terms []string, texts ...string) bool {
+    for _, term := range terms {
+        found := false
+        for _, text := range texts {
+            if _, n := HighlightTerms(text, []string{term}); n > 0 {
+                found = true
+                break
+            }
+        }
+        if !found {
+            return false
+        }
+    }
+    return true
+}
@@ -0,0 +1,181 @@
+package models
+
+import (
+    "context"
+    "crypto/rand"
+    "crypto/sha256"
+    "database/sql"
+    "encoding/base64"
+    "encoding/hex"
+    "errors"
+    "time"
+)
+
+type Session struct {
+    ID         uint       `json:"id"`
+    UserID     uint       `json:"user_id"`
+    UserAgent  string     `json:"user_agent"`
+    CreatedAt  time.Time  `json:"created_at"`
+    LastUsedAt time.Time  `json:"last_used_at"`
+    RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// NewToken возвращает случайный токен для клиента и его хеш для хранения;
+// так выпускаются refresh-токены и токены из писем.
+func NewToken() (token, hash string, err error) {
+    buf := make([]byte, 32)
+    if _, err := rand.Read(buf); err != nil {
+        return "", "", err
+    }
+    token = base64.RawURLEncoding.EncodeToString(buf)
+    return token, HashToken(token), nil
+}
+
+func HashToken(token string) string {
+    sum := sha256.Sum256([]byte(token))
+    return hex.EncodeToString(sum[:])
+}
+
+func (s *PostgresStore) CreateSession(ctx context.Context, userID uint, userAgent, refreshHash string, expiresAt time.Time) (*Session, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+
+    var session Session
+    err = tx.QueryRowContext(ctx,
+        `INSERT INTO sessions (user_id, user_agent, created_at, last_used_at) 
+         VALUES ($1, $2, NOW(), NOW()) 
+         RETURNING id, user_id, user_agent, created_at, last_used_at`,
+        userID, userAgent,
+    ).Scan(&session.ID, &session.UserID, &session.UserAgent, &session.CreatedAt, &session.LastUsedAt)
+    if err != nil {
+        return nil, err
+    }
+
+    _, err = tx.ExecContext(ctx,
+        "INSERT INTO refresh_tokens (session_id, token_hash, created_at, expires_at) VALUES ($1, $2, NOW(), $3)",
+        session.ID, refreshHash, expiresAt,
+    )
+    if err != nil {
+        return nil, err
+    }
+
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+    return &session, nil
+}
+
+func (s *PostgresStore) GetSession(ctx context.Context, id uint) (*Session, error) {
+    var session Session
+    err := s.db.QueryRowContext(ctx,
+        "SELECT id, user_id, user_agent, created_at, last_used_at, revoked_at FROM sessions WHERE id = $1",
+        id,
+    ).Scan(&session.ID, &session.UserID, &session.UserAgent, &session.CreatedAt, &session.LastUsedAt, &session.RevokedAt)
+    if err != nil {
+        return nil, notFound(err)
+    }
+    return &session, nil
+}
+
+// RotateRefreshToken помечает предъявленный токен использованным и выпускает
+// на его место новый в той же сессии. Повторное предъявление уже
+// использованного токена считается кражей и отзывает сессию целиком.
+func (s *PostgresStore) RotateRefreshToken(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (*Session, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+
+    var tokenID uint
+    var tokenExpiresAt time.Time
+    var usedAt *time.Time
+    var session Session
+    err = tx.QueryRowContext(ctx,
+        `SELECT rt.id, rt.expires_at, rt.used_at, 
+                s.id, s.user_id, s.user_agent, s.created_at, s.last_used_at, s.revoked_at
+         FROM refresh_tokens rt
+         JOIN sessions s ON s.id = rt.session_id
+         WHERE rt.token_hash = $1
+         FOR UPDATE`,
+        oldHash,
+    ).Scan(&tokenID, &tokenExpiresAt, &usedAt,
+        &session.ID, &session.UserID, &session.UserAgent, &session.CreatedAt, &session.LastUsedAt, &session.RevokedAt)
+    if errors.Is(err, sql.ErrNoRows) {
+        return nil, ErrNotFound
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    if session.RevokedAt != nil {
+        return nil, ErrSessionRevoked
+    }
+    if usedAt != nil {
+        if _, err := tx.ExecContext(ctx, "UPDATE sessions SET revoked_at = NOW() WHERE id = $1", session.ID); err != nil {
+            return nil, err
+        }
+        if err := tx.Commit(); err != nil {
+            return nil, err
+        }
+        return nil, ErrRefreshTokenReused
+    }
+    if time.Now().After(tokenExpiresAt) {
+        return nil, ErrRefreshTokenExpired
+    }
+
+    if _, err := tx.ExecContext(ctx, "UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1", tokenID); err != nil {
+        return nil, err
+    }
+    _, err = tx.ExecContext(ctx,
+        "INSERT INTO refresh_tokens (session_id, token_hash, created_at, expires_at) VALUES ($1, $2, NOW(), $3)",
+        session.ID, newHash, expiresAt,
+    )
+    if err != nil {
+        return nil, err
+    }
+    err = tx.QueryRowContext(ctx,
+        "UPDATE sessions SET last_used_at = NOW() WHERE id = $1 RETURNING last_used_at",
+        session.ID,
+    ).Scan(&session.LastUsedAt)
+    if err != nil {
+        return nil, err
+    }
+
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+    return &session, nil
+}
+
+func (s *PostgresStore) RevokeSession(ctx context.Context, id, userID uint) error {
+    result, err := s.db.ExecContext(ctx,
+        "UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL",
+        id, userID,
+    )
+    if err != nil {
+        return err
+    }
+
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+
+    if rowsAffected == 0 {
+        return ErrNotFound
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) RevokeUserSessions(ctx context.Context, userID uint) error {
+    _, err := s.db.ExecContext(ctx,
+        "UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL",
+        userID,
+    )
+    return err
+}
@@ -0,0 +1,271 @@
+package models
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "time"
+    "github.com/lib/pq"
+)
+
+// EmailDelivery определяет, как уведомления доходят по почте.
+type EmailDelivery string
+
+const (
+    EmailImmediate EmailDelivery = "immediate"
+    // EmailDigest заменяет отдельные письма ежедневной сводкой.
+    EmailDigest EmailDelivery = "digest"
+)
+
+// ConfigurableTypes — типы уведомлений, которые пользователь может выключить.
+var ConfigurableTypes = []NotificationType{
+    NotificationTaskCreated,
+    NotificationTaskDueSoon,
+    NotificationTaskDueToday,
+    NotificationTaskOverdue,
+    NotificationReminder,
+}
+
+// ClockTime — время суток в минутах от полуночи; в JSON — строка "15:04".
+type ClockTime int
+
+func ParseClockTime(s string) (ClockTime, error) {
+    t, err := time.Parse("15:04", s)
+    if err != nil {
+        return 0, fmt.Errorf("invalid time of day %q, want HH:MM", s)
+    }
+    return ClockTime(t.Hour()*60 + t.Minute()), nil
+}
+
+func (c ClockTime) String() string {
+    return fmt.Sprintf("%02d:%02d", int(c)/60, int(c)%60)
+}
+
+func (c ClockTime) MarshalJSON() ([]byte, error) {
+    return json.Marshal(c.String())
+}
+
+func (c *ClockTime) UnmarshalJSON(data []byte) error {
+    var s string
+    if err := json.Unmarshal(data, &s); err != nil {
+        return fmt.Errorf("time of day must be a string")
+    }
+    parsed, err := ParseClockTime(s)
+    if err != nil {
+        return err
+    }
+    *c = parsed
+    return nil
+}
+
+// clockAt возвращает время суток момента t.
+func clockAt(t time.Time) ClockTime {
+    return ClockTime(t.Hour()*60 + t.Minute())
+}
+
+// QuietHours — период [Start, End) в часовом поясе пользователя; может
+// переходить через полночь.
+type QuietHours struct {
+    Start ClockTime `json:"start"`
+    End   ClockTime `json:"end"`
+}
+
+func (q QuietHours) Contains(c ClockTime) bool {
+    if q.Start < q.End {
+        return c >= q.Start && c < q.End
+    }
+    return c >= q.Start || c < q.End
+}
+
+type NotificationChannels struct {
+    InApp   bool `json:"in_app"`
+    Email   bool `json:"email"`
+    Webhook bool `json:"webhook"`
+}
+
+// NotificationSettings — настройки уведомлений пользователя. Выключенный
+// тип не создаёт уведомлений вовсе; в тихие часы напоминания откладываются
+// до их окончания.
+type NotificationSettings struct {
+    Types         map[NotificationType]bool `json:"types"`
+    Channels      NotificationChannels      `json:"channels"`
+    QuietHours    *QuietHours               `json:"quiet_hours"`
+    EmailDelivery EmailDelivery             `json:"email_delivery"`
+}
+
+func DefaultNotificationSettings() *NotificationSettings {
+    s := &NotificationSettings{
+        Types:         map[NotificationType]bool{},
+        Channels:      NotificationChannels{InApp: true, Email: true, Webhook: true},
+        EmailDelivery: EmailImmediate,
+    }
+    for _, kind := range ConfigurableTypes {
+        s.Types[kind] = true
+    }
+    return s
+}
+
+func (s *NotificationSettings) Validate() error {
+    for kind := range s.Types {
+        if !isConfigurable(kind) {
+            return Invalid("types", fmt.Sprintf("unknown notification type %q", kind))
+        }
+    }
+    if q := s.QuietHours; q != nil {
+        if q.Start < 0 || q.Start >= 24*60 || q.End < 0 || q.End >= 24*60 {
+            return Invalid("quiet_hours", "quiet hours must be within a day")
+        }
+        if q.Start == q.End {
+            return Invalid("quiet_hours", "quiet hours must not start and end at the same time")
+        }
+    }
+    if s.EmailDelivery != EmailImmediate && s.EmailDelivery != EmailDigest {
+        return Invalid("email_delivery", "email_delivery must be immediate or digest")
+    }
+    return nil
+}
+
+func isConfigurable(kind NotificationType) bool {
+    for _, k := range ConfigurableTypes {
+        if k == kind {
+            return true
+        }
+    }
+    return false
+}
+
+// TypeEnabled сообщает, включён ли тип; типы вне ConfigurableTypes включены
+// всегда.
+func (s *NotificationSettings) TypeEnabled(kind NotificationType) bool {
+    enabled, ok := s.Types[kind]
+    return enabled || !ok
+}
+
+// Allows сообщает, нужно ли вообще создавать уведомление типа kind.
+func (s *NotificationSettings) Allows(kind NotificationType) bool {
+    c := s.Channels
+    return s.TypeEnabled(kind) && (c.InApp || c.Email || c.Webhook)
+}
+
+// Quiet сообщает, приходится ли now на тихие часы в часовом поясе loc.
+func (s *NotificationSettings) Quiet(now time.Time, loc *time.Location) bool {
+    return s.QuietHours != nil && s.QuietHours.Contains(clockAt(now.In(loc)))
+}
+
+// QuietUntil возвращает конец тихих часов, идущих в момент now, или сам now
+// вне тихих часов.
+func (s *NotificationSettings) QuietUntil(now time.Time, loc *time.Location) time.Time {
+    if !s.Quiet(now, loc) {
+        return now
+    }
+    local := now.In(loc)
+    end := s.QuietHours.End
+    y, m, d := local.Date()
+    if clockAt(local) >= end {
+        d++
+    }
+    return time.Date(y, m, d, int(end)/60, int(end)%60, 0, 0, loc)
+}
+
+func (s *NotificationSettings) disabledTypes() []string {
+    disabled := []string{}
+    for _, kind := range ConfigurableTypes {
+        if !s.TypeEnabled(kind) {
+            disabled = append(disabled, string(kind))
+        }
+    }
+    return disabled
+}
+
+// Выражения над LEFT JOIN notification_settings ns ON ns.user_id = u.id;
+// без строки настроек действуют значения по умолчанию.
+const (
+    settingsColumns = `COALESCE(ns.disabled_types, '{}'), COALESCE(ns.in_app, true), COALESCE(ns.email, true),
+                COALESCE(ns.webhook, true), ns.quiet_start, ns.quiet_end, COALESCE(ns.email_delivery, 'immediate')`
+    settingsJoin       = `LEFT JOIN notification_settings ns ON ns.user_id = u.id`
+    settingsInApp      = `COALESCE(ns.in_app, true)`
+    settingsAnyChannel = `COALESCE(ns.in_app OR ns.email OR ns.webhook, true)`
+)
+
+// settingsTypeEnabled проверяет, что тип kind (выражение SQL) не выключен.
+func settingsTypeEnabled(kind string) string {
+    return `NOT (` + kind + ` = ANY(COALESCE(ns.disabled_types, '{}')))`
+}
+
+// settingsQuiet проверяет, идут ли сейчас тихие часы в часовом поясе tz.
+func settingsQuiet(tz string) string {
+    local := `(NOW() AT TIME ZONE ` + tz + `)`
+    minute := `(EXTRACT(HOUR FROM ` + local + `) * 60 + EXTRACT(MINUTE FROM ` + local + `))`
+    return `COALESCE(CASE WHEN ns.quiet_start < ns.quiet_end
+                     THEN ` + minute + ` >= ns.quiet_start AND ` + minute + ` < ns.quiet_end
+                     ELSE ` + minute + ` >= ns.quiet_start OR ` + minute + ` < ns.quiet_end END, false)`
+}
+
+// settingsRow принимает колонки settingsColumns; dest можно дописать к
+// колонкам другого запроса.
+type settingsRow struct {
+    disabled   []string
+    quietStart sql.NullInt64
+    quietEnd   sql.NullInt64
+    settings   NotificationSettings
+}
+
+func (r *settingsRow) dest() []interface{} {
+    c := &r.settings.Channels
+    return []interface{}{
+        pq.Array(&r.disabled), &c.InApp, &c.Email, &c.Webhook, &r.quietStart, &r.quietEnd, &r.settings.EmailDelivery,
+    }
+}
+
+func (r *settingsRow) result() *NotificationSettings {
+    s := r.settings
+    s.Types = map[NotificationType]bool{}
+    for _, kind := range ConfigurableTypes {
+        s.Types[kind] = true
+    }
+    for _, kind := range r.disabled {
+        s.Types[NotificationType(kind)] = false
+    }
+    if r.quietStart.Valid && r.quietEnd.Valid {
+        s.QuietHours = &QuietHours{Start: ClockTime(r.quietStart.Int64), End: ClockTime(r.quietEnd.Int64)}
+    }
+    return &s
+}
+
+func (s *PostgresStore) GetNotificationSettings(ctx context.Context, userID uint) (*NotificationSettings, error) {
+    var row settingsRow
+    err := s.db.QueryRowContext(ctx,
+        `SELECT `+settingsColumns+`
+         FROM users u `+settingsJoin+`
+         WHERE u.id = $1`,
+        userID,
+    ).Scan(row.dest()...)
+    if err != nil {
+        return nil, notFound(err)
+    }
+    return row.result(), nil
+}
+
+// UpdateNotificationSettings сохраняет настройки целиком.
+func (s *PostgresStore) UpdateNotificationSettings(ctx context.Context, userID uint, settings *NotificationSettings) (*NotificationSettings, error) {
+    var quietStart, quietEnd *int
+    if q := settings.QuietHours; q != nil {
+        start, end := int(q.Start), int(q.End)
+        quietStart, quietEnd = &start, &end
+    }
+    _, err := s.db.ExecContext(ctx,
+        `INSERT INTO notification_settings (user_id, disabled_types, in_app, email, webhook, quiet_start, quiet_end, email_delivery, updated_at)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+         ON CONFLICT (user_id) DO UPDATE SET
+             disabled_types = EXCLUDED.disabled_types, in_app = EXCLUDED.in_app, email = EXCLUDED.email,
+             webhook = EXCLUDED.webhook, quiet_start = EXCLUDED.quiet_start, quiet_end = EXCLUDED.quiet_end,
+             email_delivery = EXCLUDED.email_delivery, updated_at = EXCLUDED.updated_at`,
+        userID, pq.Array(settings.disabledTypes()), settings.Channels.InApp, settings.Channels.Email,
+        settings.Channels.Webhook, quietStart, quietEnd, settings.EmailDelivery,
+    )
+    if err != nil {
+        return nil, dbError(err)
+    }
+    return s.GetNotificationSettings(ctx, userID)
+}
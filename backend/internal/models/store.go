@@ -0,0 +1,157 @@
+package models
+
+import (
+    "context"
+    "time"
+)
+
+type TaskStore interface {
+    CreateTask(ctx context.Context, task *Task) (*Task, error)
+    GetTask(ctx context.Context, id, userID uint) (*Task, error)
+    GetUserTasks(ctx context.Context, userID uint) ([]Task, error)
+    ListTasks(ctx context.Context, userID uint, filter TaskFilter) (*TaskPage, error)
+    UpdateTask(ctx context.Context, task *Task) (*Task, error)
+    PatchTask(ctx context.Context, id, userID uint, patch TaskPatch) (*Task, error)
+    UpdateTaskCategory(ctx context.Context, taskID, categoryID, userID uint, version int) (*Task, error)
+    DeleteTask(ctx context.Context, id, userID uint, children DeleteChildren, version int) error
+    CreateNextOccurrence(ctx context.Context, completed *Task, next *Task) (*Task, error)
+    GetSubtasks(ctx context.Context, parentID, userID uint) ([]Task, error)
+    ReorderSubtasks(ctx context.Context, parentID, userID uint, ids []uint) error
+    SyncParentCompletion(ctx context.Context, parentID uint) error
+}
+
+type CategoryStore interface {
+    CreateCategory(ctx context.Context, category *Category) (*Category, error)
+    GetCategory(ctx context.Context, id, userID uint) (*Category, error)
+    GetUserCategories(ctx context.Context, userID uint) ([]Category, error)
+    DeleteCategory(ctx context.Context, id, userID uint, version int) error
+}
+
+// UserStore также хранит одноразовые токены из писем: токен передаётся
+// только хешем, использование токена его удаляет.
+type UserStore interface {
+    CreateUser(ctx context.Context, email, password, name, timezone string) (*User, error)
+    GetUserByEmail(ctx context.Context, email string) (*User, error)
+    GetUserByID(ctx context.Context, id uint) (*User, error)
+    UpdateUserProfile(ctx context.Context, id uint, name, timezone, locale string) (*User, error)
+    CreateAuthToken(ctx context.Context, userID uint, purpose AuthTokenPurpose, hash string, expiresAt time.Time) error
+    VerifyEmail(ctx context.Context, hash string) (*User, error)
+    ResetPassword(ctx context.Context, hash, password string) (*User, error)
+}
+
+type SessionStore interface {
+    CreateSession(ctx context.Context, userID uint, userAgent, refreshHash string, expiresAt time.Time) (*Session, error)
+    GetSession(ctx context.Context, id uint) (*Session, error)
+    RotateRefreshToken(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (*Session, error)
+    RevokeSession(ctx context.Context, id, userID uint) error
+    RevokeUserSessions(ctx context.Context, userID uint) error
+}
+
+type NotificationStore interface {
+    CreateNotification(ctx context.Context, userID uint, kind NotificationType, params NotificationParams) error
+    GetUserNotifications(ctx context.Context, userID uint) ([]Notification, error)
+    ListNotifications(ctx context.Context, userID uint, filter NotificationFilter) (*NotificationPage, error)
+    CountUnreadNotifications(ctx context.Context, userID uint) (int, error)
+    MarkNotificationAsRead(ctx context.Context, id, userID uint) error
+    MarkNotificationsRead(ctx context.Context, userID uint, sel NotificationSelection) (int64, error)
+    DeleteNotification(ctx context.Context, id, userID uint) error
+    DeleteNotifications(ctx context.Context, userID uint, sel NotificationSelection) (int64, error)
+    PurgeNotifications(ctx context.Context, before time.Time) (int64, error)
+    FireReminders(ctx context.Context) (int, error)
+}
+
+// NotificationSettingsStore хранит настройки, с которыми сверяются хранилища
+// и рассылка при создании и доставке уведомлений.
+type NotificationSettingsStore interface {
+    GetNotificationSettings(ctx context.Context, userID uint) (*NotificationSettings, error)
+    UpdateNotificationSettings(ctx context.Context, userID uint, settings *NotificationSettings) (*NotificationSettings, error)
+}
+
+type ReminderStore interface {
+    CreateReminder(ctx context.Context, reminder *Reminder) (*Reminder, error)
+    GetTaskReminders(ctx context.Context, taskID, userID uint) ([]Reminder, error)
+    UpdateReminder(ctx context.Context, reminder *Reminder) (*Reminder, error)
+    DeleteReminder(ctx context.Context, id, taskID, userID uint) error
+    SnoozeNotification(ctx context.Context, notificationID, userID uint, until time.Time) (*Reminder, error)
+}
+
+// DeliveryStore ведёт очередь доставки уведомлений по внешним каналам и
+// ежедневные сводки.
+type DeliveryStore interface {
+    ClaimDeliveries(ctx context.Context, channel DeliveryChannel, limit int, lease time.Duration) ([]PendingDelivery, error)
+    CompleteDelivery(ctx context.Context, id uint, result DeliveryResult) error
+    GetNotificationDeliveries(ctx context.Context, notificationID, userID uint) ([]Delivery, error)
+    ClaimDigests(ctx context.Context, hour int) ([]Digest, error)
+    ReleaseDigest(ctx context.Context, userID uint, day time.Time) error
+}
+
+// WebhookStore хранит вебхуки пользователей и очередь их доставок; события
+// в очередь ставят сами хранилища.
+type WebhookStore interface {
+    CreateWebhook(ctx context.Context, webhook *Webhook) (*Webhook, error)
+    GetWebhook(ctx context.Context, id, userID uint) (*Webhook, error)
+    GetUserWebhooks(ctx context.Context, userID uint) ([]Webhook, error)
+    UpdateWebhook(ctx context.Context, webhook *Webhook) (*Webhook, error)
+    DeleteWebhook(ctx context.Context, id, userID uint) error
+    GetWebhookDeliveries(ctx context.Context, webhookID, userID uint, limit int) ([]WebhookDelivery, error)
+    QueueWebhookTest(ctx context.Context, webhookID, userID uint) (*WebhookDelivery, error)
+    ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]PendingWebhookDelivery, error)
+    CompleteWebhookDelivery(ctx context.Context, id uint, result WebhookResult) error
+    PurgeWebhookDeliveries(ctx context.Context, before time.Time) (int64, error)
+}
+
+// EventStore читает журнал событий, который пишут сами хранилища при
+// изменении задач и создании уведомлений.
+type EventStore interface {
+    ListEvents(ctx context.Context, userID uint, afterID int64, limit int) ([]Event, error)
+    LatestEventID(ctx context.Context, userID uint) (int64, error)
+    PurgeEvents(ctx context.Context, before time.Time) (int64, error)
+}
+
+// SyncStore выдаёт журнал изменений для синхронизации клиентов. Журнал
+// ведут сами хранилища при любом изменении задач, категорий и уведомлений.
+type SyncStore interface {
+    GetChanges(ctx context.Context, userID uint, since string, limit int) (*ChangeSet, error)
+    ResolveClientID(ctx context.Context, userID uint, entity, clientID string) (uint, error)
+    // TrashedTaskByClientID и TrashedCategoryByClientID находят по client_id
+    // записи в корзине: ResolveClientID их не видит, а client_id они занимают.
+    TrashedTaskByClientID(ctx context.Context, userID uint, clientID string) (*Task, error)
+    TrashedCategoryByClientID(ctx context.Context, userID uint, clientID string) (*Category, error)
+}
+
+// TrashStore работает с корзиной: удалённые задачи и категории хранятся в
+// ней до PurgeTrash и не видны остальным методам хранилища.
+type TrashStore interface {
+    ListTrash(ctx context.Context, userID uint) (*Trash, error)
+    RestoreTask(ctx context.Context, id, userID uint) (*Task, error)
+    RestoreCategory(ctx context.Context, id, userID uint) (*Category, error)
+    PurgeTrash(ctx context.Context, before time.Time) (int64, error)
+}
+
+// HistoryStore читает историю изменений задач. Историю пишут сами хранилища
+// вместе с изменением; автора они берут из контекста, см. WithActor.
+type HistoryStore interface {
+    ListTaskEvents(ctx context.Context, userID uint, filter HistoryFilter) (*HistoryPage, error)
+}
+
+type SearchStore interface {
+    Search(ctx context.Context, userID uint, q string, limit int) (*SearchResults, error)
+}
+
+// Store объединяет все хранилища; реализуется PostgresStore и memstore.Store.
+type Store interface {
+    TaskStore
+    CategoryStore
+    UserStore
+    SessionStore
+    NotificationStore
+    NotificationSettingsStore
+    ReminderStore
+    DeliveryStore
+    WebhookStore
+    EventStore
+    SearchStore
+    SyncStore
+    TrashStore
+    HistoryStore
+}
@@ -0,0 +1,2126 @@
+// Package storetest содержит общий набор проверок, которому должна
+// удовлетворять любая реализация models.Store.
+package storetest
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+    "todo-app/internal/models"
+)
+
+// Factory возвращает новое пустое хранилище для каждого подтеста.
+type Factory func(t *testing.T) models.Store
+
+func Run(t *testing.T, newStore Factory) {
+    t.Run("Users", func(t *testing.T) { testUsers(t, newStore(t)) })
+    t.Run("Categories", func(t *testing.T) { testCategories(t, newStore(t)) })
+    t.Run("Tasks", func(t *testing.T) { testTasks(t, newStore(t)) })
+    t.Run("PatchTask", func(t *testing.T) { testPatchTask(t, newStore(t)) })
+    t.Run("Versions", func(t *testing.T) { testVersions(t, newStore(t)) })
+    t.Run("Sync", func(t *testing.T) { testSync(t, newStore(t)) })
+    t.Run("Trash", func(t *testing.T) { testTrash(t, newStore(t)) })
+    t.Run("History", func(t *testing.T) { testHistory(t, newStore(t)) })
+    t.Run("Notifications", func(t *testing.T) { testNotifications(t, newStore(t)) })
+    t.Run("Recurrence", func(t *testing.T) { testRecurrence(t, newStore(t)) })
+    t.Run("Subtasks", func(t *testing.T) { testSubtasks(t, newStore(t)) })
+    t.Run("ListTasks", func(t *testing.T) { testListTasks(t, newStore(t)) })
+    t.Run("Search", func(t *testing.T) { testSearch(t, newStore(t)) })
+    t.Run("Sessions", func(t *testing.T) { testSessions(t, newStore(t)) })
+    t.Run("Ownership", func(t *testing.T) { testOwnership(t, newStore(t)) })
+    t.Run("Events", func(t *testing.T) { testEvents(t, newStore(t)) })
+    t.Run("Reminders", func(t *testing.T) { testReminders(t, newStore(t)) })
+    t.Run("Timezones", func(t *testing.T) { testTimezones(t, newStore(t)) })
+    t.Run("NotificationManagement", func(t *testing.T) { testNotificationManagement(t, newStore(t)) })
+    t.Run("Deliveries", func(t *testing.T) { testDeliveries(t, newStore(t)) })
+    t.Run("Digests", func(t *testing.T) { testDigests(t, newStore(t)) })
+    t.Run("NotificationSettings", func(t *testing.T) { testNotificationSettings(t, newStore(t)) })
+    t.Run("Webhooks", func(t *testing.T) { testWebhooks(t, newStore(t)) })
+    t.Run("AuthTokens", func(t *testing.T) { testAuthTokens(t, newStore(t)) })
+}
+
+func mustUser(t *testing.T, s models.Store, email string) *models.User {
+    t.Helper()
+    user, err := s.CreateUser(context.Background(), email, "secret", "User "+email, "")
+    if err != nil {
+        t.Fatalf("CreateUser(%q): %v", email, err)
+    }
+    return user
+}
+
+// mustVerify подтверждает адрес пользователя так же, как ссылка из письма.
+func mustVerify(t *testing.T, s models.Store, userID uint) {
+    t.Helper()
+    ctx := context.Background()
+    hash := models.HashToken(fmt.Sprintf("verify-%d", userID))
+    if err := s.CreateAuthToken(ctx, userID, models.TokenVerifyEmail, hash, time.Now().Add(time.Hour)); err != nil {
+        t.Fatalf("CreateAuthToken: %v", err)
+    }
+    if _, err := s.VerifyEmail(ctx, hash); err != nil {
+        t.Fatalf("VerifyEmail: %v", err)
+    }
+}
+
+func mustCategory(t *testing.T, s models.Store, name string, userID uint) *models.Category {
+    t.Helper()
+    category, err := s.CreateCategory(context.Background(), &models.Category{Name: name, UserID: userID})
+    if err != nil {
+        t.Fatalf("CreateCategory(%q): %v", name, err)
+    }
+    return category
+}
+
+func mustTask(t *testing.T, s models.Store, task models.Task) *models.Task {
+    t.Helper()
+    created, err := s.CreateTask(context.Background(), &task)
+    if err != nil {
+        t.Fatalf("CreateTask(%q): %v", task.Title, err)
+    }
+    return created
+}
+
+func taskTitles(tasks []models.Task) string {
+    titles := make([]string, len(tasks))
+    for i, task := range tasks {
+        titles[i] = task.Title
+    }
+    return strings.Join(titles, ",")
+}
+
+func testUsers(t *testing.T, s models.Store) {
+    ctx := context.Background()
+    user := mustUser(t, s, "a@example.com")
+    if user.ID == 0 || user.Email != "a@example.com" {
+        t.Fatalf("CreateUser returned %+v", user)
+    }
+
+    if _, err := s.CreateUser(ctx, "a@example.com", "other", "Dup", ""); !errors.Is(err, models.ErrConflict) {
+        t.Fatalf("CreateUser with duplicate email error = %v, want ErrConflict", err)
+    }
+
+    found, err := s.GetUserByEmail(ctx, "a@example.com")
+    if err != nil {
+        t.Fatalf("GetUserByEmail: %v", err)
+    }
+    if found.ID != user.ID {
+        t.Fatalf("GetUserByEmail returned user %d, want %d", found.ID, user.ID)
+    }
+    if !found.CheckPassword("secret") || found.CheckPassword("wrong") {
+        t.Fatal("stored password hash does not match")
+    }
+
+    if _, err := s.GetUserByEmail(ctx, "missing@example.com"); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("GetUserByEmail(missing) error = %v, want ErrNotFound", err)
+    }
+}
+
+func testCategories(t *testing.T, s models.Store) {
+    ctx := context.Background()
+    alice := mustUser(t, s, "alice@example.com")
+    bob := mustUser(t, s, "bob@example.com")
+
+    work := mustCategory(t, s, "Work", alice.ID)
+    mustCategory(t, s, "Home", alice.ID)
+    mustCategory(t, s, "Bob's", bob.ID)
+
+    categories, err := s.GetUserCategories(ctx, alice.ID)
+    if err != nil {
+        t.Fatalf("GetUserCategories: %v", err)
+    }
+    if len(categories) != 2 {
+        t.Fatalf("GetUserCategories returned %d categories, want 2", len(categories))
+    }
+    if categories[0].CreatedAt.Before(categories[1].CreatedAt) {
+        t.Fatal("GetUserCategories is not ordered by created_at DESC")
+    }
+
+    if _, err := s.GetCategory(ctx, work.ID, bob.ID); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("GetCategory(foreign) error = %v, want ErrNotFound", err)
+    }
+    if err := s.DeleteCategory(ctx, work.ID, bob.ID, 0); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("DeleteCategory(foreign) error = %v, want ErrNotFound", err)
+    }
+
+    task := mustTask(t, s, models.Task{Title: "Report", UserID: alice.ID, CategoryID: &work.ID, DueDate: time.Now().Add(72 * time.Hour)})
+    if err := s.DeleteCategory(ctx, work.ID, alice.ID, 0); err != nil {
+        t.Fatalf("DeleteCategory: %v", err)
+    }
+    if _, err := s.GetCategory(ctx, work.ID, alice.ID); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("GetCategory(deleted) error = %v, want ErrNotFound", err)
+    }
+
+    got, err := s.GetTask(ctx, task.ID, alice.ID)
+    if err != nil {
+        t.Fatalf("GetTask: %v", err)
+    }
+    if got.CategoryID != nil || got.Category != nil {
+        t.Fatal("deleting a category did not clear category_id on its tasks")
+    }
+}
+
+func testTasks(t *testing.T, s models.Store) {
+    ctx := context.Background()
+    alice := mustUser(t, s, "alice@example.com")
+    bob := mustUser(t, s, "bob@example.com")
+    work := mustCategory(t, s, "Work", alice.ID)
+
+    base := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+    later := mustTask(t, s, models.Task{Title: "later", UserID: alice.ID, DueDate: base.Add(time.Hour)})
+    low := mustTask(t, s, models.Task{Title: "low", UserID: alice.ID, DueDate: base, Priority: models.Low})
+    high := mustTask(t, s, models.Task{Title: "high", UserID: alice.ID, DueDate: base, Priority: models.High, CategoryID: &work.ID})
+    mustTask(t, s, models.Task{Title: "bob", UserID: bob.ID, DueDate: base})
+
+    if high.Completed || high.CreatedAt.IsZero() || high.UpdatedAt.IsZero() {
+        t.Fatalf("CreateTask returned %+v", high)
+    }
+    if high.Category == nil || high.Category.Name != "Work" {
+        t.Fatalf("CreateTask did not populate category: %+v", high.Category)
+    }
+
+    tasks, err := s.GetUserTasks(ctx, alice.ID)
+    if err != nil {
+        t.Fatalf("GetUserTasks: %v", err)
+    }
+    if got := taskTitles(tasks); got != "high,low,later" {
+        t.Fatalf("GetUserTasks order = %s, want high,low,later", got)
+    }
+
+    byCategory, err := s.ListTasks(ctx, alice.ID, models.TaskFilter{CategoryID: &work.ID})
+    if err != nil {
+        t.Fatalf("ListTasks(category): %v", err)
+    }
+    if got := taskTitles(byCategory.Tasks); got != "high" {
+        t.Fatalf("ListTasks(category) = %s, want high", got)
+    }
+
+    if _, err := s.GetTask(ctx, low.ID, bob.ID); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("GetTask(foreign) error = %v, want ErrNotFound", err)
+    }
+
+    update := *low
+    update.Title = "low updated"
+    update.Completed = true
+    update.Priority = models.Medium
+    update.CategoryID = &work.ID
+    updated, err := s.UpdateTask(ctx, &update)
+    if err != nil {
+        t.Fatalf("UpdateTask: %v", err)
+    }
+    if updated.Title != "low updated" || !updated.Completed || updated.Priority != models.Medium {
+        t.Fatalf("UpdateTask returned %+v", updated)
+    }
+    if updated.Category == nil || updated.Category.ID != work.ID {
+        t.Fatal("UpdateTask did not populate category")
+    }
+    if _, err := s.UpdateTask(ctx, &models.Task{ID: 100000, Title: "missing", DueDate: base}); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("UpdateTask(missing) error = %v, want ErrNotFound", err)
+    }
+
+    if _, err := s.UpdateTaskCategory(ctx, later.ID, work.ID, alice.ID, 0); err != nil {
+        t.Fatalf("UpdateTaskCategory: %v", err)
+    }
+    if _, err := s.UpdateTaskCategory(ctx, later.ID, work.ID, bob.ID, 0); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("UpdateTaskCategory(foreign) error = %v, want ErrNotFound", err)
+    }
+    byCategory, _ = s.ListTasks(ctx, alice.ID, models.TaskFilter{CategoryID: &work.ID})
+    if len(byCategory.Tasks) != 3 {
+        t.Fatalf("ListTasks(category) returned %d tasks after recategorizing, want 3", len(byCategory.Tasks))
+    }
+
+    if err := s.CreateNotification(ctx, alice.ID, models.NotificationTaskCreated, models.TaskParams(later)); err != nil {
+        t.Fatalf("CreateNotification: %v", err)
+    }
+    if err := s.DeleteTask(ctx, later.ID, alice.ID, models.CascadeChildren, 0); err != nil {
+        t.Fatalf("DeleteTask: %v", err)
+    }
+    if _, err := s.GetTask(ctx, later.ID, alice.ID); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("GetTask(deleted) error = %v, want ErrNotFound", err)
+    }
+    // Уведомления удаляются вместе с задачей при очистке корзины
+    if _, err := s.PurgeTrash(ctx, time.Now().Add(time.Hour)); err != nil {
+        t.Fatalf("PurgeTrash: %v", err)
+    }
+    notifications, _ := s.GetUserNotifications(ctx, alice.ID)
+    if len(notifications) != 0 {
+        t.Fatal("purging a task did not delete its notifications")
+    }
+}
+
+func testPatchTask(t *testing.T, s models.Store) {
+    ctx := context.Background()
+    alice := mustUser(t, s, "alice@example.com")
+    bob := mustUser(t, s, "bob@example.com")
+    work := mustCategory(t, s, "Work", alice.ID)
+    foreign := mustCategory(t, s, "Foreign", bob.ID)
+
+    due := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+    parent := mustTask(t, s, models.Task{Title: "parent", UserID: alice.ID, DueDate: due})
+    task := mustTask(t, s, models.Task{
+        Title: "task", Description: "keep", UserID: alice.ID, DueDate: due,
+        Priority: models.High, CategoryID: &work.ID, Recurrence: "FREQ=DAILY", RecurrenceStart: &due,
+    })
+
+    // Непереданные поля остаются прежними
+    completed := true
+    patched, err := s.PatchTask(ctx, task.ID, alice.ID, models.TaskPatch{Completed: &completed})
+    if err != nil {
+        t.Fatalf("PatchTask: %v", err)
+    }
+    if !patched.Completed || patched.Title != "task" || patched.Description != "keep" || patched.Priority != models.High ||
+        !patched.DueDate.Equal(due) || patched.Recurrence != "FREQ=DAILY" || patched.RecurrenceStart == nil {
+        t.Fatalf("PatchTask(completed) = %+v", patched)
+    }
+    if patched.CategoryID == nil || *patched.CategoryID != work.ID || patched.Category == nil {
+        t.Fatalf("PatchTask(completed) lost the category: %+v", patched)
+    }
+
+    // Ноль убирает категорию и родителя, пустое правило — повторение
+    none, empty := uint(0), ""
+    patched, err = s.PatchTask(ctx, task.ID, alice.ID, models.TaskPatch{ParentID: &parent.ID})
+    if err != nil || patched.ParentID == nil || *patched.ParentID != parent.ID {
+        t.Fatalf("PatchTask(parent) = %+v, %v", patched, err)
+    }
+    patched, err = s.PatchTask(ctx, task.ID, alice.ID, models.TaskPatch{CategoryID: &none, ParentID: &none, Recurrence: &empty})
+    if err != nil {
+        t.Fatalf("PatchTask(clear): %v", err)
+    }
+    if patched.CategoryID != nil || patched.Category != nil || patched.ParentID != nil || patched.Recurrence != "" || patched.RecurrenceStart != nil {
+        t.Fatalf("PatchTask(clear) = %+v", patched)
+    }
+    if patched.Title != "task" || !patched.Completed {
+        t.Fatalf("PatchTask(clear) changed other fields: %+v", patched)
+    }
+
+    if _, err := s.PatchTask(ctx, task.ID, alice.ID, models.TaskPatch{CategoryID: &foreign.ID}); !errors.Is(err, models.ErrInvalidCategory) {
+        t.Fatalf("PatchTask(foreign category) error = %v, want ErrInvalidCategory", err)
+    }
+    title := "hijacked"
+    if _, err := s.PatchTask(ctx, task.ID, bob.ID, models.TaskPatch{Title: &title}); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("PatchTask(foreign) error = %v, want ErrNotFound", err)
+    }
+    if got, _ := s.GetTask(ctx, task.ID, alice.ID); got == nil || got.Title != "task" {
+        t.Fatalf("foreign PatchTask changed the task: %+v", got)
+    }
+}
+
+func testVersions(t *testing.T, s models.Store) {
+    ctx := context.Background()
+    alice := mustUser(t, s, "alice@example.com")
+    bob := mustUser(t, s, "bob@example.com")
+    work := mustCategory(t, s, "Work", alice.ID)
+    if work.Version != 1 {
+        t.Fatalf("new category version = %d, want 1", work.Version)
+    }
+
+    due := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+    parent := mustTask(t, s, models.Task{Title: "parent", UserID: alice.ID, DueDate: due, AutoComplete: true})
+    task := mustTask(t, s, models.Task{Title: "task", UserID: alice.ID, DueDate: due, CategoryID: &work.ID, ParentID: &parent.ID})
+    if task.Version != 1 {
+        t.Fatalf("new task version = %d, want 1", task.Version)
+    }
+
+    // Совпавшая версия пропускает изменение и увеличивает версию
+    title := "renamed"
+    patched, err := s.PatchTask(ctx, task.ID, alice.ID, models.TaskPatch{Title: &title, Version: 1})
+    if err != nil || patched.Version != 2 {
+        t.Fatalf("PatchTask(version 1) = %+v, %v", patched, err)
+    }
+    stale := "stale"
+    if _, err := s.PatchTask(ctx, task.ID, alice.ID, models.TaskPatch{Title: &stale, Version: 1}); !errors.Is(err, models.ErrVersionMismatch) {
+        t.Fatalf("PatchTask(stale version) error = %v, want ErrVersionMismatch", err)
+    }
+    if _, err := s.PatchTask(ctx, task.ID, bob.ID, models.TaskPatch{Title: &stale, Version: 2}); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("PatchTask(foreign, version) error = %v, want ErrNotFound", err)
+    }
+    if got, _ := s.GetTask(ctx, task.ID, alice.ID); got == nil || got.Title != "renamed" || got.Version != 2 {
+        t.Fatalf("rejected PatchTask changed the task: %+v", got)
+    }
+
+    // Изменение без новых значений не трогает версию и не порождает событий
+    // и записей для синхронизации
+    events, err := s.ListEvents(ctx, alice.ID, 0, 100)
+    if err != nil || len(events) == 0 {
+        t.Fatalf("ListEvents = %d events, %v", len(events), err)
+    }
+    before, err := s.GetChanges(ctx, alice.ID, "", models.DefaultSyncLimit)
+    if err != nil {
+        t.Fatalf("GetChanges: %v", err)
+    }
+    same, err := s.PatchTask(ctx, task.ID, alice.ID, models.TaskPatch{Title: &title, Version: 2})
+    if err != nil || same.Version != 2 || !same.UpdatedAt.Equal(patched.UpdatedAt) {
+        t.Fatalf("PatchTask(no changes) = %+v, %v, want version 2", same, err)
+    }
+    if after, err := s.ListEvents(ctx, alice.ID, events[len(events)-1].ID, 100); err != nil || len(after) != 0 {
+        t.Fatalf("PatchTask(no changes) events = %+v, %v", after, err)
+    }
+    if after, err := s.GetChanges(ctx, alice.ID, before.Token, models.DefaultSyncLimit); err != nil || changeCount(after) != 0 {
+        t.Fatalf("PatchTask(no changes) sync changes = %+v, %v", after, err)
+    }
+
+    if _, err := s.UpdateTaskCategory(ctx, task.ID, 0, alice.ID, 1); !errors.Is(err, models.ErrVersionMismatch) {
+        t.Fatalf("UpdateTaskCategory(stale version) error = %v, want ErrVersionMismatch", err)
+    }
+    moved, err := s.UpdateTaskCategory(ctx, task.ID, 0, alice.ID, 2)
+    if err != nil || moved.Version != 3 || moved.CategoryID != nil {
+        t.Fatalf("UpdateTaskCategory(version 2) = %+v, %v", moved, err)
+    }
+
+    // Версию меняют и косвенные изменения
+    completed := true
+    if _, err := s.PatchTask(ctx, task.ID, alice.ID, models.TaskPatch{Completed: &completed}); err != nil {
+        t.Fatalf("PatchTask(completed): %v", err)
+    }
+    if err := s.SyncParentCompletion(ctx, parent.ID); err != nil {
+        t.Fatalf("SyncParentCompletion: %v", err)
+    }
+    if got, _ := s.GetTask(ctx, task.ID, alice.ID); got == nil || got.Version != 4 {
+        t.Fatalf("task version after two changes = %+v, want 4", got)
+    }
+    if got, _ := s.GetTask(ctx, parent.ID, alice.ID); got == nil || !got.Completed || got.Version != 2 {
+        t.Fatalf("auto-completed parent = %+v, want version 2", got)
+    }
+
+    if err := s.DeleteTask(ctx, task.ID, alice.ID, models.CascadeChildren, 3); !errors.Is(err, models.ErrVersionMismatch) {
+        t.Fatalf("DeleteTask(stale version) error = %v, want ErrVersionMismatch", err)
+    }
+    if err := s.DeleteTask(ctx, task.ID, alice.ID, models.CascadeChildren, 4); err != nil {
+        t.Fatalf("DeleteTask(version 4): %v", err)
+    }
+    if err := s.DeleteCategory(ctx, work.ID, alice.ID, 2); !errors.Is(err, models.ErrVersionMismatch) {
+        t.Fatalf("DeleteCategory(stale version) error = %v, want ErrVersionMismatch", err)
+    }
+    if err := s.DeleteCategory(ctx, work.ID, alice.ID, 1); err != nil {
+        t.Fatalf("DeleteCategory(version 1): %v", err)
+    }
+}
+
+func changeCount(set *models.ChangeSet) int {
+    return len(set.Tasks) + len(set.Categories) + len(set.Notifications) +
+        len(set.Deleted.Tasks) + len(set.Deleted.Categories) + len(set.Deleted.Notifications)
+}
+
+func testSync(t *testing.T, s models.Store) {
+    ctx := context.Background()
+    alice := mustUser(t, s, "alice@example.com")
+    bob := mustUser(t, s, "bob@example.com")
+
+    empty, err := s.GetChanges(ctx, alice.ID, "", models.DefaultSyncLimit)
+    if err != nil || empty.Token == "" || changeCount(empty) != 0 || empty.HasMore {
+        t.Fatalf("GetChanges(new user) = %+v, %v", empty, err)
+    }
+
+    work, err := s.CreateCategory(ctx, &models.Category{Name: "Work", UserID: alice.ID, ClientID: "c-1"})
+    if err != nil || work.ClientID != "c-1" {
+        t.Fatalf("CreateCategory(client id) = %+v, %v", work, err)
+    }
+    due := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+    task := mustTask(t, s, models.Task{Title: "task", UserID: alice.ID, DueDate: due, CategoryID: &work.ID, ClientID: "t-1"})
+    if task.ClientID != "t-1" {
+        t.Fatalf("CreateTask kept client id %q, want t-1", task.ClientID)
+    }
+    if _, err := s.CreateTask(ctx, &models.Task{Title: "dup", UserID: alice.ID, DueDate: due, ClientID: "t-1"}); !errors.Is(err, models.ErrClientIDTaken) {
+        t.Fatalf("CreateTask(duplicate client id) error = %v, want ErrClientIDTaken", err)
+    }
+    if _, err := s.CreateCategory(ctx, &models.Category{Name: "Dup", UserID: alice.ID, ClientID: "c-1"}); !errors.Is(err, models.ErrClientIDTaken) {
+        t.Fatalf("CreateCategory(duplicate client id) error = %v, want ErrClientIDTaken", err)
+    }
+    // client_id уникален только в пределах пользователя
+    bobTask := mustTask(t, s, models.Task{Title: "bob", UserID: bob.ID, DueDate: due, ClientID: "t-1"})
+
+    if id, err := s.ResolveClientID(ctx, alice.ID, models.EntityTask, "t-1"); err != nil || id != task.ID {
+        t.Fatalf("ResolveClientID(alice task) = %d, %v, want %d", id, err, task.ID)
+    }
+    if id, err := s.ResolveClientID(ctx, bob.ID, models.EntityTask, "t-1"); err != nil || id != bobTask.ID {
+        t.Fatalf("ResolveClientID(bob task) = %d, %v, want %d", id, err, bobTask.ID)
+    }
+    if id, err := s.ResolveClientID(ctx, alice.ID, models.EntityCategory, "c-1"); err != nil || id != work.ID {
+        t.Fatalf("ResolveClientID(category) = %d, %v, want %d", id, err, work.ID)
+    }
+    if _, err := s.ResolveClientID(ctx, alice.ID, models.EntityCategory, "t-1"); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("ResolveClientID(unknown) error = %v, want ErrNotFound", err)
+    }
+
+    full, err := s.GetChanges(ctx, alice.ID, empty.Token, models.DefaultSyncLimit)
+    if err != nil {
+        t.Fatalf("GetChanges: %v", err)
+    }
+    if len(full.Tasks) != 1 || full.Tasks[0].ID != task.ID || len(full.Categories) != 1 || full.Categories[0].ID != work.ID {
+        t.Fatalf("GetChanges returned %+v, want alice's task and category", full)
+    }
+    if again, err := s.GetChanges(ctx, alice.ID, full.Token, models.DefaultSyncLimit); err != nil || changeCount(again) != 0 || again.Token != full.Token {
+        t.Fatalf("GetChanges(latest token) = %+v, %v, want nothing and the same token", again, err)
+    }
+
+    // Удаление категории меняет и её задачи
+    if err := s.DeleteCategory(ctx, work.ID, alice.ID, 0); err != nil {
+        t.Fatalf("DeleteCategory: %v", err)
+    }
+    if err := s.CreateNotification(ctx, alice.ID, models.NotificationTaskCreated, models.TaskParams(task)); err != nil {
+        t.Fatalf("CreateNotification: %v", err)
+    }
+    first, err := s.GetChanges(ctx, alice.ID, full.Token, 2)
+    if err != nil || changeCount(first) != 2 || !first.HasMore {
+        t.Fatalf("GetChanges(limit 2) = %+v, %v, want two changes and more", first, err)
+    }
+    rest, err := s.GetChanges(ctx, alice.ID, first.Token, 2)
+    if err != nil || changeCount(rest) != 1 || rest.HasMore {
+        t.Fatalf("GetChanges(rest) = %+v, %v, want one change", rest, err)
+    }
+    delta, err := s.GetChanges(ctx, alice.ID, full.Token, models.DefaultSyncLimit)
+    if err != nil {
+        t.Fatalf("GetChanges(delta): %v", err)
+    }
+    if len(delta.Deleted.Categories) != 1 || delta.Deleted.Categories[0] != work.ID {
+        t.Fatalf("deleted categories = %v, want [%d]", delta.Deleted.Categories, work.ID)
+    }
+    if len(delta.Tasks) != 1 || delta.Tasks[0].CategoryID != nil {
+        t.Fatalf("changed tasks = %+v, want the task without category", delta.Tasks)
+    }
+    if len(delta.Notifications) != 1 || delta.Notifications[0].TaskID != task.ID {
+        t.Fatalf("changed notifications = %+v, want one for the task", delta.Notifications)
+    }
+
+    // Задача в корзине становится надгробием, а её уведомления удаляет
+    // каскадом очистка корзины
+    if err := s.DeleteTask(ctx, task.ID, alice.ID, models.CascadeChildren, 0); err != nil {
+        t.Fatalf("DeleteTask: %v", err)
+    }
+    trashed, err := s.GetChanges(ctx, alice.ID, delta.Token, models.DefaultSyncLimit)
+    if err != nil || len(trashed.Deleted.Tasks) != 1 || changeCount(trashed) != 1 {
+        t.Fatalf("GetChanges(after delete) = %+v, %v, want a task tombstone", trashed, err)
+    }
+    if _, err := s.PurgeTrash(ctx, time.Now().Add(time.Hour)); err != nil {
+        t.Fatalf("PurgeTrash: %v", err)
+    }
+    deleted, err := s.GetChanges(ctx, alice.ID, trashed.Token, models.DefaultSyncLimit)
+    if err != nil || len(deleted.Deleted.Tasks) != 1 || len(deleted.Deleted.Categories) != 1 || len(deleted.Deleted.Notifications) != 1 || changeCount(deleted) != 3 {
+        t.Fatalf("GetChanges(after purge) = %+v, %v, want task, category and notification tombstones", deleted, err)
+    }
+    // Полной синхронизации надгробия не нужны
+    if fresh, err := s.GetChanges(ctx, alice.ID, "", models.DefaultSyncLimit); err != nil || changeCount(fresh) != 0 {
+        t.Fatalf("GetChanges(full) = %+v, %v, want nothing", fresh, err)
+    }
+    if _, err := s.ResolveClientID(ctx, alice.ID, models.EntityTask, "t-1"); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("ResolveClientID(deleted) error = %v, want ErrNotFound", err)
+    }
+
+    var validation *models.ValidationError
+    if _, err := s.GetChanges(ctx, alice.ID, "not a token", models.DefaultSyncLimit); !errors.As(err, &validation) {
+        t.Fatalf("GetChanges(invalid token) error = %v, want ValidationError", err)
+    }
+}
+
+func testTrash(t *testing.T, s models.Store) {
+    ctx := context.Background()
+    alice := mustUser(t, s, "alice@example.com")
+    bob := mustUser(t, s, "bob@example.com")
+    due := time.Now().Add(48 * time.Hour)
+
+    work := mustCategory(t, s, "Work", alice.ID)
+    home, err := s.CreateCategory(ctx, &models.Category{Name: "Home", UserID: alice.ID, ClientID: "c-1"})
+    if err != nil {
+        t.Fatalf("CreateCategory: %v", err)
+    }
+    parent := mustTask(t, s, models.Task{Title: "parent", UserID: alice.ID, DueDate: due, CategoryID: &work.ID, ClientID: "t-1"})
+    child := mustTask(t, s, models.Task{Title: "child", UserID: alice.ID, DueDate: due, ParentID: &parent.ID})
+    moved := mustTask(t, s, models.Task{Title: "moved", UserID: alice.ID, DueDate: due, CategoryID: &work.ID})
+
+    start, err := s.LatestEventID(ctx, alice.ID)
+    if err != nil {
+        t.Fatalf("LatestEventID: %v", err)
+    }
+    if err := s.DeleteTask(ctx, parent.ID, alice.ID, models.CascadeChildren, 0); err != nil {
+        t.Fatalf("DeleteTask: %v", err)
+    }
+    for _, id := range []uint{parent.ID, child.ID} {
+        if _, err := s.GetTask(ctx, id, alice.ID); !errors.Is(err, models.ErrNotFound) {
+            t.Fatalf("GetTask(trashed %d) error = %v, want ErrNotFound", id, err)
+        }
+    }
+    if tasks, _ := s.GetUserTasks(ctx, alice.ID); taskTitles(tasks) != "moved" {
+        t.Fatalf("GetUserTasks = %q, want only moved", taskTitles(tasks))
+    }
+    if _, err := s.CreateTask(ctx, &models.Task{Title: "sub", UserID: alice.ID, DueDate: due, ParentID: &parent.ID}); !errors.Is(err, models.ErrInvalidParent) {
+        t.Fatalf("CreateTask(trashed parent) error = %v, want ErrInvalidParent", err)
+    }
+    // client_id записи в корзине остаётся занятым, но не находится
+    if _, err := s.CreateTask(ctx, &models.Task{Title: "dup", UserID: alice.ID, DueDate: due, ClientID: "t-1"}); !errors.Is(err, models.ErrClientIDTaken) {
+        t.Fatalf("CreateTask(trashed client id) error = %v, want ErrClientIDTaken", err)
+    }
+    if _, err := s.ResolveClientID(ctx, alice.ID, models.EntityTask, "t-1"); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("ResolveClientID(trashed) error = %v, want ErrNotFound", err)
+    }
+    if got, err := s.TrashedTaskByClientID(ctx, alice.ID, "t-1"); err != nil || got.ID != parent.ID || got.DeletedAt == nil {
+        t.Fatalf("TrashedTaskByClientID = %+v, %v, want the trashed parent", got, err)
+    }
+    if _, err := s.TrashedTaskByClientID(ctx, bob.ID, "t-1"); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("TrashedTaskByClientID(foreign) error = %v, want ErrNotFound", err)
+    }
+
+    trash, err := s.ListTrash(ctx, alice.ID)
+    if err != nil || len(trash.Tasks) != 2 || len(trash.Categories) != 0 {
+        t.Fatalf("ListTrash = %+v, %v, want parent and child", trash, err)
+    }
+    for _, task := range trash.Tasks {
+        if task.DeletedAt == nil || !task.DeletedAt.Equal(*trash.Tasks[0].DeletedAt) {
+            t.Fatalf("trashed task %d deleted_at = %v, want the same time for the whole subtree", task.ID, task.DeletedAt)
+        }
+    }
+    if other, _ := s.ListTrash(ctx, bob.ID); len(other.Tasks) != 0 {
+        t.Fatalf("ListTrash(bob) = %+v, want nothing", other)
+    }
+    if _, err := s.RestoreTask(ctx, parent.ID, bob.ID); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("RestoreTask(foreign) error = %v, want ErrNotFound", err)
+    }
+    if _, err := s.RestoreTask(ctx, moved.ID, alice.ID); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("RestoreTask(live) error = %v, want ErrNotFound", err)
+    }
+
+    restored, err := s.RestoreTask(ctx, parent.ID, alice.ID)
+    if err != nil || restored.DeletedAt != nil || restored.Progress == nil || restored.Progress.Total != 1 {
+        t.Fatalf("RestoreTask = %+v, %v, want the parent with its subtask", restored, err)
+    }
+    if got, err := s.GetTask(ctx, child.ID, alice.ID); err != nil || got.ParentID == nil || *got.ParentID != parent.ID {
+        t.Fatalf("GetTask(child) = %+v, %v, want it restored under the parent", got, err)
+    }
+    if _, err := s.RestoreTask(ctx, parent.ID, alice.ID); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("RestoreTask(again) error = %v, want ErrNotFound", err)
+    }
+
+    // Подзадача, восстановленная без родителя, поднимается на верхний уровень
+    if err := s.DeleteTask(ctx, parent.ID, alice.ID, models.CascadeChildren, 0); err != nil {
+        t.Fatalf("DeleteTask: %v", err)
+    }
+    orphan, err := s.RestoreTask(ctx, child.ID, alice.ID)
+    if err != nil || orphan.ParentID != nil {
+        t.Fatalf("RestoreTask(child) = %+v, %v, want a top-level task", orphan, err)
+    }
+
+    // Категория в корзине отпускает задачи и возвращает те, что не перенесены
+    if err := s.DeleteCategory(ctx, work.ID, alice.ID, 0); err != nil {
+        t.Fatalf("DeleteCategory: %v", err)
+    }
+    if _, err := s.GetCategory(ctx, work.ID, alice.ID); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("GetCategory(trashed) error = %v, want ErrNotFound", err)
+    }
+    if got, _ := s.GetTask(ctx, moved.ID, alice.ID); got.CategoryID != nil {
+        t.Fatalf("task kept trashed category %v", *got.CategoryID)
+    }
+    if _, err := s.CreateTask(ctx, &models.Task{Title: "new", UserID: alice.ID, DueDate: due, CategoryID: &work.ID}); !errors.Is(err, models.ErrInvalidCategory) {
+        t.Fatalf("CreateTask(trashed category) error = %v, want ErrInvalidCategory", err)
+    }
+    if _, err := s.UpdateTaskCategory(ctx, orphan.ID, work.ID, alice.ID, 0); !errors.Is(err, models.ErrInvalidCategory) {
+        t.Fatalf("UpdateTaskCategory(trashed category) error = %v, want ErrInvalidCategory", err)
+    }
+    if _, err := s.PatchTask(ctx, moved.ID, alice.ID, models.TaskPatch{CategoryID: &home.ID}); err != nil {
+        t.Fatalf("PatchTask(category): %v", err)
+    }
+    if _, err := s.RestoreCategory(ctx, work.ID, bob.ID); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("RestoreCategory(foreign) error = %v, want ErrNotFound", err)
+    }
+    category, err := s.RestoreCategory(ctx, work.ID, alice.ID)
+    if err != nil || category.DeletedAt != nil {
+        t.Fatalf("RestoreCategory = %+v, %v", category, err)
+    }
+    if got, _ := s.GetTask(ctx, moved.ID, alice.ID); got.CategoryID == nil || *got.CategoryID != home.ID {
+        t.Fatalf("RestoreCategory moved a recategorized task back: %+v", got)
+    }
+    if _, err := s.RestoreTask(ctx, parent.ID, alice.ID); err != nil {
+        t.Fatalf("RestoreTask(parent): %v", err)
+    }
+    if got, _ := s.GetTask(ctx, parent.ID, alice.ID); got.CategoryID == nil || *got.CategoryID != work.ID {
+        t.Fatalf("RestoreCategory did not relink a task from the trash: %+v", got)
+    }
+    if _, err := s.TrashedTaskByClientID(ctx, alice.ID, "t-1"); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("TrashedTaskByClientID(restored) error = %v, want ErrNotFound", err)
+    }
+
+    events, err := s.ListEvents(ctx, alice.ID, start, models.MaxEventBatch)
+    if err != nil {
+        t.Fatalf("ListEvents: %v", err)
+    }
+    seen := map[string]bool{}
+    for _, e := range events {
+        seen[e.Type] = true
+    }
+    for _, want := range []string{models.EventTaskDeleted, models.EventTaskRestored, models.EventCategoryDeleted, models.EventCategoryRestored} {
+        if !seen[want] {
+            t.Errorf("no %s event in %+v", want, events)
+        }
+    }
+
+    // Очистка удаляет только то, что пролежало в корзине дольше срока
+    if err := s.DeleteTask(ctx, moved.ID, alice.ID, models.CascadeChildren, 0); err != nil {
+        t.Fatalf("DeleteTask: %v", err)
+    }
+    if err := s.DeleteCategory(ctx, home.ID, alice.ID, 0); err != nil {
+        t.Fatalf("DeleteCategory: %v", err)
+    }
+    if got, err := s.TrashedCategoryByClientID(ctx, alice.ID, "c-1"); err != nil || got.ID != home.ID || got.DeletedAt == nil {
+        t.Fatalf("TrashedCategoryByClientID = %+v, %v, want the trashed category", got, err)
+    }
+    if purged, err := s.PurgeTrash(ctx, time.Now().Add(-time.Hour)); err != nil || purged != 0 {
+        t.Fatalf("PurgeTrash(past) = %d, %v, want 0", purged, err)
+    }
+    if purged, err := s.PurgeTrash(ctx, time.Now().Add(time.Hour)); err != nil || purged != 2 {
+        t.Fatalf("PurgeTrash = %d, %v, want 2", purged, err)
+    }
+    if trash, _ := s.ListTrash(ctx, alice.ID); len(trash.Tasks) != 0 || len(trash.Categories) != 0 {
+        t.Fatalf("ListTrash after purge = %+v, want nothing", trash)
+    }
+    if _, err := s.RestoreTask(ctx, moved.ID, alice.ID); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("RestoreTask(purged) error = %v, want ErrNotFound", err)
+    }
+}
+
+// historyActions перечисляет действия страницы истории от новых к старым.
+func historyActions(page *models.HistoryPage) string {
+    actions := make([]string, len(page.Events))
+    for i, e := range page.Events {
+        actions[i] = e.Action
+    }
+    return strings.Join(actions, ",")
+}
+
+// changedValues разбирает старое и новое значение поля из записи истории.
+func changedValues(t *testing.T, e models.TaskEvent, field string, old, new interface{}) {
+    t.Helper()
+    change, ok := e.Changes[field]
+    if !ok {
+        t.Fatalf("event %+v has no %s change", e, field)
+    }
+    if err := json.Unmarshal(change.Old, old); err != nil {
+        t.Fatalf("%s old value %s: %v", field, change.Old, err)
+    }
+    if err := json.Unmarshal(change.New, new); err != nil {
+        t.Fatalf("%s new value %s: %v", field, change.New, err)
+    }
+}
+
+func testHistory(t *testing.T, s models.Store) {
+    alice := mustUser(t, s, "alice@example.com")
+    bob := mustUser(t, s, "bob@example.com")
+    ctx := models.WithActor(context.Background(), models.Actor{UserID: alice.ID, Source: models.SourceAPI})
+    due := time.Now().Add(48 * time.Hour)
+
+    task, err := s.CreateTask(ctx, &models.Task{Title: "draft", UserID: alice.ID, DueDate: due})
+    if err != nil {
+        t.Fatalf("CreateTask: %v", err)
+    }
+    other := mustTask(t, s, models.Task{Title: "other", UserID: alice.ID, DueDate: due})
+
+    title := "final"
+    if _, err := s.PatchTask(ctx, task.ID, alice.ID, models.TaskPatch{Title: &title}); err != nil {
+        t.Fatalf("PatchTask: %v", err)
+    }
+    // Изменение без отслеживаемых полей в историю не попадает
+    if _, err := s.PatchTask(ctx, task.ID, alice.ID, models.TaskPatch{Title: &title}); err != nil {
+        t.Fatalf("PatchTask(same title): %v", err)
+    }
+    done := true
+    syncCtx := models.WithActor(context.Background(), models.Actor{UserID: alice.ID, Source: models.SourceSync})
+    if _, err := s.PatchTask(syncCtx, task.ID, alice.ID, models.TaskPatch{Completed: &done}); err != nil {
+        t.Fatalf("PatchTask(sync): %v", err)
+    }
+    if err := s.DeleteTask(ctx, task.ID, alice.ID, models.CascadeChildren, 0); err != nil {
+        t.Fatalf("DeleteTask: %v", err)
+    }
+    if _, err := s.RestoreTask(ctx, task.ID, alice.ID); err != nil {
+        t.Fatalf("RestoreTask: %v", err)
+    }
+    if err := s.DeleteTask(ctx, task.ID, alice.ID, models.CascadeChildren, 0); err != nil {
+        t.Fatalf("DeleteTask: %v", err)
+    }
+    schedulerCtx := models.WithActor(context.Background(), models.Actor{Source: models.SourceScheduler})
+    if _, err := s.PurgeTrash(schedulerCtx, time.Now().Add(time.Hour)); err != nil {
+        t.Fatalf("PurgeTrash: %v", err)
+    }
+
+    // История остаётся после окончательного удаления задачи
+    page, err := s.ListTaskEvents(ctx, alice.ID, models.HistoryFilter{TaskID: task.ID})
+    if err != nil {
+        t.Fatalf("ListTaskEvents: %v", err)
+    }
+    if got := historyActions(page); got != "purged,deleted,restored,deleted,updated,updated,created" {
+        t.Fatalf("history = %s", got)
+    }
+    for _, e := range page.Events {
+        if e.TaskID != task.ID || e.UserID != alice.ID || e.CreatedAt.IsZero() {
+            t.Fatalf("history event %+v", e)
+        }
+    }
+
+    created := page.Events[6]
+    if created.Source != models.SourceAPI || created.ActorID == nil || *created.ActorID != alice.ID {
+        t.Fatalf("created event actor = %v from %s", created.ActorID, created.Source)
+    }
+    var oldTitle, newTitle *string
+    changedValues(t, created, "title", &oldTitle, &newTitle)
+    if oldTitle != nil || newTitle == nil || *newTitle != "draft" {
+        t.Fatalf("created title change = %v -> %v", oldTitle, newTitle)
+    }
+    if _, ok := created.Changes["category_id"]; ok {
+        t.Fatalf("created event lists an empty category: %+v", created.Changes)
+    }
+
+    renamed := page.Events[5]
+    if len(renamed.Changes) != 1 {
+        t.Fatalf("rename changes = %+v, want only the title", renamed.Changes)
+    }
+    changedValues(t, renamed, "title", &oldTitle, &newTitle)
+    if oldTitle == nil || *oldTitle != "draft" || newTitle == nil || *newTitle != "final" {
+        t.Fatalf("rename = %v -> %v", oldTitle, newTitle)
+    }
+
+    completed := page.Events[4]
+    if completed.Source != models.SourceSync || completed.ActorID == nil || *completed.ActorID != alice.ID {
+        t.Fatalf("sync event actor = %v from %s", completed.ActorID, completed.Source)
+    }
+    var wasDone, isDone bool
+    changedValues(t, completed, "completed", &wasDone, &isDone)
+    if wasDone || !isDone {
+        t.Fatalf("completed change = %v -> %v", wasDone, isDone)
+    }
+
+    purged := page.Events[0]
+    if purged.Source != models.SourceScheduler || purged.ActorID != nil || len(purged.Changes) != 0 {
+        t.Fatalf("purge event = %+v, want a scheduler event without actor and changes", purged)
+    }
+
+    // Без автора в контексте изменение считается пришедшим через API
+    feed, err := s.ListTaskEvents(ctx, alice.ID, models.HistoryFilter{})
+    if err != nil {
+        t.Fatalf("ListTaskEvents(feed): %v", err)
+    }
+    if len(feed.Events) != 8 || feed.Events[6].TaskID != other.ID || feed.Events[6].Source != models.SourceAPI || feed.Events[6].ActorID != nil {
+        t.Fatalf("activity = %+v, want both tasks with other created without actor", feed.Events)
+    }
+
+    first, err := s.ListTaskEvents(ctx, alice.ID, models.HistoryFilter{Limit: 5})
+    if err != nil || len(first.Events) != 5 || first.NextCursor == "" {
+        t.Fatalf("ListTaskEvents(limit 5) = %+v, %v", first, err)
+    }
+    second, err := s.ListTaskEvents(ctx, alice.ID, models.HistoryFilter{Limit: 5, Cursor: first.NextCursor})
+    if err != nil || len(second.Events) != 3 || second.NextCursor != "" || second.Events[0].ID >= first.Events[4].ID {
+        t.Fatalf("ListTaskEvents(second page) = %+v, %v", second, err)
+    }
+    if _, err := s.ListTaskEvents(ctx, alice.ID, models.HistoryFilter{Cursor: "%%%"}); !errors.Is(err, models.ErrInvalidCursor) {
+        t.Fatalf("ListTaskEvents(bad cursor) error = %v, want ErrInvalidCursor", err)
+    }
+
+    for _, filter := range []models.HistoryFilter{{TaskID: task.ID}, {}} {
+        if page, err := s.ListTaskEvents(ctx, bob.ID, filter); err != nil || len(page.Events) != 0 {
+            t.Fatalf("ListTaskEvents(bob, %+v) = %+v, %v, want nothing", filter, page, err)
+        }
+    }
+}
+
+func testNotifications(t *testing.T, s models.Store) {
+    ctx := context.Background()
+    alice := mustUser(t, s, "alice@example.com")
+    bob := mustUser(t, s, "bob@example.com")
+
+    overdue := mustTask(t, s, models.Task{Title: "overdue", UserID: alice.ID, DueDate: time.Now().Add(-time.Hour)})
+    soon := mustTask(t, s, models.Task{Title: "soon", UserID: alice.ID, DueDate: time.Now().Add(48 * time.Hour)})
+    mustTask(t, s, models.Task{Title: "later", UserID: alice.ID, DueDate: time.Now().Add(10 * 24 * time.Hour)})
+    done := mustTask(t, s, models.Task{Title: "done", UserID: bob.ID, DueDate: time.Now().Add(-time.Hour)})
+    done.Completed = true
+    if _, err := s.UpdateTask(ctx, done); err != nil {
+        t.Fatalf("UpdateTask: %v", err)
+    }
+
+    if fired, err := s.FireReminders(ctx); err != nil || fired != 2 {
+        t.Fatalf("FireReminders = %d, %v; want 2", fired, err)
+    }
+
+    notifications, err := s.GetUserNotifications(ctx, alice.ID)
+    if err != nil {
+        t.Fatalf("GetUserNotifications: %v", err)
+    }
+    kinds := map[uint]models.NotificationType{}
+    for _, n := range notifications {
+        if n.Read {
+            t.Fatal("new notification is already read")
+        }
+        if n.Params.TaskID != n.TaskID || n.Params.DueDate == nil {
+            t.Fatalf("notification params = %+v", n.Params)
+        }
+        kinds[n.TaskID] = n.Type
+    }
+    if len(notifications) != 2 {
+        t.Fatalf("FireReminders created %d notifications for alice, want 2", len(notifications))
+    }
+    if kinds[overdue.ID] != models.NotificationTaskOverdue {
+        t.Fatalf("overdue notification type = %q", kinds[overdue.ID])
+    }
+    if kinds[soon.ID] != models.NotificationTaskDueSoon {
+        t.Fatalf("due soon notification type = %q", kinds[soon.ID])
+    }
+
+    bobNotifications, _ := s.GetUserNotifications(ctx, bob.ID)
+    if len(bobNotifications) != 0 {
+        t.Fatal("FireReminders notified about a completed task")
+    }
+
+    // Каждая ступень срабатывает один раз
+    if fired, err := s.FireReminders(ctx); err != nil || fired != 0 {
+        t.Fatalf("second FireReminders = %d, %v; want 0", fired, err)
+    }
+
+    // Перенос срока планирует напоминания заново, в том числе для уже
+    // сработавшей ступени
+    soon.DueDate = models.StartOfDay(time.Now(), time.UTC)
+    soon.AllDay = true
+    if _, err := s.UpdateTask(ctx, soon); err != nil {
+        t.Fatalf("UpdateTask: %v", err)
+    }
+    overdue.DueDate = time.Now().Add(-2 * time.Hour)
+    if _, err := s.UpdateTask(ctx, overdue); err != nil {
+        t.Fatalf("UpdateTask: %v", err)
+    }
+    if fired, err := s.FireReminders(ctx); err != nil || fired != 2 {
+        t.Fatalf("FireReminders after rescheduling = %d, %v; want 2", fired, err)
+    }
+    notifications, _ = s.GetUserNotifications(ctx, alice.ID)
+    if len(notifications) != 4 {
+        t.Fatalf("alice has %d notifications, want 4", len(notifications))
+    }
+    if notifications[0].Type != models.NotificationTaskOverdue && notifications[1].Type != models.NotificationTaskOverdue {
+        t.Fatalf("rescheduled overdue task was not reminded again: %+v", notifications[:2])
+    }
+    dueToday := false
+    for _, n := range notifications {
+        dueToday = dueToday || n.Type == models.NotificationTaskDueToday && n.Params.Title == "soon"
+    }
+    if !dueToday {
+        t.Fatalf("task moved to today got no reminder: %+v", notifications)
+    }
+
+    if err := s.MarkNotificationAsRead(ctx, notifications[0].ID, alice.ID); err != nil {
+        t.Fatalf("MarkNotificationAsRead: %v", err)
+    }
+    notifications, _ = s.GetUserNotifications(ctx, alice.ID)
+    read := 0
+    for _, n := range notifications {
+        if n.Read {
+            read++
+        }
+    }
+    if read != 1 {
+        t.Fatalf("%d notifications are read, want 1", read)
+    }
+}
+
+func testRecurrence(t *testing.T, s models.Store) {
+    ctx := context.Background()
+    alice := mustUser(t, s, "alice@example.com")
+
+    due := time.Date(2030, 1, 7, 9, 0, 0, 0, time.UTC)
+    task := mustTask(t, s, models.Task{
+        Title:           "weekly review",
+        UserID:          alice.ID,
+        DueDate:         due,
+        Recurrence:      "FREQ=WEEKLY",
+        RecurrenceStart: &due,
+    })
+    if task.Recurrence != "FREQ=WEEKLY" || task.RecurrenceStart == nil || !task.RecurrenceStart.Equal(due) {
+        t.Fatalf("CreateTask did not persist recurrence: %+v", task)
+    }
+
+    task.Completed = true
+    completed, err := s.UpdateTask(ctx, task)
+    if err != nil {
+        t.Fatalf("UpdateTask: %v", err)
+    }
+    next, err := models.NextOccurrence(completed, time.UTC)
+    if err != nil || next == nil {
+        t.Fatalf("NextOccurrence = %v, %v", next, err)
+    }
+
+    created, err := s.CreateNextOccurrence(ctx, completed, next)
+    if err != nil {
+        t.Fatalf("CreateNextOccurrence: %v", err)
+    }
+    if created.Completed || !created.DueDate.Equal(due.AddDate(0, 0, 7)) || created.Recurrence != "FREQ=WEEKLY" {
+        t.Fatalf("CreateNextOccurrence returned %+v", created)
+    }
+
+    old, err := s.GetTask(ctx, completed.ID, alice.ID)
+    if err != nil {
+        t.Fatalf("GetTask: %v", err)
+    }
+    if old.Recurrence != "" || old.RecurrenceStart != nil || !old.Completed {
+        t.Fatal("completed occurrence still carries the recurrence rule")
+    }
+}
+
+func testSubtasks(t *testing.T, s models.Store) {
+    ctx := context.Background()
+    alice := mustUser(t, s, "alice@example.com")
+    due := time.Now().Add(72 * time.Hour)
+
+    root := mustTask(t, s, models.Task{Title: "root", UserID: alice.ID, DueDate: due})
+    parent := mustTask(t, s, models.Task{Title: "parent", UserID: alice.ID, DueDate: due, ParentID: &root.ID, AutoComplete: true})
+    first := mustTask(t, s, models.Task{Title: "first", UserID: alice.ID, DueDate: due, ParentID: &parent.ID})
+    second := mustTask(t, s, models.Task{Title: "second", UserID: alice.ID, DueDate: due, ParentID: &parent.ID})
+    if first.Position >= second.Position {
+        t.Fatalf("new subtasks are not appended: positions %d, %d", first.Position, second.Position)
+    }
+
+    got, err := s.GetTask(ctx, parent.ID, alice.ID)
+    if err != nil {
+        t.Fatalf("GetTask: %v", err)
+    }
+    if got.Progress == nil || got.Progress.Total != 2 || got.Progress.Completed != 0 {
+        t.Fatalf("parent progress = %+v, want 0/2", got.Progress)
+    }
+
+    if err := s.ReorderSubtasks(ctx, parent.ID, alice.ID, []uint{second.ID}); !errors.Is(err, models.ErrInvalidOrder) {
+        t.Fatalf("ReorderSubtasks(partial) error = %v, want ErrInvalidOrder", err)
+    }
+    if err := s.ReorderSubtasks(ctx, parent.ID, alice.ID, []uint{second.ID, first.ID}); err != nil {
+        t.Fatalf("ReorderSubtasks: %v", err)
+    }
+    subtasks, err := s.GetSubtasks(ctx, parent.ID, alice.ID)
+    if err != nil {
+        t.Fatalf("GetSubtasks: %v", err)
+    }
+    if got := taskTitles(subtasks); got != "second,first" {
+        t.Fatalf("GetSubtasks order = %s, want second,first", got)
+    }
+
+    for _, child := range []*models.Task{first, second} {
+        child.Completed = true
+        if _, err := s.UpdateTask(ctx, child); err != nil {
+            t.Fatalf("UpdateTask: %v", err)
+        }
+    }
+    if err := s.SyncParentCompletion(ctx, parent.ID); err != nil {
+        t.Fatalf("SyncParentCompletion: %v", err)
+    }
+    got, _ = s.GetTask(ctx, parent.ID, alice.ID)
+    if !got.Completed || got.Progress.Completed != 2 {
+        t.Fatalf("parent with auto_complete was not completed: %+v", got)
+    }
+    rootTask, _ := s.GetTask(ctx, root.ID, alice.ID)
+    if rootTask.Completed {
+        t.Fatal("root without auto_complete was completed")
+    }
+
+    if err := s.DeleteTask(ctx, parent.ID, alice.ID, models.PromoteChildren, 0); err != nil {
+        t.Fatalf("DeleteTask(promote): %v", err)
+    }
+    promoted, err := s.GetTask(ctx, first.ID, alice.ID)
+    if err != nil {
+        t.Fatalf("promoted subtask was deleted: %v", err)
+    }
+    if promoted.ParentID == nil || *promoted.ParentID != root.ID {
+        t.Fatalf("promoted subtask parent = %v, want %d", promoted.ParentID, root.ID)
+    }
+
+    if err := s.DeleteTask(ctx, root.ID, alice.ID, models.CascadeChildren, 0); err != nil {
+        t.Fatalf("DeleteTask(cascade): %v", err)
+    }
+    if _, err := s.GetTask(ctx, second.ID, alice.ID); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("cascade delete left subtask behind: %v", err)
+    }
+}
+
+func testListTasks(t *testing.T, s models.Store) {
+    ctx := context.Background()
+    alice := mustUser(t, s, "alice@example.com")
+    bob := mustUser(t, s, "bob@example.com")
+    home := mustCategory(t, s, "Home", alice.ID)
+
+    base := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+    for i := 0; i < 7; i++ {
+        task := models.Task{
+            Title:    fmt.Sprintf("task %d", i),
+            UserID:   alice.ID,
+            DueDate:  base.Add(time.Duration(i) * time.Hour),
+            Priority: models.Priority(i % 3),
+        }
+        if i%2 == 0 {
+            task.CategoryID = &home.ID
+        }
+        mustTask(t, s, task)
+    }
+    overdue := mustTask(t, s, models.Task{Title: "Pay invoice", Description: "monthly", UserID: alice.ID, DueDate: time.Now().Add(-time.Hour)})
+    mustTask(t, s, models.Task{Title: "bob", UserID: bob.ID, DueDate: base})
+
+    list := func(filter models.TaskFilter) *models.TaskPage {
+        t.Helper()
+        page, err := s.ListTasks(ctx, alice.ID, filter)
+        if err != nil {
+            t.Fatalf("ListTasks(%+v): %v", filter, err)
+        }
+        return page
+    }
+
+    // Постраничный обход должен вернуть каждую задачу ровно один раз и в порядке сортировки
+    for _, sortName := range []string{"due_date", "-due_date", "priority", "-priority", "title", "-created_at"} {
+        all := list(models.TaskFilter{Sort: sortName, Limit: models.MaxTaskPageSize})
+        var paged []models.Task
+        filter := models.TaskFilter{Sort: sortName, Limit: 3}
+        for pages := 0; ; pages++ {
+            if pages > 10 {
+                t.Fatalf("sort %s: pagination does not terminate", sortName)
+            }
+            page := list(filter)
+            paged = append(paged, page.Tasks...)
+            if page.NextCursor == "" {
+                break
+            }
+            filter.Cursor = page.NextCursor
+        }
+        if got, want := taskTitles(paged), taskTitles(all.Tasks); got != want {
+            t.Fatalf("sort %s: paged = %s, want %s", sortName, got, want)
+        }
+        if len(paged) != 8 {
+            t.Fatalf("sort %s: paged %d tasks, want 8", sortName, len(paged))
+        }
+    }
+
+    if page := list(models.TaskFilter{CategoryID: &home.ID}); len(page.Tasks) != 4 {
+        t.Fatalf("category filter returned %d tasks, want 4", len(page.Tasks))
+    }
+    high := models.High
+    if page := list(models.TaskFilter{Priority: &high}); len(page.Tasks) != 2 {
+        t.Fatalf("priority filter returned %d tasks, want 2", len(page.Tasks))
+    }
+    yes := true
+    if page := list(models.TaskFilter{Overdue: &yes}); taskTitles(page.Tasks) != overdue.Title {
+        t.Fatalf("overdue filter = %s", taskTitles(page.Tasks))
+    }
+    from, to := base.Add(time.Hour), base.Add(3*time.Hour)
+    if page := list(models.TaskFilter{DueFrom: &from, DueTo: &to}); len(page.Tasks) != 3 {
+        t.Fatalf("due range filter returned %d tasks, want 3", len(page.Tasks))
+    }
+    if page := list(models.TaskFilter{Query: "MONTH"}); taskTitles(page.Tasks) != overdue.Title {
+        t.Fatalf("q filter = %s", taskTitles(page.Tasks))
+    }
+
+    if _, err := s.ListTasks(ctx, alice.ID, models.TaskFilter{Sort: "bogus"}); !errors.Is(err, models.ErrInvalidSort) {
+        t.Fatalf("ListTasks(bogus sort) error = %v, want ErrInvalidSort", err)
+    }
+    if _, err := s.ListTasks(ctx, alice.ID, models.TaskFilter{Cursor: "garbage"}); !errors.Is(err, models.ErrInvalidCursor) {
+        t.Fatalf("ListTasks(bad cursor) error = %v, want ErrInvalidCursor", err)
+    }
+}
+
+func testSearch(t *testing.T, s models.Store) {
+    ctx := context.Background()
+    alice := mustUser(t, s, "alice@example.com")
+    bob := mustUser(t, s, "bob@example.com")
+    due := time.Now().Add(72 * time.Hour)
+
+    mustCategory(t, s, "Invoices", alice.ID)
+    invoice := mustTask(t, s, models.Task{Title: "Send invoice", Description: "Monthly invoicing for clients", UserID: alice.ID, DueDate: due})
+    report := mustTask(t, s, models.Task{Title: "Отчёт за месяц", Description: "Подготовить отчёт", UserID: alice.ID, DueDate: due})
+    mustTask(t, s, models.Task{Title: "Send invoice", UserID: bob.ID, DueDate: due})
+
+    results, err := s.Search(ctx, alice.ID, "invoic", 10)
+    if err != nil {
+        t.Fatalf("Search: %v", err)
+    }
+    if len(results.Tasks) != 1 || results.Tasks[0].Task.ID != invoice.ID {
+        t.Fatalf("Search(invoic) tasks = %+v, want only task %d", results.Tasks, invoice.ID)
+    }
+    if !strings.Contains(results.Tasks[0].TitleHighlight, models.HighlightStart) {
+        t.Fatalf("title highlight %q has no marks", results.Tasks[0].TitleHighlight)
+    }
+    if len(results.Categories) != 1 || results.Categories[0].Category.Name != "Invoices" {
+        t.Fatalf("Search(invoic) categories = %+v", results.Categories)
+    }
+
+    results, err = s.Search(ctx, alice.ID, "отчёт", 10)
+    if err != nil {
+        t.Fatalf("Search: %v", err)
+    }
+    if len(results.Tasks) != 1 || results.Tasks[0].Task.ID != report.ID {
+        t.Fatalf("Search(отчёт) tasks = %+v, want only task %d", results.Tasks, report.ID)
+    }
+
+    // Подсветка экранирует разметку из текста задачи
+    mustTask(t, s, models.Task{Title: `<img src=x onerror="alert(1)"> markup`, UserID: alice.ID, DueDate: due})
+    results, err = s.Search(ctx, alice.ID, "markup", 10)
+    if err != nil || len(results.Tasks) != 1 {
+        t.Fatalf("Search(markup) = %+v, %v", results, err)
+    }
+    if got := results.Tasks[0].TitleHighlight; strings.Contains(got, "<img") || !strings.Contains(got, models.HighlightStart+"markup"+models.HighlightStop) {
+        t.Fatalf("title highlight = %q, want escaped markup", got)
+    }
+
+    results, err = s.Search(ctx, alice.ID, "&|!", 10)
+    if err != nil {
+        t.Fatalf("Search(operators): %v", err)
+    }
+    if len(results.Tasks) != 0 {
+        t.Fatal("Search without words returned results")
+    }
+}
+
+func testSessions(t *testing.T, s models.Store) {
+    ctx := context.Background()
+    user := mustUser(t, s, "a@example.com")
+    other := mustUser(t, s, "b@example.com")
+    expires := time.Now().Add(time.Hour)
+
+    session, err := s.CreateSession(ctx, user.ID, "test", models.HashToken("r1"), expires)
+    if err != nil {
+        t.Fatalf("CreateSession: %v", err)
+    }
+
+    rotated, err := s.RotateRefreshToken(ctx, models.HashToken("r1"), models.HashToken("r2"), expires)
+    if err != nil {
+        t.Fatalf("RotateRefreshToken: %v", err)
+    }
+    if rotated.ID != session.ID || rotated.UserID != user.ID {
+        t.Fatalf("RotateRefreshToken returned %+v", rotated)
+    }
+
+    if _, err := s.RotateRefreshToken(ctx, models.HashToken("unknown"), models.HashToken("x"), expires); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("RotateRefreshToken(unknown) error = %v, want ErrNotFound", err)
+    }
+
+    // Повторное использование старого токена отзывает сессию вместе с новым токеном
+    if _, err := s.RotateRefreshToken(ctx, models.HashToken("r1"), models.HashToken("r3"), expires); !errors.Is(err, models.ErrRefreshTokenReused) {
+        t.Fatalf("reused token error = %v, want ErrRefreshTokenReused", err)
+    }
+    if _, err := s.RotateRefreshToken(ctx, models.HashToken("r2"), models.HashToken("r3"), expires); !errors.Is(err, models.ErrSessionRevoked) {
+        t.Fatalf("token of revoked session error = %v, want ErrSessionRevoked", err)
+    }
+    got, err := s.GetSession(ctx, session.ID)
+    if err != nil {
+        t.Fatalf("GetSession: %v", err)
+    }
+    if got.RevokedAt == nil {
+        t.Fatal("session not revoked after token reuse")
+    }
+
+    expired, err := s.CreateSession(ctx, user.ID, "test", models.HashToken("old"), time.Now().Add(-time.Minute))
+    if err != nil {
+        t.Fatalf("CreateSession: %v", err)
+    }
+    if _, err := s.RotateRefreshToken(ctx, models.HashToken("old"), models.HashToken("new"), expires); !errors.Is(err, models.ErrRefreshTokenExpired) {
+        t.Fatalf("expired token error = %v, want ErrRefreshTokenExpired", err)
+    }
+
+    if err := s.RevokeSession(ctx, expired.ID, other.ID); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("RevokeSession by another user error = %v, want ErrNotFound", err)
+    }
+    if err := s.RevokeSession(ctx, expired.ID, user.ID); err != nil {
+        t.Fatalf("RevokeSession: %v", err)
+    }
+
+    first, _ := s.CreateSession(ctx, user.ID, "a", models.HashToken("a"), expires)
+    second, _ := s.CreateSession(ctx, user.ID, "b", models.HashToken("b"), expires)
+    foreign, _ := s.CreateSession(ctx, other.ID, "c", models.HashToken("c"), expires)
+    if err := s.RevokeUserSessions(ctx, user.ID); err != nil {
+        t.Fatalf("RevokeUserSessions: %v", err)
+    }
+    for _, id := range []uint{first.ID, second.ID} {
+        if got, _ := s.GetSession(ctx, id); got == nil || got.RevokedAt == nil {
+            t.Fatalf("session %d not revoked by RevokeUserSessions", id)
+        }
+    }
+    if got, _ := s.GetSession(ctx, foreign.ID); got == nil || got.RevokedAt != nil {
+        t.Fatal("RevokeUserSessions revoked another user's session")
+    }
+}
+
+// testOwnership проверяет, что методы, изменяющие данные, не трогают чужие
+// записи и отвечают на них так же, как на несуществующие.
+func testOwnership(t *testing.T, s models.Store) {
+    ctx := context.Background()
+    alice := mustUser(t, s, "alice@example.com")
+    bob := mustUser(t, s, "bob@example.com")
+    aliceCategory := mustCategory(t, s, "alice", alice.ID)
+    bobCategory := mustCategory(t, s, "bob", bob.ID)
+    task := mustTask(t, s, models.Task{Title: "alice", UserID: alice.ID, CategoryID: &aliceCategory.ID, DueDate: time.Now()})
+
+    if _, err := s.CreateTask(ctx, &models.Task{Title: "x", UserID: bob.ID, CategoryID: &aliceCategory.ID, DueDate: time.Now()}); !errors.Is(err, models.ErrInvalidCategory) {
+        t.Fatalf("CreateTask with foreign category error = %v, want ErrInvalidCategory", err)
+    }
+    if _, err := s.CreateTask(ctx, &models.Task{Title: "x", UserID: bob.ID, ParentID: &task.ID, DueDate: time.Now()}); !errors.Is(err, models.ErrInvalidParent) {
+        t.Fatalf("CreateTask with foreign parent error = %v, want ErrInvalidParent", err)
+    }
+
+    hijack := *task
+    hijack.UserID = bob.ID
+    hijack.CategoryID = nil
+    hijack.Title = "hijacked"
+    if _, err := s.UpdateTask(ctx, &hijack); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("UpdateTask by another user error = %v, want ErrNotFound", err)
+    }
+    update := *task
+    update.CategoryID = &bobCategory.ID
+    if _, err := s.UpdateTask(ctx, &update); !errors.Is(err, models.ErrInvalidCategory) {
+        t.Fatalf("UpdateTask with foreign category error = %v, want ErrInvalidCategory", err)
+    }
+    if _, err := s.UpdateTaskCategory(ctx, task.ID, bobCategory.ID, alice.ID, 0); !errors.Is(err, models.ErrInvalidCategory) {
+        t.Fatalf("UpdateTaskCategory with foreign category error = %v, want ErrInvalidCategory", err)
+    }
+    if err := s.DeleteTask(ctx, task.ID, bob.ID, models.CascadeChildren, 0); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("DeleteTask by another user error = %v, want ErrNotFound", err)
+    }
+    if err := s.DeleteCategory(ctx, aliceCategory.ID, bob.ID, 0); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("DeleteCategory by another user error = %v, want ErrNotFound", err)
+    }
+
+    got, err := s.GetTask(ctx, task.ID, alice.ID)
+    if err != nil {
+        t.Fatalf("GetTask after foreign writes: %v", err)
+    }
+    if got.Title != task.Title || got.CategoryID == nil || *got.CategoryID != aliceCategory.ID {
+        t.Fatalf("foreign writes changed the task: %+v", got)
+    }
+
+    if _, err := s.UpdateTaskCategory(ctx, task.ID, 0, alice.ID, 0); err != nil {
+        t.Fatalf("UpdateTaskCategory(0): %v", err)
+    }
+    if got, _ := s.GetTask(ctx, task.ID, alice.ID); got.CategoryID != nil {
+        t.Fatalf("UpdateTaskCategory(0) left category %d", *got.CategoryID)
+    }
+
+    if err := s.CreateNotification(ctx, alice.ID, models.NotificationTaskCreated, models.TaskParams(task)); err != nil {
+        t.Fatalf("CreateNotification: %v", err)
+    }
+    notifications, _ := s.GetUserNotifications(ctx, alice.ID)
+    if err := s.MarkNotificationAsRead(ctx, notifications[0].ID, bob.ID); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("MarkNotificationAsRead by another user error = %v, want ErrNotFound", err)
+    }
+    notifications, _ = s.GetUserNotifications(ctx, alice.ID)
+    if notifications[0].Read {
+        t.Fatal("another user marked the notification as read")
+    }
+}
+
+func testEvents(t *testing.T, s models.Store) {
+    ctx := context.Background()
+    user := mustUser(t, s, "a@example.com")
+    other := mustUser(t, s, "b@example.com")
+
+    start, err := s.LatestEventID(ctx, user.ID)
+    if err != nil {
+        t.Fatalf("LatestEventID: %v", err)
+    }
+    parent := mustTask(t, s, models.Task{Title: "parent", UserID: user.ID, DueDate: time.Now()})
+    child := mustTask(t, s, models.Task{Title: "child", UserID: user.ID, ParentID: &parent.ID, DueDate: time.Now()})
+    parent.Title = "renamed"
+    if _, err := s.UpdateTask(ctx, parent); err != nil {
+        t.Fatalf("UpdateTask: %v", err)
+    }
+    if err := s.CreateNotification(ctx, user.ID, models.NotificationReminder, models.TaskParams(parent)); err != nil {
+        t.Fatalf("CreateNotification: %v", err)
+    }
+    if err := s.DeleteTask(ctx, parent.ID, user.ID, models.CascadeChildren, 0); err != nil {
+        t.Fatalf("DeleteTask: %v", err)
+    }
+
+    events, err := s.ListEvents(ctx, user.ID, start, models.MaxEventBatch)
+    if err != nil {
+        t.Fatalf("ListEvents: %v", err)
+    }
+    want := []string{
+        models.EventTaskCreated, models.EventTaskCreated, models.EventTaskUpdated,
+        models.EventNotificationCreated, models.EventTaskDeleted, models.EventTaskDeleted,
+    }
+    if len(events) != len(want) {
+        t.Fatalf("ListEvents returned %d events, want %d: %+v", len(events), len(want), events)
+    }
+    deleted := map[uint]bool{}
+    for i, e := range events {
+        if e.Type != want[i] {
+            t.Errorf("event %d type = %q, want %q", i, e.Type, want[i])
+        }
+        if i > 0 && e.ID <= events[i-1].ID {
+            t.Errorf("event ids are not increasing: %d after %d", e.ID, events[i-1].ID)
+        }
+        if e.Type == models.EventTaskDeleted {
+            var data models.TaskEventData
+            if err := json.Unmarshal(e.Data, &data); err != nil {
+                t.Fatalf("decode task event: %v", err)
+            }
+            deleted[data.ID] = true
+        }
+    }
+    if !deleted[parent.ID] || !deleted[child.ID] {
+        t.Errorf("task.deleted events cover %v, want %d and %d", deleted, parent.ID, child.ID)
+    }
+    var n models.Notification
+    if err := json.Unmarshal(events[3].Data, &n); err != nil || n.Type != models.NotificationReminder || n.Params.Title != parent.Title || n.TaskID != parent.ID {
+        t.Errorf("notification event data = %s (%v)", events[3].Data, err)
+    }
+
+    if latest, _ := s.LatestEventID(ctx, user.ID); latest != events[len(events)-1].ID {
+        t.Errorf("LatestEventID = %d, want %d", latest, events[len(events)-1].ID)
+    }
+    if page, _ := s.ListEvents(ctx, user.ID, events[1].ID, 2); len(page) != 2 || page[0].ID != events[2].ID {
+        t.Errorf("ListEvents after %d limit 2 = %+v", events[1].ID, page)
+    }
+    if foreign, _ := s.ListEvents(ctx, other.ID, 0, models.MaxEventBatch); len(foreign) != 0 {
+        t.Errorf("another user sees %d events", len(foreign))
+    }
+
+    if _, err := s.PurgeEvents(ctx, time.Now().Add(-time.Hour)); err != nil {
+        t.Fatalf("PurgeEvents: %v", err)
+    }
+    if kept, _ := s.ListEvents(ctx, user.ID, start, models.MaxEventBatch); len(kept) != len(events) {
+        t.Errorf("PurgeEvents removed recent events: %d left", len(kept))
+    }
+    if _, err := s.PurgeEvents(ctx, time.Now().Add(time.Hour)); err != nil {
+        t.Fatalf("PurgeEvents: %v", err)
+    }
+    if left, _ := s.ListEvents(ctx, user.ID, start, models.MaxEventBatch); len(left) != 0 {
+        t.Errorf("PurgeEvents left %d events", len(left))
+    }
+}
+
+func testReminders(t *testing.T, s models.Store) {
+    ctx := context.Background()
+    alice := mustUser(t, s, "alice@example.com")
+    bob := mustUser(t, s, "bob@example.com")
+    // Срок далеко, чтобы не срабатывали ступени напоминаний о сроке
+    task := mustTask(t, s, models.Task{Title: "task", UserID: alice.ID, DueDate: time.Now().Add(10 * 24 * time.Hour)})
+
+    past := time.Now().Add(-time.Minute)
+    before := 10*24*60 + 60
+    absolute, err := s.CreateReminder(ctx, &models.Reminder{TaskID: task.ID, UserID: alice.ID, RemindAt: &past})
+    if err != nil {
+        t.Fatalf("CreateReminder(absolute): %v", err)
+    }
+    relative, err := s.CreateReminder(ctx, &models.Reminder{TaskID: task.ID, UserID: alice.ID, MinutesBefore: &before})
+    if err != nil {
+        t.Fatalf("CreateReminder(relative): %v", err)
+    }
+    if want := task.DueDate.Add(-time.Duration(before) * time.Minute); relative.TriggerAt.Sub(want).Abs() > time.Second {
+        t.Fatalf("relative TriggerAt = %v, want %v", relative.TriggerAt, want)
+    }
+    if _, err := s.CreateReminder(ctx, &models.Reminder{TaskID: task.ID, UserID: bob.ID, RemindAt: &past}); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("CreateReminder on another user's task error = %v, want ErrNotFound", err)
+    }
+
+    reminders, err := s.GetTaskReminders(ctx, task.ID, alice.ID)
+    if err != nil || len(reminders) != 2 {
+        t.Fatalf("GetTaskReminders = %d, %v; want 2", len(reminders), err)
+    }
+    if foreign, _ := s.GetTaskReminders(ctx, task.ID, bob.ID); len(foreign) != 0 {
+        t.Fatalf("another user sees %d reminders", len(foreign))
+    }
+
+    if fired, err := s.FireReminders(ctx); err != nil || fired != 2 {
+        t.Fatalf("FireReminders = %d, %v; want 2", fired, err)
+    }
+    if fired, _ := s.FireReminders(ctx); fired != 0 {
+        t.Fatalf("reminders fired again: %d", fired)
+    }
+    notifications, _ := s.GetUserNotifications(ctx, alice.ID)
+    if len(notifications) != 2 || notifications[0].Type != models.NotificationReminder || notifications[0].Params.Title != "task" {
+        t.Fatalf("notifications after firing = %+v", notifications)
+    }
+    reminders, _ = s.GetTaskReminders(ctx, task.ID, alice.ID)
+    for _, r := range reminders {
+        if r.FiredAt == nil {
+            t.Errorf("reminder %d has no fired_at", r.ID)
+        }
+    }
+
+    // Перенос срока снова взводит только смещённое напоминание
+    task.DueDate = task.DueDate.Add(-time.Hour)
+    if _, err := s.UpdateTask(ctx, task); err != nil {
+        t.Fatalf("UpdateTask: %v", err)
+    }
+    if fired, _ := s.FireReminders(ctx); fired != 1 {
+        t.Fatalf("FireReminders after moving the due date = %d, want 1", fired)
+    }
+
+    // Изменение напоминания взводит его заново
+    if _, err := s.UpdateReminder(ctx, &models.Reminder{ID: absolute.ID, TaskID: task.ID, UserID: bob.ID, RemindAt: &past}); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("UpdateReminder by another user error = %v, want ErrNotFound", err)
+    }
+    updated, err := s.UpdateReminder(ctx, &models.Reminder{ID: absolute.ID, TaskID: task.ID, UserID: alice.ID, RemindAt: &past})
+    if err != nil || updated.FiredAt != nil {
+        t.Fatalf("UpdateReminder = %+v, %v", updated, err)
+    }
+    task.Completed = true
+    if _, err := s.UpdateTask(ctx, task); err != nil {
+        t.Fatalf("UpdateTask: %v", err)
+    }
+    if fired, _ := s.FireReminders(ctx); fired != 0 {
+        t.Fatalf("reminder of a completed task fired: %d", fired)
+    }
+    task.Completed = false
+    if _, err := s.UpdateTask(ctx, task); err != nil {
+        t.Fatalf("UpdateTask: %v", err)
+    }
+    if fired, _ := s.FireReminders(ctx); fired != 1 {
+        t.Fatalf("updated reminder fired %d times, want 1", fired)
+    }
+
+    notifications, _ = s.GetUserNotifications(ctx, alice.ID)
+    if _, err := s.SnoozeNotification(ctx, notifications[0].ID, bob.ID, time.Now().Add(time.Hour)); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("SnoozeNotification by another user error = %v, want ErrNotFound", err)
+    }
+    snoozed, err := s.SnoozeNotification(ctx, notifications[0].ID, alice.ID, time.Now().Add(time.Hour))
+    if err != nil {
+        t.Fatalf("SnoozeNotification: %v", err)
+    }
+    if snoozed.TaskID != task.ID || snoozed.RemindAt == nil || snoozed.FiredAt != nil {
+        t.Fatalf("snoozed reminder = %+v", snoozed)
+    }
+    notifications, _ = s.GetUserNotifications(ctx, alice.ID)
+    if !notifications[0].Read {
+        t.Fatal("snoozed notification is not marked as read")
+    }
+
+    if err := s.DeleteReminder(ctx, relative.ID, task.ID, bob.ID); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("DeleteReminder by another user error = %v, want ErrNotFound", err)
+    }
+    if err := s.DeleteReminder(ctx, relative.ID, task.ID, alice.ID); err != nil {
+        t.Fatalf("DeleteReminder: %v", err)
+    }
+    if reminders, _ := s.GetTaskReminders(ctx, task.ID, alice.ID); len(reminders) != 2 {
+        t.Fatalf("%d reminders left, want 2", len(reminders))
+    }
+
+    if err := s.DeleteTask(ctx, task.ID, alice.ID, models.CascadeChildren, 0); err != nil {
+        t.Fatalf("DeleteTask: %v", err)
+    }
+    if reminders, _ := s.GetTaskReminders(ctx, task.ID, alice.ID); len(reminders) != 0 {
+        t.Fatalf("reminders survived task deletion: %d", len(reminders))
+    }
+}
+
+func testTimezones(t *testing.T, s models.Store) {
+    ctx := context.Background()
+    user := mustUser(t, s, "alice@example.com")
+    if user.Timezone != models.DefaultTimezone {
+        t.Fatalf("new user timezone = %q, want %q", user.Timezone, models.DefaultTimezone)
+    }
+
+    updated, err := s.UpdateUserProfile(ctx, user.ID, "Alice", "Asia/Vladivostok", "en")
+    if err != nil || updated.Name != "Alice" || updated.Timezone != "Asia/Vladivostok" || updated.Locale != "en" {
+        t.Fatalf("UpdateUserProfile = %+v, %v", updated, err)
+    }
+    found, err := s.GetUserByID(ctx, user.ID)
+    if err != nil || found.Timezone != "Asia/Vladivostok" || found.Locale != "en" {
+        t.Fatalf("GetUserByID = %+v, %v", found, err)
+    }
+    if _, err := s.UpdateUserProfile(ctx, user.ID+100, "Nobody", "UTC", ""); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("UpdateUserProfile of missing user error = %v, want ErrNotFound", err)
+    }
+
+    // Задача на весь день просрочена только после полуночи по времени владельца
+    loc := models.Location("Asia/Vladivostok")
+    today := models.StartOfDay(time.Now(), loc).UTC()
+    current := mustTask(t, s, models.Task{Title: "today", UserID: user.ID, DueDate: today, AllDay: true})
+    mustTask(t, s, models.Task{Title: "yesterday", UserID: user.ID, DueDate: today.AddDate(0, 0, -1), AllDay: true})
+
+    stored, err := s.GetTask(ctx, current.ID, user.ID)
+    if err != nil || !stored.AllDay || !stored.DueDate.Equal(today) {
+        t.Fatalf("GetTask = %+v, %v; want all-day task due %v", stored, err, today)
+    }
+
+    overdue := true
+    page, err := s.ListTasks(ctx, user.ID, models.TaskFilter{Overdue: &overdue})
+    if err != nil || taskTitles(page.Tasks) != "yesterday" {
+        t.Fatalf("ListTasks(overdue) = %v, %v; want yesterday", page, err)
+    }
+
+    if fired, err := s.FireReminders(ctx); err != nil || fired != 2 {
+        t.Fatalf("FireReminders = %d, %v; want 2", fired, err)
+    }
+    notifications, err := s.GetUserNotifications(ctx, user.ID)
+    if err != nil {
+        t.Fatalf("GetUserNotifications: %v", err)
+    }
+    kinds := map[string]models.NotificationType{}
+    for _, n := range notifications {
+        kinds[n.Params.Title] = n.Type
+    }
+    if kinds["today"] != models.NotificationTaskDueToday || kinds["yesterday"] != models.NotificationTaskOverdue {
+        t.Fatalf("FireReminders notification types = %v", kinds)
+    }
+}
+
+func testNotificationManagement(t *testing.T, s models.Store) {
+    ctx := context.Background()
+    alice := mustUser(t, s, "alice@example.com")
+    bob := mustUser(t, s, "bob@example.com")
+    task := mustTask(t, s, models.Task{Title: "task", UserID: alice.ID, DueDate: time.Now().Add(24 * time.Hour)})
+    bobTask := mustTask(t, s, models.Task{Title: "bob", UserID: bob.ID, DueDate: time.Now().Add(24 * time.Hour)})
+    for i := 0; i < 5; i++ {
+        if err := s.CreateNotification(ctx, alice.ID, models.NotificationReminder, models.TaskParams(task)); err != nil {
+            t.Fatalf("CreateNotification: %v", err)
+        }
+    }
+    if err := s.CreateNotification(ctx, bob.ID, models.NotificationReminder, models.TaskParams(bobTask)); err != nil {
+        t.Fatalf("CreateNotification: %v", err)
+    }
+
+    // Страницы идут от новых к старым и не пересекаются
+    var ids []uint
+    filter := models.NotificationFilter{Limit: 2}
+    for {
+        page, err := s.ListNotifications(ctx, alice.ID, filter)
+        if err != nil {
+            t.Fatalf("ListNotifications: %v", err)
+        }
+        for _, n := range page.Notifications {
+            if len(ids) > 0 && n.ID >= ids[len(ids)-1] {
+                t.Fatalf("ListNotifications order: %d after %v", n.ID, ids)
+            }
+            ids = append(ids, n.ID)
+        }
+        if page.NextCursor == "" {
+            break
+        }
+        filter.Cursor = page.NextCursor
+    }
+    if len(ids) != 5 {
+        t.Fatalf("paged through %d notifications, want 5", len(ids))
+    }
+    if _, err := s.ListNotifications(ctx, alice.ID, models.NotificationFilter{Cursor: "garbage"}); !errors.Is(err, models.ErrInvalidCursor) {
+        t.Fatalf("ListNotifications with bad cursor error = %v, want ErrInvalidCursor", err)
+    }
+
+    // Чужие id в выборе не затрагиваются
+    updated, err := s.MarkNotificationsRead(ctx, bob.ID, models.NotificationSelection{IDs: ids[:2]})
+    if err != nil || updated != 0 {
+        t.Fatalf("MarkNotificationsRead(foreign) = %d, %v; want 0", updated, err)
+    }
+    updated, err = s.MarkNotificationsRead(ctx, alice.ID, models.NotificationSelection{IDs: ids[:2]})
+    if err != nil || updated != 2 {
+        t.Fatalf("MarkNotificationsRead(ids) = %d, %v; want 2", updated, err)
+    }
+    if count, err := s.CountUnreadNotifications(ctx, alice.ID); err != nil || count != 3 {
+        t.Fatalf("CountUnreadNotifications = %d, %v; want 3", count, err)
+    }
+    unread, err := s.ListNotifications(ctx, alice.ID, models.NotificationFilter{UnreadOnly: true})
+    if err != nil || len(unread.Notifications) != 3 {
+        t.Fatalf("ListNotifications(unread_only) = %+v, %v", unread, err)
+    }
+
+    past := time.Now().Add(-time.Hour)
+    if updated, _ := s.MarkNotificationsRead(ctx, alice.ID, models.NotificationSelection{Before: &past}); updated != 0 {
+        t.Fatalf("MarkNotificationsRead(before) touched %d newer notifications", updated)
+    }
+    if updated, _ := s.MarkNotificationsRead(ctx, alice.ID, models.NotificationSelection{}); updated != 3 {
+        t.Fatalf("MarkNotificationsRead(all) = %d, want 3", updated)
+    }
+    if count, _ := s.CountUnreadNotifications(ctx, bob.ID); count != 1 {
+        t.Fatalf("marking all as read changed another user's notifications: %d unread", count)
+    }
+
+    if err := s.DeleteNotification(ctx, ids[0], bob.ID); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("DeleteNotification by another user error = %v, want ErrNotFound", err)
+    }
+    if err := s.DeleteNotification(ctx, ids[0], alice.ID); err != nil {
+        t.Fatalf("DeleteNotification: %v", err)
+    }
+    if deleted, err := s.DeleteNotifications(ctx, alice.ID, models.NotificationSelection{IDs: ids[1:3]}); err != nil || deleted != 2 {
+        t.Fatalf("DeleteNotifications = %d, %v; want 2", deleted, err)
+    }
+
+    // Очистка удаляет только прочитанные уведомления
+    if purged, err := s.PurgeNotifications(ctx, time.Now().Add(time.Hour)); err != nil || purged != 2 {
+        t.Fatalf("PurgeNotifications = %d, %v; want 2", purged, err)
+    }
+    if count, _ := s.CountUnreadNotifications(ctx, bob.ID); count != 1 {
+        t.Fatal("PurgeNotifications deleted an unread notification")
+    }
+}
+
+func testDeliveries(t *testing.T, s models.Store) {
+    ctx := context.Background()
+    alice := mustUser(t, s, "alice@example.com")
+    bob := mustUser(t, s, "bob@example.com")
+    task := mustTask(t, s, models.Task{Title: "task", UserID: alice.ID, DueDate: time.Now().Add(24 * time.Hour)})
+    if err := s.CreateNotification(ctx, alice.ID, models.NotificationReminder, models.TaskParams(task)); err != nil {
+        t.Fatalf("CreateNotification: %v", err)
+    }
+    notifications, err := s.GetUserNotifications(ctx, alice.ID)
+    if err != nil || len(notifications) != 1 {
+        t.Fatalf("GetUserNotifications = %+v, %v", notifications, err)
+    }
+    notificationID := notifications[0].ID
+
+    // Каждое уведомление сразу ставится в очередь почты
+    deliveries, err := s.GetNotificationDeliveries(ctx, notificationID, alice.ID)
+    if err != nil || len(deliveries) != 1 {
+        t.Fatalf("GetNotificationDeliveries = %+v, %v", deliveries, err)
+    }
+    if d := deliveries[0]; d.Channel != models.ChannelEmail || d.Status != models.DeliveryPending || d.Attempts != 0 {
+        t.Fatalf("queued delivery = %+v", d)
+    }
+    if _, err := s.GetNotificationDeliveries(ctx, notificationID, bob.ID); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("GetNotificationDeliveries by another user error = %v, want ErrNotFound", err)
+    }
+
+    claimed, err := s.ClaimDeliveries(ctx, models.ChannelEmail, 10, time.Hour)
+    if err != nil || len(claimed) != 1 {
+        t.Fatalf("ClaimDeliveries = %+v, %v", claimed, err)
+    }
+    p := claimed[0]
+    if p.Attempts != 1 || p.Notification.ID != notificationID || p.Notification.Type != models.NotificationReminder ||
+        p.Notification.Params.Title != "task" || p.Recipient.Email != "alice@example.com" || p.Recipient.Password != "" {
+        t.Fatalf("claimed delivery = %+v", p)
+    }
+
+    // Захваченная доставка недоступна до конца аренды
+    if again, err := s.ClaimDeliveries(ctx, models.ChannelEmail, 10, time.Hour); err != nil || len(again) != 0 {
+        t.Fatalf("ClaimDeliveries during lease = %+v, %v", again, err)
+    }
+
+    retryAt := time.Now().Add(-time.Second)
+    err = s.CompleteDelivery(ctx, p.ID, models.DeliveryResult{Status: models.DeliveryPending, Error: "timeout", RetryAt: &retryAt})
+    if err != nil {
+        t.Fatalf("CompleteDelivery(retry): %v", err)
+    }
+    claimed, err = s.ClaimDeliveries(ctx, models.ChannelEmail, 10, time.Hour)
+    if err != nil || len(claimed) != 1 || claimed[0].Attempts != 2 || claimed[0].LastError != "timeout" {
+        t.Fatalf("ClaimDeliveries after retry = %+v, %v", claimed, err)
+    }
+
+    if err := s.CompleteDelivery(ctx, p.ID, models.DeliveryResult{Status: models.DeliverySent}); err != nil {
+        t.Fatalf("CompleteDelivery(sent): %v", err)
+    }
+    deliveries, err = s.GetNotificationDeliveries(ctx, notificationID, alice.ID)
+    if err != nil || len(deliveries) != 1 {
+        t.Fatalf("GetNotificationDeliveries = %+v, %v", deliveries, err)
+    }
+    if d := deliveries[0]; d.Status != models.DeliverySent || d.SentAt == nil || d.LastError != "" || d.Attempts != 2 {
+        t.Fatalf("sent delivery = %+v", d)
+    }
+    if err := s.CompleteDelivery(ctx, p.ID+1000, models.DeliveryResult{Status: models.DeliverySent}); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("CompleteDelivery(missing) error = %v, want ErrNotFound", err)
+    }
+
+    // Отправленные доставки из очереди больше не забираются
+    if again, err := s.ClaimDeliveries(ctx, models.ChannelEmail, 10, 0); err != nil || len(again) != 0 {
+        t.Fatalf("ClaimDeliveries after send = %+v, %v", again, err)
+    }
+}
+
+func testDigests(t *testing.T, s models.Store) {
+    ctx := context.Background()
+    user := mustUser(t, s, "alice@example.com")
+    now := time.Now()
+    mustTask(t, s, models.Task{Title: "overdue", UserID: user.ID, DueDate: now.Add(-48 * time.Hour)})
+    mustTask(t, s, models.Task{Title: "today", UserID: user.ID, DueDate: models.StartOfDay(now, time.UTC), AllDay: true})
+    mustTask(t, s, models.Task{Title: "later", UserID: user.ID, DueDate: now.Add(10 * 24 * time.Hour)})
+    done := mustTask(t, s, models.Task{Title: "done", UserID: user.ID, DueDate: now.Add(-24 * time.Hour)})
+    done.Completed = true
+    if _, err := s.UpdateTask(ctx, done); err != nil {
+        t.Fatalf("UpdateTask: %v", err)
+    }
+
+    find := func(digests []models.Digest) *models.Digest {
+        for i := range digests {
+            if digests[i].User.ID == user.ID {
+                return &digests[i]
+            }
+        }
+        return nil
+    }
+
+    // Сводку получают только выбравшие её вместо отдельных писем
+    digests, err := s.ClaimDigests(ctx, 0)
+    if err != nil || find(digests) != nil {
+        t.Fatalf("ClaimDigests without digest mode = %+v, %v", digests, err)
+    }
+    settings := models.DefaultNotificationSettings()
+    settings.EmailDelivery = models.EmailDigest
+    if _, err := s.UpdateNotificationSettings(ctx, user.ID, settings); err != nil {
+        t.Fatalf("UpdateNotificationSettings: %v", err)
+    }
+
+    // и только на подтверждённый адрес
+    digests, err = s.ClaimDigests(ctx, 0)
+    if err != nil || find(digests) != nil {
+        t.Fatalf("ClaimDigests for unverified email = %+v, %v", digests, err)
+    }
+    mustVerify(t, s, user.ID)
+
+    // Час сводки ещё не наступил
+    digests, err = s.ClaimDigests(ctx, 24)
+    if err != nil || len(digests) != 0 {
+        t.Fatalf("ClaimDigests(24) = %+v, %v", digests, err)
+    }
+
+    digests, err = s.ClaimDigests(ctx, 0)
+    if err != nil {
+        t.Fatalf("ClaimDigests: %v", err)
+    }
+    digest := find(digests)
+    if digest == nil {
+        t.Fatalf("ClaimDigests did not return the user's digest: %+v", digests)
+    }
+    if digest.User.Email != "alice@example.com" || digest.User.Password != "" {
+        t.Fatalf("digest user = %+v", digest.User)
+    }
+    if got, want := digest.Day.Format("2006-01-02"), now.UTC().Format("2006-01-02"); got != want {
+        t.Fatalf("digest day = %s, want %s", got, want)
+    }
+    if got := taskTitles(digest.Overdue); got != "overdue" {
+        t.Fatalf("digest overdue = %q, want overdue", got)
+    }
+    if got := taskTitles(digest.DueToday); got != "today" {
+        t.Fatalf("digest due today = %q, want today", got)
+    }
+
+    // Сводка захватывается один раз за день, пока её не вернут
+    digests, err = s.ClaimDigests(ctx, 0)
+    if err != nil || find(digests) != nil {
+        t.Fatalf("ClaimDigests repeated = %+v, %v", digests, err)
+    }
+    if err := s.ReleaseDigest(ctx, user.ID, digest.Day); err != nil {
+        t.Fatalf("ReleaseDigest: %v", err)
+    }
+    digests, err = s.ClaimDigests(ctx, 0)
+    if err != nil || find(digests) == nil {
+        t.Fatalf("ClaimDigests after release = %+v, %v", digests, err)
+    }
+}
+
+func testNotificationSettings(t *testing.T, s models.Store) {
+    ctx := context.Background()
+    alice := mustUser(t, s, "alice@example.com")
+    bob := mustUser(t, s, "bob@example.com")
+
+    settings, err := s.GetNotificationSettings(ctx, alice.ID)
+    if err != nil {
+        t.Fatalf("GetNotificationSettings: %v", err)
+    }
+    if !reflect.DeepEqual(settings, models.DefaultNotificationSettings()) {
+        t.Fatalf("default settings = %+v", settings)
+    }
+    if _, err := s.GetNotificationSettings(ctx, bob.ID+1000); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("GetNotificationSettings(missing) error = %v, want ErrNotFound", err)
+    }
+
+    update := func(change func(*models.NotificationSettings)) {
+        t.Helper()
+        settings := models.DefaultNotificationSettings()
+        change(settings)
+        updated, err := s.UpdateNotificationSettings(ctx, alice.ID, settings)
+        if err != nil {
+            t.Fatalf("UpdateNotificationSettings: %v", err)
+        }
+        if !reflect.DeepEqual(updated, settings) {
+            t.Fatalf("UpdateNotificationSettings = %+v, want %+v", updated, settings)
+        }
+    }
+    claim := func() []models.PendingDelivery {
+        t.Helper()
+        claimed, err := s.ClaimDeliveries(ctx, models.ChannelEmail, 10, time.Hour)
+        if err != nil {
+            t.Fatalf("ClaimDeliveries: %v", err)
+        }
+        return claimed
+    }
+    task := mustTask(t, s, models.Task{Title: "task", UserID: alice.ID, DueDate: time.Now().Add(-time.Hour)})
+
+    // Выключенный тип не создаёт уведомлений ни в одном канале
+    update(func(ns *models.NotificationSettings) { ns.Types[models.NotificationTaskCreated] = false })
+    if err := s.CreateNotification(ctx, alice.ID, models.NotificationTaskCreated, models.TaskParams(task)); err != nil {
+        t.Fatalf("CreateNotification: %v", err)
+    }
+    if count, _ := s.CountUnreadNotifications(ctx, alice.ID); count != 0 {
+        t.Fatalf("disabled type created %d notifications", count)
+    }
+    if claimed := claim(); len(claimed) != 0 {
+        t.Fatalf("disabled type queued deliveries: %+v", claimed)
+    }
+
+    // Без канала в приложении уведомление не видно в списке, но уходит по почте
+    update(func(ns *models.NotificationSettings) { ns.Channels.InApp = false })
+    if err := s.CreateNotification(ctx, alice.ID, models.NotificationTaskCreated, models.TaskParams(task)); err != nil {
+        t.Fatalf("CreateNotification: %v", err)
+    }
+    if notifications, _ := s.GetUserNotifications(ctx, alice.ID); len(notifications) != 0 {
+        t.Fatalf("notifications without in-app channel = %+v", notifications)
+    }
+    if count, _ := s.CountUnreadNotifications(ctx, alice.ID); count != 0 {
+        t.Fatalf("unread count without in-app channel = %d", count)
+    }
+    claimed := claim()
+    if len(claimed) != 1 || claimed[0].Settings.Channels.InApp || claimed[0].Recipient.ID != alice.ID {
+        t.Fatalf("deliveries without in-app channel = %+v", claimed)
+    }
+
+    // В тихие часы напоминания откладываются до их окончания
+    now := time.Now().In(models.Location(alice.Timezone))
+    minute := models.ClockTime(now.Hour()*60 + now.Minute())
+    quiet := &models.QuietHours{Start: (minute + 24*60 - 60) % (24 * 60), End: (minute + 60) % (24 * 60)}
+    update(func(ns *models.NotificationSettings) { ns.QuietHours = quiet })
+    if fired, err := s.FireReminders(ctx); err != nil || fired != 0 {
+        t.Fatalf("FireReminders during quiet hours = %d, %v; want 0", fired, err)
+    }
+    update(func(*models.NotificationSettings) {})
+    if fired, err := s.FireReminders(ctx); err != nil || fired == 0 {
+        t.Fatalf("FireReminders after quiet hours = %d, %v", fired, err)
+    }
+
+    if settings, err := s.GetNotificationSettings(ctx, bob.ID); err != nil || !reflect.DeepEqual(settings, models.DefaultNotificationSettings()) {
+        t.Fatalf("alice's settings changed bob's: %+v, %v", settings, err)
+    }
+}
+
+func testWebhooks(t *testing.T, s models.Store) {
+    ctx := context.Background()
+    alice := mustUser(t, s, "alice@example.com")
+    bob := mustUser(t, s, "bob@example.com")
+
+    create := func(userID uint, events ...string) *models.Webhook {
+        t.Helper()
+        webhook, err := s.CreateWebhook(ctx, &models.Webhook{
+            UserID: userID, URL: "https://example.com/hook", Secret: "whsec_test", Events: events, Active: true,
+        })
+        if err != nil {
+            t.Fatalf("CreateWebhook: %v", err)
+        }
+        return webhook
+    }
+    queued := func(webhookID uint) string {
+        t.Helper()
+        deliveries, err := s.GetWebhookDeliveries(ctx, webhookID, alice.ID, 100)
+        if err != nil {
+            t.Fatalf("GetWebhookDeliveries: %v", err)
+        }
+        events := make([]string, len(deliveries))
+        for i, d := range deliveries {
+            events[i] = d.Event
+        }
+        return strings.Join(events, ",")
+    }
+
+    tasks := create(alice.ID, models.EventTaskCreated, models.EventTaskCompleted)
+    others := create(alice.ID, "category.*", models.EventNotificationCreated)
+    create(bob.ID, "task.*", "category.*")
+    if tasks.ID == 0 || tasks.Secret != "whsec_test" || !tasks.Active || len(tasks.Events) != 2 {
+        t.Fatalf("CreateWebhook = %+v", tasks)
+    }
+    if _, err := s.GetWebhook(ctx, tasks.ID, bob.ID); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("GetWebhook by another user error = %v, want ErrNotFound", err)
+    }
+    if webhooks, err := s.GetUserWebhooks(ctx, alice.ID); err != nil || len(webhooks) != 2 || webhooks[0].ID != tasks.ID {
+        t.Fatalf("GetUserWebhooks = %+v, %v", webhooks, err)
+    }
+
+    // События попадают только в подписанные вебхуки владельца
+    task := mustTask(t, s, models.Task{Title: "task", UserID: alice.ID, DueDate: time.Now().Add(time.Hour)})
+    task.Completed = true
+    if _, err := s.UpdateTask(ctx, task); err != nil {
+        t.Fatalf("UpdateTask: %v", err)
+    }
+    category := mustCategory(t, s, "work", alice.ID)
+    if err := s.DeleteCategory(ctx, category.ID, alice.ID, 0); err != nil {
+        t.Fatalf("DeleteCategory: %v", err)
+    }
+    if err := s.CreateNotification(ctx, alice.ID, models.NotificationReminder, models.TaskParams(task)); err != nil {
+        t.Fatalf("CreateNotification: %v", err)
+    }
+    if got, want := queued(tasks.ID), "task.completed,task.created"; got != want {
+        t.Fatalf("task webhook deliveries = %q, want %q", got, want)
+    }
+    if got, want := queued(others.ID), "notification.created,category.deleted,category.created"; got != want {
+        t.Fatalf("category webhook deliveries = %q, want %q", got, want)
+    }
+    events, err := s.ListEvents(ctx, alice.ID, 0, models.MaxEventBatch)
+    if err != nil {
+        t.Fatalf("ListEvents: %v", err)
+    }
+    var completed models.TaskEventData
+    for _, e := range events {
+        if e.Type == models.EventTaskCompleted {
+            json.Unmarshal(e.Data, &completed)
+        }
+    }
+    if completed.ID != task.ID || !completed.Completed {
+        t.Fatalf("events have no task.completed for the task: %+v", events)
+    }
+
+    // Канал вебхуков выключен в настройках уведомлений
+    settings := models.DefaultNotificationSettings()
+    settings.Channels.Webhook = false
+    if _, err := s.UpdateNotificationSettings(ctx, alice.ID, settings); err != nil {
+        t.Fatalf("UpdateNotificationSettings: %v", err)
+    }
+    if err := s.CreateNotification(ctx, alice.ID, models.NotificationReminder, models.TaskParams(task)); err != nil {
+        t.Fatalf("CreateNotification: %v", err)
+    }
+    if got := queued(others.ID); strings.Count(got, models.EventNotificationCreated) != 1 {
+        t.Fatalf("notification queued with the webhook channel off: %q", got)
+    }
+
+    // Доставки выключенного вебхука не забираются
+    others.Active = false
+    others.Secret = ""
+    updated, err := s.UpdateWebhook(ctx, others)
+    if err != nil || updated.Active || updated.Secret != "whsec_test" {
+        t.Fatalf("UpdateWebhook = %+v, %v", updated, err)
+    }
+    bobHook := *others
+    bobHook.UserID = bob.ID
+    if _, err := s.UpdateWebhook(ctx, &bobHook); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("UpdateWebhook by another user error = %v, want ErrNotFound", err)
+    }
+
+    claimed, err := s.ClaimWebhookDeliveries(ctx, 10, time.Hour)
+    if err != nil || len(claimed) != 2 {
+        t.Fatalf("ClaimWebhookDeliveries = %+v, %v", claimed, err)
+    }
+    p := claimed[0]
+    var data models.TaskEventData
+    if err := json.Unmarshal(p.Data, &data); err != nil || data.ID != task.ID {
+        t.Fatalf("claimed delivery data = %s, %v", p.Data, err)
+    }
+    if p.Event != models.EventTaskCreated || p.WebhookID != tasks.ID || p.Attempts != 1 ||
+        p.URL != "https://example.com/hook" || p.Secret != "whsec_test" {
+        t.Fatalf("claimed delivery = %+v", p)
+    }
+    if again, err := s.ClaimWebhookDeliveries(ctx, 10, time.Hour); err != nil || len(again) != 0 {
+        t.Fatalf("ClaimWebhookDeliveries during lease = %+v, %v", again, err)
+    }
+
+    retryAt := time.Now().Add(-time.Second)
+    err = s.CompleteWebhookDelivery(ctx, p.ID, models.WebhookResult{
+        Status: models.DeliveryPending, ResponseStatus: 503, Error: "unexpected response status 503", RetryAt: &retryAt,
+    })
+    if err != nil {
+        t.Fatalf("CompleteWebhookDelivery(retry): %v", err)
+    }
+    claimed, err = s.ClaimWebhookDeliveries(ctx, 10, time.Hour)
+    if err != nil || len(claimed) != 1 || claimed[0].Attempts != 2 || claimed[0].ResponseStatus == nil || *claimed[0].ResponseStatus != 503 {
+        t.Fatalf("ClaimWebhookDeliveries after retry = %+v, %v", claimed, err)
+    }
+    if err := s.CompleteWebhookDelivery(ctx, p.ID, models.WebhookResult{Status: models.DeliverySent, ResponseStatus: 200}); err != nil {
+        t.Fatalf("CompleteWebhookDelivery(sent): %v", err)
+    }
+    deliveries, err := s.GetWebhookDeliveries(ctx, tasks.ID, alice.ID, 1)
+    if err != nil || len(deliveries) != 1 {
+        t.Fatalf("GetWebhookDeliveries(limit 1) = %+v, %v", deliveries, err)
+    }
+    deliveries, _ = s.GetWebhookDeliveries(ctx, tasks.ID, alice.ID, 10)
+    if d := deliveries[1]; d.Status != models.DeliverySent || d.DeliveredAt == nil || d.LastError != "" || *d.ResponseStatus != 200 {
+        t.Fatalf("sent delivery = %+v", d)
+    }
+    if _, err := s.GetWebhookDeliveries(ctx, tasks.ID, bob.ID, 10); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("GetWebhookDeliveries by another user error = %v, want ErrNotFound", err)
+    }
+
+    if _, err := s.QueueWebhookTest(ctx, tasks.ID, bob.ID); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("QueueWebhookTest by another user error = %v, want ErrNotFound", err)
+    }
+    test, err := s.QueueWebhookTest(ctx, tasks.ID, alice.ID)
+    if err != nil || test.Event != models.EventWebhookTest || test.Status != models.DeliveryPending {
+        t.Fatalf("QueueWebhookTest = %+v, %v", test, err)
+    }
+
+    // Очистка журнала не трогает ожидающие доставки
+    if _, err := s.PurgeWebhookDeliveries(ctx, time.Now().Add(time.Hour)); err != nil {
+        t.Fatalf("PurgeWebhookDeliveries: %v", err)
+    }
+    if got, want := queued(tasks.ID), "webhook.test,task.completed"; got != want {
+        t.Fatalf("deliveries after purge = %q, want %q", got, want)
+    }
+
+    if err := s.DeleteWebhook(ctx, tasks.ID, bob.ID); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("DeleteWebhook by another user error = %v, want ErrNotFound", err)
+    }
+    if err := s.DeleteWebhook(ctx, tasks.ID, alice.ID); err != nil {
+        t.Fatalf("DeleteWebhook: %v", err)
+    }
+    if _, err := s.GetWebhookDeliveries(ctx, tasks.ID, alice.ID, 10); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("GetWebhookDeliveries after delete error = %v, want ErrNotFound", err)
+    }
+}
+
+func testAuthTokens(t *testing.T, s models.Store) {
+    ctx := context.Background()
+    user := mustUser(t, s, "alice@example.com")
+    other := mustUser(t, s, "bob@example.com")
+    expires := time.Now().Add(time.Hour)
+
+    if user.Verified() {
+        t.Fatal("new user is verified")
+    }
+
+    // Новый токен вытесняет прежний того же назначения
+    if err := s.CreateAuthToken(ctx, user.ID, models.TokenVerifyEmail, models.HashToken("v1"), expires); err != nil {
+        t.Fatalf("CreateAuthToken: %v", err)
+    }
+    if err := s.CreateAuthToken(ctx, user.ID, models.TokenVerifyEmail, models.HashToken("v2"), expires); err != nil {
+        t.Fatalf("CreateAuthToken: %v", err)
+    }
+    if _, err := s.VerifyEmail(ctx, models.HashToken("v1")); !errors.Is(err, models.ErrValidation) {
+        t.Fatalf("VerifyEmail with replaced token error = %v, want ErrValidation", err)
+    }
+    // Токен сброса пароля не подтверждает адрес
+    if err := s.CreateAuthToken(ctx, user.ID, models.TokenResetPassword, models.HashToken("r1"), expires); err != nil {
+        t.Fatalf("CreateAuthToken: %v", err)
+    }
+    if _, err := s.VerifyEmail(ctx, models.HashToken("r1")); !errors.Is(err, models.ErrValidation) {
+        t.Fatalf("VerifyEmail with reset token error = %v, want ErrValidation", err)
+    }
+
+    verified, err := s.VerifyEmail(ctx, models.HashToken("v2"))
+    if err != nil || verified.ID != user.ID || !verified.Verified() {
+        t.Fatalf("VerifyEmail = %+v, %v", verified, err)
+    }
+    if _, err := s.VerifyEmail(ctx, models.HashToken("v2")); !errors.Is(err, models.ErrValidation) {
+        t.Fatalf("VerifyEmail with used token error = %v, want ErrValidation", err)
+    }
+    if got, _ := s.GetUserByID(ctx, other.ID); got == nil || got.Verified() {
+        t.Fatal("VerifyEmail verified another user")
+    }
+
+    // Сброс пароля меняет пароль, отзывает сессии и тоже одноразовый
+    session, err := s.CreateSession(ctx, user.ID, "test", models.HashToken("refresh"), expires)
+    if err != nil {
+        t.Fatalf("CreateSession: %v", err)
+    }
+    foreign, err := s.CreateSession(ctx, other.ID, "test", models.HashToken("foreign"), expires)
+    if err != nil {
+        t.Fatalf("CreateSession: %v", err)
+    }
+    if _, err := s.ResetPassword(ctx, models.HashToken("r1"), "changed"); err != nil {
+        t.Fatalf("ResetPassword: %v", err)
+    }
+    if _, err := s.ResetPassword(ctx, models.HashToken("r1"), "again"); !errors.Is(err, models.ErrValidation) {
+        t.Fatalf("ResetPassword with used token error = %v, want ErrValidation", err)
+    }
+    got, err := s.GetUserByEmail(ctx, "alice@example.com")
+    if err != nil || !got.CheckPassword("changed") || got.CheckPassword("secret") {
+        t.Fatalf("password not changed by ResetPassword: %v", err)
+    }
+    if got, _ := s.GetSession(ctx, session.ID); got == nil || got.RevokedAt == nil {
+        t.Fatal("ResetPassword did not revoke the user's sessions")
+    }
+    if got, _ := s.GetSession(ctx, foreign.ID); got == nil || got.RevokedAt != nil {
+        t.Fatal("ResetPassword revoked another user's session")
+    }
+
+    // Сброс пароля по ссылке из письма подтверждает адрес
+    if err := s.CreateAuthToken(ctx, other.ID, models.TokenResetPassword, models.HashToken("r2"), expires); err != nil {
+        t.Fatalf("CreateAuthToken: %v", err)
+    }
+    reset, err := s.ResetPassword(ctx, models.HashToken("r2"), "changed")
+    if err != nil || reset.ID != other.ID || !reset.Verified() {
+        t.Fatalf("ResetPassword = %+v, %v", reset, err)
+    }
+
+    if err := s.CreateAuthToken(ctx, user.ID, models.TokenResetPassword, models.HashToken("expired"), time.Now().Add(-time.Minute)); err != nil {
+        t.Fatalf("CreateAuthToken: %v", err)
+    }
+    if _, err := s.ResetPassword(ctx, models.HashToken("expired"), "x"); !errors.Is(err, models.ErrValidation) {
+        t.Fatalf("ResetPassword with expired token error = %v, want ErrValidation", err)
+    }
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+)
+
+// ValidateParent проверяет, что parentID принадлежит пользователю и не является
+// самой задачей taskID или её потомком. taskID равен нулю для новой задачи.
+func ValidateParent(ctx context.Context, tasks TaskStore, taskID, parentID, userID uint) error {
+    for id := parentID; ; {
+        if taskID != 0 && id == taskID {
+            return ErrInvalidParent
+        }
+        parent, err := tasks.GetTask(ctx, id, userID)
+        if errors.Is(err, ErrNotFound) {
+            return ErrInvalidParent
+        }
+        if err != nil {
+            return err
+        }
+        if parent.ParentID == nil {
+            return nil
+        }
+        id = *parent.ParentID
+    }
+}
+
+func (s *PostgresStore) GetSubtasks(ctx context.Context, parentID, userID uint) ([]Task, error) {
+    return s.queryTasks(ctx,
+        `SELECT `+taskColumns+`
+         FROM tasks t
+         LEFT JOIN categories c ON t.category_id = c.id
+         WHERE t.parent_id = $1 AND t.user_id = $2 AND t.deleted_at IS NULL 
+         ORDER BY t.position ASC, t.id ASC`,
+        parentID, userID,
+    )
+}
+
+func (s *PostgresStore) ReorderSubtasks(ctx context.Context, parentID, userID uint, ids []uint) error {
+    tx, err := s.beginTx(ctx)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    rows, err := tx.QueryContext(ctx,
+        "SELECT id FROM tasks WHERE parent_id = $1 AND user_id = $2 AND deleted_at IS NULL FOR UPDATE",
+        parentID, userID,
+    )
+    if err != nil {
+        return err
+    }
+    children := map[uint]bool{}
+    for rows.Next() {
+        var id uint
+        if err := rows.Scan(&id); err != nil {
+            rows.Close()
+            return err
+        }
+        children[id] = true
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return err
+    }
+
+    if err := CheckOrder(children, ids); err != nil {
+        return err
+    }
+
+    for position, id := range ids {
+        _, err := tx.ExecContext(ctx,
+            "UPDATE tasks SET position = $1, updated_at = NOW() WHERE id = $2",
+            position, id,
+        )
+        if err != nil {
+            return err
+        }
+    }
+    return tx.Commit()
+}
+
+// CheckOrder требует, чтобы ids перечисляли все подзадачи ровно по одному разу.
+func CheckOrder(children map[uint]bool, ids []uint) error {
+    if len(ids) != len(children) {
+        return ErrInvalidOrder
+    }
+    seen := map[uint]bool{}
+    for _, id := range ids {
+        if !children[id] || seen[id] {
+            return ErrInvalidOrder
+        }
+        seen[id] = true
+    }
+    return nil
+}
+
+// SyncParentCompletion приводит флаг completed задачи parentID с auto_complete
+// в соответствие с её подзадачами и поднимается выше, пока что-то меняется.
+func (s *PostgresStore) SyncParentCompletion(ctx context.Context, parentID uint) error {
+    tx, err := s.beginTx(ctx)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    for id := &parentID; id != nil; {
+        var next *uint
+        err := tx.QueryRowContext(ctx,
+            `UPDATE tasks p 
+             SET completed = NOT EXISTS (SELECT 1 FROM tasks c WHERE c.parent_id = p.id AND c.deleted_at IS NULL AND NOT c.completed),
+                 updated_at = NOW() 
+             WHERE p.id = $1 AND p.auto_complete AND p.deleted_at IS NULL 
+               AND p.completed IS DISTINCT FROM NOT EXISTS (SELECT 1 FROM tasks c WHERE c.parent_id = p.id AND c.deleted_at IS NULL AND NOT c.completed)
+             RETURNING p.parent_id`,
+            *id,
+        ).Scan(&next)
+        if errors.Is(err, sql.ErrNoRows) {
+            break
+        }
+        if err != nil {
+            return err
+        }
+        id = next
+    }
+    return tx.Commit()
+}
@@ -0,0 +1,289 @@
+package models
+
+import (
+    "context"
+    "encoding/base64"
+    "strconv"
+    "github.com/lib/pq"
+)
+
+// Виды записей в журнале изменений.
+const (
+    EntityTask         = "task"
+    EntityCategory     = "category"
+    EntityNotification = "notification"
+
+    DefaultSyncLimit = 500
+    MaxSyncLimit     = 1000
+)
+
+var ErrInvalidSyncToken = Invalid("since", "invalid sync token")
+
+// Change — строка журнала изменений: последнее изменение записи. Удалённая
+// запись остаётся в журнале надгробием с Deleted.
+type Change struct {
+    ID       int64
+    UserID   uint
+    Entity   string
+    EntityID uint
+    Deleted  bool
+}
+
+// Tombstones перечисляет id удалённых записей.
+type Tombstones struct {
+    Tasks         []uint `json:"tasks"`
+    Categories    []uint `json:"categories"`
+    Notifications []uint `json:"notifications"`
+}
+
+// ChangeSet — записи, изменённые после токена, в их текущем виде. Token
+// продолжает синхронизацию; HasMore означает, что выданы не все изменения и
+// следующую порцию нужно запросить сразу.
+type ChangeSet struct {
+    Token         string         `json:"token"`
+    HasMore       bool           `json:"has_more"`
+    Tasks         []Task         `json:"tasks"`
+    Categories    []Category     `json:"categories"`
+    Notifications []Notification `json:"notifications"`
+    Deleted       Tombstones     `json:"deleted"`
+
+    changed map[string][]uint
+}
+
+func encodeSyncToken(id int64) string {
+    return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+// DecodeSyncToken возвращает номер последнего изменения, которое клиент уже
+// получил. Пустой токен означает полную синхронизацию.
+func DecodeSyncToken(token string) (int64, error) {
+    if token == "" {
+        return 0, nil
+    }
+    data, err := base64.RawURLEncoding.DecodeString(token)
+    if err != nil {
+        return 0, ErrInvalidSyncToken
+    }
+    id, err := strconv.ParseInt(string(data), 10, 64)
+    if err != nil || id < 0 {
+        return 0, ErrInvalidSyncToken
+    }
+    return id, nil
+}
+
+// NewChangeSet раскладывает строки журнала после изменения after. changes
+// упорядочены по ID и выбраны на одну больше limit, чтобы узнать HasMore.
+// При полной синхронизации надгробия не нужны, но токен проходит и их:
+// иначе они придут со следующей порцией.
+func NewChangeSet(after int64, changes []Change, limit int) *ChangeSet {
+    set := &ChangeSet{
+        Token:         encodeSyncToken(after),
+        Tasks:         []Task{},
+        Categories:    []Category{},
+        Notifications: []Notification{},
+        Deleted:       Tombstones{Tasks: []uint{}, Categories: []uint{}, Notifications: []uint{}},
+        changed:       map[string][]uint{},
+    }
+    if len(changes) > limit {
+        changes = changes[:limit]
+        set.HasMore = true
+    }
+    for _, c := range changes {
+        if c.Deleted {
+            if after != 0 {
+                set.tombstone(c.Entity, c.EntityID)
+            }
+        } else {
+            set.changed[c.Entity] = append(set.changed[c.Entity], c.EntityID)
+        }
+        set.Token = encodeSyncToken(c.ID)
+    }
+    return set
+}
+
+func (set *ChangeSet) tombstone(entity string, id uint) {
+    switch entity {
+    case EntityTask:
+        set.Deleted.Tasks = append(set.Deleted.Tasks, id)
+    case EntityCategory:
+        set.Deleted.Categories = append(set.Deleted.Categories, id)
+    case EntityNotification:
+        set.Deleted.Notifications = append(set.Deleted.Notifications, id)
+    }
+}
+
+// Changed возвращает id изменённых записей вида entity, которые хранилище
+// должно прочитать.
+func (set *ChangeSet) Changed(entity string) []uint {
+    return set.changed[entity]
+}
+
+// Fill сохраняет прочитанные записи. Записи, удалённые между чтением журнала
+// и чтением самих записей, попадают в надгробия.
+func (set *ChangeSet) Fill(tasks []Task, categories []Category, notifications []Notification) {
+    found := map[string]map[uint]bool{EntityTask: {}, EntityCategory: {}, EntityNotification: {}}
+    for _, t := range tasks {
+        found[EntityTask][t.ID] = true
+        set.Tasks = append(set.Tasks, t)
+    }
+    for _, c := range categories {
+        found[EntityCategory][c.ID] = true
+        set.Categories = append(set.Categories, c)
+    }
+    for _, n := range notifications {
+        found[EntityNotification][n.ID] = true
+        set.Notifications = append(set.Notifications, n)
+    }
+    for entity, ids := range set.changed {
+        for _, id := range ids {
+            if !found[entity][id] {
+                set.tombstone(entity, id)
+            }
+        }
+    }
+}
+
+func idArray(ids []uint) interface{} {
+    values := make([]int64, len(ids))
+    for i, id := range ids {
+        values[i] = int64(id)
+    }
+    return pq.Array(values)
+}
+
+// GetChanges читает журнал, который ведут триггеры миграции 0017_changes.
+// Записи читаются после журнала и могут оказаться новее токена: их изменение
+// придёт повторно со следующим токеном, клиент просто применит его ещё раз.
+func (s *PostgresStore) GetChanges(ctx context.Context, userID uint, since string, limit int) (*ChangeSet, error) {
+    after, err := DecodeSyncToken(since)
+    if err != nil {
+        return nil, err
+    }
+
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT id, user_id, entity, entity_id, deleted
+         FROM changes
+         WHERE user_id = $1 AND id > $2
+         ORDER BY id
+         LIMIT $3`,
+        userID, after, limit+1,
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var changes []Change
+    for rows.Next() {
+        var c Change
+        if err := rows.Scan(&c.ID, &c.UserID, &c.Entity, &c.EntityID, &c.Deleted); err != nil {
+            return nil, err
+        }
+        changes = append(changes, c)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+    set := NewChangeSet(after, changes, limit)
+
+    tasks, err := s.queryTasks(ctx,
+        `SELECT `+taskColumns+`
+         FROM tasks t
+         LEFT JOIN categories c ON t.category_id = c.id
+         WHERE t.user_id = $1 AND t.id = ANY($2) AND t.deleted_at IS NULL
+         ORDER BY t.id`,
+        userID, idArray(set.Changed(EntityTask)),
+    )
+    if err != nil {
+        return nil, err
+    }
+    categories, err := s.queryCategories(ctx,
+        `SELECT `+categoryColumns+`
+         FROM categories
+         WHERE user_id = $1 AND id = ANY($2) AND deleted_at IS NULL
+         ORDER BY id`,
+        userID, idArray(set.Changed(EntityCategory)),
+    )
+    if err != nil {
+        return nil, err
+    }
+
+    notificationRows, err := s.db.QueryContext(ctx,
+        `SELECT id, user_id, task_id, type, params, message, created_at, read
+         FROM notifications
+         WHERE user_id = $1 AND in_app AND id = ANY($2)
+         ORDER BY id`,
+        userID, idArray(set.Changed(EntityNotification)),
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer notificationRows.Close()
+
+    var notifications []Notification
+    for notificationRows.Next() {
+        var n Notification
+        err := notificationRows.Scan(&n.ID, &n.UserID, &n.TaskID, &n.Type, &n.Params, &n.Message, &n.CreatedAt, &n.Read)
+        if err != nil {
+            return nil, err
+        }
+        notifications = append(notifications, n)
+    }
+    if err := notificationRows.Err(); err != nil {
+        return nil, err
+    }
+
+    set.Fill(tasks, categories, notifications)
+    return set, nil
+}
+
+// ResolveClientID находит запись вида entity по идентификатору, выданному
+// клиентом.
+func (s *PostgresStore) ResolveClientID(ctx context.Context, userID uint, entity, clientID string) (uint, error) {
+    var table string
+    switch entity {
+    case EntityTask:
+        table = "tasks"
+    case EntityCategory:
+        table = "categories"
+    default:
+        return 0, ErrNotFound
+    }
+
+    var id uint
+    err := s.db.QueryRowContext(ctx,
+        "SELECT id FROM "+table+" WHERE user_id = $1 AND client_id = $2 AND deleted_at IS NULL",
+        userID, clientID,
+    ).Scan(&id)
+    if err != nil {
+        return 0, notFound(err)
+    }
+    return id, nil
+}
+
+func (s *PostgresStore) TrashedTaskByClientID(ctx context.Context, userID uint, clientID string) (*Task, error) {
+    task, err := scanTask(s.db.QueryRowContext(ctx,
+        `SELECT `+taskColumns+`
+         FROM tasks t
+         LEFT JOIN categories c ON t.category_id = c.id
+         WHERE t.user_id = $1 AND t.client_id = $2 AND t.deleted_at IS NOT NULL`,
+        userID, clientID,
+    ))
+    if err != nil {
+        return nil, notFound(err)
+    }
+    return task, nil
+}
+
+func (s *PostgresStore) TrashedCategoryByClientID(ctx context.Context, userID uint, clientID string) (*Category, error) {
+    category, err := scanCategory(s.db.QueryRowContext(ctx,
+        `SELECT `+categoryColumns+`
+         FROM categories
+         WHERE user_id = $1 AND client_id = $2 AND deleted_at IS NOT NULL`,
+        userID, clientID,
+    ))
+    if err != nil {
+        return nil, notFound(err)
+    }
+    return category, nil
+}
@@ -1,7 +1,10 @@
 package models
 
 import (
-    "todo-app/internal/db"
+    "context"
+    "database/sql"
+    "fmt"
+    "strings"
     "time"
 )
 
@@ -21,99 +24,436 @@ type Task struct {
     UserID      uint      `json:"user_id"`
     CategoryID  *uint     `json:"category_id"`
     DueDate     time.Time `json:"due_date"`
+    // AllDay означает срок без времени: DueDate — начало дня в часовом поясе владельца.
+    AllDay      bool      `json:"all_day"`
     Priority    Priority  `json:"priority"`
     CreatedAt   time.Time `json:"created_at"`
     UpdatedAt   time.Time `json:"updated_at"`
+    // Version растёт при каждом изменении; клиент получает её в ETag.
+    Version     int       `json:"version"`
     Category    *Category `json:"category,omitempty"`
+
+    // Recurrence хранит правило повторения в формате RRULE (RFC 5545),
+    // RecurrenceStart — начало серии (DTSTART).
+    Recurrence      string     `json:"recurrence,omitempty"`
+    RecurrenceStart *time.Time `json:"recurrence_start,omitempty"`
+
+    // ParentID указывает на родительскую задачу; Position задаёт порядок среди соседей.
+    // AutoComplete завершает задачу, когда завершены все её подзадачи.
+    ParentID     *uint         `json:"parent_id"`
+    Position     int           `json:"position"`
+    AutoComplete bool          `json:"auto_complete"`
+    Progress     *TaskProgress `json:"progress,omitempty"`
+
+    // NextOccurrence заполняется только в ответе на завершение повторяющейся задачи.
+    NextOccurrence *Task `json:"next_occurrence,omitempty"`
+
+    // ClientID выдаёт клиент задаче, созданной без связи; уникален у пользователя.
+    ClientID string `json:"client_id,omitempty"`
+
+    // DeletedAt задан у задачи в корзине.
+    DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
-func CreateTask(title, description string, userID uint, dueDate time.Time, priority Priority, categoryID *uint) (*Task, error) {
+type TaskProgress struct {
+    Total     int `json:"total"`
+    Completed int `json:"completed"`
+}
+
+// DeleteChildren определяет судьбу подзадач при удалении родителя.
+type DeleteChildren string
+
+const (
+    CascadeChildren DeleteChildren = "cascade"
+    PromoteChildren DeleteChildren = "promote"
+)
+
+const taskColumns = `t.id, t.title, t.description, t.completed, t.user_id, t.category_id, t.due_date, t.all_day, t.priority, t.created_at, t.updated_at,
+                t.recurrence, t.recurrence_start, t.parent_id, t.position, t.auto_complete, t.version, COALESCE(t.client_id, ''), t.deleted_at,
+                (SELECT COUNT(*) FROM tasks s WHERE s.parent_id = t.id AND s.deleted_at IS NULL),
+                (SELECT COUNT(*) FROM tasks s WHERE s.parent_id = t.id AND s.deleted_at IS NULL AND s.completed),
+                COALESCE(c.id, 0), COALESCE(c.name, ''), COALESCE(c.user_id, 0), COALESCE(c.created_at, NOW()), COALESCE(c.version, 0)`
+
+type rowScanner interface {
+    Scan(dest ...interface{}) error
+}
+
+// scanTask читает колонки taskColumns; extra получает дополнительные колонки,
+// выбранные после них.
+func scanTask(row rowScanner, extra ...interface{}) (*Task, error) {
     var task Task
-    err := db.DB.QueryRow(
-        `INSERT INTO tasks (title, description, completed, user_id, category_id, due_date, priority, created_at, updated_at) 
-         VALUES ($1, $2, false, $3, $4, $5, $6, NOW(), NOW()) 
-         RETURNING id, title, description, completed, user_id, category_id, due_date, priority, created_at, updated_at`,
-        title, description, userID, categoryID, dueDate, priority,
-    ).Scan(&task.ID, &task.Title, &task.Description, &task.Completed, &task.UserID, &task.CategoryID,
-           &task.DueDate, &task.Priority, &task.CreatedAt, &task.UpdatedAt)
+    var category Category
+    var categoryID *uint
+    var progress TaskProgress
+    dest := []interface{}{
+        &task.ID, &task.Title, &task.Description, &task.Completed, &task.UserID, &categoryID,
+        &task.DueDate, &task.AllDay, &task.Priority, &task.CreatedAt, &task.UpdatedAt,
+        &task.Recurrence, &task.RecurrenceStart, &task.ParentID, &task.Position, &task.AutoComplete, &task.Version, &task.ClientID, &task.DeletedAt,
+        &progress.Total, &progress.Completed,
+        &category.ID, &category.Name, &category.UserID, &category.CreatedAt, &category.Version,
+    }
+    err := row.Scan(append(dest, extra...)...)
+    if err != nil {
+        return nil, err
+    }
+    if progress.Total > 0 {
+        task.Progress = &progress
+    }
+    task.CategoryID = categoryID
+    if categoryID != nil {
+        task.Category = &category
+    }
+    return &task, nil
+}
 
+func (s *PostgresStore) queryTasks(ctx context.Context, query string, args ...interface{}) ([]Task, error) {
+    rows, err := s.db.QueryContext(ctx, query, args...)
     if err != nil {
         return nil, err
     }
+    defer rows.Close()
 
+    var tasks []Task
+    for rows.Next() {
+        task, err := scanTask(rows)
+        if err != nil {
+            return nil, err
+        }
+        tasks = append(tasks, *task)
+    }
+    return tasks, rows.Err()
+}
+
+type execer interface {
+    QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+    ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// checkReferences проверяет, что категория и родительская задача принадлежат
+// владельцу задачи и не лежат в корзине: внешние ключи этого не гарантируют.
+func checkReferences(ctx context.Context, q execer, task *Task) error {
+    var exists bool
     if task.CategoryID != nil {
-        category, err := GetCategory(*task.CategoryID, userID)
-        if err == nil {
-            task.Category = category
+        err := q.QueryRowContext(ctx,
+            "SELECT EXISTS (SELECT 1 FROM categories WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL)",
+            *task.CategoryID, task.UserID,
+        ).Scan(&exists)
+        if err != nil {
+            return err
+        }
+        if !exists {
+            return ErrInvalidCategory
+        }
+    }
+    if task.ParentID != nil {
+        err := q.QueryRowContext(ctx,
+            "SELECT EXISTS (SELECT 1 FROM tasks WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL)",
+            *task.ParentID, task.UserID,
+        ).Scan(&exists)
+        if err != nil {
+            return err
+        }
+        if !exists {
+            return ErrInvalidParent
         }
     }
+    return nil
+}
 
-    return &task, nil
+func insertTask(ctx context.Context, q execer, task *Task) (uint, error) {
+    if err := checkReferences(ctx, q, task); err != nil {
+        return 0, err
+    }
+
+    var id uint
+    err := q.QueryRowContext(ctx,
+        `INSERT INTO tasks (title, description, completed, user_id, category_id, due_date, priority, recurrence, recurrence_start,
+                            parent_id, auto_complete, all_day, client_id, position, created_at, updated_at) 
+         VALUES ($1, $2, false, $3, $4, $5, $6, $7, $8, $9, $10, $11, NULLIF($12, ''),
+                 COALESCE((SELECT MAX(position) + 1 FROM tasks WHERE parent_id = $9 AND deleted_at IS NULL), 0), NOW(), NOW()) 
+         RETURNING id`,
+        task.Title, task.Description, task.UserID, task.CategoryID, task.DueDate, task.Priority,
+        task.Recurrence, task.RecurrenceStart, task.ParentID, task.AutoComplete, task.AllDay, task.ClientID,
+    ).Scan(&id)
+    return id, dbError(err)
+}
+
+func (s *PostgresStore) CreateTask(ctx context.Context, task *Task) (*Task, error) {
+    tx, err := s.beginTx(ctx)
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+
+    id, err := insertTask(ctx, tx, task)
+    if err != nil {
+        return nil, err
+    }
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+
+    return s.GetTask(ctx, id, task.UserID)
+}
+
+func (s *PostgresStore) GetTask(ctx context.Context, id, userID uint) (*Task, error) {
+    task, err := scanTask(s.db.QueryRowContext(ctx,
+        `SELECT `+taskColumns+`
+         FROM tasks t
+         LEFT JOIN categories c ON t.category_id = c.id
+         WHERE t.id = $1 AND t.user_id = $2 AND t.deleted_at IS NULL`,
+        id, userID,
+    ))
+    if err != nil {
+        return nil, notFound(err)
+    }
+    return task, nil
 }
 
-func GetUserTasks(userID uint) ([]Task, error) {
-    rows, err := db.DB.Query(
-        `SELECT t.id, t.title, t.description, t.completed, t.user_id, t.category_id, t.due_date, t.priority, t.created_at, t.updated_at,
-                COALESCE(c.id, 0), COALESCE(c.name, ''), COALESCE(c.user_id, 0), COALESCE(c.created_at, NOW())
+func (s *PostgresStore) GetUserTasks(ctx context.Context, userID uint) ([]Task, error) {
+    return s.queryTasks(ctx,
+        `SELECT `+taskColumns+`
          FROM tasks t
          LEFT JOIN categories c ON t.category_id = c.id
-         WHERE t.user_id = $1 
+         WHERE t.user_id = $1 AND t.deleted_at IS NULL 
          ORDER BY t.due_date ASC, t.priority DESC, t.created_at DESC`,
         userID,
     )
+}
+
+func (s *PostgresStore) ListTasks(ctx context.Context, userID uint, filter TaskFilter) (*TaskPage, error) {
+    keys, err := filter.sortKeys()
+    if err != nil {
+        return nil, err
+    }
+    after, err := decodeCursor(filter.Cursor, filter.Sort, keys)
     if err != nil {
         return nil, err
     }
-    defer rows.Close()
 
-    var tasks []Task
-    for rows.Next() {
-        var task Task
-        var category Category
-        var categoryID *uint
-        err := rows.Scan(
-            &task.ID, &task.Title, &task.Description, &task.Completed, &task.UserID, &categoryID,
-            &task.DueDate, &task.Priority, &task.CreatedAt, &task.UpdatedAt,
-            &category.ID, &category.Name, &category.UserID, &category.CreatedAt,
-        )
-        if err != nil {
-            return nil, err
+    args := []interface{}{userID}
+    arg := func(v interface{}) string {
+        args = append(args, v)
+        return fmt.Sprintf("$%d", len(args))
+    }
+
+    conditions := []string{"t.user_id = $1", "t.deleted_at IS NULL"}
+    if filter.Completed != nil {
+        conditions = append(conditions, "t.completed = "+arg(*filter.Completed))
+    }
+    if filter.Priority != nil {
+        conditions = append(conditions, "t.priority = "+arg(*filter.Priority))
+    }
+    if filter.CategoryID != nil {
+        conditions = append(conditions, "t.category_id = "+arg(*filter.CategoryID))
+    }
+    if filter.DueFrom != nil {
+        conditions = append(conditions, "t.due_date >= "+arg(*filter.DueFrom))
+    }
+    if filter.DueTo != nil {
+        conditions = append(conditions, "t.due_date <= "+arg(*filter.DueTo))
+    }
+    if filter.Overdue != nil {
+        overdue := "(NOT t.completed AND " + taskDeadline("(SELECT timezone FROM users WHERE id = $1)") + " < NOW())"
+        if !*filter.Overdue {
+            overdue = "NOT " + overdue
+        }
+        conditions = append(conditions, overdue)
+    }
+    if filter.Query != "" {
+        pattern := arg("%" + escapeLike(filter.Query) + "%")
+        conditions = append(conditions, "(t.title ILIKE "+pattern+" OR t.description ILIKE "+pattern+")")
+    }
+
+    // Keyset-пагинация: (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ... с учётом направления
+    if after != nil {
+        var alternatives []string
+        for i, key := range keys {
+            var parts []string
+            for j := 0; j < i; j++ {
+                parts = append(parts, "t."+keys[j].column+" = "+arg(after[j]))
+            }
+            op := " > "
+            if key.desc {
+                op = " < "
+            }
+            parts = append(parts, "t."+key.column+op+arg(after[i]))
+            alternatives = append(alternatives, "("+strings.Join(parts, " AND ")+")")
         }
-        task.CategoryID = categoryID
-        if categoryID != nil {
-            task.Category = &category
+        conditions = append(conditions, "("+strings.Join(alternatives, " OR ")+")")
+    }
+
+    var order []string
+    for _, key := range keys {
+        direction := "ASC"
+        if key.desc {
+            direction = "DESC"
         }
-        tasks = append(tasks, task)
+        order = append(order, "t."+key.column+" "+direction)
+    }
+
+    limit := filter.limit()
+    tasks, err := s.queryTasks(ctx,
+        `SELECT `+taskColumns+`
+         FROM tasks t
+         LEFT JOIN categories c ON t.category_id = c.id
+         WHERE `+strings.Join(conditions, " AND ")+`
+         ORDER BY `+strings.Join(order, ", ")+`
+         LIMIT `+arg(limit+1),
+        args...,
+    )
+    if err != nil {
+        return nil, err
+    }
+
+    page := &TaskPage{Tasks: tasks}
+    if page.Tasks == nil {
+        page.Tasks = []Task{}
+    }
+    if len(tasks) > limit {
+        page.Tasks = tasks[:limit]
+        page.NextCursor = encodeCursor(filter.Sort, keys, &page.Tasks[limit-1])
     }
-    return tasks, nil
+    return page, nil
 }
 
-func UpdateTask(id uint, title, description string, completed bool, dueDate time.Time, priority Priority, categoryID *uint) (*Task, error) {
-    var task Task
-    err := db.DB.QueryRow(
-        `UPDATE tasks 
-         SET title = $1, description = $2, completed = $3, due_date = $4, priority = $5, category_id = $6, updated_at = NOW() 
-         WHERE id = $7 
-         RETURNING id, title, description, completed, user_id, category_id, due_date, priority, created_at, updated_at`,
-        title, description, completed, dueDate, priority, categoryID, id,
-    ).Scan(&task.ID, &task.Title, &task.Description, &task.Completed, &task.UserID, &task.CategoryID,
-           &task.DueDate, &task.Priority, &task.CreatedAt, &task.UpdatedAt)
+// taskDeadline повторяет Task.Deadline в SQL; tz — выражение с именем
+// часового пояса владельца задачи t.
+func taskDeadline(tz string) string {
+    return `CASE WHEN t.all_day
+                 THEN (((t.due_date AT TIME ZONE ` + tz + `)::date + 1)::timestamp AT TIME ZONE ` + tz + `)
+                 ELSE t.due_date END`
+}
+
+func escapeLike(s string) string {
+    return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// UpdateTask изменяет задачу, только если она принадлежит task.UserID;
+// чужая задача неотличима от несуществующей.
+func (s *PostgresStore) UpdateTask(ctx context.Context, task *Task) (*Task, error) {
+    return s.PatchTask(ctx, task.ID, task.UserID, NewTaskPatch(task))
+}
 
+// UpdateTaskCategory переносит задачу в категорию того же пользователя;
+// categoryID = 0 убирает задачу из категории. version, если не ноль, —
+// ожидаемая версия задачи.
+func (s *PostgresStore) UpdateTaskCategory(ctx context.Context, taskID, categoryID, userID uint, version int) (*Task, error) {
+    tx, err := s.beginTx(ctx)
     if err != nil {
         return nil, err
     }
+    defer tx.Rollback()
 
-    if task.CategoryID != nil {
-        category, err := GetCategory(*task.CategoryID, task.UserID)
-        if err == nil {
-            task.Category = category
+    var category *uint
+    if categoryID != 0 {
+        category = &categoryID
+        if err := checkReferences(ctx, tx, &Task{UserID: userID, CategoryID: category}); err != nil {
+            return nil, err
         }
     }
 
-    return &task, nil
+    result, err := tx.ExecContext(ctx,
+        `UPDATE tasks SET category_id = $1, trashed_category_id = NULL, updated_at = NOW() 
+         WHERE id = $2 AND user_id = $3 AND deleted_at IS NULL AND ($4 = 0 OR version = $4)`,
+        category, taskID, userID, version,
+    )
+    if err != nil {
+        return nil, dbError(err)
+    }
+
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return nil, err
+    }
+
+    if rowsAffected == 0 {
+        return nil, versionConflict(ctx, tx, "tasks", taskID, userID, version)
+    }
+
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+
+    return s.GetTask(ctx, taskID, userID)
 }
 
-func DeleteTask(id uint) error {
-    _, err := db.DB.Exec("DELETE FROM tasks WHERE id = $1", id)
-    return err
-} 
\ No newline at end of file
+// DeleteTask переносит задачу пользователя в корзину вместе с подзадачами;
+// version, если не ноль, — ожидаемая версия задачи. У задач, удалённых
+// вместе, одинаковый deleted_at: по нему их восстанавливает RestoreTask.
+func (s *PostgresStore) DeleteTask(ctx context.Context, id, userID uint, children DeleteChildren, version int) error {
+    tx, err := s.beginTx(ctx)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    var current int
+    err = tx.QueryRowContext(ctx,
+        "SELECT version FROM tasks WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL FOR UPDATE",
+        id, userID,
+    ).Scan(&current)
+    if err != nil {
+        return notFound(err)
+    }
+    if version != 0 && version != current {
+        return ErrVersionMismatch
+    }
+
+    // Подзадачи поднимаются на уровень удаляемой задачи; иначе уходят в корзину вместе с ней
+    if children == PromoteChildren {
+        _, err = tx.ExecContext(ctx,
+            `UPDATE tasks 
+             SET parent_id = (SELECT parent_id FROM tasks WHERE id = $1), updated_at = NOW() 
+             WHERE parent_id = $1 AND deleted_at IS NULL`,
+            id,
+        )
+        if err != nil {
+            return err
+        }
+    }
+
+    _, err = tx.ExecContext(ctx,
+        `WITH RECURSIVE subtree AS (
+             SELECT id FROM tasks WHERE id = $1
+             UNION ALL
+             SELECT t.id FROM tasks t JOIN subtree s ON t.parent_id = s.id WHERE t.deleted_at IS NULL
+         )
+         UPDATE tasks SET deleted_at = NOW(), updated_at = NOW() 
+         WHERE id IN (SELECT id FROM subtree)`,
+        id,
+    )
+    if err != nil {
+        return err
+    }
+    return tx.Commit()
+}
+
+// CreateNextOccurrence создаёт следующее вхождение серии и переносит на него
+// правило повторения, снимая его с завершённой задачи.
+func (s *PostgresStore) CreateNextOccurrence(ctx context.Context, completed *Task, next *Task) (*Task, error) {
+    tx, err := s.beginTx(ctx)
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+
+    id, err := insertTask(ctx, tx, next)
+    if err != nil {
+        return nil, err
+    }
+
+    _, err = tx.ExecContext(ctx,
+        "UPDATE tasks SET recurrence = '', recurrence_start = NULL, updated_at = NOW() WHERE id = $1",
+        completed.ID,
+    )
+    if err != nil {
+        return nil, err
+    }
+
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+
+    return s.GetTask(ctx, id, next.UserID)
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+    "time"
+)
+
+const DefaultTimezone = "UTC"
+
+// ValidateTimezone принимает только имена из базы IANA, например
+// "Asia/Vladivostok"; пустое имя означает DefaultTimezone.
+func ValidateTimezone(name string) error {
+    if name == "" {
+        return nil
+    }
+    if _, err := time.LoadLocation(name); err != nil || name == "Local" {
+        return Invalid("timezone", "unknown time zone")
+    }
+    return nil
+}
+
+// Location возвращает часовой пояс пользователя; неизвестное имя
+// трактуется как UTC.
+func Location(name string) *time.Location {
+    loc, err := time.LoadLocation(name)
+    if err != nil || name == "" || name == "Local" {
+        return time.UTC
+    }
+    return loc
+}
+
+// StartOfDay возвращает полночь дня t в часовом поясе loc.
+func StartOfDay(t time.Time, loc *time.Location) time.Time {
+    y, m, d := t.In(loc).Date()
+    return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+// Deadline — момент, после которого задача просрочена. Задача на весь день
+// просрочена только с началом следующего дня в часовом поясе владельца.
+func (t *Task) Deadline(loc *time.Location) time.Time {
+    if t.AllDay {
+        return StartOfDay(t.DueDate, loc).AddDate(0, 0, 1)
+    }
+    return t.DueDate
+}
@@ -0,0 +1,146 @@
+package models
+
+import (
+    "context"
+    "time"
+)
+
+// Trash — содержимое корзины пользователя, сначала удалённые последними.
+type Trash struct {
+    Tasks      []Task     `json:"tasks"`
+    Categories []Category `json:"categories"`
+}
+
+func (s *PostgresStore) ListTrash(ctx context.Context, userID uint) (*Trash, error) {
+    tasks, err := s.queryTasks(ctx,
+        `SELECT `+taskColumns+`
+         FROM tasks t
+         LEFT JOIN categories c ON t.category_id = c.id
+         WHERE t.user_id = $1 AND t.deleted_at IS NOT NULL 
+         ORDER BY t.deleted_at DESC, t.id ASC`,
+        userID,
+    )
+    if err != nil {
+        return nil, err
+    }
+    categories, err := s.queryCategories(ctx,
+        `SELECT `+categoryColumns+` 
+         FROM categories 
+         WHERE user_id = $1 AND deleted_at IS NOT NULL 
+         ORDER BY deleted_at DESC, id ASC`,
+        userID,
+    )
+    if err != nil {
+        return nil, err
+    }
+
+    trash := &Trash{Tasks: tasks, Categories: categories}
+    if trash.Tasks == nil {
+        trash.Tasks = []Task{}
+    }
+    if trash.Categories == nil {
+        trash.Categories = []Category{}
+    }
+    return trash, nil
+}
+
+// RestoreTask возвращает задачу из корзины вместе с подзадачами, удалёнными
+// одновременно с ней. Если родитель остался в корзине, задача поднимается на
+// верхний уровень.
+func (s *PostgresStore) RestoreTask(ctx context.Context, id, userID uint) (*Task, error) {
+    tx, err := s.beginTx(ctx)
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+
+    var orphaned bool
+    err = tx.QueryRowContext(ctx,
+        `SELECT COALESCE(p.deleted_at IS NOT NULL, false)
+         FROM tasks t
+         LEFT JOIN tasks p ON p.id = t.parent_id
+         WHERE t.id = $1 AND t.user_id = $2 AND t.deleted_at IS NOT NULL
+         FOR UPDATE OF t`,
+        id, userID,
+    ).Scan(&orphaned)
+    if err != nil {
+        return nil, notFound(err)
+    }
+
+    _, err = tx.ExecContext(ctx,
+        `WITH RECURSIVE subtree AS (
+             SELECT id, deleted_at FROM tasks WHERE id = $1
+             UNION ALL
+             SELECT t.id, t.deleted_at FROM tasks t JOIN subtree s ON t.parent_id = s.id AND t.deleted_at = s.deleted_at
+         )
+         UPDATE tasks 
+         SET deleted_at = NULL,
+             parent_id = CASE WHEN id = $1 AND $2 THEN NULL ELSE parent_id END,
+             position = CASE WHEN id = $1 AND $2 THEN 0 ELSE position END,
+             updated_at = NOW() 
+         WHERE id IN (SELECT id FROM subtree)`,
+        id, orphaned,
+    )
+    if err != nil {
+        return nil, err
+    }
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+
+    return s.GetTask(ctx, id, userID)
+}
+
+// RestoreCategory возвращает категорию из корзины и задачи, которые были в
+// ней при удалении и с тех пор не перенесены в другую категорию.
+func (s *PostgresStore) RestoreCategory(ctx context.Context, id, userID uint) (*Category, error) {
+    tx, err := s.beginTx(ctx)
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+
+    category, err := scanCategory(tx.QueryRowContext(ctx,
+        `UPDATE categories SET deleted_at = NULL 
+         WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL 
+         RETURNING `+categoryColumns,
+        id, userID,
+    ))
+    if err != nil {
+        return nil, notFound(err)
+    }
+
+    _, err = tx.ExecContext(ctx,
+        `UPDATE tasks SET category_id = $1, trashed_category_id = NULL, updated_at = NOW() 
+         WHERE trashed_category_id = $1 AND category_id IS NULL`,
+        id,
+    )
+    if err != nil {
+        return nil, err
+    }
+    return category, tx.Commit()
+}
+
+// PurgeTrash окончательно удаляет задачи и категории, попавшие в корзину до
+// before, и возвращает их число.
+func (s *PostgresStore) PurgeTrash(ctx context.Context, before time.Time) (int64, error) {
+    tx, err := s.beginTx(ctx)
+    if err != nil {
+        return 0, err
+    }
+    defer tx.Rollback()
+
+    var purged int64
+    for _, table := range []string{"tasks", "categories"} {
+        result, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE deleted_at < $1", before)
+        if err != nil {
+            return 0, err
+        }
+        n, err := result.RowsAffected()
+        if err != nil {
+            return 0, err
+        }
+        purged += n
+    }
+    return purged, tx.Commit()
+}
@@ -1,7 +1,8 @@
 package models
 
 import (
-    "todo-app/internal/db"
+    "context"
+    "time"
     "golang.org/x/crypto/bcrypt"
 )
 
@@ -10,46 +11,102 @@ type User struct {
     Email    string `json:"email"`
     Password string `json:"-"`
     Name     string `json:"name"`
+    Timezone string `json:"timezone"`
+    // Locale — язык уведомлений; пустой означает язык из Accept-Language.
+    Locale string `json:"locale"`
+    // EmailVerifiedAt пуст, пока адрес не подтверждён ссылкой из письма.
+    EmailVerifiedAt *time.Time `json:"email_verified_at"`
 }
 
-func CreateUser(email, password, name string) (*User, error) {
+// Verified сообщает, подтверждён ли адрес; без этого часть возможностей
+// недоступна.
+func (u *User) Verified() bool {
+    return u.EmailVerifiedAt != nil
+}
+
+func HashPassword(password string) (string, error) {
     hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+    if err != nil {
+        return "", err
+    }
+    return string(hashedPassword), nil
+}
+
+// CreateUser создаёт пользователя; пустой timezone означает DefaultTimezone.
+func (s *PostgresStore) CreateUser(ctx context.Context, email, password, name, timezone string) (*User, error) {
+    hashedPassword, err := HashPassword(password)
     if err != nil {
         return nil, err
     }
 
+    if timezone == "" {
+        timezone = DefaultTimezone
+    }
+
     var id uint
-    err = db.DB.QueryRow(
-        "INSERT INTO users (email, password, name) VALUES ($1, $2, $3) RETURNING id",
-        email, string(hashedPassword), name,
+    err = s.db.QueryRowContext(ctx,
+        "INSERT INTO users (email, password, name, timezone) VALUES ($1, $2, $3, $4) RETURNING id",
+        email, hashedPassword, name, timezone,
     ).Scan(&id)
     if err != nil {
-        return nil, err
+        return nil, dbError(err)
     }
 
     return &User{
-        ID:    id,
-        Email: email,
-        Name:  name,
+        ID:       id,
+        Email:    email,
+        Name:     name,
+        Timezone: timezone,
     }, nil
 }
 
-func GetUserByEmail(email string) (*User, error) {
+func (s *PostgresStore) GetUserByEmail(ctx context.Context, email string) (*User, error) {
     var user User
     var hashedPassword string
-    err := db.DB.QueryRow(
-        "SELECT id, email, password, name FROM users WHERE email = $1",
+    err := s.db.QueryRowContext(ctx,
+        "SELECT id, email, password, name, timezone, locale, email_verified_at FROM users WHERE email = $1",
         email,
-    ).Scan(&user.ID, &user.Email, &hashedPassword, &user.Name)
+    ).Scan(&user.ID, &user.Email, &hashedPassword, &user.Name, &user.Timezone, &user.Locale, &user.EmailVerifiedAt)
     if err != nil {
-        return nil, err
+        return nil, notFound(err)
     }
 
     user.Password = hashedPassword
     return &user, nil
 }
 
+func (s *PostgresStore) GetUserByID(ctx context.Context, id uint) (*User, error) {
+    var user User
+    err := s.db.QueryRowContext(ctx,
+        "SELECT id, email, password, name, timezone, locale, email_verified_at FROM users WHERE id = $1",
+        id,
+    ).Scan(&user.ID, &user.Email, &user.Password, &user.Name, &user.Timezone, &user.Locale, &user.EmailVerifiedAt)
+    if err != nil {
+        return nil, notFound(err)
+    }
+    return &user, nil
+}
+
+// UpdateUserProfile меняет имя, часовой пояс и язык пользователя.
+func (s *PostgresStore) UpdateUserProfile(ctx context.Context, id uint, name, timezone, locale string) (*User, error) {
+    result, err := s.db.ExecContext(ctx,
+        "UPDATE users SET name = $1, timezone = $2, locale = $3 WHERE id = $4",
+        name, timezone, locale, id,
+    )
+    if err != nil {
+        return nil, err
+    }
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return nil, err
+    }
+    if rowsAffected == 0 {
+        return nil, ErrNotFound
+    }
+    return s.GetUserByID(ctx, id)
+}
+
 func (u *User) CheckPassword(password string) bool {
     err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
     return err == nil
-} 
\ No newline at end of file
+}
@@ -0,0 +1,359 @@
+package models
+
+import (
+    "context"
+    "crypto/rand"
+    "database/sql"
+    "encoding/base64"
+    "encoding/json"
+    "fmt"
+    "net/url"
+    "strings"
+    "time"
+    "github.com/lib/pq"
+)
+
+// EventWebhookTest — событие проверочной доставки; на него нельзя подписаться.
+const EventWebhookTest = "webhook.test"
+
+const (
+    maxWebhookURLLength = 2048
+
+    DefaultWebhookDeliveries = 50
+    MaxWebhookDeliveries     = 200
+)
+
+// WebhookEvents — события, на которые можно подписать вебхук. Подписка
+// "task.*" включает все события группы.
+var WebhookEvents = []string{
+    EventTaskCreated,
+    EventTaskUpdated,
+    EventTaskCompleted,
+    EventTaskDeleted,
+    EventTaskRestored,
+    EventCategoryCreated,
+    EventCategoryUpdated,
+    EventCategoryDeleted,
+    EventCategoryRestored,
+    EventNotificationCreated,
+}
+
+// Webhook — адрес, на который отправляются события пользователя. Secret
+// подписывает тела запросов и показывается только при создании и смене.
+type Webhook struct {
+    ID        uint      `json:"id"`
+    UserID    uint      `json:"-"`
+    URL       string    `json:"url"`
+    Secret    string    `json:"secret,omitempty"`
+    Events    []string  `json:"events"`
+    Active    bool      `json:"active"`
+    CreatedAt time.Time `json:"created_at"`
+    UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NewWebhookSecret возвращает случайный ключ подписи.
+func NewWebhookSecret() (string, error) {
+    buf := make([]byte, 32)
+    if _, err := rand.Read(buf); err != nil {
+        return "", err
+    }
+    return "whsec_" + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func (w *Webhook) Validate() error {
+    u, err := url.Parse(w.URL)
+    if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(w.URL) > maxWebhookURLLength {
+        return Invalid("url", "url must be an absolute http or https URL")
+    }
+    if len(w.Events) == 0 {
+        return Invalid("events", "at least one event is required")
+    }
+    for _, event := range w.Events {
+        if !isWebhookEvent(event) {
+            return Invalid("events", fmt.Sprintf("unknown event %q", event))
+        }
+    }
+    return nil
+}
+
+func isWebhookEvent(event string) bool {
+    group := strings.TrimSuffix(event, ".*")
+    for _, e := range WebhookEvents {
+        if e == event || (group != event && strings.HasPrefix(e, group+".")) {
+            return true
+        }
+    }
+    return false
+}
+
+// Subscribed сообщает, подписан ли вебхук на событие. Повторяет отбор в
+// функции queue_webhooks.
+func (w *Webhook) Subscribed(event string) bool {
+    group := event
+    if i := strings.Index(event, "."); i >= 0 {
+        group = event[:i]
+    }
+    for _, e := range w.Events {
+        if e == event || e == group+".*" {
+            return true
+        }
+    }
+    return false
+}
+
+// WebhookDelivery — отправка одного события на один вебхук; вместе они
+// образуют журнал доставок.
+type WebhookDelivery struct {
+    ID             uint            `json:"id"`
+    WebhookID      uint            `json:"webhook_id"`
+    Event          string          `json:"event"`
+    Data           json.RawMessage `json:"data"`
+    Status         DeliveryStatus  `json:"status"`
+    Attempts       int             `json:"attempts"`
+    ResponseStatus *int            `json:"response_status,omitempty"`
+    LastError      string          `json:"last_error,omitempty"`
+    NextAttemptAt  time.Time       `json:"next_attempt_at"`
+    DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+    CreatedAt      time.Time       `json:"created_at"`
+}
+
+// PendingWebhookDelivery — доставка, захваченная отправителем, с адресом и
+// ключом подписи.
+type PendingWebhookDelivery struct {
+    WebhookDelivery
+    URL    string
+    Secret string
+}
+
+// WebhookResult — итог попытки; ResponseStatus равен нулю, если ответа не
+// было.
+type WebhookResult struct {
+    Status         DeliveryStatus
+    ResponseStatus int
+    Error          string
+    RetryAt        *time.Time
+}
+
+const webhookColumns = `w.id, w.user_id, w.url, w.secret, w.events, w.active, w.created_at, w.updated_at`
+
+func scanWebhook(row rowScanner) (*Webhook, error) {
+    var w Webhook
+    err := row.Scan(&w.ID, &w.UserID, &w.URL, &w.Secret, pq.Array(&w.Events), &w.Active, &w.CreatedAt, &w.UpdatedAt)
+    if err != nil {
+        return nil, err
+    }
+    return &w, nil
+}
+
+const webhookDeliveryColumns = `d.id, d.webhook_id, d.event, d.data, d.status, d.attempts, d.response_status, d.last_error, d.next_attempt_at, d.delivered_at, d.created_at`
+
+func scanWebhookDelivery(row rowScanner, extra ...interface{}) (*WebhookDelivery, error) {
+    var d WebhookDelivery
+    var data []byte
+    var responseStatus sql.NullInt64
+    dest := []interface{}{
+        &d.ID, &d.WebhookID, &d.Event, &data, &d.Status, &d.Attempts, &responseStatus, &d.LastError, &d.NextAttemptAt, &d.DeliveredAt, &d.CreatedAt,
+    }
+    if err := row.Scan(append(dest, extra...)...); err != nil {
+        return nil, err
+    }
+    d.Data = data
+    if responseStatus.Valid {
+        status := int(responseStatus.Int64)
+        d.ResponseStatus = &status
+    }
+    return &d, nil
+}
+
+func (s *PostgresStore) CreateWebhook(ctx context.Context, webhook *Webhook) (*Webhook, error) {
+    created, err := scanWebhook(s.db.QueryRowContext(ctx,
+        `INSERT INTO webhooks AS w (user_id, url, secret, events, active)
+         VALUES ($1, $2, $3, $4, $5)
+         RETURNING `+webhookColumns,
+        webhook.UserID, webhook.URL, webhook.Secret, pq.Array(webhook.Events), webhook.Active,
+    ))
+    if err != nil {
+        return nil, dbError(err)
+    }
+    return created, nil
+}
+
+func (s *PostgresStore) GetWebhook(ctx context.Context, id, userID uint) (*Webhook, error) {
+    webhook, err := scanWebhook(s.db.QueryRowContext(ctx,
+        `SELECT `+webhookColumns+` FROM webhooks w WHERE w.id = $1 AND w.user_id = $2`,
+        id, userID,
+    ))
+    return webhook, notFound(err)
+}
+
+func (s *PostgresStore) GetUserWebhooks(ctx context.Context, userID uint) ([]Webhook, error) {
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT `+webhookColumns+` FROM webhooks w WHERE w.user_id = $1 ORDER BY w.id`,
+        userID,
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    webhooks := []Webhook{}
+    for rows.Next() {
+        webhook, err := scanWebhook(rows)
+        if err != nil {
+            return nil, err
+        }
+        webhooks = append(webhooks, *webhook)
+    }
+    return webhooks, rows.Err()
+}
+
+// UpdateWebhook меняет адрес, подписки и активность; пустой Secret оставляет
+// прежний ключ.
+func (s *PostgresStore) UpdateWebhook(ctx context.Context, webhook *Webhook) (*Webhook, error) {
+    updated, err := scanWebhook(s.db.QueryRowContext(ctx,
+        `UPDATE webhooks AS w
+         SET url = $3, events = $4, active = $5, secret = COALESCE(NULLIF($6, ''), secret), updated_at = NOW()
+         WHERE w.id = $1 AND w.user_id = $2
+         RETURNING `+webhookColumns,
+        webhook.ID, webhook.UserID, webhook.URL, pq.Array(webhook.Events), webhook.Active, webhook.Secret,
+    ))
+    if err != nil {
+        return nil, dbError(err)
+    }
+    return updated, nil
+}
+
+func (s *PostgresStore) DeleteWebhook(ctx context.Context, id, userID uint) error {
+    result, err := s.db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1 AND user_id = $2", id, userID)
+    if err != nil {
+        return err
+    }
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if rowsAffected == 0 {
+        return ErrNotFound
+    }
+    return nil
+}
+
+// GetWebhookDeliveries возвращает последние limit доставок вебхука, новые
+// первыми.
+func (s *PostgresStore) GetWebhookDeliveries(ctx context.Context, webhookID, userID uint, limit int) ([]WebhookDelivery, error) {
+    if _, err := s.GetWebhook(ctx, webhookID, userID); err != nil {
+        return nil, err
+    }
+
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT `+webhookDeliveryColumns+`
+         FROM webhook_deliveries d
+         WHERE d.webhook_id = $1
+         ORDER BY d.id DESC
+         LIMIT $2`,
+        webhookID, limit,
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    deliveries := []WebhookDelivery{}
+    for rows.Next() {
+        d, err := scanWebhookDelivery(rows)
+        if err != nil {
+            return nil, err
+        }
+        deliveries = append(deliveries, *d)
+    }
+    return deliveries, rows.Err()
+}
+
+// QueueWebhookTest ставит в очередь проверочное событие webhook.test.
+func (s *PostgresStore) QueueWebhookTest(ctx context.Context, webhookID, userID uint) (*WebhookDelivery, error) {
+    d, err := scanWebhookDelivery(s.db.QueryRowContext(ctx,
+        `INSERT INTO webhook_deliveries AS d (webhook_id, event, data)
+         SELECT id, $3, jsonb_build_object('webhook_id', id) FROM webhooks WHERE id = $1 AND user_id = $2
+         RETURNING `+webhookDeliveryColumns,
+        webhookID, userID, EventWebhookTest,
+    ))
+    return d, notFound(err)
+}
+
+// ClaimWebhookDeliveries забирает до limit доставок активных вебхуков так же,
+// как ClaimDeliveries; доставки выключенного вебхука ждут его включения.
+func (s *PostgresStore) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]PendingWebhookDelivery, error) {
+    rows, err := s.db.QueryContext(ctx,
+        `WITH claimed AS (
+             UPDATE webhook_deliveries
+             SET attempts = attempts + 1, next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond'
+             WHERE id IN (
+                 SELECT d.id FROM webhook_deliveries d
+                 JOIN webhooks w ON w.id = d.webhook_id
+                 WHERE d.status = 'pending' AND d.next_attempt_at <= NOW() AND w.active
+                 ORDER BY d.next_attempt_at, d.id
+                 LIMIT $1
+                 FOR UPDATE OF d SKIP LOCKED
+             )
+             RETURNING *
+         )
+         SELECT `+webhookDeliveryColumns+`, w.url, w.secret
+         FROM claimed d
+         JOIN webhooks w ON w.id = d.webhook_id
+         ORDER BY d.id`,
+        limit, lease.Milliseconds(),
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var claimed []PendingWebhookDelivery
+    for rows.Next() {
+        var p PendingWebhookDelivery
+        d, err := scanWebhookDelivery(rows, &p.URL, &p.Secret)
+        if err != nil {
+            return nil, err
+        }
+        p.WebhookDelivery = *d
+        claimed = append(claimed, p)
+    }
+    return claimed, rows.Err()
+}
+
+func (s *PostgresStore) CompleteWebhookDelivery(ctx context.Context, id uint, result WebhookResult) error {
+    var responseStatus *int
+    if result.ResponseStatus != 0 {
+        responseStatus = &result.ResponseStatus
+    }
+    res, err := s.db.ExecContext(ctx,
+        `UPDATE webhook_deliveries
+         SET status = $2, response_status = $3, last_error = $4, next_attempt_at = COALESCE($5, next_attempt_at),
+             delivered_at = CASE WHEN $6 THEN NOW() ELSE delivered_at END
+         WHERE id = $1`,
+        id, result.Status, responseStatus, result.Error, result.RetryAt, result.Status == DeliverySent,
+    )
+    if err != nil {
+        return err
+    }
+    rowsAffected, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if rowsAffected == 0 {
+        return ErrNotFound
+    }
+    return nil
+}
+
+// PurgeWebhookDeliveries удаляет из журнала завершённые доставки старше before.
+func (s *PostgresStore) PurgeWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+    result, err := s.db.ExecContext(ctx,
+        "DELETE FROM webhook_deliveries WHERE created_at < $1 AND status <> 'pending'",
+        before,
+    )
+    if err != nil {
+        return 0, err
+    }
+    return result.RowsAffected()
+}
@@ -0,0 +1,374 @@
+// Package recurrence реализует подмножество RRULE из RFC 5545:
+// FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, BYDAY, UNTIL и COUNT.
+package recurrence
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+type Frequency string
+
+const (
+    Daily   Frequency = "DAILY"
+    Weekly  Frequency = "WEEKLY"
+    Monthly Frequency = "MONTHLY"
+    Yearly  Frequency = "YEARLY"
+)
+
+// Защита от бесконечного перебора для правил, которые почти ничего не порождают.
+const maxPeriods = 100000
+
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+// WeekdayNum — элемент BYDAY: день недели с необязательным порядковым номером
+// (1MO — первый понедельник, -1FR — последняя пятница).
+type WeekdayNum struct {
+    N   int
+    Day time.Weekday
+}
+
+type Rule struct {
+    Freq     Frequency
+    Interval int
+    ByDay    []WeekdayNum
+    Until    *time.Time
+    Count    int
+}
+
+var weekdayCodes = map[string]time.Weekday{
+    "MO": time.Monday,
+    "TU": time.Tuesday,
+    "WE": time.Wednesday,
+    "TH": time.Thursday,
+    "FR": time.Friday,
+    "SA": time.Saturday,
+    "SU": time.Sunday,
+}
+
+var weekdayNames = map[time.Weekday]string{
+    time.Monday:    "MO",
+    time.Tuesday:   "TU",
+    time.Wednesday: "WE",
+    time.Thursday:  "TH",
+    time.Friday:    "FR",
+    time.Saturday:  "SA",
+    time.Sunday:    "SU",
+}
+
+func invalid(format string, args ...interface{}) error {
+    return fmt.Errorf("%w: %s", ErrInvalidRule, fmt.Sprintf(format, args...))
+}
+
+// Parse разбирает строку вида "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE".
+// Префикс "RRULE:" допускается.
+func Parse(s string) (*Rule, error) {
+    s = strings.TrimSpace(s)
+    s = strings.TrimPrefix(strings.TrimPrefix(s, "RRULE:"), "rrule:")
+    if s == "" {
+        return nil, invalid("empty rule")
+    }
+
+    rule := &Rule{Interval: 1}
+    seen := map[string]bool{}
+    for _, part := range strings.Split(s, ";") {
+        if part == "" {
+            continue
+        }
+        key, value, ok := strings.Cut(part, "=")
+        if !ok {
+            return nil, invalid("malformed part %q", part)
+        }
+        key = strings.ToUpper(strings.TrimSpace(key))
+        value = strings.ToUpper(strings.TrimSpace(value))
+        if seen[key] {
+            return nil, invalid("duplicate %s", key)
+        }
+        seen[key] = true
+
+        switch key {
+        case "FREQ":
+            switch Frequency(value) {
+            case Daily, Weekly, Monthly, Yearly:
+                rule.Freq = Frequency(value)
+            default:
+                return nil, invalid("unsupported FREQ %q", value)
+            }
+        case "INTERVAL":
+            n, err := strconv.Atoi(value)
+            if err != nil || n < 1 {
+                return nil, invalid("INTERVAL must be a positive integer")
+            }
+            rule.Interval = n
+        case "COUNT":
+            n, err := strconv.Atoi(value)
+            if err != nil || n < 1 {
+                return nil, invalid("COUNT must be a positive integer")
+            }
+            rule.Count = n
+        case "UNTIL":
+            until, err := parseUntil(value)
+            if err != nil {
+                return nil, err
+            }
+            rule.Until = &until
+        case "BYDAY":
+            for _, item := range strings.Split(value, ",") {
+                wd, err := parseWeekdayNum(item)
+                if err != nil {
+                    return nil, err
+                }
+                rule.ByDay = append(rule.ByDay, wd)
+            }
+        case "WKST":
+            if value != "MO" {
+                return nil, invalid("only WKST=MO is supported")
+            }
+        default:
+            return nil, invalid("unsupported part %s", key)
+        }
+    }
+
+    if err := rule.Validate(); err != nil {
+        return nil, err
+    }
+    return rule, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+    for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+        if t, err := time.Parse(layout, value); err == nil {
+            if layout == "20060102" {
+                // Дата без времени включает весь день
+                t = t.Add(24*time.Hour - time.Second)
+            }
+            return t, nil
+        }
+    }
+    return time.Time{}, invalid("malformed UNTIL %q", value)
+}
+
+func parseWeekdayNum(s string) (WeekdayNum, error) {
+    s = strings.TrimSpace(s)
+    if len(s) < 2 {
+        return WeekdayNum{}, invalid("malformed BYDAY %q", s)
+    }
+    day, ok := weekdayCodes[s[len(s)-2:]]
+    if !ok {
+        return WeekdayNum{}, invalid("unknown weekday %q", s)
+    }
+    wd := WeekdayNum{Day: day}
+    if prefix := s[:len(s)-2]; prefix != "" {
+        n, err := strconv.Atoi(prefix)
+        if err != nil || n == 0 || n > 5 || n < -5 {
+            return WeekdayNum{}, invalid("malformed BYDAY ordinal %q", s)
+        }
+        wd.N = n
+    }
+    return wd, nil
+}
+
+func (r *Rule) Validate() error {
+    if r.Freq == "" {
+        return invalid("FREQ is required")
+    }
+    if r.Interval < 1 {
+        return invalid("INTERVAL must be a positive integer")
+    }
+    if r.Until != nil && r.Count > 0 {
+        return invalid("UNTIL and COUNT are mutually exclusive")
+    }
+    for _, wd := range r.ByDay {
+        if wd.N != 0 && r.Freq != Monthly {
+            return invalid("BYDAY ordinals are only supported with FREQ=MONTHLY")
+        }
+    }
+    if r.Freq == Yearly && len(r.ByDay) > 0 {
+        return invalid("BYDAY is not supported with FREQ=YEARLY")
+    }
+    return nil
+}
+
+func (r *Rule) String() string {
+    parts := []string{"FREQ=" + string(r.Freq)}
+    if r.Interval > 1 {
+        parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+    }
+    if len(r.ByDay) > 0 {
+        days := make([]string, len(r.ByDay))
+        for i, wd := range r.ByDay {
+            days[i] = weekdayNames[wd.Day]
+            if wd.N != 0 {
+                days[i] = strconv.Itoa(wd.N) + days[i]
+            }
+        }
+        parts = append(parts, "BYDAY="+strings.Join(days, ","))
+    }
+    if r.Until != nil {
+        parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+    }
+    if r.Count > 0 {
+        parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+    }
+    return strings.Join(parts, ";")
+}
+
+// each перебирает вхождения серии, начинающейся в dtstart, в хронологическом
+// порядке, пока fn возвращает true.
+func (r *Rule) each(dtstart time.Time, fn func(time.Time) bool) {
+    emitted := 0
+    for period := 0; period < maxPeriods; period++ {
+        candidates := r.expand(dtstart, period)
+        for _, c := range candidates {
+            if c.Before(dtstart) {
+                continue
+            }
+            if r.Until != nil && c.After(*r.Until) {
+                return
+            }
+            if r.Count > 0 && emitted >= r.Count {
+                return
+            }
+            emitted++
+            if !fn(c) {
+                return
+            }
+        }
+    }
+}
+
+func (r *Rule) matchesDay(day time.Weekday) bool {
+    if len(r.ByDay) == 0 {
+        return true
+    }
+    for _, wd := range r.ByDay {
+        if wd.Day == day {
+            return true
+        }
+    }
+    return false
+}
+
+// expand возвращает отсортированные вхождения внутри period-го интервала правила.
+func (r *Rule) expand(dtstart time.Time, period int) []time.Time {
+    step := period * r.Interval
+    y, m, d := dtstart.Date()
+    hh, mm, ss := dtstart.Clock()
+    loc := dtstart.Location()
+    at := func(year int, month time.Month, day int) time.Time {
+        return time.Date(year, month, day, hh, mm, ss, dtstart.Nanosecond(), loc)
+    }
+
+    switch r.Freq {
+    case Daily:
+        c := at(y, m, d+step)
+        if r.matchesDay(c.Weekday()) {
+            return []time.Time{c}
+        }
+        return nil
+
+    case Weekly:
+        offset := (int(dtstart.Weekday()) + 6) % 7
+        weekStart := at(y, m, d-offset+7*step)
+        if len(r.ByDay) == 0 {
+            return []time.Time{weekStart.AddDate(0, 0, offset)}
+        }
+        var out []time.Time
+        for _, wd := range r.ByDay {
+            out = append(out, weekStart.AddDate(0, 0, (int(wd.Day)+6)%7))
+        }
+        sortTimes(out)
+        return dedupe(out)
+
+    case Monthly:
+        first := at(y, m+time.Month(step), 1)
+        year, month := first.Year(), first.Month()
+        days := daysIn(year, month)
+        if len(r.ByDay) == 0 {
+            if d > days {
+                return nil
+            }
+            return []time.Time{at(year, month, d)}
+        }
+        var out []time.Time
+        for _, wd := range r.ByDay {
+            var matches []int
+            for day := 1; day <= days; day++ {
+                if time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Weekday() == wd.Day {
+                    matches = append(matches, day)
+                }
+            }
+            switch {
+            case wd.N == 0:
+                for _, day := range matches {
+                    out = append(out, at(year, month, day))
+                }
+            case wd.N > 0 && wd.N <= len(matches):
+                out = append(out, at(year, month, matches[wd.N-1]))
+            case wd.N < 0 && -wd.N <= len(matches):
+                out = append(out, at(year, month, matches[len(matches)+wd.N]))
+            }
+        }
+        sortTimes(out)
+        return dedupe(out)
+
+    case Yearly:
+        year := y + step
+        if d > daysIn(year, m) {
+            return nil
+        }
+        return []time.Time{at(year, m, d)}
+    }
+    return nil
+}
+
+func daysIn(year int, month time.Month) int {
+    return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func sortTimes(ts []time.Time) {
+    sort.Slice(ts, func(i, j int) bool { return ts[i].Before(ts[j]) })
+}
+
+func dedupe(ts []time.Time) []time.Time {
+    out := ts[:0]
+    for i, t := range ts {
+        if i == 0 || !t.Equal(ts[i-1]) {
+            out = append(out, t)
+        }
+    }
+    return out
+}
+
+// After возвращает первое вхождение серии строго позже t.
+func (r *Rule) After(dtstart, t time.Time) (time.Time, bool) {
+    var next time.Time
+    found := false
+    r.each(dtstart, func(c time.Time) bool {
+        if c.After(t) {
+            next, found = c, true
+            return false
+        }
+        return true
+    })
+    return next, found
+}
+
+// Between возвращает не более limit вхождений в интервале [from, to].
+func (r *Rule) Between(dtstart, from, to time.Time, limit int) []time.Time {
+    var out []time.Time
+    r.each(dtstart, func(c time.Time) bool {
+        if c.After(to) {
+            return false
+        }
+        if !c.Before(from) {
+            out = append(out, c)
+        }
+        return limit <= 0 || len(out) < limit
+    })
+    return out
+}
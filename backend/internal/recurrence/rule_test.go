@@ -0,0 +1,218 @@
+package recurrence
+
+import (
+    "errors"
+    "testing"
+    "time"
+    _ "time/tzdata"
+)
+
+func TestParseErrors(t *testing.T) {
+    rules := []string{
+        "",
+        "RRULE:",
+        "FREQ",
+        "INTERVAL=2",
+        "FREQ=HOURLY",
+        "FREQ=DAILY;FREQ=WEEKLY",
+        "FREQ=DAILY;interval=2;INTERVAL=3",
+        "FREQ=DAILY;INTERVAL=0",
+        "FREQ=DAILY;COUNT=-1",
+        "FREQ=DAILY;UNTIL=tomorrow",
+        "FREQ=DAILY;UNTIL=20240105;COUNT=3",
+        "FREQ=WEEKLY;BYDAY=1MO",
+        "FREQ=DAILY;BYDAY=-1FR",
+        "FREQ=MONTHLY;BYDAY=6MO",
+        "FREQ=MONTHLY;BYDAY=0MO",
+        "FREQ=MONTHLY;BYDAY=XX",
+        "FREQ=YEARLY;BYDAY=MO",
+        "FREQ=WEEKLY;WKST=SU",
+        "FREQ=DAILY;BYMONTH=1",
+    }
+    for _, s := range rules {
+        if rule, err := Parse(s); !errors.Is(err, ErrInvalidRule) {
+            t.Errorf("Parse(%q) = %v, %v, want ErrInvalidRule", s, rule, err)
+        }
+    }
+}
+
+func TestString(t *testing.T) {
+    tests := []struct {
+        rule string
+        want string
+    }{
+        {"FREQ=DAILY", "FREQ=DAILY"},
+        {"RRULE:freq=weekly;byday=mo, we", "FREQ=WEEKLY;BYDAY=MO,WE"},
+        {"FREQ=DAILY;INTERVAL=1;WKST=MO", "FREQ=DAILY"},
+        {"FREQ=MONTHLY;INTERVAL=2;BYDAY=1MO,-1FR", "FREQ=MONTHLY;INTERVAL=2;BYDAY=1MO,-1FR"},
+        {"COUNT=3;FREQ=YEARLY", "FREQ=YEARLY;COUNT=3"},
+        // Дата без времени включает весь день
+        {"FREQ=DAILY;UNTIL=20240105", "FREQ=DAILY;UNTIL=20240105T235959Z"},
+        {"FREQ=WEEKLY;UNTIL=20240105T093000Z", "FREQ=WEEKLY;UNTIL=20240105T093000Z"},
+    }
+    for _, tt := range tests {
+        rule, err := Parse(tt.rule)
+        if err != nil {
+            t.Errorf("Parse(%q): %v", tt.rule, err)
+            continue
+        }
+        if got := rule.String(); got != tt.want {
+            t.Errorf("Parse(%q).String() = %q, want %q", tt.rule, got, tt.want)
+            continue
+        }
+        again, err := Parse(tt.want)
+        if err != nil || again.String() != tt.want {
+            t.Errorf("Parse(%q) does not round-trip: %v, %v", tt.want, again, err)
+        }
+    }
+}
+
+func mustLoad(t *testing.T, name string) *time.Location {
+    t.Helper()
+    loc, err := time.LoadLocation(name)
+    if err != nil {
+        t.Fatalf("LoadLocation(%s): %v", name, err)
+    }
+    return loc
+}
+
+func TestBetween(t *testing.T) {
+    berlin := mustLoad(t, "Europe/Berlin")
+    date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 9, 0, 0, 0, time.UTC) }
+    tests := []struct {
+        name    string
+        rule    string
+        dtstart time.Time
+        limit   int
+        layout  string
+        want    []string
+    }{
+        {
+            name: "weekly by day", rule: "FREQ=WEEKLY;BYDAY=MO,WE,FR", dtstart: date(2024, 1, 3), limit: 4,
+            want: []string{"2024-01-03", "2024-01-05", "2024-01-08", "2024-01-10"},
+        },
+        {
+            name: "weekly every other week", rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH", dtstart: date(2024, 1, 1), limit: 4,
+            want: []string{"2024-01-02", "2024-01-04", "2024-01-16", "2024-01-18"},
+        },
+        {
+            name: "daily on weekends", rule: "FREQ=DAILY;BYDAY=SA,SU", dtstart: date(2024, 1, 1), limit: 3,
+            want: []string{"2024-01-06", "2024-01-07", "2024-01-13"},
+        },
+        {
+            name: "last friday of the month", rule: "FREQ=MONTHLY;BYDAY=-1FR", dtstart: date(2024, 1, 1), limit: 4,
+            want: []string{"2024-01-26", "2024-02-23", "2024-03-29", "2024-04-26"},
+        },
+        {
+            name: "first monday and last sunday", rule: "FREQ=MONTHLY;BYDAY=1MO,-1SU", dtstart: date(2024, 1, 1), limit: 4,
+            want: []string{"2024-01-01", "2024-01-28", "2024-02-05", "2024-02-25"},
+        },
+        {
+            name: "every tuesday of the month", rule: "FREQ=MONTHLY;BYDAY=TU", dtstart: date(2024, 2, 1), limit: 5,
+            want: []string{"2024-02-06", "2024-02-13", "2024-02-20", "2024-02-27", "2024-03-05"},
+        },
+        {
+            name: "monthly on the 31st skips short months", rule: "FREQ=MONTHLY", dtstart: date(2024, 1, 31), limit: 5,
+            want: []string{"2024-01-31", "2024-03-31", "2024-05-31", "2024-07-31", "2024-08-31"},
+        },
+        {
+            name: "yearly on february 29 skips common years", rule: "FREQ=YEARLY", dtstart: date(2024, 2, 29), limit: 3,
+            want: []string{"2024-02-29", "2028-02-29", "2032-02-29"},
+        },
+        {
+            name: "count", rule: "FREQ=DAILY;COUNT=3", dtstart: date(2024, 1, 1), limit: 10,
+            want: []string{"2024-01-01", "2024-01-02", "2024-01-03"},
+        },
+        {
+            name: "until date includes the whole day", rule: "FREQ=DAILY;UNTIL=20240103", dtstart: date(2024, 1, 1), limit: 10,
+            want: []string{"2024-01-01", "2024-01-02", "2024-01-03"},
+        },
+        {
+            name: "until time", rule: "FREQ=DAILY;UNTIL=20240103T085959Z", dtstart: date(2024, 1, 1), limit: 10,
+            want: []string{"2024-01-01", "2024-01-02"},
+        },
+        {
+            name: "daily interval", rule: "FREQ=DAILY;INTERVAL=3", dtstart: date(2024, 1, 1), limit: 3,
+            want: []string{"2024-01-01", "2024-01-04", "2024-01-07"},
+        },
+        {
+            name: "monthly interval with count", rule: "FREQ=MONTHLY;INTERVAL=2;COUNT=3", dtstart: date(2024, 1, 15), limit: 10,
+            want: []string{"2024-01-15", "2024-03-15", "2024-05-15"},
+        },
+        {
+            name: "yearly interval", rule: "FREQ=YEARLY;INTERVAL=2", dtstart: date(2024, 6, 1), limit: 3,
+            want: []string{"2024-06-01", "2026-06-01", "2028-06-01"},
+        },
+        // Переходы на летнее и зимнее время сохраняют местное время вхождений
+        {
+            name: "daily across spring forward", rule: "FREQ=DAILY", dtstart: time.Date(2024, 3, 30, 9, 0, 0, 0, berlin), limit: 3,
+            layout: "2006-01-02 15:04 MST",
+            want:   []string{"2024-03-30 09:00 CET", "2024-03-31 09:00 CEST", "2024-04-01 09:00 CEST"},
+        },
+        {
+            name: "time in the spring gap", rule: "FREQ=DAILY", dtstart: time.Date(2024, 3, 30, 2, 30, 0, 0, berlin), limit: 3,
+            layout: "2006-01-02 15:04 MST",
+            want:   []string{"2024-03-30 02:30 CET", "2024-03-31 03:30 CEST", "2024-04-01 02:30 CEST"},
+        },
+        {
+            name: "weekly across fall back", rule: "FREQ=WEEKLY;BYDAY=SA,SU", dtstart: time.Date(2024, 10, 26, 9, 0, 0, 0, berlin), limit: 4,
+            layout: "2006-01-02 15:04 MST",
+            want:   []string{"2024-10-26 09:00 CEST", "2024-10-27 09:00 CET", "2024-11-02 09:00 CET", "2024-11-03 09:00 CET"},
+        },
+        {
+            name: "monthly last friday across fall back", rule: "FREQ=MONTHLY;BYDAY=-1FR", dtstart: time.Date(2024, 10, 1, 18, 0, 0, 0, berlin), limit: 2,
+            layout: "2006-01-02 15:04 MST",
+            want:   []string{"2024-10-25 18:00 CEST", "2024-11-29 18:00 CET"},
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            rule, err := Parse(tt.rule)
+            if err != nil {
+                t.Fatalf("Parse(%q): %v", tt.rule, err)
+            }
+            layout := tt.layout
+            if layout == "" {
+                layout = "2006-01-02"
+            }
+            got := rule.Between(tt.dtstart, tt.dtstart, tt.dtstart.AddDate(10, 0, 0), tt.limit)
+            if len(got) != len(tt.want) {
+                t.Fatalf("Between returned %v, want %v", got, tt.want)
+            }
+            for i, occurrence := range got {
+                if s := occurrence.Format(layout); s != tt.want[i] {
+                    t.Errorf("occurrence %d = %s, want %s", i, s, tt.want[i])
+                }
+            }
+        })
+    }
+}
+
+func TestBetweenWindow(t *testing.T) {
+    rule, _ := Parse("FREQ=DAILY")
+    dtstart := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+    // Границы окна включаются
+    got := rule.Between(dtstart, dtstart.AddDate(0, 0, 5), dtstart.AddDate(0, 0, 7), 0)
+    if len(got) != 3 || !got[0].Equal(dtstart.AddDate(0, 0, 5)) || !got[2].Equal(dtstart.AddDate(0, 0, 7)) {
+        t.Errorf("Between = %v, want days 6 to 8", got)
+    }
+}
+
+func TestAfter(t *testing.T) {
+    dtstart := time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC)
+    monthly, _ := Parse("FREQ=MONTHLY")
+    // Следующее вхождение строго позже t
+    next, ok := monthly.After(dtstart, dtstart)
+    if !ok || !next.Equal(time.Date(2024, 3, 31, 9, 0, 0, 0, time.UTC)) {
+        t.Errorf("After = %v, %v, want 2024-03-31", next, ok)
+    }
+
+    limited, _ := Parse("FREQ=MONTHLY;COUNT=2")
+    if next, ok := limited.After(dtstart, time.Date(2024, 3, 31, 9, 0, 0, 0, time.UTC)); ok {
+        t.Errorf("After past COUNT = %v, want no occurrence", next)
+    }
+    until, _ := Parse("FREQ=MONTHLY;UNTIL=20240430")
+    if next, ok := until.After(dtstart, time.Date(2024, 3, 31, 9, 0, 0, 0, time.UTC)); ok {
+        t.Errorf("After past UNTIL = %v, want no occurrence", next)
+    }
+}
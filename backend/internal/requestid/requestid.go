@@ -0,0 +1,45 @@
+// Package requestid хранит идентификатор запроса в контексте, чтобы его
+// можно было вернуть клиенту в ошибке и найти в логах.
+package requestid
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+)
+
+const Header = "X-Request-ID"
+
+// Клиентский идентификатор длиннее этого заменяется своим.
+const maxLength = 128
+
+type contextKey struct{}
+
+func New() string {
+    buf := make([]byte, 16)
+    rand.Read(buf)
+    return hex.EncodeToString(buf)
+}
+
+// Valid пропускает только короткие идентификаторы из печатных ASCII-символов,
+// чтобы клиент не мог подмешать в логи переводы строк.
+func Valid(id string) bool {
+    if id == "" || len(id) > maxLength {
+        return false
+    }
+    for i := 0; i < len(id); i++ {
+        if id[i] < 0x21 || id[i] > 0x7e {
+            return false
+        }
+    }
+    return true
+}
+
+func WithID(ctx context.Context, id string) context.Context {
+    return context.WithValue(ctx, contextKey{}, id)
+}
+
+func FromContext(ctx context.Context) string {
+    id, _ := ctx.Value(contextKey{}).(string)
+    return id
+}
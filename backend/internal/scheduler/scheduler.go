@@ -0,0 +1,87 @@
+// Package scheduler выполняет фоновые задачи сервера вне обработки запросов:
+// рассылку напоминаний о сроках, очистку журнала событий, старых уведомлений,
+// журнала доставок вебхуков и корзины.
+package scheduler
+
+import (
+    "context"
+    "log"
+    "time"
+    "todo-app/internal/config"
+    "todo-app/internal/models"
+)
+
+type Store interface {
+    FireReminders(ctx context.Context) (int, error)
+    PurgeEvents(ctx context.Context, before time.Time) (int64, error)
+    PurgeNotifications(ctx context.Context, before time.Time) (int64, error)
+    PurgeWebhookDeliveries(ctx context.Context, before time.Time) (int64, error)
+    PurgeTrash(ctx context.Context, before time.Time) (int64, error)
+}
+
+type Scheduler struct {
+    store                 Store
+    interval              time.Duration
+    eventRetention        time.Duration
+    notificationRetention time.Duration
+    webhookLogRetention   time.Duration
+    trashRetention        time.Duration
+}
+
+func New(cfg *config.Config, store Store) *Scheduler {
+    return &Scheduler{
+        store:                 store,
+        interval:              cfg.Scheduler.DueTasksInterval,
+        eventRetention:        cfg.Stream.EventRetention,
+        notificationRetention: cfg.Notifications.ReadRetention,
+        webhookLogRetention:   cfg.Webhooks.LogRetention,
+        trashRetention:        cfg.Trash.Retention,
+    }
+}
+
+// Run выполняет задачи сразу и затем каждые interval, пока не отменён ctx.
+// Одновременный запуск на нескольких репликах безопасен: напоминания
+// рассылаются под advisory lock, а очистка идемпотентна.
+func (s *Scheduler) Run(ctx context.Context) {
+    ticker := time.NewTicker(s.interval)
+    defer ticker.Stop()
+    for {
+        s.Tick(ctx)
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+func (s *Scheduler) Tick(ctx context.Context) {
+    // Изменения задач из планировщика попадают в историю без автора
+    ctx = models.WithActor(ctx, models.Actor{Source: models.SourceScheduler})
+
+    fired, err := s.store.FireReminders(ctx)
+    if err != nil {
+        log.Printf("Error firing reminders: %v", err)
+    } else if fired > 0 {
+        log.Printf("Fired %d reminders", fired)
+    }
+
+    if _, err := s.store.PurgeEvents(ctx, time.Now().Add(-s.eventRetention)); err != nil {
+        log.Printf("Error purging events: %v", err)
+    }
+    purged, err := s.store.PurgeNotifications(ctx, time.Now().Add(-s.notificationRetention))
+    if err != nil {
+        log.Printf("Error purging notifications: %v", err)
+    } else if purged > 0 {
+        log.Printf("Purged %d read notifications", purged)
+    }
+    if _, err := s.store.PurgeWebhookDeliveries(ctx, time.Now().Add(-s.webhookLogRetention)); err != nil {
+        log.Printf("Error purging webhook deliveries: %v", err)
+    }
+    purged, err = s.store.PurgeTrash(ctx, time.Now().Add(-s.trashRetention))
+    if err != nil {
+        log.Printf("Error purging trash: %v", err)
+    } else if purged > 0 {
+        log.Printf("Purged %d records from trash", purged)
+    }
+}
@@ -0,0 +1,79 @@
+package server_test
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "testing"
+    "todo-app/internal/memstore"
+    "todo-app/internal/models"
+    "todo-app/internal/server/servertest"
+)
+
+func TestPasswordReset(t *testing.T) {
+    e := servertest.NewEnv(t, memstore.New())
+    forgot, reset := "/api/auth/forgot-password", "/api/auth/reset-password"
+
+    e.Expect(e.Do("POST", forgot, "", map[string]string{"email": "not-an-email"}), http.StatusUnprocessableEntity)
+    // Ответ не выдаёт, зарегистрирован ли адрес
+    e.Expect(e.Do("POST", forgot, "", map[string]string{"email": "nobody@example.com"}), http.StatusAccepted)
+    e.Expect(e.Do("POST", forgot, "", map[string]string{"email": "bob@example.com"}), http.StatusAccepted)
+    token := e.Token("bob@example.com", "/reset-password", 1)
+    for _, msg := range e.Mail.Messages() {
+        if msg.To == "nobody@example.com" {
+            t.Errorf("reset email sent to an unknown address: %+v", msg)
+        }
+    }
+
+    e.Expect(e.Do("POST", reset, "", map[string]string{"token": token, "password": ""}), http.StatusUnprocessableEntity)
+    e.Expect(e.Do("POST", reset, "", map[string]string{"token": token, "password": "changed"}), http.StatusNoContent)
+    e.Expect(e.Do("POST", reset, "", map[string]string{"token": token, "password": "stolen"}), http.StatusUnprocessableEntity)
+
+    // Сброс пароля завершает все сессии, старый пароль больше не подходит
+    e.Expect(e.Do("GET", "/api/tasks", e.Bob, nil), http.StatusUnauthorized)
+    e.Expect(e.Do("POST", "/api/auth/login", "", map[string]string{"email": "bob@example.com", "password": "secret"}), http.StatusUnauthorized)
+    e.Expect(e.Do("POST", "/api/auth/login", "", map[string]string{"email": "bob@example.com", "password": "changed"}), http.StatusOK)
+}
+
+func TestEmailVerification(t *testing.T) {
+    e := servertest.NewEnv(t, memstore.New())
+    verify, resend := "/api/auth/verify-email", "/api/auth/resend-verification"
+
+    e.Expect(e.Do("POST", verify, "", map[string]string{"token": "forged"}), http.StatusUnprocessableEntity)
+    e.Expect(e.Do("POST", resend, e.Bob, nil), http.StatusUnprocessableEntity)
+
+    // Без подтверждения адреса вебхуки недоступны; новая ссылка отменяет старую
+    carol, _ := e.RegisterUnverified("carol@example.com", "")
+    e.Expect(e.Do("GET", "/api/webhooks", carol, nil), http.StatusForbidden)
+    first := e.Token("carol@example.com", "/verify-email", 1)
+    e.Expect(e.Do("POST", resend, carol, nil), http.StatusAccepted)
+    second := e.Token("carol@example.com", "/verify-email", 2)
+    e.Expect(e.Do("POST", verify, "", map[string]string{"token": first}), http.StatusUnprocessableEntity)
+    e.Expect(e.Do("POST", verify, "", map[string]string{"token": second}), http.StatusNoContent)
+    e.Expect(e.Do("POST", verify, "", map[string]string{"token": second}), http.StatusUnprocessableEntity)
+    e.Expect(e.Do("GET", "/api/webhooks", carol, nil), http.StatusOK)
+}
+
+// unavailableSessions имитирует недоступную базу при проверке сессии.
+type unavailableSessions struct {
+    *memstore.Store
+    down bool
+}
+
+func (s *unavailableSessions) GetSession(ctx context.Context, id uint) (*models.Session, error) {
+    if s.down {
+        return nil, errors.New("connection refused")
+    }
+    return s.Store.GetSession(ctx, id)
+}
+
+func TestSessionCheckFailure(t *testing.T) {
+    store := &unavailableSessions{Store: memstore.New()}
+    e := servertest.NewEnv(t, store)
+
+    // Сбой хранилища — ошибка сервера, а не выход из системы
+    store.down = true
+    e.Expect(e.Do("GET", "/api/tasks", e.Bob, nil), http.StatusInternalServerError)
+    store.down = false
+    e.Expect(e.Do("GET", "/api/tasks", e.Bob, nil), http.StatusOK)
+}
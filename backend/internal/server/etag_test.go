@@ -0,0 +1,109 @@
+package server_test
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "testing"
+    "time"
+    "todo-app/internal/apierror"
+    "todo-app/internal/memstore"
+    "todo-app/internal/models"
+    "todo-app/internal/server/servertest"
+)
+
+func TestTaskListETag(t *testing.T) {
+    e := servertest.NewEnv(t, memstore.New())
+    due := time.Now().UTC().Format(time.RFC3339)
+
+    rec := e.Do("GET", "/api/tasks", e.Bob, nil)
+    e.Expect(rec, http.StatusOK)
+    notModified := map[string]string{"If-None-Match": rec.Header().Get("ETag")}
+    e.Expect(e.DoHeader("GET", "/api/tasks", e.Bob, notModified, nil), http.StatusNotModified)
+    // Тег зависит от параметров запроса и от собственных изменений
+    e.Expect(e.DoHeader("GET", "/api/tasks?sort=title", e.Bob, notModified, nil), http.StatusOK)
+    e.Expect(e.Do("POST", "/api/tasks", e.Bob, map[string]interface{}{"title": "bob-list", "due_date": due}), http.StatusOK)
+    e.Expect(e.DoHeader("GET", "/api/tasks", e.Bob, notModified, nil), http.StatusOK)
+}
+
+func TestTaskConditionalRequests(t *testing.T) {
+    e := servertest.NewEnv(t, memstore.New())
+    due := time.Now().UTC().Format(time.RFC3339)
+
+    rec := e.Do("POST", "/api/tasks", e.Bob, map[string]interface{}{"title": "bob-versioned", "due_date": due})
+    var own models.Task
+    e.Decode(rec, &own)
+    if tag := rec.Header().Get("ETag"); tag != `"1"` || own.Version != 1 {
+        t.Fatalf("created task ETag = %s, version %d", tag, own.Version)
+    }
+    path := fmt.Sprintf("/api/tasks/%d", own.ID)
+    e.Expect(e.DoHeader("GET", path, e.Bob, map[string]string{"If-None-Match": `"1"`}, nil), http.StatusNotModified)
+
+    rec = e.DoHeader("PATCH", path, e.Bob, map[string]string{"If-Match": `"1"`}, map[string]interface{}{"priority": 2})
+    e.Expect(rec, http.StatusOK)
+    if tag := rec.Header().Get("ETag"); tag != `"2"` {
+        t.Errorf("patched task ETag = %s, want \"2\"", tag)
+    }
+    // Второе устройство с устаревшей версией не затирает изменение
+    rec = e.DoHeader("PUT", path, e.Bob, map[string]string{"If-Match": `"1"`}, map[string]interface{}{"title": "stale", "due_date": due})
+    e.Expect(rec, http.StatusPreconditionFailed)
+    var failure apierror.Response
+    json.Unmarshal(rec.Body.Bytes(), &failure)
+    if failure.Code != apierror.CodePreconditionFailed || rec.Header().Get("ETag") != `"2"` {
+        t.Errorf("stale PUT = %s, ETag %s", rec.Body.String(), rec.Header().Get("ETag"))
+    }
+    e.Expect(e.DoHeader("GET", path, e.Bob, map[string]string{"If-None-Match": `"1"`}, nil), http.StatusOK)
+
+    // Перенос в категорию — тоже запись задачи
+    move := fmt.Sprintf("/api/categories/tasks/%d", own.ID)
+    category := map[string]interface{}{"category_id": e.BobCategory}
+    e.Expect(e.DoHeader("PUT", move, e.Bob, map[string]string{"If-Match": `"1"`}, category), http.StatusPreconditionFailed)
+    rec = e.DoHeader("PUT", move, e.Bob, map[string]string{"If-Match": `"2"`}, category)
+    e.Expect(rec, http.StatusOK)
+    if tag := rec.Header().Get("ETag"); tag != `"3"` {
+        t.Errorf("moved task ETag = %s, want \"3\"", tag)
+    }
+
+    e.Expect(e.DoHeader("DELETE", path, e.Bob, map[string]string{"If-Match": `"2"`}, nil), http.StatusPreconditionFailed)
+    e.Expect(e.DoHeader("DELETE", path, e.Bob, map[string]string{"If-Match": `"2", "3"`}, nil), http.StatusOK)
+}
+
+func TestCategoryConditionalRequests(t *testing.T) {
+    e := servertest.NewEnv(t, memstore.New())
+
+    var own models.Category
+    e.Decode(e.Do("POST", "/api/categories", e.Bob, map[string]string{"name": "bob-versioned"}), &own)
+    path := fmt.Sprintf("/api/categories/%d", own.ID)
+    rec := e.Do("GET", path, e.Bob, nil)
+    e.Expect(rec, http.StatusOK)
+    if tag := rec.Header().Get("ETag"); tag != `"1"` {
+        t.Errorf("category ETag = %s, want \"1\"", tag)
+    }
+    e.Expect(e.DoHeader("GET", path, e.Bob, map[string]string{"If-None-Match": `W/"1"`}, nil), http.StatusNotModified)
+    e.Expect(e.DoHeader("DELETE", path, e.Bob, map[string]string{"If-Match": `"2"`}, nil), http.StatusPreconditionFailed)
+    e.Expect(e.DoHeader("DELETE", path, e.Bob, map[string]string{"If-Match": "*"}, nil), http.StatusNoContent)
+}
+
+func TestCompleteRecurringTaskETag(t *testing.T) {
+    e := servertest.NewEnv(t, memstore.New())
+    due := time.Now().UTC().Format(time.RFC3339)
+
+    var own models.Task
+    e.Decode(e.Do("POST", "/api/tasks", e.Bob, map[string]interface{}{
+        "title": "bob-recurring", "due_date": due, "recurrence": "FREQ=DAILY",
+    }), &own)
+    path := fmt.Sprintf("/api/tasks/%d", own.ID)
+    rec := e.Do("PATCH", path, e.Bob, map[string]interface{}{"completed": true})
+    e.Expect(rec, http.StatusOK)
+    var completed models.Task
+    e.Decode(rec, &completed)
+    if completed.NextOccurrence == nil || completed.Recurrence != "" {
+        t.Fatalf("completed recurring task = %s", rec.Body.String())
+    }
+    // Тег ответа совпадает с версией в хранилище
+    tag := rec.Header().Get("ETag")
+    if current := e.Do("GET", path, e.Bob, nil).Header().Get("ETag"); tag != current || tag != fmt.Sprintf(`"%d"`, completed.Version) {
+        t.Errorf("completion ETag = %s, version %d, stored ETag %s", tag, completed.Version, current)
+    }
+    e.Expect(e.DoHeader("PATCH", path, e.Bob, map[string]string{"If-Match": tag}, map[string]interface{}{"priority": 2}), http.StatusOK)
+}
@@ -0,0 +1,74 @@
+package server_test
+
+import (
+    "fmt"
+    "net/http"
+    "testing"
+    "time"
+    "todo-app/internal/memstore"
+    "todo-app/internal/models"
+    "todo-app/internal/server/servertest"
+)
+
+func TestTaskHistory(t *testing.T) {
+    e := servertest.NewEnv(t, memstore.New())
+    due := time.Now().UTC().Format(time.RFC3339)
+
+    var task models.Task
+    e.Decode(e.Do("POST", "/api/tasks", e.Bob, map[string]interface{}{"title": "draft", "due_date": due}), &task)
+    path := fmt.Sprintf("/api/tasks/%d", task.ID)
+    e.Expect(e.Do("PATCH", path, e.Bob, map[string]interface{}{"title": "final"}), http.StatusOK)
+    e.Expect(e.Do("GET", path+"/history?limit=0", e.Bob, nil), http.StatusUnprocessableEntity)
+
+    rec := e.Do("GET", path+"/history", e.Bob, nil)
+    e.Expect(rec, http.StatusOK)
+    var page models.HistoryPage
+    e.Decode(rec, &page)
+    if len(page.Events) != 2 || page.Events[0].Action != models.TaskActionUpdated || page.Events[1].Action != models.TaskActionCreated {
+        t.Fatalf("GET %s/history returned %s", path, rec.Body.String())
+    }
+    for _, event := range page.Events {
+        if event.ActorID == nil || *event.ActorID != e.BobID || event.Source != models.SourceAPI {
+            t.Errorf("history event %+v, want bob via api", event)
+        }
+    }
+    if _, ok := page.Events[0].Changes["title"]; !ok || len(page.Events[0].Changes) != 1 {
+        t.Errorf("rename changes = %+v", page.Events[0].Changes)
+    }
+
+    // История удалённой задачи остаётся доступной
+    e.Expect(e.Do("DELETE", path, e.Bob, nil), http.StatusOK)
+    var deleted models.HistoryPage
+    e.Decode(e.Do("GET", path+"/history", e.Bob, nil), &deleted)
+    if len(deleted.Events) != 3 || deleted.Events[0].Action != models.TaskActionDeleted {
+        t.Errorf("history after delete = %+v", deleted.Events)
+    }
+}
+
+func TestActivity(t *testing.T) {
+    e := servertest.NewEnv(t, memstore.New())
+    due := time.Now().UTC().Format(time.RFC3339)
+
+    var task models.Task
+    e.Decode(e.Do("POST", "/api/tasks", e.Bob, map[string]interface{}{"title": "bob-activity", "due_date": due}), &task)
+    results := applySync(e, map[string]interface{}{
+        "entity": "task", "op": "update", "id": task.ID, "data": map[string]bool{"completed": true},
+    })
+    if got := syncStatuses(results); got != "applied" {
+        t.Fatalf("sync update = %s", got)
+    }
+
+    rec := e.Do("GET", "/api/activity?limit=1", e.Bob, nil)
+    e.Expect(rec, http.StatusOK)
+    var page models.HistoryPage
+    e.Decode(rec, &page)
+    if len(page.Events) != 1 || page.Events[0].TaskID != task.ID || page.Events[0].Source != models.SourceSync || page.NextCursor == "" {
+        t.Fatalf("GET /api/activity returned %s", rec.Body.String())
+    }
+    var next models.HistoryPage
+    e.Decode(e.Do("GET", "/api/activity?cursor="+page.NextCursor, e.Bob, nil), &next)
+    if len(next.Events) != 1 || next.Events[0].Action != models.TaskActionCreated || next.NextCursor != "" {
+        t.Errorf("second activity page = %+v", next)
+    }
+    e.Expect(e.Do("GET", "/api/activity?cursor=forged!", e.Bob, nil), http.StatusUnprocessableEntity)
+}
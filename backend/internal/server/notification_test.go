@@ -0,0 +1,49 @@
+package server_test
+
+import (
+    "fmt"
+    "strings"
+    "testing"
+    "todo-app/internal/memstore"
+    "todo-app/internal/models"
+    "todo-app/internal/server/servertest"
+)
+
+func TestNotificationDeliveries(t *testing.T) {
+    e := servertest.NewEnv(t, memstore.New())
+
+    // У подтверждённого адреса уведомление уходит и по почте
+    rec := e.Do("GET", fmt.Sprintf("/api/notifications/%d/deliveries", e.Notification), e.Alice, nil)
+    var deliveries []models.Delivery
+    e.Decode(rec, &deliveries)
+    if len(deliveries) != 1 || deliveries[0].Channel != models.ChannelEmail {
+        t.Errorf("notification deliveries = %s, want one email delivery", rec.Body.String())
+    }
+}
+
+func TestNotificationLocalization(t *testing.T) {
+    e := servertest.NewEnv(t, memstore.New())
+
+    // Без настройки языка текст рендерится по Accept-Language, без заголовка — по-русски
+    for header, prefix := range map[string]string{
+        "en-US,en;q=0.9,ru;q=0.8": "Reminder: ",
+        "ru;q=0.5,en;q=0":         "Напоминание: ",
+        "":                        "Напоминание: ",
+    } {
+        rec := e.DoHeader("GET", "/api/notifications", e.Alice, map[string]string{"Accept-Language": header}, nil)
+        var page models.NotificationPage
+        e.Decode(rec, &page)
+        found := false
+        for _, n := range page.Notifications {
+            if n.ID == e.Notification {
+                found = true
+                if !strings.HasPrefix(n.Message, prefix) {
+                    t.Errorf("Accept-Language %q: message = %q, want prefix %q", header, n.Message, prefix)
+                }
+            }
+        }
+        if !found {
+            t.Errorf("Accept-Language %q: notification %d is not listed: %s", header, e.Notification, rec.Body.String())
+        }
+    }
+}
@@ -0,0 +1,139 @@
+// Package server собирает HTTP-маршруты API поверх хранилища.
+package server
+
+import (
+    "net/http"
+    "github.com/gorilla/mux"
+    "todo-app/internal/apierror"
+    "todo-app/internal/config"
+    "todo-app/internal/email"
+    "todo-app/internal/events"
+    "todo-app/internal/handlers"
+    "todo-app/internal/middleware"
+    "todo-app/internal/models"
+)
+
+// hub будит открытые потоки /api/notifications/stream; его наполняет
+// events.Listen или memstore.Store.OnEvent. outbox отправляет письма
+// подтверждения адреса и сброса пароля.
+func NewRouter(cfg *config.Config, store models.Store, hub *events.Hub, outbox *email.Outbox) *mux.Router {
+    r := mux.NewRouter()
+    r.Use(middleware.RequestID)
+    r.Use(middleware.CORS(cfg.CORS))
+
+    // Middleware роутера не вызываются для несовпавших маршрутов, поэтому
+    // обработчики ошибок оборачиваются отдельно.
+    r.NotFoundHandler = middleware.RequestID(middleware.CORS(cfg.CORS)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        apierror.NotFound(w, r, "Route not found")
+    })))
+    r.MethodNotAllowedHandler = middleware.RequestID(middleware.CORS(cfg.CORS)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        apierror.Write(w, r, http.StatusMethodNotAllowed, apierror.CodeMethodNotAllowed, "Method not allowed", nil)
+    })))
+
+    jwtSecret := []byte(cfg.Auth.JWTSecret)
+    authHandler := handlers.NewAuthHandler(cfg.Auth, cfg.Mail.AppURL, store, store, outbox)
+    userHandler := handlers.NewUserHandler(store, store)
+    taskHandler := handlers.NewTaskHandler(store, store, store, store)
+    notificationHandler := handlers.NewNotificationHandler(store, store, store, store)
+    reminderHandler := handlers.NewReminderHandler(store, store, store)
+    categoryHandler := handlers.NewCategoryHandler(store, store, store)
+    webhookHandler := handlers.NewWebhookHandler(store)
+    searchHandler := handlers.NewSearchHandler(store)
+    syncHandler := handlers.NewSyncHandler(store, store, store, store, store, store)
+    trashHandler := handlers.NewTrashHandler(store, store)
+    historyHandler := handlers.NewHistoryHandler(store, store)
+    streamHandler := handlers.NewStreamHandler(cfg.Stream, store, store, hub)
+
+    r.HandleFunc("/api/auth/login", authHandler.Login).Methods("POST", "OPTIONS")
+    r.HandleFunc("/api/auth/register", authHandler.Register).Methods("POST", "OPTIONS")
+    r.HandleFunc("/api/auth/refresh", authHandler.Refresh).Methods("POST", "OPTIONS")
+    r.HandleFunc("/api/auth/forgot-password", authHandler.ForgotPassword).Methods("POST", "OPTIONS")
+    r.HandleFunc("/api/auth/reset-password", authHandler.ResetPassword).Methods("POST", "OPTIONS")
+    r.HandleFunc("/api/auth/verify-email", authHandler.VerifyEmail).Methods("POST", "OPTIONS")
+
+    sessionRouter := r.PathPrefix("/api/auth").Subrouter()
+    sessionRouter.Use(middleware.AuthMiddleware(jwtSecret, store))
+    sessionRouter.HandleFunc("/logout", authHandler.Logout).Methods("POST", "OPTIONS")
+    sessionRouter.HandleFunc("/logout-all", authHandler.LogoutAll).Methods("POST", "OPTIONS")
+    sessionRouter.HandleFunc("/resend-verification", authHandler.ResendVerification).Methods("POST", "OPTIONS")
+
+    userRouter := r.PathPrefix("/api/users").Subrouter()
+    userRouter.Use(middleware.AuthMiddleware(jwtSecret, store))
+    userRouter.HandleFunc("/me", userHandler.Me).Methods("GET", "OPTIONS")
+    userRouter.HandleFunc("/me", userHandler.UpdateMe).Methods("PUT", "OPTIONS")
+    userRouter.HandleFunc("/me/notification-settings", userHandler.NotificationSettings).Methods("GET", "OPTIONS")
+    userRouter.HandleFunc("/me/notification-settings", userHandler.UpdateNotificationSettings).Methods("PUT", "OPTIONS")
+
+    taskRouter := r.PathPrefix("/api/tasks").Subrouter()
+    taskRouter.Use(middleware.AuthMiddleware(jwtSecret, store))
+    taskRouter.HandleFunc("", taskHandler.Create).Methods("POST", "OPTIONS")
+    taskRouter.HandleFunc("", taskHandler.List).Methods("GET", "OPTIONS")
+    taskRouter.HandleFunc("/occurrences", taskHandler.Occurrences).Methods("GET", "OPTIONS")
+    taskRouter.HandleFunc("/{id}", taskHandler.Get).Methods("GET", "OPTIONS")
+    taskRouter.HandleFunc("/{id}", taskHandler.Update).Methods("PUT", "OPTIONS")
+    taskRouter.HandleFunc("/{id}", taskHandler.Patch).Methods("PATCH", "OPTIONS")
+    taskRouter.HandleFunc("/{id}", taskHandler.Delete).Methods("DELETE", "OPTIONS")
+    taskRouter.HandleFunc("/{id}/restore", trashHandler.RestoreTask).Methods("POST", "OPTIONS")
+    taskRouter.HandleFunc("/{id}/history", historyHandler.Task).Methods("GET", "OPTIONS")
+    taskRouter.HandleFunc("/{id}/subtasks", taskHandler.Subtasks).Methods("GET", "OPTIONS")
+    taskRouter.HandleFunc("/{id}/subtasks/order", taskHandler.ReorderSubtasks).Methods("PUT", "OPTIONS")
+    taskRouter.HandleFunc("/{id}/reminders", reminderHandler.List).Methods("GET", "OPTIONS")
+    taskRouter.HandleFunc("/{id}/reminders", reminderHandler.Create).Methods("POST", "OPTIONS")
+    taskRouter.HandleFunc("/{id}/reminders/{reminderId}", reminderHandler.Update).Methods("PUT", "OPTIONS")
+    taskRouter.HandleFunc("/{id}/reminders/{reminderId}", reminderHandler.Delete).Methods("DELETE", "OPTIONS")
+
+    categoryRouter := r.PathPrefix("/api/categories").Subrouter()
+    categoryRouter.Use(middleware.AuthMiddleware(jwtSecret, store))
+    categoryRouter.HandleFunc("", categoryHandler.List).Methods("GET", "OPTIONS")
+    categoryRouter.HandleFunc("", categoryHandler.Create).Methods("POST", "OPTIONS")
+    categoryRouter.HandleFunc("/{id}", categoryHandler.Get).Methods("GET", "OPTIONS")
+    categoryRouter.HandleFunc("/{id}", categoryHandler.Delete).Methods("DELETE", "OPTIONS")
+    categoryRouter.HandleFunc("/{id}/restore", trashHandler.RestoreCategory).Methods("POST", "OPTIONS")
+    categoryRouter.HandleFunc("/{id}/tasks", categoryHandler.GetTasks).Methods("GET", "OPTIONS")
+    categoryRouter.HandleFunc("/tasks/{id}", categoryHandler.UpdateTaskCategory).Methods("PUT", "OPTIONS")
+
+    notificationRouter := r.PathPrefix("/api/notifications").Subrouter()
+    notificationRouter.Use(middleware.AuthMiddleware(jwtSecret, store))
+    notificationRouter.HandleFunc("", notificationHandler.List).Methods("GET", "OPTIONS")
+    notificationRouter.HandleFunc("", notificationHandler.DeleteMany).Methods("DELETE", "OPTIONS")
+    notificationRouter.HandleFunc("/stream", streamHandler.Stream).Methods("GET", "OPTIONS")
+    notificationRouter.HandleFunc("/unread-count", notificationHandler.UnreadCount).Methods("GET", "OPTIONS")
+    notificationRouter.HandleFunc("/read-all", notificationHandler.ReadAll).Methods("POST", "OPTIONS")
+    notificationRouter.HandleFunc("/read", notificationHandler.MarkManyAsRead).Methods("POST", "OPTIONS")
+    notificationRouter.HandleFunc("/{id}", notificationHandler.Delete).Methods("DELETE", "OPTIONS")
+    notificationRouter.HandleFunc("/{id}/read", notificationHandler.MarkAsRead).Methods("POST", "OPTIONS")
+    notificationRouter.HandleFunc("/{id}/snooze", notificationHandler.Snooze).Methods("POST", "OPTIONS")
+    notificationRouter.HandleFunc("/{id}/deliveries", notificationHandler.Deliveries).Methods("GET", "OPTIONS")
+
+    webhookRouter := r.PathPrefix("/api/webhooks").Subrouter()
+    webhookRouter.Use(middleware.AuthMiddleware(jwtSecret, store))
+    // Вебхуки шлют запросы на произвольные адреса, поэтому нужен
+    // подтверждённый адрес почты
+    webhookRouter.Use(middleware.RequireVerified(store))
+    webhookRouter.HandleFunc("", webhookHandler.List).Methods("GET", "OPTIONS")
+    webhookRouter.HandleFunc("", webhookHandler.Create).Methods("POST", "OPTIONS")
+    webhookRouter.HandleFunc("/{id}", webhookHandler.Get).Methods("GET", "OPTIONS")
+    webhookRouter.HandleFunc("/{id}", webhookHandler.Update).Methods("PUT", "OPTIONS")
+    webhookRouter.HandleFunc("/{id}", webhookHandler.Delete).Methods("DELETE", "OPTIONS")
+    webhookRouter.HandleFunc("/{id}/deliveries", webhookHandler.Deliveries).Methods("GET", "OPTIONS")
+    webhookRouter.HandleFunc("/{id}/test", webhookHandler.Test).Methods("POST", "OPTIONS")
+
+    searchRouter := r.PathPrefix("/api/search").Subrouter()
+    searchRouter.Use(middleware.AuthMiddleware(jwtSecret, store))
+    searchRouter.HandleFunc("", searchHandler.Search).Methods("GET", "OPTIONS")
+
+    syncRouter := r.PathPrefix("/api/sync").Subrouter()
+    syncRouter.Use(middleware.AuthMiddleware(jwtSecret, store))
+    syncRouter.HandleFunc("", syncHandler.Changes).Methods("GET", "OPTIONS")
+    syncRouter.HandleFunc("", syncHandler.Apply).Methods("POST", "OPTIONS")
+
+    trashRouter := r.PathPrefix("/api/trash").Subrouter()
+    trashRouter.Use(middleware.AuthMiddleware(jwtSecret, store))
+    trashRouter.HandleFunc("", trashHandler.List).Methods("GET", "OPTIONS")
+
+    activityRouter := r.PathPrefix("/api/activity").Subrouter()
+    activityRouter.Use(middleware.AuthMiddleware(jwtSecret, store))
+    activityRouter.HandleFunc("", historyHandler.Activity).Methods("GET", "OPTIONS")
+
+    return r
+}
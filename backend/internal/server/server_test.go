@@ -0,0 +1,12 @@
+package server_test
+
+import (
+    "testing"
+    "todo-app/internal/memstore"
+    "todo-app/internal/models"
+    "todo-app/internal/server/servertest"
+)
+
+func TestMatrix(t *testing.T) {
+    servertest.Run(t, func(t *testing.T) models.Store { return memstore.New() })
+}
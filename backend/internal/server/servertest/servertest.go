@@ -0,0 +1,687 @@
+// Package servertest проверяет, что маршруты API не дают одному пользователю
+// читать или менять данные другого. Run вызывается из тестов конкретного
+// хранилища и требует сценарий для каждого зарегистрированного маршрута.
+// Env с теми же данными используют тесты отдельных функций сервера.
+package servertest
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "regexp"
+    "sort"
+    "strings"
+    "testing"
+    "time"
+    "github.com/gorilla/mux"
+    "todo-app/internal/apierror"
+    "todo-app/internal/config"
+    "todo-app/internal/email"
+    "todo-app/internal/events"
+    "todo-app/internal/handlers"
+    "todo-app/internal/models"
+    "todo-app/internal/server"
+)
+
+// Factory возвращает новое пустое хранилище.
+type Factory func(t *testing.T) models.Store
+
+// Метка, по которой ищутся утечки данных alice в ответах для bob.
+const secret = "alice-secret"
+
+var mailToken = regexp.MustCompile(`token=([A-Za-z0-9_-]+)`)
+
+// Env — сервер на заданном хранилище с двумя подтверждёнными
+// пользователями. У alice есть категория, задача с подзадачей, напоминание,
+// уведомление и вебхук; у bob — только категория. Каждый тест получает свой
+// Env, поэтому тесты не зависят друг от друга.
+type Env struct {
+    T      *testing.T
+    Router *mux.Router
+    Store  models.Store
+    Mail   *email.Fake
+
+    Alice, Bob            string
+    AliceID, BobID        uint
+    Task, Subtask         uint
+    Category, BobCategory uint
+    Notification          uint
+    Reminder              uint
+    Webhook               uint
+}
+
+func (e *Env) Do(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+    e.T.Helper()
+    return e.DoHeader(method, path, token, nil, body)
+}
+
+// DoHeader выполняет запрос с дополнительными заголовками, например If-Match.
+func (e *Env) DoHeader(method, path, token string, header map[string]string, body interface{}) *httptest.ResponseRecorder {
+    e.T.Helper()
+    var payload bytes.Buffer
+    if body != nil {
+        if err := json.NewEncoder(&payload).Encode(body); err != nil {
+            e.T.Fatalf("encode body: %v", err)
+        }
+    }
+    req := httptest.NewRequest(method, path, &payload)
+    req.Header.Set("Content-Type", "application/json")
+    if token != "" {
+        req.Header.Set("Authorization", "Bearer "+token)
+    }
+    for name, value := range header {
+        req.Header.Set(name, value)
+    }
+    rec := httptest.NewRecorder()
+    e.Router.ServeHTTP(rec, req)
+    return rec
+}
+
+// Expect проверяет код ответа и отсутствие данных alice в теле.
+func (e *Env) Expect(rec *httptest.ResponseRecorder, status int) {
+    e.T.Helper()
+    if rec.Code != status {
+        e.T.Errorf("status = %d, want %d; body: %s", rec.Code, status, rec.Body.String())
+    }
+    if strings.Contains(rec.Body.String(), secret) {
+        e.T.Errorf("response leaks another user's data: %s", rec.Body.String())
+    }
+}
+
+func (e *Env) Decode(rec *httptest.ResponseRecorder, v interface{}) {
+    e.T.Helper()
+    if rec.Code >= 300 {
+        e.T.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+    }
+    if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+        e.T.Fatalf("decode response: %v", err)
+    }
+}
+
+// Stream читает поток событий с начала журнала, пока не истечёт таймаут.
+func (e *Env) Stream(token string) *httptest.ResponseRecorder {
+    e.T.Helper()
+    ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+    defer cancel()
+    req := httptest.NewRequest("GET", "/api/notifications/stream", nil).WithContext(ctx)
+    req.Header.Set("Authorization", "Bearer "+token)
+    req.Header.Set("Last-Event-ID", "0")
+    rec := httptest.NewRecorder()
+    e.Router.ServeHTTP(rec, req)
+    return rec
+}
+
+// Token ждёт n-е письмо на адрес to со ссылкой path и возвращает токен из
+// неё. Письма отправляются в фоне, поэтому их приходится ждать.
+func (e *Env) Token(to, path string, n int) string {
+    e.T.Helper()
+    for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+        var found []string
+        for _, msg := range e.Mail.Messages() {
+            if msg.To == to && strings.Contains(msg.Text, path+"?token=") {
+                found = append(found, mailToken.FindStringSubmatch(msg.Text)[1])
+            }
+        }
+        if len(found) >= n {
+            return found[n-1]
+        }
+    }
+    e.T.Fatalf("no email #%d with %s to %s", n, path, to)
+    return ""
+}
+
+// Register создаёт пользователя и подтверждает его адрес.
+func (e *Env) Register(address, timezone string) (string, uint) {
+    e.T.Helper()
+    token, id := e.RegisterUnverified(address, timezone)
+    e.Expect(e.Do("POST", "/api/auth/verify-email", "", map[string]string{
+        "token": e.Token(address, "/verify-email", 1),
+    }), http.StatusNoContent)
+    return token, id
+}
+
+func (e *Env) RegisterUnverified(address, timezone string) (string, uint) {
+    e.T.Helper()
+    var resp struct {
+        Token string `json:"token"`
+    }
+    e.Decode(e.Do("POST", "/api/auth/register", "", map[string]string{
+        "email": address, "password": "secret", "name": address, "timezone": timezone,
+    }), &resp)
+    user, err := e.Store.GetUserByEmail(context.Background(), address)
+    if err != nil {
+        e.T.Fatalf("GetUserByEmail: %v", err)
+    }
+    return resp.Token, user.ID
+}
+
+func (e *Env) Login(email string) string {
+    e.T.Helper()
+    var resp struct {
+        Token string `json:"token"`
+    }
+    e.Decode(e.Do("POST", "/api/auth/login", "", map[string]string{"email": email, "password": "secret"}), &resp)
+    return resp.Token
+}
+
+func NewEnv(t *testing.T, store models.Store) *Env {
+    cfg := config.Default()
+    cfg.Env = config.EnvDevelopment
+    mail := &email.Fake{}
+    e := &Env{T: t, Router: server.NewRouter(cfg, store, events.NewHub(), email.NewOutbox(mail)), Store: store, Mail: mail}
+
+    e.Alice, e.AliceID = e.Register("alice@example.com", "Asia/Vladivostok")
+    e.Bob, e.BobID = e.Register("bob@example.com", "")
+
+    var category models.Category
+    e.Decode(e.Do("POST", "/api/categories", e.Alice, map[string]string{"name": secret + "-category"}), &category)
+    e.Category = category.ID
+    e.Decode(e.Do("POST", "/api/categories", e.Bob, map[string]string{"name": "bob-category"}), &category)
+    e.BobCategory = category.ID
+
+    due := time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+    var task models.Task
+    e.Decode(e.Do("POST", "/api/tasks", e.Alice, map[string]interface{}{
+        "title": secret + "-task", "description": secret, "due_date": due,
+        "category_id": e.Category, "recurrence": "FREQ=DAILY",
+    }), &task)
+    e.Task = task.ID
+    e.Decode(e.Do("POST", "/api/tasks", e.Alice, map[string]interface{}{
+        "title": secret + "-subtask", "due_date": due, "parent_id": e.Task,
+    }), &task)
+    e.Subtask = task.ID
+
+    var reminder models.Reminder
+    e.Decode(e.Do("POST", fmt.Sprintf("/api/tasks/%d/reminders", e.Task), e.Alice, map[string]int{"minutes_before": 60}), &reminder)
+    e.Reminder = reminder.ID
+
+    ctx := context.Background()
+    if err := store.CreateNotification(ctx, e.AliceID, models.NotificationReminder, models.NotificationParams{TaskID: e.Task, Title: secret + "-notification"}); err != nil {
+        t.Fatalf("CreateNotification: %v", err)
+    }
+    notifications, err := store.GetUserNotifications(ctx, e.AliceID)
+    if err != nil || len(notifications) == 0 {
+        t.Fatalf("GetUserNotifications: %v", err)
+    }
+    e.Notification = notifications[0].ID
+
+    var webhook models.Webhook
+    e.Decode(e.Do("POST", "/api/webhooks", e.Alice, map[string]interface{}{
+        "url": "https://example.com/" + secret, "events": []string{"task.*"},
+    }), &webhook)
+    e.Webhook = webhook.ID
+    return e
+}
+
+// cases описывает для каждого маршрута попытку bob добраться до данных alice.
+func cases(e *Env) map[string]func() {
+    task := func(format string) string { return fmt.Sprintf(format, e.Task) }
+    due := time.Now().UTC().Format(time.RFC3339)
+
+    return map[string]func(){
+        "POST /api/auth/login": func() {
+            e.Expect(e.Do("POST", "/api/auth/login", "", map[string]string{"email": "alice@example.com", "password": "wrong"}), http.StatusUnauthorized)
+        },
+        "POST /api/auth/register": func() {
+            e.Expect(e.Do("POST", "/api/auth/register", "", map[string]string{"email": "alice@example.com", "password": "x", "name": "x"}), http.StatusConflict)
+            e.Expect(e.Do("POST", "/api/auth/register", "", map[string]string{"email": "Carol <carol@example.com>", "password": "x", "name": "x"}), http.StatusUnprocessableEntity)
+        },
+        "POST /api/auth/forgot-password": func() {
+            // Ссылка на сброс пароля alice уходит только ей
+            e.Expect(e.Do("POST", "/api/auth/forgot-password", "", map[string]string{"email": "alice@example.com"}), http.StatusAccepted)
+            e.Token("alice@example.com", "/reset-password", 1)
+            for _, msg := range e.Mail.Messages() {
+                if msg.To != "alice@example.com" && strings.Contains(msg.Text, "/reset-password") {
+                    e.T.Errorf("reset email sent to %s", msg.To)
+                }
+            }
+        },
+        "POST /api/auth/reset-password": func() {
+            e.Expect(e.Do("POST", "/api/auth/reset-password", "", map[string]string{"token": "forged", "password": "x"}), http.StatusUnprocessableEntity)
+        },
+        "POST /api/auth/verify-email": func() {
+            path := "/api/auth/verify-email"
+            e.Expect(e.Do("POST", path, "", map[string]string{"token": "forged"}), http.StatusUnprocessableEntity)
+            e.Expect(e.Do("POST", path, "", map[string]string{"token": e.Token("bob@example.com", "/verify-email", 1)}), http.StatusUnprocessableEntity)
+        },
+        "POST /api/auth/resend-verification": func() {
+            e.Expect(e.Do("POST", "/api/auth/resend-verification", e.Bob, nil), http.StatusUnprocessableEntity)
+        },
+        "POST /api/auth/refresh": func() {
+            e.Expect(e.Do("POST", "/api/auth/refresh", "", map[string]string{"refresh_token": "forged"}), http.StatusUnauthorized)
+        },
+        "POST /api/auth/logout": func() {
+            e.Expect(e.Do("POST", "/api/auth/logout", e.Login("bob@example.com"), nil), http.StatusNoContent)
+        },
+        "POST /api/auth/logout-all": func() {
+            e.Expect(e.Do("POST", "/api/auth/logout-all", e.Login("bob@example.com"), nil), http.StatusNoContent)
+            e.Bob = e.Login("bob@example.com")
+        },
+
+        "POST /api/tasks": func() {
+            e.Expect(e.Do("POST", "/api/tasks", e.Bob, map[string]interface{}{
+                "title": "x", "due_date": due, "category_id": e.Category,
+            }), http.StatusNotFound)
+            e.Expect(e.Do("POST", "/api/tasks", e.Bob, map[string]interface{}{
+                "title": "x", "due_date": due, "parent_id": e.Task,
+            }), http.StatusUnprocessableEntity)
+        },
+        "GET /api/tasks": func() {
+            rec := e.Do("GET", "/api/tasks", e.Bob, nil)
+            e.Expect(rec, http.StatusOK)
+            tag := rec.Header().Get("ETag")
+            if tag == "" {
+                e.T.Fatal("task list has no ETag")
+            }
+            // Изменения alice не сбрасывают тег списка bob
+            if rec := e.Do("PATCH", task("/api/tasks/%d"), e.Alice, map[string]interface{}{"priority": 1}); rec.Code != http.StatusOK {
+                e.T.Errorf("owner patch status = %d, want 200: %s", rec.Code, rec.Body.String())
+            }
+            e.Expect(e.DoHeader("GET", "/api/tasks", e.Bob, map[string]string{"If-None-Match": tag}, nil), http.StatusNotModified)
+        },
+        "GET /api/tasks/{id}": func() {
+            e.Expect(e.Do("GET", task("/api/tasks/%d"), e.Bob, nil), http.StatusNotFound)
+            // Чужая задача не выдаёт себя ответом 412
+            e.Expect(e.DoHeader("DELETE", task("/api/tasks/%d"), e.Bob, map[string]string{"If-Match": `"1"`}, nil), http.StatusNotFound)
+        },
+        "GET /api/tasks/occurrences": func() {
+            e.Expect(e.Do("GET", "/api/tasks/occurrences", e.Bob, nil), http.StatusOK)
+        },
+        "PUT /api/tasks/{id}": func() {
+            e.Expect(e.Do("PUT", task("/api/tasks/%d"), e.Bob, map[string]interface{}{
+                "title": "hijacked", "due_date": due, "completed": true,
+            }), http.StatusNotFound)
+            e.Expect(e.Do("PUT", task("/api/tasks/%d"), e.Alice, map[string]interface{}{
+                "title": secret + "-task", "due_date": due, "category_id": e.BobCategory,
+            }), http.StatusNotFound)
+            // Владелец по-прежнему может менять свою задачу
+            if rec := e.Do("PUT", task("/api/tasks/%d"), e.Alice, map[string]interface{}{
+                "title": secret + "-task", "due_date": due, "category_id": e.Category,
+            }); rec.Code != http.StatusOK {
+                e.T.Errorf("owner update status = %d, want 200: %s", rec.Code, rec.Body.String())
+            }
+        },
+        "PATCH /api/tasks/{id}": func() {
+            e.Expect(e.Do("PATCH", task("/api/tasks/%d"), e.Bob, map[string]interface{}{"completed": true}), http.StatusNotFound)
+            e.Expect(e.Do("PATCH", task("/api/tasks/%d"), e.Alice, map[string]interface{}{"category_id": e.BobCategory}), http.StatusNotFound)
+
+            var own models.Task
+            e.Decode(e.Do("POST", "/api/tasks", e.Bob, map[string]interface{}{"title": "bob-task", "due_date": due}), &own)
+            e.Expect(e.Do("PATCH", fmt.Sprintf("/api/tasks/%d", own.ID), e.Bob, map[string]interface{}{"parent_id": e.Task}), http.StatusUnprocessableEntity)
+        },
+        "DELETE /api/tasks/{id}": func() {
+            e.Expect(e.Do("DELETE", task("/api/tasks/%d"), e.Bob, nil), http.StatusNotFound)
+        },
+        "POST /api/tasks/{id}/restore": func() {
+            e.Expect(e.Do("POST", task("/api/tasks/%d/restore"), e.Bob, nil), http.StatusNotFound)
+
+            var trashed models.Task
+            e.Decode(e.Do("POST", "/api/tasks", e.Alice, map[string]interface{}{"title": secret + "-trashed", "due_date": due}), &trashed)
+            path := fmt.Sprintf("/api/tasks/%d", trashed.ID)
+            if rec := e.Do("DELETE", path, e.Alice, nil); rec.Code != http.StatusOK {
+                e.T.Fatalf("alice's DELETE %s = %d", path, rec.Code)
+            }
+            e.Expect(e.Do("POST", path+"/restore", e.Bob, nil), http.StatusNotFound)
+        },
+        "GET /api/tasks/{id}/history": func() {
+            e.Expect(e.Do("GET", task("/api/tasks/%d/history"), e.Bob, nil), http.StatusNotFound)
+        },
+        "GET /api/tasks/{id}/subtasks": func() {
+            e.Expect(e.Do("GET", task("/api/tasks/%d/subtasks"), e.Bob, nil), http.StatusNotFound)
+        },
+        "PUT /api/tasks/{id}/subtasks/order": func() {
+            e.Expect(e.Do("PUT", task("/api/tasks/%d/subtasks/order"), e.Bob, map[string]interface{}{
+                "ids": []uint{e.Subtask},
+            }), http.StatusNotFound)
+        },
+        "GET /api/tasks/{id}/reminders": func() {
+            e.Expect(e.Do("GET", task("/api/tasks/%d/reminders"), e.Bob, nil), http.StatusNotFound)
+        },
+        "POST /api/tasks/{id}/reminders": func() {
+            e.Expect(e.Do("POST", task("/api/tasks/%d/reminders"), e.Bob, map[string]int{"minutes_before": 5}), http.StatusNotFound)
+        },
+        "PUT /api/tasks/{id}/reminders/{reminderId}": func() {
+            e.Expect(e.Do("PUT", fmt.Sprintf("/api/tasks/%d/reminders/%d", e.Task, e.Reminder), e.Bob, map[string]int{"minutes_before": 5}), http.StatusNotFound)
+        },
+        "DELETE /api/tasks/{id}/reminders/{reminderId}": func() {
+            e.Expect(e.Do("DELETE", fmt.Sprintf("/api/tasks/%d/reminders/%d", e.Task, e.Reminder), e.Bob, nil), http.StatusNotFound)
+        },
+
+        "GET /api/categories": func() {
+            e.Expect(e.Do("GET", "/api/categories", e.Bob, nil), http.StatusOK)
+        },
+        "GET /api/categories/{id}": func() {
+            e.Expect(e.Do("GET", fmt.Sprintf("/api/categories/%d", e.Category), e.Bob, nil), http.StatusNotFound)
+        },
+        "POST /api/categories": func() {
+            e.Expect(e.Do("POST", "/api/categories", e.Bob, map[string]string{"name": "bob-other"}), http.StatusCreated)
+        },
+        "DELETE /api/categories/{id}": func() {
+            e.Expect(e.Do("DELETE", fmt.Sprintf("/api/categories/%d", e.Category), e.Bob, nil), http.StatusNotFound)
+        },
+        "POST /api/categories/{id}/restore": func() {
+            e.Expect(e.Do("POST", fmt.Sprintf("/api/categories/%d/restore", e.Category), e.Bob, nil), http.StatusNotFound)
+
+            var trashed models.Category
+            e.Decode(e.Do("POST", "/api/categories", e.Alice, map[string]string{"name": secret + "-trashed"}), &trashed)
+            path := fmt.Sprintf("/api/categories/%d", trashed.ID)
+            if rec := e.Do("DELETE", path, e.Alice, nil); rec.Code != http.StatusNoContent {
+                e.T.Fatalf("alice's DELETE %s = %d", path, rec.Code)
+            }
+            e.Expect(e.Do("POST", path+"/restore", e.Bob, nil), http.StatusNotFound)
+        },
+        "GET /api/categories/{id}/tasks": func() {
+            e.Expect(e.Do("GET", fmt.Sprintf("/api/categories/%d/tasks", e.Category), e.Bob, nil), http.StatusNotFound)
+        },
+        "PUT /api/categories/tasks/{id}": func() {
+            e.Expect(e.Do("PUT", task("/api/categories/tasks/%d"), e.Bob, map[string]uint{"category_id": e.BobCategory}), http.StatusNotFound)
+            e.Expect(e.Do("PUT", task("/api/categories/tasks/%d"), e.Alice, map[string]uint{"category_id": e.BobCategory}), http.StatusNotFound)
+        },
+
+        "GET /api/notifications": func() {
+            e.Expect(e.Do("GET", "/api/notifications", e.Bob, nil), http.StatusOK)
+        },
+        "GET /api/notifications/stream": func() {
+            rec := e.Stream(e.Bob)
+            e.Expect(rec, http.StatusOK)
+            var eventType string
+            for _, line := range strings.Split(rec.Body.String(), "\n") {
+                if strings.HasPrefix(line, "event: ") {
+                    eventType = strings.TrimPrefix(line, "event: ")
+                }
+                if !strings.HasPrefix(line, "data: ") || !strings.HasPrefix(eventType, "task.") {
+                    continue
+                }
+                var data models.TaskEventData
+                json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &data)
+                if data.ID == e.Task || data.ID == e.Subtask {
+                    e.T.Errorf("stream leaks another user's task event: %s", line)
+                }
+            }
+            // alice получает свои события
+            if rec := e.Stream(e.Alice); !strings.Contains(rec.Body.String(), "event: "+models.EventNotificationCreated) {
+                e.T.Errorf("owner stream has no notification event: %d %s", rec.Code, rec.Body.String())
+            }
+        },
+        "DELETE /api/notifications": func() {
+            rec := e.Do("DELETE", "/api/notifications", e.Bob, map[string][]uint{"ids": {e.Notification}})
+            e.Expect(rec, http.StatusOK)
+            var resp struct{ Affected int64 }
+            if e.Decode(rec, &resp); resp.Affected != 0 {
+                e.T.Errorf("bob deleted %d of alice's notifications", resp.Affected)
+            }
+        },
+        "GET /api/notifications/unread-count": func() {
+            rec := e.Do("GET", "/api/notifications/unread-count", e.Bob, nil)
+            e.Expect(rec, http.StatusOK)
+            var resp struct{ Count int }
+            if e.Decode(rec, &resp); resp.Count != 0 {
+                e.T.Errorf("bob's unread count includes alice's notifications: %d", resp.Count)
+            }
+        },
+        "POST /api/notifications/read-all": func() {
+            e.Expect(e.Do("POST", "/api/notifications/read-all", e.Bob, nil), http.StatusOK)
+        },
+        "POST /api/notifications/read": func() {
+            rec := e.Do("POST", "/api/notifications/read", e.Bob, map[string][]uint{"ids": {e.Notification}})
+            e.Expect(rec, http.StatusOK)
+            var resp struct{ Affected int64 }
+            if e.Decode(rec, &resp); resp.Affected != 0 {
+                e.T.Errorf("bob marked %d of alice's notifications as read", resp.Affected)
+            }
+        },
+        "DELETE /api/notifications/{id}": func() {
+            e.Expect(e.Do("DELETE", fmt.Sprintf("/api/notifications/%d", e.Notification), e.Bob, nil), http.StatusNotFound)
+        },
+        "POST /api/notifications/{id}/read": func() {
+            e.Expect(e.Do("POST", fmt.Sprintf("/api/notifications/%d/read", e.Notification), e.Bob, nil), http.StatusNotFound)
+        },
+        "POST /api/notifications/{id}/snooze": func() {
+            e.Expect(e.Do("POST", fmt.Sprintf("/api/notifications/%d/snooze", e.Notification), e.Bob, nil), http.StatusNotFound)
+        },
+        "GET /api/notifications/{id}/deliveries": func() {
+            e.Expect(e.Do("GET", fmt.Sprintf("/api/notifications/%d/deliveries", e.Notification), e.Bob, nil), http.StatusNotFound)
+        },
+
+        "GET /api/webhooks": func() {
+            e.Expect(e.Do("GET", "/api/webhooks", e.Bob, nil), http.StatusOK)
+        },
+        "POST /api/webhooks": func() {
+            e.Expect(e.Do("POST", "/api/webhooks", e.Bob, map[string]interface{}{
+                "url": "https://example.com/hook", "events": []string{"task.*"},
+            }), http.StatusCreated)
+        },
+        "GET /api/webhooks/{id}": func() {
+            e.Expect(e.Do("GET", fmt.Sprintf("/api/webhooks/%d", e.Webhook), e.Bob, nil), http.StatusNotFound)
+        },
+        "PUT /api/webhooks/{id}": func() {
+            path := fmt.Sprintf("/api/webhooks/%d", e.Webhook)
+            e.Expect(e.Do("PUT", path, e.Bob, map[string]interface{}{"url": "https://evil.example.com"}), http.StatusNotFound)
+            e.Expect(e.Do("PUT", path, e.Bob, map[string]interface{}{"rotate_secret": true}), http.StatusNotFound)
+        },
+        "DELETE /api/webhooks/{id}": func() {
+            e.Expect(e.Do("DELETE", fmt.Sprintf("/api/webhooks/%d", e.Webhook), e.Bob, nil), http.StatusNotFound)
+        },
+        "GET /api/webhooks/{id}/deliveries": func() {
+            e.Expect(e.Do("GET", fmt.Sprintf("/api/webhooks/%d/deliveries", e.Webhook), e.Bob, nil), http.StatusNotFound)
+        },
+        "POST /api/webhooks/{id}/test": func() {
+            e.Expect(e.Do("POST", fmt.Sprintf("/api/webhooks/%d/test", e.Webhook), e.Bob, nil), http.StatusNotFound)
+        },
+
+        "GET /api/search": func() {
+            e.Expect(e.Do("GET", "/api/search?q=alice", e.Bob, nil), http.StatusOK)
+        },
+
+        "GET /api/sync": func() {
+            rec := e.Do("GET", "/api/sync", e.Bob, nil)
+            e.Expect(rec, http.StatusOK)
+            var full models.ChangeSet
+            e.Decode(rec, &full)
+            if len(full.Categories) == 0 || full.Categories[0].UserID != e.BobID {
+                e.T.Errorf("GET /api/sync returned %s for bob", rec.Body.String())
+            }
+
+            // Изменения alice не попадают в журнал bob
+            if rec := e.Do("POST", "/api/categories", e.Alice, map[string]string{"name": secret + "-sync"}); rec.Code != http.StatusCreated {
+                e.T.Fatalf("alice's POST /api/categories = %d", rec.Code)
+            }
+            rec = e.Do("GET", "/api/sync?since="+full.Token, e.Bob, nil)
+            e.Expect(rec, http.StatusOK)
+            var delta models.ChangeSet
+            e.Decode(rec, &delta)
+            if len(delta.Tasks)+len(delta.Categories)+len(delta.Notifications) != 0 || delta.Token != full.Token {
+                e.T.Errorf("GET /api/sync?since returned %s for bob", rec.Body.String())
+            }
+        },
+        "GET /api/trash": func() {
+            var trashed models.Task
+            e.Decode(e.Do("POST", "/api/tasks", e.Alice, map[string]interface{}{"title": secret + "-bin", "due_date": due}), &trashed)
+            if rec := e.Do("DELETE", fmt.Sprintf("/api/tasks/%d", trashed.ID), e.Alice, nil); rec.Code != http.StatusOK {
+                e.T.Fatalf("alice's DELETE /api/tasks/%d = %d", trashed.ID, rec.Code)
+            }
+            e.Expect(e.Do("GET", "/api/trash", e.Bob, nil), http.StatusOK)
+        },
+        "GET /api/activity": func() {
+            rec := e.Do("GET", "/api/activity", e.Bob, nil)
+            e.Expect(rec, http.StatusOK)
+            var page models.HistoryPage
+            e.Decode(rec, &page)
+            for _, got := range page.Events {
+                if got.UserID != e.BobID {
+                    e.T.Errorf("GET /api/activity returned event %d of user %d for bob", got.ID, got.UserID)
+                }
+            }
+        },
+        "POST /api/sync": func() {
+            path := "/api/sync"
+            apply := func(mutations ...map[string]interface{}) []handlers.SyncResult {
+                rec := e.Do("POST", path, e.Bob, map[string]interface{}{"mutations": mutations})
+                e.Expect(rec, http.StatusOK)
+                var resp handlers.SyncResponse
+                e.Decode(rec, &resp)
+                if len(resp.Results) != len(mutations) {
+                    e.T.Fatalf("POST %s returned %s", path, rec.Body.String())
+                }
+                return resp.Results
+            }
+            status := func(results []handlers.SyncResult) string {
+                statuses := make([]string, len(results))
+                for i, result := range results {
+                    statuses[i] = result.Status
+                }
+                return strings.Join(statuses, ",")
+            }
+
+            results := apply(
+                map[string]interface{}{"entity": "task", "op": "update", "id": e.Task, "data": map[string]bool{"completed": true}},
+                map[string]interface{}{"entity": "task", "op": "delete", "id": e.Subtask, "version": 1},
+                map[string]interface{}{"entity": "category", "op": "delete", "id": e.Category, "version": 1},
+                map[string]interface{}{"entity": "notification", "op": "update", "id": e.Notification, "data": map[string]bool{"read": true}},
+                map[string]interface{}{"entity": "task", "op": "create", "client_id": "t-1", "data": map[string]interface{}{
+                    "title": "stolen", "due_date": due, "category_id": e.Category,
+                }},
+                map[string]interface{}{"entity": "task", "op": "create", "client_id": "t-2", "data": map[string]interface{}{
+                    "title": "stolen", "due_date": due, "parent_id": e.Task,
+                }},
+            )
+            // Чужие записи удаления не находят, и удалять нечего; остальное отклоняется
+            if got := status(results); got != "rejected,applied,applied,rejected,rejected,rejected" {
+                e.T.Errorf("bob's mutations of alice's records = %s", got)
+            }
+            if results[0].Error == nil || results[0].Error.Code != apierror.CodeNotFound {
+                e.T.Errorf("update of a foreign task error = %+v", results[0].Error)
+            }
+        },
+
+        "GET /api/users/me": func() {
+            rec := e.Do("GET", "/api/users/me", e.Bob, nil)
+            e.Expect(rec, http.StatusOK)
+            var user models.User
+            e.Decode(rec, &user)
+            if user.ID != e.BobID {
+                e.T.Errorf("GET /api/users/me returned %+v for bob", user)
+            }
+        },
+        "PUT /api/users/me": func() {
+            e.Expect(e.Do("PUT", "/api/users/me", e.Bob, map[string]string{"timezone": "Europe/Berlin"}), http.StatusOK)
+            alice, err := e.Store.GetUserByID(context.Background(), e.AliceID)
+            if err != nil || alice.Timezone != "Asia/Vladivostok" {
+                e.T.Errorf("bob's profile update changed alice: %+v, %v", alice, err)
+            }
+        },
+        "GET /api/users/me/notification-settings": func() {
+            e.Expect(e.Do("GET", "/api/users/me/notification-settings", e.Bob, nil), http.StatusOK)
+        },
+        "PUT /api/users/me/notification-settings": func() {
+            e.Expect(e.Do("PUT", "/api/users/me/notification-settings", e.Bob, map[string]interface{}{
+                "types":       map[string]bool{string(models.NotificationTaskDueSoon): false},
+                "quiet_hours": map[string]string{"start": "22:00", "end": "07:30"},
+            }), http.StatusOK)
+            alice, err := e.Store.GetNotificationSettings(context.Background(), e.AliceID)
+            if err != nil || alice.QuietHours != nil || !alice.TypeEnabled(models.NotificationTaskDueSoon) {
+                e.T.Errorf("bob's settings update changed alice: %+v, %v", alice, err)
+            }
+        },
+    }
+}
+
+// routes перечисляет зарегистрированные маршруты в виде "METHOD /path".
+func routes(t *testing.T, router *mux.Router) []string {
+    var out []string
+    err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+        path, err := route.GetPathTemplate()
+        if err != nil {
+            return nil
+        }
+        methods, err := route.GetMethods()
+        if err != nil {
+            return nil
+        }
+        for _, method := range methods {
+            if method != http.MethodOptions {
+                out = append(out, method+" "+path)
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        t.Fatalf("walk routes: %v", err)
+    }
+    sort.Strings(out)
+    return out
+}
+
+// Run прогоняет сценарий для каждого маршрута и проверяет, что данные alice
+// после всех попыток bob остались нетронутыми.
+func Run(t *testing.T, newStore Factory) {
+    store := newStore(t)
+    e := NewEnv(t, store)
+    matrix := cases(e)
+    ctx := context.Background()
+    webhook, err := store.GetWebhook(ctx, e.Webhook, e.AliceID)
+    if err != nil {
+        t.Fatalf("GetWebhook: %v", err)
+    }
+
+    for _, route := range routes(t, e.Router) {
+        check, ok := matrix[route]
+        if !ok {
+            t.Errorf("route %s has no cross-user case", route)
+            continue
+        }
+        t.Run(route, func(t *testing.T) {
+            e.T = t
+            check()
+        })
+    }
+    e.T = t
+
+    task, err := store.GetTask(ctx, e.Task, e.AliceID)
+    if err != nil {
+        t.Fatalf("alice's task is gone: %v", err)
+    }
+    if task.Title != secret+"-task" || task.Completed || task.CategoryID == nil || *task.CategoryID != e.Category {
+        t.Errorf("alice's task was modified: %+v", task)
+    }
+    subtask, err := store.GetTask(ctx, e.Subtask, e.AliceID)
+    if err != nil {
+        t.Errorf("alice's subtask is gone: %v", err)
+    } else if subtask.Title != secret+"-subtask" || subtask.ParentID == nil || *subtask.ParentID != e.Task {
+        t.Errorf("alice's subtask was modified: %+v", subtask)
+    }
+    if _, err := store.GetCategory(ctx, e.Category, e.AliceID); err != nil {
+        t.Errorf("alice's category is gone: %v", err)
+    }
+    reminders, _ := store.GetTaskReminders(ctx, e.Task, e.AliceID)
+    if len(reminders) != 1 || reminders[0].MinutesBefore == nil || *reminders[0].MinutesBefore != 60 {
+        t.Errorf("alice's reminders were modified: %+v", reminders)
+    }
+    notifications, _ := store.GetUserNotifications(ctx, e.AliceID)
+    found := false
+    for _, n := range notifications {
+        if n.ID == e.Notification {
+            found = true
+            if n.Read {
+                t.Error("alice's notification was marked as read")
+            }
+        }
+    }
+    if !found {
+        t.Error("alice's notification is gone")
+    }
+    after, err := store.GetWebhook(ctx, e.Webhook, e.AliceID)
+    if err != nil {
+        t.Errorf("alice's webhook is gone: %v", err)
+    } else if after.URL != webhook.URL || after.Secret != webhook.Secret || after.Active != webhook.Active ||
+        strings.Join(after.Events, ",") != strings.Join(webhook.Events, ",") || !after.UpdatedAt.Equal(webhook.UpdatedAt) {
+        t.Errorf("alice's webhook was modified: %+v, was %+v", after, webhook)
+    }
+    // Выход bob из всех сессий не должен затронуть сессию alice
+    if rec := e.Do("GET", "/api/tasks", e.Alice, nil); rec.Code != http.StatusOK {
+        t.Errorf("alice's session stopped working: %d", rec.Code)
+    }
+}
@@ -0,0 +1,43 @@
+package server_test
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "todo-app/internal/memstore"
+    "todo-app/internal/models"
+    "todo-app/internal/server/servertest"
+)
+
+func TestNotificationSettings(t *testing.T) {
+    e := servertest.NewEnv(t, memstore.New())
+    path := "/api/users/me/notification-settings"
+
+    rec := e.Do("GET", path, e.Bob, nil)
+    var settings models.NotificationSettings
+    e.Decode(rec, &settings)
+    if !settings.Channels.InApp || settings.EmailDelivery == "" || settings.QuietHours != nil {
+        t.Errorf("default settings = %s", rec.Body.String())
+    }
+
+    e.Expect(e.Do("PUT", path, e.Bob, map[string]interface{}{
+        "quiet_hours": map[string]string{"start": "22:00", "end": "22:00"},
+    }), http.StatusUnprocessableEntity)
+    e.Expect(e.Do("PUT", path, e.Bob, map[string]interface{}{
+        "types": map[string]bool{"no_such_type": false},
+    }), http.StatusUnprocessableEntity)
+
+    rec = e.Do("PUT", path, e.Bob, map[string]interface{}{
+        "types":       map[string]bool{string(models.NotificationTaskDueSoon): false},
+        "quiet_hours": map[string]string{"start": "22:00", "end": "07:30"},
+    })
+    e.Expect(rec, http.StatusOK)
+    // Ответ на PUT и последующее чтение возвращают сохранённые настройки
+    for _, rec := range []*httptest.ResponseRecorder{rec, e.Do("GET", path, e.Bob, nil)} {
+        var saved models.NotificationSettings
+        e.Decode(rec, &saved)
+        if saved.TypeEnabled(models.NotificationTaskDueSoon) || saved.QuietHours == nil || saved.QuietHours.End.String() != "07:30" {
+            t.Errorf("saved settings = %s", rec.Body.String())
+        }
+    }
+}
@@ -0,0 +1,92 @@
+package server_test
+
+import (
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+    "todo-app/internal/apierror"
+    "todo-app/internal/handlers"
+    "todo-app/internal/memstore"
+    "todo-app/internal/server/servertest"
+)
+
+// applySync отправляет пакет изменений от имени bob.
+func applySync(e *servertest.Env, mutations ...map[string]interface{}) []handlers.SyncResult {
+    e.T.Helper()
+    rec := e.Do("POST", "/api/sync", e.Bob, map[string]interface{}{"mutations": mutations})
+    e.Expect(rec, http.StatusOK)
+    var resp handlers.SyncResponse
+    e.Decode(rec, &resp)
+    if len(resp.Results) != len(mutations) {
+        e.T.Fatalf("POST /api/sync returned %s", rec.Body.String())
+    }
+    return resp.Results
+}
+
+func syncStatuses(results []handlers.SyncResult) string {
+    statuses := make([]string, len(results))
+    for i, result := range results {
+        statuses[i] = result.Status
+    }
+    return strings.Join(statuses, ",")
+}
+
+func TestSyncValidation(t *testing.T) {
+    e := servertest.NewEnv(t, memstore.New())
+
+    e.Expect(e.Do("GET", "/api/sync?since=forged", e.Bob, nil), http.StatusUnprocessableEntity)
+    e.Expect(e.Do("GET", "/api/sync?limit=0", e.Bob, nil), http.StatusUnprocessableEntity)
+    e.Expect(e.Do("POST", "/api/sync", e.Bob, map[string]interface{}{"mutations": make([]map[string]string, 101)}), http.StatusUnprocessableEntity)
+}
+
+func TestSyncOfflineBatch(t *testing.T) {
+    e := servertest.NewEnv(t, memstore.New())
+    due := time.Now().UTC().Format(time.RFC3339)
+
+    batch := []map[string]interface{}{
+        {"entity": "category", "op": "create", "client_id": "c-1", "data": map[string]string{"name": "offline"}},
+        {"entity": "task", "op": "create", "client_id": "t-1", "data": map[string]interface{}{
+            "title": "offline", "due_date": due, "category_id": "c-1",
+        }},
+        {"entity": "task", "op": "update", "id": "t-1", "version": 1, "data": map[string]string{"title": "edited"}},
+    }
+    results := applySync(e, batch...)
+    if got := syncStatuses(results); got != "applied,applied,applied" {
+        t.Fatalf("offline batch = %s", got)
+    }
+    category, created := results[0].ID, results[1].Task
+    if created == nil || created.CategoryID == nil || *created.CategoryID != category || results[2].Task.Title != "edited" {
+        t.Errorf("offline batch created %+v in category %d", results[1].Task, category)
+    }
+
+    // Повторная отправка не создаёт дубликатов, а устаревшее изменение
+    // возвращает текущую задачу
+    retry := applySync(e, batch...)
+    if got := syncStatuses(retry); got != "applied,applied,conflict" {
+        t.Errorf("retried batch = %s", got)
+    }
+    if retry[0].ID != category || retry[1].ID != created.ID || retry[2].Task == nil || retry[2].Task.Version != 2 {
+        t.Errorf("retried batch returned %+v", retry)
+    }
+    if retry[2].Error == nil || retry[2].Error.Code != apierror.CodePreconditionFailed {
+        t.Errorf("conflict error = %+v", retry[2].Error)
+    }
+
+    results = applySync(e, map[string]interface{}{"entity": "task", "op": "delete", "id": "t-1", "version": 2})
+    if got := syncStatuses(results); got != "applied" {
+        t.Errorf("delete by client id = %s", got)
+    }
+
+    // Повтор создания задачи из корзины — конфликт с её состоянием на сервере
+    results = applySync(e, batch[1])
+    if got := syncStatuses(results); got != "conflict" {
+        t.Fatalf("replayed create of a trashed task = %s", got)
+    }
+    if task := results[0].Task; results[0].ID != created.ID || task == nil || task.DeletedAt == nil {
+        t.Errorf("replayed create returned %+v", results[0])
+    }
+    if results[0].Error == nil || results[0].Error.Code != apierror.CodeConflict {
+        t.Errorf("replayed create error = %+v", results[0].Error)
+    }
+}
@@ -0,0 +1,54 @@
+package server_test
+
+import (
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+    "todo-app/internal/memstore"
+    "todo-app/internal/models"
+    "todo-app/internal/server/servertest"
+)
+
+func TestPatchTask(t *testing.T) {
+    e := servertest.NewEnv(t, memstore.New())
+    due := time.Now().UTC().Format(time.RFC3339)
+
+    var own models.Task
+    e.Decode(e.Do("POST", "/api/tasks", e.Bob, map[string]interface{}{
+        "title": "bob-task", "description": "keep", "due_date": due, "category_id": e.BobCategory,
+    }), &own)
+    path := fmt.Sprintf("/api/tasks/%d", own.ID)
+    e.Expect(e.Do("PATCH", path, e.Bob, map[string]interface{}{"title": nil}), http.StatusUnprocessableEntity)
+    e.Expect(e.Do("PATCH", path, e.Bob, map[string]interface{}{"user_id": e.AliceID}), http.StatusUnprocessableEntity)
+
+    // Отсутствующие поля не меняются, null убирает категорию
+    rec := e.Do("PATCH", path, e.Bob, map[string]interface{}{"completed": true, "category_id": nil})
+    e.Expect(rec, http.StatusOK)
+    var patched models.Task
+    e.Decode(rec, &patched)
+    if !patched.Completed || patched.CategoryID != nil || patched.Title != "bob-task" || patched.Description != "keep" || !patched.DueDate.Equal(own.DueDate) {
+        t.Errorf("PATCH returned %+v", patched)
+    }
+}
+
+func TestSubtasksWithoutChildren(t *testing.T) {
+    e := servertest.NewEnv(t, memstore.New())
+    due := time.Now().UTC().Format(time.RFC3339)
+
+    var own models.Task
+    e.Decode(e.Do("POST", "/api/tasks", e.Bob, map[string]interface{}{"title": "bob-leaf", "due_date": due}), &own)
+    path := fmt.Sprintf("/api/tasks/%d/subtasks", own.ID)
+    // Пустой список подзадач — массив, а не null
+    for _, rec := range []*httptest.ResponseRecorder{
+        e.Do("GET", path, e.Bob, nil),
+        e.Do("PUT", path+"/order", e.Bob, map[string][]uint{"ids": {}}),
+    } {
+        e.Expect(rec, http.StatusOK)
+        if body := strings.TrimSpace(rec.Body.String()); body != "[]" {
+            t.Errorf("subtasks of a leaf task = %s, want []", body)
+        }
+    }
+}
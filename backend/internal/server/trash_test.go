@@ -0,0 +1,55 @@
+package server_test
+
+import (
+    "fmt"
+    "net/http"
+    "testing"
+    "time"
+    "todo-app/internal/memstore"
+    "todo-app/internal/models"
+    "todo-app/internal/server/servertest"
+)
+
+func TestTrash(t *testing.T) {
+    e := servertest.NewEnv(t, memstore.New())
+    due := time.Now().UTC().Format(time.RFC3339)
+
+    var task models.Task
+    e.Decode(e.Do("POST", "/api/tasks", e.Bob, map[string]interface{}{
+        "title": "bob-bin", "due_date": due, "category_id": e.BobCategory,
+    }), &task)
+    taskPath := fmt.Sprintf("/api/tasks/%d", task.ID)
+    categoryPath := fmt.Sprintf("/api/categories/%d", e.BobCategory)
+    e.Expect(e.Do("POST", taskPath+"/restore", e.Bob, nil), http.StatusNotFound)
+
+    e.Expect(e.Do("DELETE", taskPath, e.Bob, nil), http.StatusOK)
+    e.Expect(e.Do("DELETE", categoryPath, e.Bob, nil), http.StatusNoContent)
+    e.Expect(e.Do("GET", taskPath, e.Bob, nil), http.StatusNotFound)
+
+    rec := e.Do("GET", "/api/trash", e.Bob, nil)
+    e.Expect(rec, http.StatusOK)
+    var trash models.Trash
+    e.Decode(rec, &trash)
+    if len(trash.Tasks) != 1 || trash.Tasks[0].ID != task.ID || len(trash.Categories) != 1 || trash.Categories[0].ID != e.BobCategory {
+        t.Fatalf("GET /api/trash returned %s", rec.Body.String())
+    }
+
+    // Восстановленная категория возвращает задачу, удалённую вместе с ней
+    rec = e.Do("POST", taskPath+"/restore", e.Bob, nil)
+    e.Expect(rec, http.StatusOK)
+    if rec.Header().Get("ETag") == "" {
+        t.Error("restored task has no ETag")
+    }
+    e.Expect(e.Do("POST", categoryPath+"/restore", e.Bob, nil), http.StatusOK)
+    e.Expect(e.Do("POST", categoryPath+"/restore", e.Bob, nil), http.StatusNotFound)
+    var restored models.Task
+    e.Decode(e.Do("GET", taskPath, e.Bob, nil), &restored)
+    if restored.DeletedAt != nil || restored.CategoryID == nil || *restored.CategoryID != e.BobCategory {
+        t.Errorf("restored task = %+v", restored)
+    }
+
+    e.Decode(e.Do("GET", "/api/trash", e.Bob, nil), &trash)
+    if len(trash.Tasks) != 0 || len(trash.Categories) != 0 {
+        t.Errorf("trash after restore = %+v", trash)
+    }
+}
@@ -0,0 +1,34 @@
+package server_test
+
+import (
+    "net/http"
+    "testing"
+    "todo-app/internal/memstore"
+    "todo-app/internal/models"
+    "todo-app/internal/server/servertest"
+)
+
+func TestUpdateProfile(t *testing.T) {
+    e := servertest.NewEnv(t, memstore.New())
+
+    e.Expect(e.Do("PUT", "/api/users/me", e.Bob, map[string]string{"timezone": "Mars/Olympus"}), http.StatusUnprocessableEntity)
+    e.Expect(e.Do("PUT", "/api/users/me", e.Bob, map[string]string{"locale": "de"}), http.StatusUnprocessableEntity)
+
+    rec := e.Do("PUT", "/api/users/me", e.Bob, map[string]string{"timezone": "Europe/Berlin", "locale": "en-GB"})
+    e.Expect(rec, http.StatusOK)
+    var updated models.User
+    e.Decode(rec, &updated)
+    if updated.Timezone != "Europe/Berlin" || updated.Locale != "en" {
+        t.Errorf("PUT /api/users/me returned %s", rec.Body.String())
+    }
+
+    // Пустой часовой пояс возвращает пояс по умолчанию, остальные поля не меняются
+    rec = e.Do("PUT", "/api/users/me", e.Bob, map[string]string{"timezone": ""})
+    e.Expect(rec, http.StatusOK)
+    rec = e.Do("GET", "/api/users/me", e.Bob, nil)
+    var me models.User
+    e.Decode(rec, &me)
+    if me.Timezone != models.DefaultTimezone || me.Locale != "en" {
+        t.Errorf("GET /api/users/me returned %s", rec.Body.String())
+    }
+}
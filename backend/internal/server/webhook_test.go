@@ -0,0 +1,55 @@
+package server_test
+
+import (
+    "fmt"
+    "net/http"
+    "strings"
+    "testing"
+    "todo-app/internal/memstore"
+    "todo-app/internal/models"
+    "todo-app/internal/server/servertest"
+)
+
+func TestWebhooks(t *testing.T) {
+    e := servertest.NewEnv(t, memstore.New())
+
+    e.Expect(e.Do("POST", "/api/webhooks", e.Bob, map[string]interface{}{
+        "url": "ftp://example.com", "events": []string{"task.created"},
+    }), http.StatusUnprocessableEntity)
+    e.Expect(e.Do("POST", "/api/webhooks", e.Bob, map[string]interface{}{
+        "url": "https://example.com/hook", "events": []string{"task.archived"},
+    }), http.StatusUnprocessableEntity)
+
+    rec := e.Do("POST", "/api/webhooks", e.Bob, map[string]interface{}{
+        "url": "https://example.com/hook", "events": []string{"task.completed", "category.*"},
+    })
+    e.Expect(rec, http.StatusCreated)
+    var created models.Webhook
+    e.Decode(rec, &created)
+    if !strings.HasPrefix(created.Secret, "whsec_") || !created.Active {
+        t.Fatalf("POST /api/webhooks returned %+v", created)
+    }
+
+    // Ключ показывается только при создании и смене
+    rec = e.Do("GET", "/api/webhooks", e.Bob, nil)
+    var webhooks []models.Webhook
+    e.Decode(rec, &webhooks)
+    if len(webhooks) != 1 || webhooks[0].ID != created.ID || webhooks[0].Secret != "" {
+        t.Errorf("GET /api/webhooks returned %s, want one webhook without secret", rec.Body.String())
+    }
+    path := fmt.Sprintf("/api/webhooks/%d", created.ID)
+    rec = e.Do("PUT", path, e.Bob, map[string]interface{}{"rotate_secret": true})
+    var rotated models.Webhook
+    e.Decode(rec, &rotated)
+    if !strings.HasPrefix(rotated.Secret, "whsec_") || rotated.Secret == created.Secret || rotated.URL != created.URL {
+        t.Errorf("rotating the secret returned %s", rec.Body.String())
+    }
+
+    e.Expect(e.Do("GET", path+"/deliveries?limit=0", e.Bob, nil), http.StatusUnprocessableEntity)
+    rec = e.Do("POST", path+"/test", e.Bob, nil)
+    var delivery models.WebhookDelivery
+    e.Decode(rec, &delivery)
+    if delivery.Event != models.EventWebhookTest || delivery.WebhookID != created.ID || delivery.Status != models.DeliveryPending {
+        t.Errorf("POST %s/test returned %s", path, rec.Body.String())
+    }
+}
@@ -0,0 +1,202 @@
+package webhook
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "log"
+    "net"
+    "net/http"
+    "strconv"
+    "sync"
+    "syscall"
+    "time"
+    "todo-app/internal/config"
+    "todo-app/internal/models"
+)
+
+const (
+    // batchSize доставок одного захвата отправляются параллельно.
+    batchSize = 20
+    // maxResponseBody — сколько читать из ответа, чтобы переиспользовать
+    // соединение.
+    maxResponseBody = 64 << 10
+    userAgent       = "todo-app-webhooks/1.0"
+)
+
+type Store interface {
+    ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.PendingWebhookDelivery, error)
+    CompleteWebhookDelivery(ctx context.Context, id uint, result models.WebhookResult) error
+}
+
+// Dispatcher разбирает очередь доставок вебхуков.
+type Dispatcher struct {
+    store  Store
+    client *http.Client
+    cfg    config.WebhooksConfig
+
+    // Now подменяется в тестах; по умолчанию time.Now.
+    Now func() time.Time
+}
+
+// NewDispatcher не следует перенаправлениям: ответ 3xx считается неудачей.
+// Соединения с внутренними адресами запрещены, см. checkAddress.
+func NewDispatcher(cfg config.WebhooksConfig, store Store) *Dispatcher {
+    return &Dispatcher{store: store, client: newClient(cfg.Timeout, checkAddress), cfg: cfg, Now: time.Now}
+}
+
+// newClient собирает клиент, который проверяет адрес каждого соединения
+// функцией control. Прокси не используется: соединение с ним прошло бы
+// проверку вместо адреса получателя.
+func newClient(timeout time.Duration, control func(network, address string, c syscall.RawConn) error) *http.Client {
+    dialer := &net.Dialer{Timeout: timeout, Control: control}
+    transport := http.DefaultTransport.(*http.Transport).Clone()
+    transport.Proxy = nil
+    transport.DialContext = dialer.DialContext
+    return &http.Client{
+        Timeout:   timeout,
+        Transport: transport,
+        CheckRedirect: func(*http.Request, []*http.Request) error {
+            return http.ErrUseLastResponse
+        },
+    }
+}
+
+var errForbiddenAddress = errors.New("destination address is not allowed")
+
+// sharedAddressSpace — адреса за NAT провайдера (RFC 6598).
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// checkAddress не даёт вебхукам обращаться во внутреннюю сеть: к loopback,
+// частным и link-local адресам, включая 169.254.169.254 с метаданными
+// облака. Проверяется уже разрешённый адрес соединения, поэтому имя, которое
+// указывает внутрь, тоже отсекается.
+func checkAddress(network, address string, _ syscall.RawConn) error {
+    host, _, err := net.SplitHostPort(address)
+    if err != nil {
+        return err
+    }
+    ip := net.ParseIP(host)
+    if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+        ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() ||
+        sharedAddressSpace.Contains(ip) {
+        return fmt.Errorf("%w: %s", errForbiddenAddress, host)
+    }
+    return nil
+}
+
+// Run выполняет Tick сразу и затем каждые cfg.Interval, пока не отменён ctx.
+func (d *Dispatcher) Run(ctx context.Context) {
+    ticker := time.NewTicker(d.cfg.Interval)
+    defer ticker.Stop()
+    for {
+        d.Tick(ctx)
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+func (d *Dispatcher) Tick(ctx context.Context) {
+    sent, err := d.DeliverPending(ctx)
+    if err != nil {
+        log.Printf("Error delivering webhooks: %v", err)
+    } else if sent > 0 {
+        log.Printf("Delivered %d webhooks", sent)
+    }
+}
+
+// DeliverPending отправляет доставки, время попытки которых наступило, и
+// возвращает число успешных.
+func (d *Dispatcher) DeliverPending(ctx context.Context) (int, error) {
+    // Аренда покрывает запрос с таймаутом и запись результата
+    lease := 2*d.cfg.Timeout + time.Minute
+    sent := 0
+    for {
+        claimed, err := d.store.ClaimWebhookDeliveries(ctx, batchSize, lease)
+        if err != nil {
+            return sent, err
+        }
+
+        results := make([]models.WebhookResult, len(claimed))
+        var wg sync.WaitGroup
+        for i := range claimed {
+            wg.Add(1)
+            go func(i int) {
+                defer wg.Done()
+                results[i] = d.deliver(ctx, claimed[i])
+            }(i)
+        }
+        wg.Wait()
+
+        for i, p := range claimed {
+            if err := d.store.CompleteWebhookDelivery(ctx, p.ID, results[i]); err != nil {
+                return sent, err
+            }
+            if results[i].Status == models.DeliverySent {
+                sent++
+            }
+        }
+        if len(claimed) < batchSize {
+            return sent, nil
+        }
+    }
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, p models.PendingWebhookDelivery) models.WebhookResult {
+    body, err := json.Marshal(Payload{ID: p.ID, Event: p.Event, CreatedAt: p.CreatedAt, Data: p.Data})
+    if err != nil {
+        return models.WebhookResult{Status: models.DeliveryFailed, Error: err.Error()}
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+    if err != nil {
+        return models.WebhookResult{Status: models.DeliveryFailed, Error: err.Error()}
+    }
+    timestamp := strconv.FormatInt(d.Now().Unix(), 10)
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("User-Agent", userAgent)
+    req.Header.Set(HeaderID, strconv.FormatUint(uint64(p.ID), 10))
+    req.Header.Set(HeaderEvent, p.Event)
+    req.Header.Set(HeaderTimestamp, timestamp)
+    req.Header.Set(HeaderSignature, Sign(p.Secret, timestamp, body))
+
+    resp, err := d.client.Do(req)
+    // Запрещённый адрес не станет разрешённым при повторе
+    if errors.Is(err, errForbiddenAddress) {
+        return models.WebhookResult{Status: models.DeliveryFailed, Error: errForbiddenAddress.Error()}
+    }
+    if err != nil {
+        return d.retry(p, 0, err.Error())
+    }
+    io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+    resp.Body.Close()
+
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        return d.retry(p, resp.StatusCode, fmt.Sprintf("unexpected response status %d", resp.StatusCode))
+    }
+    return models.WebhookResult{Status: models.DeliverySent, ResponseStatus: resp.StatusCode}
+}
+
+// retry откладывает доставку после неудачи или завершает её, если попытки
+// исчерпаны.
+func (d *Dispatcher) retry(p models.PendingWebhookDelivery, status int, message string) models.WebhookResult {
+    if p.Attempts >= d.cfg.MaxAttempts {
+        return models.WebhookResult{Status: models.DeliveryFailed, ResponseStatus: status, Error: message}
+    }
+    retryAt := d.Now().Add(d.backoff(p.Attempts))
+    return models.WebhookResult{Status: models.DeliveryPending, ResponseStatus: status, Error: message, RetryAt: &retryAt}
+}
+
+// backoff возвращает паузу после неудачной попытки attempt (с единицы).
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+    delay := d.cfg.RetryBackoff
+    for i := 1; i < attempt && delay < 24*time.Hour; i++ {
+        delay *= 2
+    }
+    return delay
+}
@@ -0,0 +1,158 @@
+package webhook
+
+import (
+    "context"
+    "encoding/json"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strconv"
+    "sync"
+    "testing"
+    "time"
+    "todo-app/internal/config"
+    "todo-app/internal/models"
+)
+
+// fakeStore отдаёт доставки одним захватом и запоминает их итоги.
+type fakeStore struct {
+    mu      sync.Mutex
+    pending []models.PendingWebhookDelivery
+    results map[uint]models.WebhookResult
+}
+
+func (s *fakeStore) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.PendingWebhookDelivery, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    claimed := s.pending
+    s.pending = nil
+    return claimed, nil
+}
+
+func (s *fakeStore) CompleteWebhookDelivery(ctx context.Context, id uint, result models.WebhookResult) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.results[id] = result
+    return nil
+}
+
+func newFakeStore(url string, ids ...uint) *fakeStore {
+    s := &fakeStore{results: map[uint]models.WebhookResult{}}
+    for _, id := range ids {
+        s.pending = append(s.pending, models.PendingWebhookDelivery{
+            WebhookDelivery: models.WebhookDelivery{
+                ID:        id,
+                Event:     models.EventTaskCreated,
+                Data:      json.RawMessage(`{"id":7}`),
+                Attempts:  1,
+                CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+            },
+            URL:    url + "/" + strconv.FormatUint(uint64(id), 10),
+            Secret: "whsec",
+        })
+    }
+    return s
+}
+
+var testConfig = config.WebhooksConfig{
+    Interval:     time.Second,
+    Timeout:      5 * time.Second,
+    MaxAttempts:  3,
+    RetryBackoff: time.Minute,
+    LogRetention: time.Hour,
+}
+
+func TestDeliverPending(t *testing.T) {
+    type received struct {
+        header http.Header
+        body   []byte
+    }
+    var mu sync.Mutex
+    requests := map[string]received{}
+    receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        body, _ := io.ReadAll(r.Body)
+        mu.Lock()
+        requests[r.URL.Path] = received{header: r.Header.Clone(), body: body}
+        mu.Unlock()
+        if r.URL.Path == "/2" {
+            w.WriteHeader(http.StatusInternalServerError)
+        }
+    }))
+    defer receiver.Close()
+
+    store := newFakeStore(receiver.URL, 1, 2)
+    d := NewDispatcher(testConfig, store)
+    // Получатель слушает loopback, поэтому проверку адресов отключаем
+    d.client = newClient(testConfig.Timeout, nil)
+    now := time.Unix(1700000000, 0)
+    d.Now = func() time.Time { return now }
+
+    sent, err := d.DeliverPending(context.Background())
+    if err != nil || sent != 1 {
+        t.Fatalf("DeliverPending = %d, %v, want 1", sent, err)
+    }
+
+    req, ok := requests["/1"]
+    if !ok {
+        t.Fatal("receiver got no request for delivery 1")
+    }
+    timestamp := req.header.Get(HeaderTimestamp)
+    if timestamp != "1700000000" || req.header.Get(HeaderID) != "1" || req.header.Get(HeaderEvent) != models.EventTaskCreated {
+        t.Errorf("delivery headers = %v", req.header)
+    }
+    if !Verify("whsec", timestamp, req.header.Get(HeaderSignature), req.body) {
+        t.Errorf("signature %q does not match the body", req.header.Get(HeaderSignature))
+    }
+    if Verify("other", timestamp, req.header.Get(HeaderSignature), req.body) {
+        t.Error("signature matches a different secret")
+    }
+    var payload Payload
+    if err := json.Unmarshal(req.body, &payload); err != nil || payload.ID != 1 || payload.Event != models.EventTaskCreated || string(payload.Data) != `{"id":7}` {
+        t.Errorf("payload = %s, %v", req.body, err)
+    }
+
+    if got := store.results[1]; got.Status != models.DeliverySent || got.ResponseStatus != http.StatusOK {
+        t.Errorf("delivery 1 result = %+v", got)
+    }
+    got := store.results[2]
+    if got.Status != models.DeliveryPending || got.ResponseStatus != http.StatusInternalServerError ||
+        got.RetryAt == nil || !got.RetryAt.Equal(now.Add(time.Minute)) {
+        t.Errorf("delivery 2 result = %+v, want a retry in a minute", got)
+    }
+}
+
+func TestDeliverRejectsInternalAddresses(t *testing.T) {
+    var hits int
+    receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        hits++
+    }))
+    defer receiver.Close()
+
+    store := newFakeStore(receiver.URL, 1)
+    if _, err := NewDispatcher(testConfig, store).DeliverPending(context.Background()); err != nil {
+        t.Fatalf("DeliverPending: %v", err)
+    }
+    if got := store.results[1]; got.Status != models.DeliveryFailed || got.Error != errForbiddenAddress.Error() || got.RetryAt != nil {
+        t.Errorf("loopback delivery result = %+v, want failed without retry", got)
+    }
+    if hits != 0 {
+        t.Errorf("receiver on loopback got %d requests", hits)
+    }
+}
+
+func TestCheckAddress(t *testing.T) {
+    forbidden := []string{
+        "127.0.0.1:80", "10.1.2.3:443", "172.16.0.1:80", "192.168.1.1:80", "169.254.169.254:80",
+        "100.64.0.1:80", "0.0.0.0:80", "[::1]:443", "[fd00::1]:443", "[fe80::1]:443", "[::ffff:10.0.0.1]:80",
+    }
+    for _, address := range forbidden {
+        if err := checkAddress("tcp", address, nil); err == nil {
+            t.Errorf("checkAddress(%s) allowed an internal address", address)
+        }
+    }
+    for _, address := range []string{"93.184.216.34:443", "[2606:4700:4700::1111]:443"} {
+        if err := checkAddress("tcp", address, nil); err != nil {
+            t.Errorf("checkAddress(%s) = %v", address, err)
+        }
+    }
+}
@@ -0,0 +1,52 @@
+// Package webhook отправляет события пользователей на их вебхуки.
+//
+// Каждое событие уходит POST-запросом с JSON-телом Payload и заголовками:
+//
+//	X-Webhook-Id         id доставки; повторные попытки приходят с тем же id
+//	X-Webhook-Event      тип события
+//	X-Webhook-Timestamp  время отправки, секунды Unix
+//	X-Webhook-Signature  "sha256=" и hex HMAC-SHA256 от "<timestamp>.<тело>"
+//
+// Получатель проверяет подпись ключом вебхука (Verify) и отвечает 2xx;
+// любой другой ответ или ошибка соединения приводят к повтору.
+package webhook
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "time"
+)
+
+const (
+    HeaderID        = "X-Webhook-Id"
+    HeaderEvent     = "X-Webhook-Event"
+    HeaderTimestamp = "X-Webhook-Timestamp"
+    HeaderSignature = "X-Webhook-Signature"
+
+    signaturePrefix = "sha256="
+)
+
+// Payload — тело запроса. Data совпадает с данными события в потоке
+// /api/notifications/stream.
+type Payload struct {
+    ID        uint            `json:"id"`
+    Event     string          `json:"event"`
+    CreatedAt time.Time       `json:"created_at"`
+    Data      json.RawMessage `json:"data"`
+}
+
+// Sign возвращает значение заголовка X-Webhook-Signature.
+func Sign(secret, timestamp string, body []byte) string {
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write([]byte(timestamp))
+    mac.Write([]byte("."))
+    mac.Write(body)
+    return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись запроса за постоянное время.
+func Verify(secret, timestamp, signature string, body []byte) bool {
+    return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
@@ -11,6 +11,8 @@ class Task {
   final int priority;
   final DateTime createdAt;
   final DateTime updatedAt;
+  // Версия растёт при каждом изменении; передаётся в If-Match
+  final int version;
   final Category? category;
 
   Task({
@@ -23,6 +25,7 @@ class Task {
     required this.priority,
     required this.createdAt,
     required this.updatedAt,
+    this.version = 0,
     this.category,
   });
 
@@ -37,6 +40,7 @@ class Task {
       priority: json['priority'],
       createdAt: DateTime.parse(json['created_at']),
       updatedAt: DateTime.parse(json['updated_at']),
+      version: json['version'] ?? 0,
       category: json['category'] != null ? Category.fromJson(json['category']) : null,
     );
   }
@@ -52,6 +56,7 @@ class Task {
       'priority': priority,
       'created_at': createdAt.toIso8601String(),
       'updated_at': updatedAt.toIso8601String(),
+      'version': version,
       'category': category?.toJson(),
     };
   }
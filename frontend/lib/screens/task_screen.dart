@@ -108,6 +108,7 @@ class TaskScreenState extends State<TaskScreen> {
           _selectedDueDate,
           _selectedPriority,
           category: _selectedCategory,
+          version: _editingTask!.version,
         );
         setState(() {
           final index = _tasks.indexWhere((t) => t.id == _editingTask!.id);
@@ -167,13 +168,9 @@ class TaskScreenState extends State<TaskScreen> {
 
   Future<void> _toggleTaskCompletion(Task task) async {
     try {
-      final updatedTask = await _taskService.updateTask(
+      final updatedTask = await _taskService.patchTask(
         task.id,
-        task.title,
-        task.description,
-        !task.completed,
-        task.dueDate,
-        task.priority,
+        {'completed': !task.completed},
       );
       setState(() {
         final index = _tasks.indexWhere((t) => t.id == task.id);
@@ -5,6 +5,8 @@ import 'package:shared_preferences/shared_preferences.dart';
 class AuthService {
   static const baseUrl = 'http://localhost:8080/api/auth';
   static const tokenKey = 'auth_token';
+  static const refreshTokenKey = 'refresh_token';
+  static const expiresAtKey = 'auth_token_expires_at';
 
   Future<String> login(String email, String password) async {
     final response = await http.post(
@@ -18,9 +20,8 @@ class AuthService {
 
     if (response.statusCode == 200) {
       final data = jsonDecode(response.body);
-      final token = data['token'];
-      await _saveToken(token);
-      return token;
+      await _saveTokens(data);
+      return data['token'];
     } else {
       throw Exception('Failed to login');
     }
@@ -39,21 +40,68 @@ class AuthService {
 
     if (response.statusCode == 200) {
       final data = jsonDecode(response.body);
-      final token = data['token'];
-      await _saveToken(token);
-      return token;
+      await _saveTokens(data);
+      return data['token'];
     } else {
       throw Exception('Failed to register');
     }
   }
 
-  Future<void> _saveToken(String token) async {
+  Future<void> logout({bool allDevices = false}) async {
+    final token = await getToken();
+    if (token != null) {
+      await http.post(
+        Uri.parse(allDevices ? '$baseUrl/logout-all' : '$baseUrl/logout'),
+        headers: {'Authorization': 'Bearer $token'},
+      );
+    }
+    await _clearTokens();
+  }
+
+  static Future<void> _saveTokens(Map<String, dynamic> data) async {
     final prefs = await SharedPreferences.getInstance();
-    await prefs.setString(tokenKey, token);
+    final expiresAt = DateTime.now().add(Duration(seconds: data['expires_in']));
+    await prefs.setString(tokenKey, data['token']);
+    await prefs.setString(refreshTokenKey, data['refresh_token']);
+    await prefs.setInt(expiresAtKey, expiresAt.millisecondsSinceEpoch);
   }
 
+  static Future<void> _clearTokens() async {
+    final prefs = await SharedPreferences.getInstance();
+    await prefs.remove(tokenKey);
+    await prefs.remove(refreshTokenKey);
+    await prefs.remove(expiresAtKey);
+  }
+
+  // Возвращает действующий access-токен, при необходимости обновляя его
+  // по refresh-токену незадолго до истечения.
   static Future<String?> getToken() async {
     final prefs = await SharedPreferences.getInstance();
-    return prefs.getString(tokenKey);
+    final token = prefs.getString(tokenKey);
+    final refreshToken = prefs.getString(refreshTokenKey);
+    final expiresAt = prefs.getInt(expiresAtKey);
+    if (token == null || refreshToken == null || expiresAt == null) {
+      return token;
+    }
+
+    final refreshAt = DateTime.fromMillisecondsSinceEpoch(expiresAt)
+        .subtract(const Duration(seconds: 30));
+    if (DateTime.now().isBefore(refreshAt)) {
+      return token;
+    }
+
+    final response = await http.post(
+      Uri.parse('$baseUrl/refresh'),
+      headers: {'Content-Type': 'application/json'},
+      body: jsonEncode({'refresh_token': refreshToken}),
+    );
+    if (response.statusCode != 200) {
+      await _clearTokens();
+      return null;
+    }
+
+    final data = jsonDecode(response.body);
+    await _saveTokens(data);
+    return data['token'];
   }
-} 
\ No newline at end of file
+}
\ No newline at end of file
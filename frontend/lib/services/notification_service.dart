@@ -1,4 +1,5 @@
 import 'dart:convert';
+import 'dart:ui' show PlatformDispatcher;
 import 'package:http/http.dart' as http;
 import 'auth_service.dart';
 
@@ -6,6 +7,10 @@ class TaskNotification {
   final int id;
   final int userId;
   final int taskId;
+  // type и params позволяют перерисовать уведомление; message сервер
+  // рендерит на языке пользователя.
+  final String type;
+  final Map<String, dynamic> params;
   final String message;
   final DateTime createdAt;
   final bool read;
@@ -14,6 +19,8 @@ class TaskNotification {
     required this.id,
     required this.userId,
     required this.taskId,
+    required this.type,
+    required this.params,
     required this.message,
     required this.createdAt,
     required this.read,
@@ -24,6 +31,8 @@ class TaskNotification {
       id: json['id'],
       userId: json['user_id'],
       taskId: json['task_id'],
+      type: json['type'] ?? 'message',
+      params: Map<String, dynamic>.from(json['params'] ?? {}),
       message: json['message'],
       createdAt: DateTime.parse(json['created_at']),
       read: json['read'],
@@ -31,25 +40,39 @@ class TaskNotification {
   }
 }
 
+class NotificationEvent {
+  final int id;
+  final String type;
+  final Map<String, dynamic> data;
+
+  NotificationEvent({required this.id, required this.type, required this.data});
+}
+
 class NotificationService {
   static const baseUrl = 'http://localhost:8080/api/notifications';
 
+  static String get _language => PlatformDispatcher.instance.locale.toLanguageTag();
+
   Future<Map<String, String>> _getHeaders() async {
     final token = await AuthService.getToken();
     return {
       'Content-Type': 'application/json',
       'Authorization': 'Bearer $token',
+      'Accept-Language': _language,
     };
   }
 
-  Future<List<TaskNotification>> getNotifications() async {
+  // Сервер отдаёт уведомления страницами; виджету достаточно первой.
+  Future<List<TaskNotification>> getNotifications({bool unreadOnly = false}) async {
     final response = await http.get(
-      Uri.parse(baseUrl),
+      Uri.parse(baseUrl).replace(queryParameters: {
+        if (unreadOnly) 'unread_only': 'true',
+      }),
       headers: await _getHeaders(),
     );
 
     if (response.statusCode == 200) {
-      final List<dynamic> data = jsonDecode(response.body);
+      final List<dynamic> data = jsonDecode(response.body)['notifications'];
       return data.map((json) => TaskNotification.fromJson(json)).toList();
     } else {
       throw Exception('Failed to load notifications');
@@ -66,4 +89,88 @@ class NotificationService {
       throw Exception('Failed to mark notification as read');
     }
   }
-} 
\ No newline at end of file
+
+  Future<void> markAllAsRead() async {
+    final response = await http.post(
+      Uri.parse('$baseUrl/read-all'),
+      headers: await _getHeaders(),
+    );
+
+    if (response.statusCode != 200) {
+      throw Exception('Failed to mark notifications as read');
+    }
+  }
+
+  Future<void> deleteNotification(int id) async {
+    final response = await http.delete(
+      Uri.parse('$baseUrl/$id'),
+      headers: await _getHeaders(),
+    );
+
+    if (response.statusCode != 204) {
+      throw Exception('Failed to delete notification');
+    }
+  }
+
+  // Откладывает уведомление: сервер создаст новое напоминание о задаче.
+  Future<void> snooze(int id, {int minutes = 10}) async {
+    final response = await http.post(
+      Uri.parse('$baseUrl/$id/snooze'),
+      headers: await _getHeaders(),
+      body: jsonEncode({'minutes': minutes}),
+    );
+
+    if (response.statusCode != 201) {
+      throw Exception('Failed to snooze notification');
+    }
+  }
+
+  // Поток событий сервера (SSE). EventSource не умеет передавать заголовок
+  // Authorization, поэтому поток читается через http.Client.
+  Stream<NotificationEvent> events({int? lastEventId}) async* {
+    final request = http.Request('GET', Uri.parse('$baseUrl/stream'));
+    final token = await AuthService.getToken();
+    request.headers['Authorization'] = 'Bearer $token';
+    request.headers['Accept'] = 'text/event-stream';
+    request.headers['Accept-Language'] = _language;
+    if (lastEventId != null) {
+      request.headers['Last-Event-ID'] = '$lastEventId';
+    }
+
+    final client = http.Client();
+    try {
+      final response = await client.send(request);
+      if (response.statusCode != 200) {
+        throw Exception('Failed to open notification stream');
+      }
+
+      int? id;
+      String? type;
+      final data = StringBuffer();
+      final lines = response.stream
+          .transform(utf8.decoder)
+          .transform(const LineSplitter());
+      await for (final line in lines) {
+        if (line.isEmpty) {
+          if (id != null && type != null && data.isNotEmpty) {
+            yield NotificationEvent(
+              id: id,
+              type: type,
+              data: jsonDecode(data.toString()),
+            );
+          }
+          type = null;
+          data.clear();
+        } else if (line.startsWith('id: ')) {
+          id = int.tryParse(line.substring(4));
+        } else if (line.startsWith('event: ')) {
+          type = line.substring(7);
+        } else if (line.startsWith('data: ')) {
+          data.write(line.substring(6));
+        }
+      }
+    } finally {
+      client.close();
+    }
+  }
+}
@@ -19,18 +19,29 @@ class TaskService {
     final url = category != null 
         ? 'http://localhost:8080/api/categories/${category.id}/tasks'
         : baseUrl;
-    
-    final response = await http.get(
-      Uri.parse(url),
-      headers: await _getHeaders(),
-    );
 
-    if (response.statusCode == 200) {
-      final List<dynamic> data = jsonDecode(response.body);
-      return data.map((json) => Task.fromJson(json)).toList();
-    } else {
-      throw Exception('Failed to load tasks');
-    }
+    final tasks = <Task>[];
+    String? cursor;
+    do {
+      final uri = Uri.parse(url).replace(
+        queryParameters: cursor != null ? {'cursor': cursor} : null,
+      );
+      final response = await http.get(
+        uri,
+        headers: await _getHeaders(),
+      );
+
+      if (response.statusCode != 200) {
+        throw Exception('Failed to load tasks');
+      }
+
+      final Map<String, dynamic> data = jsonDecode(response.body);
+      final List<dynamic> items = data['tasks'];
+      tasks.addAll(items.map((json) => Task.fromJson(json)));
+      cursor = data['next_cursor'];
+    } while (cursor != null);
+
+    return tasks;
   }
 
   Future<Task> createTask(String title, String description, DateTime dueDate, int priority, {Category? category}) async {
@@ -53,10 +64,16 @@ class TaskService {
     }
   }
 
-  Future<Task> updateTask(int id, String title, String description, bool completed, DateTime dueDate, int priority, {Category? category}) async {
+  // version — версия, которую видел пользователь: если задачу успели изменить
+  // на другом устройстве, сервер отвечает 412 и изменение не сохраняется.
+  Future<Task> updateTask(int id, String title, String description, bool completed, DateTime dueDate, int priority, {Category? category, int? version}) async {
+    final headers = await _getHeaders();
+    if (version != null && version > 0) {
+      headers['If-Match'] = '"$version"';
+    }
     final response = await http.put(
       Uri.parse('$baseUrl/$id'),
-      headers: await _getHeaders(),
+      headers: headers,
       body: jsonEncode({
         'title': title,
         'description': description,
@@ -67,6 +84,26 @@ class TaskService {
       }),
     );
 
+    if (response.statusCode == 200) {
+      return Task.fromJson(jsonDecode(response.body));
+    } else if (response.statusCode == 412) {
+      throw Exception('Task was changed on another device, reload it and try again');
+    } else {
+      throw Exception('Failed to update task');
+    }
+  }
+
+  // Меняет только переданные поля (JSON Merge Patch); null сбрасывает
+  // category_id, parent_id, recurrence и description.
+  Future<Task> patchTask(int id, Map<String, dynamic> changes) async {
+    final headers = await _getHeaders();
+    headers['Content-Type'] = 'application/merge-patch+json';
+    final response = await http.patch(
+      Uri.parse('$baseUrl/$id'),
+      headers: headers,
+      body: jsonEncode(changes),
+    );
+
     if (response.statusCode == 200) {
       return Task.fromJson(jsonDecode(response.body));
     } else {
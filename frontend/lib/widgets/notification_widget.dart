@@ -1,3 +1,4 @@
+import 'dart:async';
 import 'package:flutter/material.dart';
 import '../services/notification_service.dart';
 import 'package:intl/intl.dart';
@@ -13,23 +14,47 @@ class NotificationWidgetState extends State<NotificationWidget> {
   final NotificationService _notificationService = NotificationService();
   List<TaskNotification> _notifications = [];
   bool _isLoading = false;
+  StreamSubscription<NotificationEvent>? _subscription;
+  int? _lastEventId;
 
   @override
   void initState() {
     super.initState();
     _loadNotifications();
-    _startPeriodicUpdate();
+    _listen();
   }
 
-  void _startPeriodicUpdate() {
-    Future.delayed(const Duration(minutes: 1), () {
-      if (mounted) {
-        _loadNotifications();
-        _startPeriodicUpdate();
-      }
+  @override
+  void dispose() {
+    _subscription?.cancel();
+    super.dispose();
+  }
+
+  void _listen() {
+    _subscription = _notificationService
+        .events(lastEventId: _lastEventId)
+        .listen(_onEvent,
+            onError: (_) => _reconnect(),
+            onDone: _reconnect,
+            cancelOnError: true);
+  }
+
+  // После обрыва переподключаемся с последнего полученного события
+  void _reconnect() {
+    Future.delayed(const Duration(seconds: 5), () {
+      if (mounted) _listen();
     });
   }
 
+  void _onEvent(NotificationEvent event) {
+    _lastEventId = event.id;
+    if (event.type == 'notification.created' && mounted) {
+      setState(() {
+        _notifications.insert(0, TaskNotification.fromJson(event.data));
+      });
+    }
+  }
+
   Future<void> _loadNotifications() async {
     if (!mounted) return;
     setState(() {
@@ -69,6 +94,48 @@ class NotificationWidgetState extends State<NotificationWidget> {
     }
   }
 
+  Future<void> _markAllAsRead() async {
+    try {
+      await _notificationService.markAllAsRead();
+      await _loadNotifications();
+    } catch (e) {
+      if (mounted) {
+        ScaffoldMessenger.of(context).showSnackBar(
+          SnackBar(content: Text(e.toString())),
+        );
+      }
+    }
+  }
+
+  Future<void> _delete(TaskNotification notification) async {
+    setState(() {
+      _notifications.remove(notification);
+    });
+    try {
+      await _notificationService.deleteNotification(notification.id);
+    } catch (e) {
+      await _loadNotifications();
+      if (mounted) {
+        ScaffoldMessenger.of(context).showSnackBar(
+          SnackBar(content: Text(e.toString())),
+        );
+      }
+    }
+  }
+
+  Future<void> _snooze(TaskNotification notification) async {
+    try {
+      await _notificationService.snooze(notification.id);
+      await _loadNotifications();
+    } catch (e) {
+      if (mounted) {
+        ScaffoldMessenger.of(context).showSnackBar(
+          SnackBar(content: Text(e.toString())),
+        );
+      }
+    }
+  }
+
   @override
   Widget build(BuildContext context) {
     if (_isLoading) {
@@ -79,24 +146,63 @@ class NotificationWidgetState extends State<NotificationWidget> {
       return const Center(child: Text('Нет оповещений'));
     }
 
+    final hasUnread = _notifications.any((n) => !n.read);
+    return Column(
+      children: [
+        if (hasUnread)
+          Align(
+            alignment: Alignment.centerRight,
+            child: TextButton.icon(
+              icon: const Icon(Icons.done_all),
+              label: const Text('Прочитать все'),
+              onPressed: _markAllAsRead,
+            ),
+          ),
+        Expanded(child: _buildList()),
+      ],
+    );
+  }
+
+  Widget _buildList() {
     return ListView.builder(
       itemCount: _notifications.length,
       itemBuilder: (context, index) {
         final notification = _notifications[index];
-        return Card(
-          margin: const EdgeInsets.symmetric(horizontal: 8.0, vertical: 4.0),
-          color: notification.read ? null : Colors.blue.shade50,
-          child: ListTile(
-            title: Text(notification.message),
-            subtitle: Text(
-              DateFormat('dd.MM.yyyy HH:mm').format(notification.createdAt),
+        return Dismissible(
+          key: ValueKey(notification.id),
+          direction: DismissDirection.endToStart,
+          onDismissed: (_) => _delete(notification),
+          background: Container(
+            color: Colors.red,
+            alignment: Alignment.centerRight,
+            padding: const EdgeInsets.symmetric(horizontal: 16.0),
+            child: const Icon(Icons.delete, color: Colors.white),
+          ),
+          child: Card(
+            margin: const EdgeInsets.symmetric(horizontal: 8.0, vertical: 4.0),
+            color: notification.read ? null : Colors.blue.shade50,
+            child: ListTile(
+              title: Text(notification.message),
+              subtitle: Text(
+                DateFormat('dd.MM.yyyy HH:mm').format(notification.createdAt),
+              ),
+              trailing: notification.read
+                  ? null
+                  : Row(
+                      mainAxisSize: MainAxisSize.min,
+                      children: [
+                        IconButton(
+                          icon: const Icon(Icons.snooze),
+                          tooltip: 'Напомнить через 10 минут',
+                          onPressed: () => _snooze(notification),
+                        ),
+                        IconButton(
+                          icon: const Icon(Icons.check_circle_outline),
+                          onPressed: () => _markAsRead(notification),
+                        ),
+                      ],
+                    ),
             ),
-            trailing: notification.read
-                ? null
-                : IconButton(
-                    icon: const Icon(Icons.check_circle_outline),
-                    onPressed: () => _markAsRead(notification),
-                  ),
           ),
         );
       },